@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"os"
 	"time"
+	_ "time/tzdata" // booking series use IANA timezones, an image can lack them
 
 	"github.com/go-chi/httplog/v2"
 	"github.com/go-viper/mapstructure/v2"
@@ -38,6 +39,7 @@ func main() {
 		DecoderConfig: &mapstructure.DecoderConfig{
 			ErrorUnset:  true,
 			ErrorUnused: true,
+			DecodeHook:  mapstructure.StringToTimeDurationHookFunc(),
 			Result:      &config,
 		},
 	}
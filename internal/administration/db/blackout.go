@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/optician/meeting-room-booking/internal/administration/models"
+)
+
+// blackouts are joined with the office they apply to, directly or through their room
+const blackoutColumns = `b.id, b.office_id, b.room_id, b.reason, b.start_at as start, b.end_at as "end", b.yearly,
+	o.name as office, o.timezone`
+
+// all blackouts are stored in the same transaction or none
+func (impl *impl) CreateBlackouts(ctx context.Context, blackouts []models.NewBlackout) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(blackouts))
+	err := pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		query := `insert into blackouts (id, office_id, room_id, reason, start_at, end_at, yearly)
+					values (@id, @office_id, @room_id, @reason, @start_at, @end_at, @yearly)`
+		for _, blackout := range blackouts {
+			id := uuid.New()
+			args := pgx.NamedArgs{
+				"id":        id,
+				"office_id": blackout.OfficeId,
+				"room_id":   blackout.RoomId,
+				"reason":    blackout.Reason,
+				"start_at":  blackout.Start,
+				"end_at":    blackout.End,
+				"yearly":    blackout.Yearly,
+			}
+			if _, err := tx.Exec(ctx, query, args); err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		return nil
+	})
+	if isViolation(err, foreignKeyViolation) {
+		return nil, models.ErrBlackoutTargetNotFound
+	}
+	return ids, domainError(err)
+}
+
+func (impl *impl) DeleteBlackout(ctx context.Context, id *uuid.UUID) error {
+	tag, err := impl.dbpool.Exec(ctx, "delete from blackouts where id = @id", pgx.NamedArgs{"id": id})
+	if err == nil && tag.RowsAffected() == 0 {
+		return models.ErrBlackoutNotFound
+	}
+	return domainError(err)
+}
+
+// blackouts of the room and of its office overlapping the window, yearly ones are always returned
+// slice can't be nil if error is nil
+func (impl *impl) RoomBlackouts(ctx context.Context, roomId *uuid.UUID, window *models.TimeWindow) ([]models.Blackout, error) {
+	list := make([]models.Blackout, 0)
+	query := `select ` + blackoutColumns + `
+				from meeting_rooms r
+				join offices o on o.name = r.office
+				join blackouts b on b.room_id = r.id or b.office_id = o.id
+				where r.id = @id
+				  and (b.yearly or tstzrange(b.start_at, b.end_at) && tstzrange(@from, @to))
+				order by b.start_at`
+	args := pgx.NamedArgs{"id": roomId, "from": window.From, "to": window.To}
+	err := pgxscan.Select(ctx, impl.dbpool, &list, query, args)
+	if err == nil && len(list) == 0 {
+		var exists bool
+		query = "select exists (select 1 from meeting_rooms where id = @id)"
+		if err = impl.dbpool.QueryRow(ctx, query, args).Scan(&exists); err == nil && !exists {
+			err = models.ErrRoomNotFound
+		}
+	}
+	return list, domainError(err)
+}
+
+// blackouts which may overlap the window, yearly ones are always returned
+// slice can't be nil if error is nil
+func (impl *impl) Blackouts(ctx context.Context, window *models.TimeWindow) ([]models.Blackout, error) {
+	list := make([]models.Blackout, 0)
+	query := `select ` + blackoutColumns + `
+				from blackouts b
+				left join meeting_rooms r on r.id = b.room_id
+				join offices o on o.id = b.office_id or o.name = r.office
+				where b.yearly or tstzrange(b.start_at, b.end_at) && tstzrange(@from, @to)`
+	args := pgx.NamedArgs{"from": window.From, "to": window.To}
+	err := pgxscan.Select(ctx, impl.dbpool, &list, query, args)
+	return list, domainError(err)
+}
+
+// the office of the room, it tells the clock of the room
+func (impl *impl) RoomOffice(ctx context.Context, roomId *uuid.UUID) (models.Office, error) {
+	var office models.Office
+	query := `select o.id, o.name, o.address, o.timezone, o.opening_hours, o.floors
+				from meeting_rooms r
+				join offices o on o.name = r.office
+				where r.id = @id`
+	err := pgxscan.Get(ctx, impl.dbpool, &office, query, pgx.NamedArgs{"id": roomId})
+	if pgxscan.NotFound(err) {
+		return office, models.ErrRoomNotFound
+	}
+	return office, domainError(err)
+}
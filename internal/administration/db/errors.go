@@ -0,0 +1,57 @@
+package db
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/optician/meeting-room-booking/internal/administration/models"
+)
+
+// postgres error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+// postgres error classes of an unreachable, overloaded or restarting server
+var unavailableClasses = []string{
+	"08",  // connection exception
+	"53",  // insufficient resources
+	"57P", // operator intervention, e.g. admin shutdown
+}
+
+const roomNameConstraint = "meeting_rooms_name_key"
+
+// domainError turns postgres and connection failures into domain errors, domain errors are kept as is
+func domainError(err error) error {
+	var pgErr *pgconn.PgError
+	var connectErr *pgconn.ConnectError
+	switch {
+	case err == nil, models.KindOf(err) != models.KindUnknown:
+		return err
+	case errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == roomNameConstraint:
+		return models.ErrRoomNameTaken
+	case errors.As(err, &pgErr) && isUnavailableClass(pgErr.Code):
+		return models.Unavailable(err)
+	// timeouts and failures before a query reached the server
+	case errors.As(err, &connectErr), pgconn.Timeout(err), pgconn.SafeToRetry(err):
+		return models.Unavailable(err)
+	default:
+		return err
+	}
+}
+
+func isUnavailableClass(code string) bool {
+	for _, class := range unavailableClasses {
+		if strings.HasPrefix(code, class) {
+			return true
+		}
+	}
+	return false
+}
+
+func isViolation(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/optician/meeting-room-booking/internal/administration/models"
+)
+
+const officeColumns = "id, name, address, timezone, opening_hours, floors"
+
+func (impl *impl) CreateOffice(ctx context.Context, office *models.NewOffice) (uuid.UUID, error) {
+	id := uuid.New()
+	query := `insert into offices (id, name, address, timezone, opening_hours, floors)
+				values (@id, @name, @address, @timezone, @opening_hours, @floors)`
+	args := pgx.NamedArgs{
+		"id":            id,
+		"name":          office.Name,
+		"address":       office.Address,
+		"timezone":      office.Timezone,
+		"opening_hours": office.OpeningHours,
+		"floors":        office.Floors,
+	}
+	_, err := impl.dbpool.Exec(ctx, query, args)
+	if isViolation(err, uniqueViolation) {
+		return id, models.ErrOfficeNameTaken
+	}
+	return id, domainError(err)
+}
+
+// a renamed office renames it in its rooms, floors with rooms can't be removed
+func (impl *impl) UpdateOffice(ctx context.Context, office *models.Office) error {
+	err := pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		// waits for rooms being written against the current floors
+		var exists bool
+		query := "select true from offices where id = @id for update"
+		if err := tx.QueryRow(ctx, query, pgx.NamedArgs{"id": office.Id}).Scan(&exists); errors.Is(err, pgx.ErrNoRows) {
+			return models.ErrOfficeNotFound
+		} else if err != nil {
+			return err
+		}
+
+		query = `update offices
+					set
+						name = @name,
+						address = @address,
+						timezone = @timezone,
+						opening_hours = @opening_hours,
+						floors = @floors
+					where id = @id`
+		args := pgx.NamedArgs{
+			"id":            office.Id,
+			"name":          office.Name,
+			"address":       office.Address,
+			"timezone":      office.Timezone,
+			"opening_hours": office.OpeningHours,
+			"floors":        office.Floors,
+		}
+		if _, err := tx.Exec(ctx, query, args); err != nil {
+			return err
+		}
+
+		var misplaced bool
+		query = "select exists (select 1 from meeting_rooms where office = @name and stage <> all(@floors::int[]))"
+		if err := tx.QueryRow(ctx, query, args).Scan(&misplaced); err != nil {
+			return err
+		} else if misplaced {
+			return models.ErrFloorInUse
+		}
+		return nil
+	})
+	if isViolation(err, uniqueViolation) {
+		return models.ErrOfficeNameTaken
+	}
+	return domainError(err)
+}
+
+// slice can't be nil if error is nil
+func (impl *impl) Offices(ctx context.Context) ([]models.Office, error) {
+	list := make([]models.Office, 0)
+	query := "select " + officeColumns + " from offices order by name"
+	err := pgxscan.Select(ctx, impl.dbpool, &list, query)
+	return list, domainError(err)
+}
+
+func (impl *impl) Office(ctx context.Context, id *uuid.UUID) (models.Office, error) {
+	var office models.Office
+	query := "select " + officeColumns + " from offices where id = @id"
+	err := pgxscan.Get(ctx, impl.dbpool, &office, query, pgx.NamedArgs{"id": id})
+	if pgxscan.NotFound(err) {
+		return office, models.ErrOfficeNotFound
+	}
+	return office, domainError(err)
+}
+
+// an office with rooms can't be deleted
+func (impl *impl) DeleteOffice(ctx context.Context, id *uuid.UUID) error {
+	tag, err := impl.dbpool.Exec(ctx, "delete from offices where id = @id", pgx.NamedArgs{"id": id})
+	switch {
+	case isViolation(err, foreignKeyViolation):
+		return models.ErrOfficeInUse
+	case err == nil && tag.RowsAffected() == 0:
+		return models.ErrOfficeNotFound
+	default:
+		return domainError(err)
+	}
+}
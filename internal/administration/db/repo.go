@@ -2,22 +2,61 @@ package db
 
 import (
 	"context"
+	"errors"
+	"strings"
+	"time"
 
 	"github.com/georgysavva/scany/v2/pgxscan"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/optician/meeting-room-booking/internal/administration/models"
+	outboxDB "github.com/optician/meeting-room-booking/internal/outbox/db"
+	outbox "github.com/optician/meeting-room-booking/internal/outbox/models"
 	"go.uber.org/zap"
 )
 
 type DB interface {
-	List(context.Context) ([]models.RoomInfo, error)
+	// a page of rooms matching the query
+	List(context.Context, *models.RoomQuery) (models.RoomPage, error)
+	Room(context.Context, *uuid.UUID) (models.RoomInfo, error)
+	// the room version must be the stored one, it grows by one.
+	// The room is validated against its office in the same transaction, a broken rule is a ValidationError.
 	Update(context.Context, *models.RoomInfo) error
+	// the room is validated against its office in the same transaction, a broken rule is a ValidationError
 	Create(context.Context, *models.NewRoomInfo) (uuid.UUID, error)
-	Delete(context.Context, *uuid.UUID) error
+	// the version must be the stored one
+	Delete(ctx context.Context, id *uuid.UUID, version int64) error
+	ListWithTimetable(context.Context, *models.TimeWindow) ([]models.RoomTimetable, error)
+	Timetable(context.Context, *uuid.UUID, *models.TimeWindow) (models.RoomTimetable, error)
+	FindFree(context.Context, *models.FreeRoomQuery) ([]models.RoomInfo, error)
+	// bookings whose buffered interval overlaps the window
+	Availability(context.Context, *uuid.UUID, *models.TimeWindow) (models.Availability, error)
+	// cancels current and future bookings of the room in the same transaction
+	Lock(ctx context.Context, roomId *uuid.UUID, lock *models.NewRoomLock, at time.Time) (models.LockResult, error)
+	Unlock(ctx context.Context, roomId *uuid.UUID, unlock *models.Unlock, at time.Time) (models.RoomLock, error)
+	// all locks of the room, the latest first
+	Locks(ctx context.Context, roomId *uuid.UUID) ([]models.RoomLock, error)
+	CreateOffice(context.Context, *models.NewOffice) (uuid.UUID, error)
+	UpdateOffice(context.Context, *models.Office) error
+	Offices(context.Context) ([]models.Office, error)
+	Office(context.Context, *uuid.UUID) (models.Office, error)
+	DeleteOffice(context.Context, *uuid.UUID) error
+	CreateBlackouts(context.Context, []models.NewBlackout) ([]uuid.UUID, error)
+	DeleteBlackout(context.Context, *uuid.UUID) error
+	// blackouts of the room and of its office, yearly ones regardless of the window
+	RoomBlackouts(context.Context, *uuid.UUID, *models.TimeWindow) ([]models.Blackout, error)
+	// blackouts of every room and office which may overlap the window, yearly ones regardless of it
+	Blackouts(context.Context, *models.TimeWindow) ([]models.Blackout, error)
+	RoomOffice(context.Context, *uuid.UUID) (models.Office, error)
 }
 
+// the same values as booking event types and actors, the administration doesn't depend on the booking module
+const (
+	roomLockedEvent = "room_locked"
+	systemActor     = "system"
+)
+
 type impl struct {
 	logger *zap.SugaredLogger
 	dbpool *pgxpool.Pool
@@ -30,70 +69,504 @@ func New(dbPool *pgxpool.Pool, logger *zap.SugaredLogger) DB {
 	}
 }
 
+// keyset pagination, the room id breaks ties of the sort key
+var roomOrders = map[models.RoomSort]struct{ orderBy, after string }{
+	models.SortByName:         {"r.name, r.id", "(r.name, r.id) > (@cursor_name, @cursor_id::uuid)"},
+	models.SortByNameDesc:     {"r.name desc, r.id desc", "(r.name, r.id) < (@cursor_name, @cursor_id::uuid)"},
+	models.SortByCapacity:     {"r.capacity, r.id", "(r.capacity, r.id) > (@cursor_capacity, @cursor_id::uuid)"},
+	models.SortByCapacityDesc: {"r.capacity desc, r.id desc", "(r.capacity, r.id) < (@cursor_capacity, @cursor_id::uuid)"},
+}
+
+// only filters in use are added to the query, so the planner sees plain conditions and can use indexes
 // slice can't be nil if error is nil
-func (impl *impl) List(ctx context.Context) ([]models.RoomInfo, error) {
-	list := make([]models.RoomInfo, 0)
-	query := "select id, name, capacity, office, stage, labels from meeting_rooms"
-	err := pgxscan.Select(ctx, impl.dbpool, &list, query)
-	return list, err // wrap error
+func (impl *impl) List(ctx context.Context, search *models.RoomQuery) (models.RoomPage, error) {
+	page := models.RoomPage{Rooms: make([]models.RoomInfo, 0)}
+	order := roomOrders[search.Sort]
+	conditions := make([]string, 0)
+	args := pgx.NamedArgs{"limit": search.PageSize + 1}
+	if search.Office != "" {
+		conditions = append(conditions, "r.office = @office")
+		args["office"] = search.Office
+	}
+	if search.Stage != nil {
+		conditions = append(conditions, "r.stage = @stage")
+		args["stage"] = *search.Stage
+	}
+	if search.MinCapacity != nil {
+		conditions = append(conditions, "r.capacity >= @min_capacity")
+		args["min_capacity"] = *search.MinCapacity
+	}
+	if search.MaxCapacity != nil {
+		conditions = append(conditions, "r.capacity <= @max_capacity")
+		args["max_capacity"] = *search.MaxCapacity
+	}
+	if len(search.Labels) > 0 && search.LabelsMatch == models.MatchAnyLabel {
+		conditions = append(conditions, "r.labels && @labels::text[]")
+		args["labels"] = search.Labels
+	} else if len(search.Labels) > 0 {
+		conditions = append(conditions, "r.labels @> @labels::text[]")
+		args["labels"] = search.Labels
+	}
+	if search.NamePrefix != "" {
+		conditions = append(conditions, "r.name ilike @name_prefix")
+		args["name_prefix"] = likeEscaper.Replace(search.NamePrefix) + "%"
+	}
+	if search.Cursor != nil {
+		conditions = append(conditions, order.after)
+		args["cursor_id"] = search.Cursor.Id
+		args["cursor_name"] = search.Cursor.Name
+		args["cursor_capacity"] = search.Cursor.Capacity
+	}
+
+	query := "select " + roomColumns + ", " + lockedColumn + " from meeting_rooms r"
+	if len(conditions) > 0 {
+		query += " where " + strings.Join(conditions, " and ")
+	}
+	query += " order by " + order.orderBy + " limit @limit"
+	if err := pgxscan.Select(ctx, impl.dbpool, &page.Rooms, query, args); err != nil {
+		return page, domainError(err)
+	}
+
+	// one more room is read to know whether there is a next page
+	if len(page.Rooms) > search.PageSize {
+		page.Rooms = page.Rooms[:search.PageSize]
+		next := models.CursorAfter(&page.Rooms[search.PageSize-1], search.Sort)
+		page.Next = &next
+	}
+	return page, nil
+}
+
+// wildcards of a name prefix are matched literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (impl *impl) Room(ctx context.Context, id *uuid.UUID) (models.RoomInfo, error) {
+	var room models.RoomInfo
+	query := "select " + roomColumns + ", " + lockedColumn + " from meeting_rooms r where r.id = @id"
+	err := pgxscan.Get(ctx, impl.dbpool, &room, query, pgx.NamedArgs{"id": id})
+	if pgxscan.NotFound(err) {
+		return room, models.ErrRoomNotFound
+	}
+	return room, domainError(err)
 }
 
 func (impl *impl) Update(ctx context.Context, room *models.RoomInfo) error {
-	query := `update meeting_rooms 
-				set 
-					name = @name,
-					capacity = @capacity,
-					office = @office,
-					stage = @stage,
-					labels = @labels
-				where id = @id`
-	args := pgx.NamedArgs{
-		"id":       room.Id,
-		"name":     room.Name,
-		"capacity": room.Capacity,
-		"office":   room.Office,
-		"stage":    room.Stage,
-		"labels":   room.Labels,
+	err := pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		if office, err := shareOffice(ctx, tx, room.Office); err != nil {
+			return err
+		} else if _, err := models.ValidateRoomInfo(room, office); err != nil {
+			return &models.ValidationError{Err: err}
+		}
+
+		query := `update meeting_rooms 
+					set 
+						name = @name,
+						capacity = @capacity,
+						office = @office,
+						stage = @stage,
+						labels = @labels,
+						buffer_before = @buffer_before,
+						buffer_after = @buffer_after,
+						version = version + 1
+					where id = @id and version = @version
+					returning version`
+		args := pgx.NamedArgs{
+			"id":            room.Id,
+			"version":       room.Version,
+			"name":          room.Name,
+			"capacity":      room.Capacity,
+			"office":        room.Office,
+			"stage":         room.Stage,
+			"labels":        room.Labels,
+			"buffer_before": room.BufferBefore,
+			"buffer_after":  room.BufferAfter,
+		}
+		updated := *room
+		if err := tx.QueryRow(ctx, query, args).Scan(&updated.Version); errors.Is(err, pgx.ErrNoRows) {
+			return staleOrMissing(ctx, tx, room.Id)
+		} else if err != nil {
+			return err
+		}
+		return outboxDB.Write(ctx, tx, outbox.RoomUpdated, room.Id, time.Now(), updated)
+	})
+	return domainError(err)
+}
+
+// shareOffice reads the office of a room and keeps its floors from changing until the room is written,
+// nil if there is no such office
+func shareOffice(ctx context.Context, tx pgx.Tx, name string) (*models.Office, error) {
+	var office models.Office
+	query := "select " + officeColumns + " from offices where name = @name for share"
+	if err := pgxscan.Get(ctx, tx, &office, query, pgx.NamedArgs{"name": name}); pgxscan.NotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
 	}
-	_, err := impl.dbpool.Exec(ctx, query, args)
-	return err // wrap error, check existance error
+	return &office, nil
+}
+
+// a change matched no row, either the room is gone or its version has moved on
+func staleOrMissing(ctx context.Context, tx pgx.Tx, id any) error {
+	var exists bool
+	query := "select exists (select 1 from meeting_rooms where id = @id)"
+	if err := tx.QueryRow(ctx, query, pgx.NamedArgs{"id": id}).Scan(&exists); err != nil {
+		return err
+	} else if exists {
+		return models.ErrRoomVersionStale
+	}
+	return models.ErrRoomNotFound
 }
 
 func (impl *impl) Create(ctx context.Context, room *models.NewRoomInfo) (uuid.UUID, error) {
 	id := uuid.New()
-	query := `insert into meeting_rooms 
-				(
-					id, 
-					name,  
-					capacity, 
-					office,
-					stage, 
-					labels 
-				)
-				values (
-					@id,
-					@name,
-					@capacity,
-					@office,
-					@stage,
-					@labels
+	err := pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		if office, err := shareOffice(ctx, tx, room.Office); err != nil {
+			return err
+		} else if _, err := models.ValidateNewRoomInfo(room, office); err != nil {
+			return &models.ValidationError{Err: err}
+		}
+
+		query := `insert into meeting_rooms 
+					(
+						id, 
+						name,  
+						capacity, 
+						office,
+						stage, 
+						labels,
+						buffer_before,
+						buffer_after
+					)
+					values (
+						@id,
+						@name,
+						@capacity,
+						@office,
+						@stage,
+						@labels,
+						@buffer_before,
+						@buffer_after
+					)
+					`
+		args := pgx.NamedArgs{
+			"id":            id,
+			"name":          room.Name,
+			"capacity":      room.Capacity,
+			"office":        room.Office,
+			"stage":         room.Stage,
+			"labels":        room.Labels,
+			"buffer_before": room.BufferBefore,
+			"buffer_after":  room.BufferAfter,
+		}
+		if _, err := tx.Exec(ctx, query, args); err != nil {
+			return err
+		}
+		created := models.RoomInfo{
+			Id:       id.String(),
+			Name:     room.Name,
+			Capacity: room.Capacity,
+			Office:   room.Office,
+			Stage:    room.Stage,
+			Labels:   room.Labels,
+
+			BufferBefore: room.BufferBefore,
+			BufferAfter:  room.BufferAfter,
+			Version:      1,
+		}
+		return outboxDB.Write(ctx, tx, outbox.RoomCreated, created.Id, time.Now(), created)
+	})
+	return id, domainError(err)
+}
+
+func (impl *impl) Delete(ctx context.Context, id *uuid.UUID, version int64) error {
+	at := time.Now()
+	err := pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		// waits for bookings being inserted into the room like a lock does
+		var exists bool
+		query := "select true from meeting_rooms where id = @id and version = @version for update"
+		args := pgx.NamedArgs{"id": id, "version": version}
+		if err := tx.QueryRow(ctx, query, args).Scan(&exists); errors.Is(err, pgx.ErrNoRows) {
+			return staleOrMissing(ctx, tx, id)
+		} else if err != nil {
+			return err
+		}
+
+		// bookings go away with the room, upcoming ones are cancelled for their hosts and attendees to know.
+		// Waitlist entries offered them are settled before the bookings are gone.
+		cancelled := make([]outbox.CancelledBooking, 0)
+		query = `with cancelled as (
+					select id, room_id, host, attendees, start_at, end_at,
+						@actor::text as cancelled_by, 'room deleted' as cancel_reason
+					from bookings
+					where room_id = @id and cancelled_at is null and end_at > @at
+				), offers as (
+					update waitlist
+					set status = 'cancelled'
+					where status = 'offered'
+					  and booking_id in (select id from cancelled)
 				)
-				`
+				select * from cancelled order by start_at`
+		args = pgx.NamedArgs{"id": id, "actor": systemActor, "at": at}
+		if err := pgxscan.Select(ctx, tx, &cancelled, query, args); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, "delete from meeting_rooms where id = @id", pgx.NamedArgs{"id": id}); err != nil {
+			return err
+		}
+		for _, booking := range cancelled {
+			if err := outboxDB.Write(ctx, tx, outbox.BookingCancelled, booking.BookingId.String(), at, booking); err != nil {
+				return err
+			}
+		}
+		return outboxDB.Write(ctx, tx, outbox.RoomDeleted, id.String(), at, outbox.DeletedRoom{Id: *id})
+	})
+	return domainError(err)
+}
+
+const roomColumns = "r.id, r.name, r.capacity, r.office, r.stage, r.labels, r.buffer_before, r.buffer_after, r.version"
+
+// a lock is active while it isn't unlocked and its end hasn't come
+const lockedColumn = `exists (
+		select 1 from room_locks l
+		where l.room_id = r.id
+		  and l.unlocked_at is null
+		  and (l.until is null or l.until > now())
+	) as locked`
+
+// busy intervals of every room are aggregated in the same query to avoid a query per room
+const timetableQuery = `select
+		` + roomColumns + `,
+		` + lockedColumn + `,
+		coalesce(t.timetable, '[]'::json) as timetable
+	from meeting_rooms r
+	left join lateral (
+		select json_agg(
+				json_build_object('bookingId', b.id, 'start', b.start_at, 'end', b.end_at)
+				order by b.start_at
+			) as timetable
+		from bookings b
+		where b.room_id = r.id
+		  and b.cancelled_at is null
+		  and tstzrange(b.start_at, b.end_at) && tstzrange(@from, @to)
+	) t on true`
+
+// slice can't be nil if error is nil
+func (impl *impl) ListWithTimetable(ctx context.Context, window *models.TimeWindow) ([]models.RoomTimetable, error) {
+	list := make([]models.RoomTimetable, 0)
+	args := pgx.NamedArgs{"from": window.From, "to": window.To}
+	err := pgxscan.Select(ctx, impl.dbpool, &list, timetableQuery, args)
+	return list, domainError(err)
+}
+
+func (impl *impl) Timetable(ctx context.Context, id *uuid.UUID, window *models.TimeWindow) (models.RoomTimetable, error) {
+	var timetable models.RoomTimetable
+	query := timetableQuery + " where r.id = @id"
+	args := pgx.NamedArgs{"id": id, "from": window.From, "to": window.To}
+	err := pgxscan.Get(ctx, impl.dbpool, &timetable, query, args)
+	if pgxscan.NotFound(err) {
+		return timetable, models.ErrRoomNotFound
+	}
+	return timetable, domainError(err)
+}
+
+// rooms with the least spare seats go first
+// slice can't be nil if error is nil
+func (impl *impl) FindFree(ctx context.Context, search *models.FreeRoomQuery) ([]models.RoomInfo, error) {
+	list := make([]models.RoomInfo, 0)
+	query := `select ` + roomColumns + `, false as locked
+				from meeting_rooms r
+				where r.capacity >= @capacity
+				  and r.office = @office
+				  and (@stage::int is null or r.stage = @stage::int)
+				  and coalesce(r.labels, '{}') @> @labels::text[]
+				  and not exists (
+					select 1 from bookings b
+					where b.room_id = r.id
+					  and b.cancelled_at is null
+					  and tstzrange(
+							b.start_at - make_interval(mins => r.buffer_before),
+							b.end_at + make_interval(mins => r.buffer_after)
+						) && tstzrange(@from, @to)
+				  )
+				  and not exists (
+					select 1 from room_locks l
+					where l.room_id = r.id
+					  and l.unlocked_at is null
+					  and tstzrange(l.locked_at, l.until) && tstzrange(@from, @to)
+				  )
+				order by r.capacity - @capacity, r.name`
+	labels := search.Labels
+	if labels == nil {
+		labels = []string{}
+	}
 	args := pgx.NamedArgs{
-		"id":       id,
-		"name":     room.Name,
-		"capacity": room.Capacity,
-		"office":   room.Office,
-		"stage":    room.Stage,
-		"labels":   room.Labels,
-	}
-	_, err := impl.dbpool.Exec(ctx, query, args)
-	return id, err // wrap error, check constraints violations
-}
-
-func (impl *impl) Delete(ctx context.Context, id *uuid.UUID) error {
-	query := "delete from meeting_rooms where id = @id"
-	args := pgx.NamedArgs{"id": id}
-	_, err := impl.dbpool.Exec(ctx, query, args)
-	return err // wrap error
+		"capacity": search.Capacity,
+		"office":   search.Office,
+		"stage":    search.Stage,
+		"labels":   labels,
+		"from":     search.Window.From,
+		"to":       search.Window.To,
+	}
+	err := pgxscan.Select(ctx, impl.dbpool, &list, query, args)
+	return list, domainError(err)
+}
+
+func (impl *impl) Availability(ctx context.Context, id *uuid.UUID, window *models.TimeWindow) (models.Availability, error) {
+	var availability models.Availability
+	query := `select
+					r.id as room_id,
+					@from::timestamptz as "from",
+					@to::timestamptz as "to",
+					r.buffer_before,
+					r.buffer_after,
+					exists (
+						select 1 from room_locks l
+						where l.room_id = r.id
+						  and l.unlocked_at is null
+						  and tstzrange(l.locked_at, l.until) && tstzrange(@from, @to)
+					) as locked,
+					coalesce(c.conflicts, '[]'::json) as conflicts
+				from meeting_rooms r
+				left join lateral (
+					select json_agg(
+							json_build_object('bookingId', b.id, 'start', b.start_at, 'end', b.end_at)
+							order by b.start_at
+						) as conflicts
+					from bookings b
+					where b.room_id = r.id
+					  and b.cancelled_at is null
+					  and tstzrange(
+							b.start_at - make_interval(mins => r.buffer_before),
+							b.end_at + make_interval(mins => r.buffer_after)
+						) && tstzrange(@from, @to)
+				) c on true
+				where r.id = @id`
+	args := pgx.NamedArgs{"id": id, "from": window.From, "to": window.To}
+	err := pgxscan.Get(ctx, impl.dbpool, &availability, query, args)
+	if pgxscan.NotFound(err) {
+		return availability, models.ErrRoomNotFound
+	}
+	availability.Available = err == nil && !availability.Locked && len(availability.Conflicts) == 0
+	return availability, domainError(err)
+}
+
+const lockColumns = "id, room_id, reason, locked_by, locked_at, until, unlocked_at, unlocked_by"
+
+func (impl *impl) Lock(ctx context.Context, roomId *uuid.UUID, lock *models.NewRoomLock, at time.Time) (models.LockResult, error) {
+	result := models.LockResult{CancelledBookings: make([]string, 0)}
+	cancelled := make([]outbox.CancelledBooking, 0)
+	err := pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		// serializes locks of the room and waits for bookings being inserted into it
+		var exists bool
+		query := "select true from meeting_rooms where id = @id for update"
+		if err := tx.QueryRow(ctx, query, pgx.NamedArgs{"id": roomId}).Scan(&exists); errors.Is(err, pgx.ErrNoRows) {
+			return models.ErrRoomNotFound
+		} else if err != nil {
+			return err
+		}
+
+		// expired locks are closed lazily, they would block a new open lock
+		query = `update room_locks
+					set unlocked_at = until, unlocked_by = @actor
+					where room_id = @room_id and unlocked_at is null and until <= @at`
+		args := pgx.NamedArgs{"room_id": roomId, "actor": systemActor, "at": at}
+		if _, err := tx.Exec(ctx, query, args); err != nil {
+			return err
+		}
+
+		query = `insert into room_locks (id, room_id, reason, locked_by, locked_at, until)
+					values (@id, @room_id, @reason, @locked_by, @at, @until)
+					on conflict do nothing
+					returning ` + lockColumns
+		args = pgx.NamedArgs{
+			"id":        uuid.New(),
+			"room_id":   roomId,
+			"reason":    lock.Reason,
+			"locked_by": lock.LockedBy,
+			"at":        at,
+			"until":     lock.Until,
+		}
+		if err := pgxscan.Get(ctx, tx, &result.Lock, query, args); pgxscan.NotFound(err) {
+			return models.ErrRoomAlreadyLocked
+		} else if err != nil {
+			return err
+		}
+
+		query = `with cancelled as (
+					update bookings
+					set cancelled_at = @at, cancelled_by = @locked_by, cancel_reason = 'room locked: ' || @reason::text
+					where room_id = @room_id
+					  and cancelled_at is null
+					  and end_at > @at
+					  and (@until::timestamptz is null or start_at < @until::timestamptz)
+					returning id, room_id, host, attendees, start_at, end_at, cancelled_by, cancel_reason
+				), events as (
+					insert into booking_events (booking_id, type, actor, occurred_at)
+					select id, @type::text, @locked_by::text, @at::timestamptz from cancelled
+				), offers as (
+					update waitlist
+					set status = 'cancelled'
+					where status = 'offered'
+					  and booking_id in (select id from cancelled)
+				)
+				select * from cancelled order by start_at`
+		args = pgx.NamedArgs{
+			"room_id":   roomId,
+			"reason":    lock.Reason,
+			"locked_by": lock.LockedBy,
+			"at":        at,
+			"until":     lock.Until,
+			"type":      roomLockedEvent,
+		}
+		if err := pgxscan.Select(ctx, tx, &cancelled, query, args); err != nil {
+			return err
+		}
+
+		if err := outboxDB.Write(ctx, tx, outbox.RoomLocked, result.Lock.RoomId, at, result.Lock); err != nil {
+			return err
+		}
+		for _, booking := range cancelled {
+			result.CancelledBookings = append(result.CancelledBookings, booking.BookingId.String())
+			if err := outboxDB.Write(ctx, tx, outbox.BookingCancelled, booking.BookingId.String(), at, booking); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return result, domainError(err)
+}
+
+func (impl *impl) Unlock(ctx context.Context, roomId *uuid.UUID, unlock *models.Unlock, at time.Time) (models.RoomLock, error) {
+	var lock models.RoomLock
+	err := pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		query := `update room_locks
+					set unlocked_at = @at, unlocked_by = @unlocked_by
+					where room_id = @room_id
+					  and unlocked_at is null
+					  and (until is null or until > @at)
+					returning ` + lockColumns
+		args := pgx.NamedArgs{"room_id": roomId, "unlocked_by": unlock.UnlockedBy, "at": at}
+		if err := pgxscan.Get(ctx, tx, &lock, query, args); pgxscan.NotFound(err) {
+			return models.ErrRoomNotLocked
+		} else if err != nil {
+			return err
+		}
+		return outboxDB.Write(ctx, tx, outbox.RoomUnlocked, lock.RoomId, at, lock)
+	})
+	return lock, domainError(err)
+}
+
+// slice can't be nil if error is nil
+func (impl *impl) Locks(ctx context.Context, roomId *uuid.UUID) ([]models.RoomLock, error) {
+	list := make([]models.RoomLock, 0)
+	query := `select ` + lockColumns + ` from room_locks where room_id = @room_id order by locked_at desc`
+	err := pgxscan.Select(ctx, impl.dbpool, &list, query, pgx.NamedArgs{"room_id": roomId})
+	if err == nil && len(list) == 0 {
+		var exists bool
+		query = "select exists (select 1 from meeting_rooms where id = @id)"
+		if err = impl.dbpool.QueryRow(ctx, query, pgx.NamedArgs{"id": roomId}).Scan(&exists); err == nil && !exists {
+			err = models.ErrRoomNotFound
+		}
+	}
+	return list, domainError(err)
 }
@@ -2,11 +2,12 @@ package db
 
 import (
 	"context"
-	"os"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/tern/v2/migrate"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/optician/meeting-room-booking/internal/administration/db/testing"
 	"github.com/optician/meeting-room-booking/internal/administration/models"
 	"github.com/optician/meeting-room-booking/internal/dbPool"
@@ -19,6 +20,7 @@ import (
 type AdministrationRepositoryTestSuite struct {
 	suite.Suite
 	pgContainer *postgres.PostgresContainer
+	pool        *pgxpool.Pool
 	repository  *DB
 	ctx         context.Context
 	logger      zap.SugaredLogger
@@ -41,25 +43,17 @@ func (suite *AdministrationRepositoryTestSuite) SetupTest() {
 	roomsDB := New(dbPool.GetPool(), logger)
 
 	suite.pgContainer = container.Container
+	suite.pool = dbPool.GetPool()
 	suite.repository = &roomsDB
 	suite.ctx = ctx
 
 	// Migration
-	if conn, err := pgx.Connect(ctx, container.ConnectionString); err != nil {
-		logger.Fatalf("cannot connect tern to DB, %v", err)
-	} else {
-		migrator, _ := migrate.NewMigrator(ctx, conn, "public.schema_version")
-		migrationsPath := "../../../migrations/" // better to use env instead
-		err = migrator.LoadMigrations(os.DirFS(migrationsPath))
-		if err != nil {
-			logger.Fatalf("Error loading migrations:\n  %v\n", err)
-		}
-		if len(migrator.Migrations) == 0 {
-			logger.Fatalf("No migrations found")
-		}
-		if err := migrator.Migrate(ctx); err != nil {
-			logger.Fatalf("migration failed, %v", err)
-		}
+	migrationsPath := "../../../migrations/" // better to use env instead
+	if err := testHelpers.Migrate(ctx, container.ConnectionString, migrationsPath); err != nil {
+		logger.Fatalf("%v", err)
+	}
+	if err := testHelpers.CreateOffices(ctx, container.ConnectionString, "FoodCourt", "BC Utopia"); err != nil {
+		logger.Fatalf("%v", err)
 	}
 }
 
@@ -88,11 +82,403 @@ func (suite *AdministrationRepositoryTestSuite) TestCreateListRooms() {
 		Office:   newRoom.Office,
 		Stage:    newRoom.Stage,
 		Labels:   newRoom.Labels,
+		Version:  1,
+	}
+
+	query, _ := models.ValidateRoomQuery(&models.RoomQuery{})
+	page, err := (*suite.repository).List(suite.ctx, &query)
+	require.Nil(suite.T(), err, "List error")
+	require.Equal(suite.T(), []models.RoomInfo{expected}, page.Rooms, "List result")
+	require.Nil(suite.T(), page.Next, "List next cursor")
+}
+
+func (suite *AdministrationRepositoryTestSuite) TestListRoomsFilteredAndPaged() {
+	rooms := []models.NewRoomInfo{
+		{Name: "Blinnaya", Capacity: 4, Office: "FoodCourt", Stage: 1, Labels: []string{"video"}},
+		{Name: "Bliny_100%", Capacity: 8, Office: "FoodCourt", Stage: 1, Labels: []string{"projector"}},
+		{Name: "Blinchik", Capacity: 12, Office: "FoodCourt", Stage: 1, Labels: []string{"video", "projector"}},
+		{Name: "Pelmennaya", Capacity: 10, Office: "FoodCourt", Stage: 2, Labels: []string{"video"}},
+		{Name: "Blintsy", Capacity: 6, Office: "BC Utopia", Stage: 1, Labels: []string{"video"}},
+	}
+	for _, room := range rooms {
+		_, err := (*suite.repository).Create(suite.ctx, &room)
+		require.Nil(suite.T(), err, "Create error")
+	}
+	names := func(page models.RoomPage) []string {
+		list := make([]string, 0, len(page.Rooms))
+		for _, room := range page.Rooms {
+			list = append(list, room.Name)
+		}
+		return list
 	}
 
-	rooms, err := (*suite.repository).List(suite.ctx)
+	stage := 1
+	query, err := models.ValidateRoomQuery(&models.RoomQuery{
+		Office:     "FoodCourt",
+		Stage:      &stage,
+		NamePrefix: "bli",
+		Labels:     []string{"video", "projector"},
+		Sort:       models.SortByCapacityDesc,
+		PageSize:   2,
+	})
+	require.Nil(suite.T(), err)
+
+	// every label by default
+	page, err := (*suite.repository).List(suite.ctx, &query)
+	require.Nil(suite.T(), err, "List error")
+	require.Equal(suite.T(), []string{"Blinchik"}, names(page))
+	require.Nil(suite.T(), page.Next)
+
+	query.LabelsMatch = models.MatchAnyLabel
+	page, err = (*suite.repository).List(suite.ctx, &query)
+	require.Nil(suite.T(), err, "List error")
+	require.Equal(suite.T(), []string{"Blinchik", "Bliny_100%"}, names(page))
+	require.NotNil(suite.T(), page.Next)
+
+	query.Cursor = page.Next
+	page, err = (*suite.repository).List(suite.ctx, &query)
+	require.Nil(suite.T(), err, "List error")
+	require.Equal(suite.T(), []string{"Blinnaya"}, names(page))
+	require.Nil(suite.T(), page.Next)
+
+	// wildcards are literal
+	minCapacity := 5
+	query, _ = models.ValidateRoomQuery(&models.RoomQuery{NamePrefix: "Bliny_1", MinCapacity: &minCapacity})
+	page, err = (*suite.repository).List(suite.ctx, &query)
 	require.Nil(suite.T(), err, "List error")
-	require.Equal(suite.T(), []models.RoomInfo{expected}, rooms, "List result")
+	require.Equal(suite.T(), []string{"Bliny_100%"}, names(page))
+}
+
+func (suite *AdministrationRepositoryTestSuite) TestTimetable() {
+	newRoom := models.NewRoomInfo{Name: "Pyshechnaya", Capacity: 4, Office: "FoodCourt", Stage: 1, Labels: []string{}}
+	roomId, err := (*suite.repository).Create(suite.ctx, &newRoom)
+	require.Nil(suite.T(), err, "Create error")
+
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	insert := "insert into bookings (id, room_id, host, start_at, end_at, cancelled_at) values ($1, $2, 'ivan', $3, $4, $5)"
+	inWindow, cancelled, outOfWindow := uuid.New(), uuid.New(), uuid.New()
+	_, err = suite.pool.Exec(suite.ctx, insert, inWindow, roomId, start, start.Add(time.Hour), nil)
+	require.Nil(suite.T(), err, "booking error")
+	_, err = suite.pool.Exec(suite.ctx, insert, cancelled, roomId, start.Add(time.Hour), start.Add(2*time.Hour), start)
+	require.Nil(suite.T(), err, "booking error")
+	_, err = suite.pool.Exec(suite.ctx, insert, outOfWindow, roomId, start.Add(48*time.Hour), start.Add(49*time.Hour), nil)
+	require.Nil(suite.T(), err, "booking error")
+
+	window := models.TimeWindow{From: start.Add(-time.Hour), To: start.Add(24 * time.Hour)}
+	timetable, err := (*suite.repository).Timetable(suite.ctx, &roomId, &window)
+	require.Nil(suite.T(), err, "Timetable error")
+	require.Len(suite.T(), timetable.Timetable, 1)
+	require.Equal(suite.T(), inWindow.String(), timetable.Timetable[0].BookingId)
+	require.True(suite.T(), start.Equal(timetable.Timetable[0].Start))
+
+	missing := uuid.New()
+	_, err = (*suite.repository).Timetable(suite.ctx, &missing, &window)
+	require.ErrorIs(suite.T(), err, models.ErrRoomNotFound)
+}
+
+func (suite *AdministrationRepositoryTestSuite) TestAvailabilityWithBuffers() {
+	newRoom := models.NewRoomInfo{Name: "Stolovaya", Capacity: 40, Office: "FoodCourt", Stage: 1, Labels: []string{}, BufferBefore: 10, BufferAfter: 15}
+	roomId, err := (*suite.repository).Create(suite.ctx, &newRoom)
+	require.Nil(suite.T(), err, "Create error")
+
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	booking := uuid.New()
+	insert := "insert into bookings (id, room_id, host, start_at, end_at) values ($1, $2, 'ivan', $3, $4)"
+	_, err = suite.pool.Exec(suite.ctx, insert, booking, roomId, start, start.Add(time.Hour))
+	require.Nil(suite.T(), err, "booking error")
+
+	// the cleanup after the booking lasts till 15:15
+	window := models.TimeWindow{From: start.Add(70 * time.Minute), To: start.Add(2 * time.Hour)}
+	availability, err := (*suite.repository).Availability(suite.ctx, &roomId, &window)
+	require.Nil(suite.T(), err, "Availability error")
+	require.False(suite.T(), availability.Available)
+	require.Len(suite.T(), availability.Conflicts, 1)
+	require.Equal(suite.T(), booking.String(), availability.Conflicts[0].BookingId)
+
+	// the setup before the booking starts at 13:50
+	window = models.TimeWindow{From: start.Add(-time.Hour), To: start.Add(-10 * time.Minute)}
+	availability, err = (*suite.repository).Availability(suite.ctx, &roomId, &window)
+	require.Nil(suite.T(), err, "Availability error")
+	require.True(suite.T(), availability.Available)
+	require.Empty(suite.T(), availability.Conflicts)
+
+	missing := uuid.New()
+	_, err = (*suite.repository).Availability(suite.ctx, &missing, &window)
+	require.ErrorIs(suite.T(), err, models.ErrRoomNotFound)
+}
+
+func (suite *AdministrationRepositoryTestSuite) TestFindFree() {
+	create := func(name string, capacity int, labels []string, bufferAfter int) uuid.UUID {
+		room := models.NewRoomInfo{Name: name, Capacity: capacity, Office: "BC Utopia", Stage: 3, Labels: labels, BufferAfter: bufferAfter}
+		id, err := (*suite.repository).Create(suite.ctx, &room)
+		require.Nil(suite.T(), err, "Create error")
+		return id
+	}
+	busy := create("Busy", 8, []string{"projector"}, 0)
+	large := create("Large", 20, []string{"projector", "video"}, 0)
+	tight := create("Tight", 9, []string{"projector"}, 0)
+	cleaned := create("Cleaned", 10, []string{"projector"}, 15)
+	create("Small", 4, []string{"projector"}, 0)
+	create("NoProjector", 8, []string{"video"}, 0)
+
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	insert := "insert into bookings (id, room_id, host, start_at, end_at) values ($1, $2, 'ivan', $3, $4)"
+	_, err := suite.pool.Exec(suite.ctx, insert, uuid.New(), busy, start.Add(30*time.Minute), start.Add(2*time.Hour))
+	require.Nil(suite.T(), err, "booking error")
+	// the cleanup after this booking lasts till 14:05
+	_, err = suite.pool.Exec(suite.ctx, insert, uuid.New(), cleaned, start.Add(-time.Hour), start.Add(-10*time.Minute))
+	require.Nil(suite.T(), err, "booking error")
+
+	query := models.FreeRoomQuery{
+		Window:   models.TimeWindow{From: start, To: start.Add(time.Hour)},
+		Capacity: 8,
+		Office:   "BC Utopia",
+		Labels:   []string{"projector"},
+	}
+	rooms, err := (*suite.repository).FindFree(suite.ctx, &query)
+	require.Nil(suite.T(), err, "FindFree error")
+
+	ids := make([]string, 0)
+	for _, room := range rooms {
+		ids = append(ids, room.Id)
+	}
+	require.Equal(suite.T(), []string{tight.String(), large.String()}, ids)
+	availability, err := (*suite.repository).Availability(suite.ctx, &cleaned, &query.Window)
+	require.Nil(suite.T(), err, "Availability error")
+	require.False(suite.T(), availability.Available, "free search and availability agree")
+}
+
+func (suite *AdministrationRepositoryTestSuite) TestLockCancelsBookings() {
+	newRoom := models.NewRoomInfo{Name: "Chaihana", Capacity: 4, Office: "FoodCourt", Stage: 1, Labels: []string{}}
+	roomId, err := (*suite.repository).Create(suite.ctx, &newRoom)
+	require.Nil(suite.T(), err, "Create error")
+
+	at := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	insert := "insert into bookings (id, room_id, host, start_at, end_at) values ($1, $2, 'ivan', $3, $4)"
+	past, current, afterLock := uuid.New(), uuid.New(), uuid.New()
+	_, err = suite.pool.Exec(suite.ctx, insert, past, roomId, at.Add(-2*time.Hour), at.Add(-time.Hour))
+	require.Nil(suite.T(), err, "booking error")
+	_, err = suite.pool.Exec(suite.ctx, insert, current, roomId, at.Add(-30*time.Minute), at.Add(30*time.Minute))
+	require.Nil(suite.T(), err, "booking error")
+	_, err = suite.pool.Exec(suite.ctx, insert, afterLock, roomId, at.Add(3*time.Hour), at.Add(4*time.Hour))
+	require.Nil(suite.T(), err, "booking error")
+
+	until := at.Add(2 * time.Hour)
+	lock := models.NewRoomLock{LockedBy: "facility", Reason: "flooded", Until: &until}
+	result, err := (*suite.repository).Lock(suite.ctx, &roomId, &lock, at)
+	require.Nil(suite.T(), err, "Lock error")
+	require.Equal(suite.T(), []string{current.String()}, result.CancelledBookings)
+
+	var events int
+	err = suite.pool.QueryRow(suite.ctx, "select count(*) from booking_events where booking_id = $1 and type = 'room_locked'", current).Scan(&events)
+	require.Nil(suite.T(), err, "events error")
+	require.Equal(suite.T(), 1, events)
+
+	query := "select type from outbox where type in ('room_locked', 'booking_cancelled') order by id"
+	rows, _ := suite.pool.Query(suite.ctx, query)
+	outboxed, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	require.Nil(suite.T(), err, "outbox error")
+	require.Equal(suite.T(), []string{"room_locked", "booking_cancelled"}, outboxed)
+
+	_, err = (*suite.repository).Lock(suite.ctx, &roomId, &lock, at.Add(time.Minute))
+	require.ErrorIs(suite.T(), err, models.ErrRoomAlreadyLocked)
+
+	unlocked, err := (*suite.repository).Unlock(suite.ctx, &roomId, &models.Unlock{UnlockedBy: "facility"}, at.Add(time.Hour))
+	require.Nil(suite.T(), err, "Unlock error")
+	require.Equal(suite.T(), result.Lock.Id, unlocked.Id)
+
+	_, err = (*suite.repository).Unlock(suite.ctx, &roomId, &models.Unlock{UnlockedBy: "facility"}, at.Add(time.Hour))
+	require.ErrorIs(suite.T(), err, models.ErrRoomNotLocked)
+
+	locks, err := (*suite.repository).Locks(suite.ctx, &roomId)
+	require.Nil(suite.T(), err, "Locks error")
+	require.Len(suite.T(), locks, 1)
+	require.NotNil(suite.T(), locks[0].UnlockedAt)
+}
+
+func (suite *AdministrationRepositoryTestSuite) TestOffices() {
+	newOffice := models.NewOffice{
+		Name:         "BC Dystopia",
+		Address:      "Nevsky prospect, 2",
+		Timezone:     "Europe/Moscow",
+		OpeningHours: []models.OpeningHours{{Day: time.Monday, Open: "09:00", Close: "18:00"}},
+		Floors:       []int{1, 2},
+	}
+	id, err := (*suite.repository).CreateOffice(suite.ctx, &newOffice)
+	require.Nil(suite.T(), err, "CreateOffice error")
+
+	_, err = (*suite.repository).CreateOffice(suite.ctx, &newOffice)
+	require.ErrorIs(suite.T(), err, models.ErrOfficeNameTaken)
+
+	office, err := (*suite.repository).Office(suite.ctx, &id)
+	require.Nil(suite.T(), err, "Office error")
+	require.Equal(suite.T(), newOffice.OpeningHours, office.OpeningHours)
+	require.Equal(suite.T(), newOffice.Floors, office.Floors)
+
+	room := models.NewRoomInfo{Name: "Pyshechnaya", Capacity: 4, Office: newOffice.Name, Stage: 2, Labels: []string{}}
+	_, err = (*suite.repository).Create(suite.ctx, &room)
+	require.Nil(suite.T(), err, "Create error")
+
+	office.Floors = []int{1}
+	require.ErrorIs(suite.T(), (*suite.repository).UpdateOffice(suite.ctx, &office), models.ErrFloorInUse)
+	require.ErrorIs(suite.T(), (*suite.repository).DeleteOffice(suite.ctx, &id), models.ErrOfficeInUse)
+
+	// rooms follow a renamed office
+	office.Floors = []int{1, 2}
+	office.Name = "BC Eutopia"
+	require.Nil(suite.T(), (*suite.repository).UpdateOffice(suite.ctx, &office), "UpdateOffice error")
+	stale := models.NewRoomInfo{Name: "Bulochnaya", Capacity: 4, Office: newOffice.Name, Stage: 1, Labels: []string{}}
+	_, err = (*suite.repository).Create(suite.ctx, &stale)
+	var validationErr *models.ValidationError
+	require.ErrorAs(suite.T(), err, &validationErr, "the old name is no office")
+	var roomOffice string
+	err = suite.pool.QueryRow(suite.ctx, "select office from meeting_rooms where name = $1", room.Name).Scan(&roomOffice)
+	require.Nil(suite.T(), err)
+	require.Equal(suite.T(), office.Name, roomOffice)
+}
+
+func (suite *AdministrationRepositoryTestSuite) TestBlackouts() {
+	newRoom := models.NewRoomInfo{Name: "Shashlychnaya", Capacity: 8, Office: "BC Utopia", Stage: 20, Labels: []string{}}
+	roomId, err := (*suite.repository).Create(suite.ctx, &newRoom)
+	require.Nil(suite.T(), err, "Create error")
+	office, err := (*suite.repository).RoomOffice(suite.ctx, &roomId)
+	require.Nil(suite.T(), err, "RoomOffice error")
+	require.Equal(suite.T(), "BC Utopia", office.Name)
+
+	strRoomId := roomId.String()
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	blackouts := []models.NewBlackout{
+		{BlackoutTarget: models.BlackoutTarget{OfficeId: &office.Id}, Reason: "New Year", Start: start, End: start.AddDate(0, 0, 8), Yearly: true},
+		{BlackoutTarget: models.BlackoutTarget{RoomId: &strRoomId}, Reason: "Painting", Start: start.AddDate(0, 2, 0), End: start.AddDate(0, 2, 2)},
+	}
+	ids, err := (*suite.repository).CreateBlackouts(suite.ctx, blackouts)
+	require.Nil(suite.T(), err, "CreateBlackouts error")
+	require.Len(suite.T(), ids, 2)
+
+	missing := uuid.NewString()
+	_, err = (*suite.repository).CreateBlackouts(suite.ctx, []models.NewBlackout{
+		{BlackoutTarget: models.BlackoutTarget{RoomId: &missing}, Start: start, End: start.Add(time.Hour)},
+	})
+	require.ErrorIs(suite.T(), err, models.ErrBlackoutTargetNotFound)
+
+	// the yearly one is returned regardless of the window
+	window := models.TimeWindow{From: start.AddDate(1, 0, 0), To: start.AddDate(1, 0, 10)}
+	list, err := (*suite.repository).RoomBlackouts(suite.ctx, &roomId, &window)
+	require.Nil(suite.T(), err, "RoomBlackouts error")
+	require.Len(suite.T(), list, 1)
+	require.Equal(suite.T(), ids[0].String(), list[0].Id)
+	require.Equal(suite.T(), "UTC", list[0].Timezone)
+
+	moment := models.TimeWindow{From: start.AddDate(0, 2, 1), To: start.AddDate(0, 2, 1).Add(time.Minute)}
+	active, err := (*suite.repository).Blackouts(suite.ctx, &moment)
+	require.Nil(suite.T(), err, "Blackouts error")
+	require.Len(suite.T(), active, 2)
+
+	require.Nil(suite.T(), (*suite.repository).DeleteBlackout(suite.ctx, &ids[1]), "DeleteBlackout error")
+	require.ErrorIs(suite.T(), (*suite.repository).DeleteBlackout(suite.ctx, &ids[1]), models.ErrBlackoutNotFound)
+
+	missingRoom := uuid.New()
+	_, err = (*suite.repository).RoomBlackouts(suite.ctx, &missingRoom, &window)
+	require.ErrorIs(suite.T(), err, models.ErrRoomNotFound)
+}
+
+func (suite *AdministrationRepositoryTestSuite) TestDomainErrors() {
+	newRoom := models.NewRoomInfo{Name: "Stolovaya", Capacity: 30, Office: "FoodCourt", Stage: 1, Labels: []string{}}
+	_, err := (*suite.repository).Create(suite.ctx, &newRoom)
+	require.Nil(suite.T(), err, "Create error")
+
+	_, err = (*suite.repository).Create(suite.ctx, &newRoom)
+	require.ErrorIs(suite.T(), err, models.ErrRoomNameTaken)
+
+	missing := uuid.New()
+	room := models.RoomInfo{Id: missing.String(), Name: "Bufet", Capacity: 4, Office: "FoodCourt", Stage: 1, Labels: []string{}, Version: 1}
+	require.ErrorIs(suite.T(), (*suite.repository).Update(suite.ctx, &room), models.ErrRoomNotFound)
+	require.ErrorIs(suite.T(), (*suite.repository).Delete(suite.ctx, &missing, 1), models.ErrRoomNotFound)
+}
+
+func (suite *AdministrationRepositoryTestSuite) TestRoomVersions() {
+	newRoom := models.NewRoomInfo{Name: "Blinnaya", Capacity: 6, Office: "FoodCourt", Stage: 1, Labels: []string{}}
+	id, err := (*suite.repository).Create(suite.ctx, &newRoom)
+	require.Nil(suite.T(), err, "Create error")
+
+	room, err := (*suite.repository).Room(suite.ctx, &id)
+	require.Nil(suite.T(), err, "Room error")
+	require.Equal(suite.T(), int64(1), room.Version)
+
+	// the first of two admins who read the same version wins
+	first, second := room, room
+	first.Capacity = 8
+	second.Capacity = 10
+	require.Nil(suite.T(), (*suite.repository).Update(suite.ctx, &first), "Update error")
+	require.ErrorIs(suite.T(), (*suite.repository).Update(suite.ctx, &second), models.ErrRoomVersionStale)
+
+	room, err = (*suite.repository).Room(suite.ctx, &id)
+	require.Nil(suite.T(), err, "Room error")
+	require.Equal(suite.T(), 8, room.Capacity)
+	require.Equal(suite.T(), int64(2), room.Version)
+
+	require.ErrorIs(suite.T(), (*suite.repository).Delete(suite.ctx, &id, 1), models.ErrRoomVersionStale)
+	require.Nil(suite.T(), (*suite.repository).Delete(suite.ctx, &id, 2), "Delete error")
+
+	_, err = (*suite.repository).Room(suite.ctx, &id)
+	require.ErrorIs(suite.T(), err, models.ErrRoomNotFound)
+}
+
+func (suite *AdministrationRepositoryTestSuite) TestDeleteCancelsUpcomingBookings() {
+	newRoom := models.NewRoomInfo{Name: "Pelmennaya", Capacity: 4, Office: "FoodCourt", Stage: 1, Labels: []string{}}
+	roomId, err := (*suite.repository).Create(suite.ctx, &newRoom)
+	require.Nil(suite.T(), err, "Create error")
+
+	now := time.Now()
+	insert := "insert into bookings (id, room_id, host, start_at, end_at) values ($1, $2, 'ivan', $3, $4)"
+	past, upcoming := uuid.New(), uuid.New()
+	_, err = suite.pool.Exec(suite.ctx, insert, past, roomId, now.Add(-2*time.Hour), now.Add(-time.Hour))
+	require.Nil(suite.T(), err, "booking error")
+	_, err = suite.pool.Exec(suite.ctx, insert, upcoming, roomId, now.Add(time.Hour), now.Add(2*time.Hour))
+	require.Nil(suite.T(), err, "booking error")
+
+	require.Nil(suite.T(), (*suite.repository).Delete(suite.ctx, &roomId, 1), "Delete error")
+
+	query := "select aggregate_id from outbox where type = 'booking_cancelled'"
+	rows, _ := suite.pool.Query(suite.ctx, query)
+	cancelled, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	require.Nil(suite.T(), err, "outbox error")
+	require.Equal(suite.T(), []string{upcoming.String()}, cancelled)
+}
+
+func (suite *AdministrationRepositoryTestSuite) TestCancelledBookingsSettleWaitlistOffers() {
+	now := time.Now()
+	// an entry of any room survives the room, its offer has to be settled
+	offered := func(roomName string) (uuid.UUID, uuid.UUID) {
+		newRoom := models.NewRoomInfo{Name: roomName, Capacity: 4, Office: "FoodCourt", Stage: 1, Labels: []string{}}
+		roomId, err := (*suite.repository).Create(suite.ctx, &newRoom)
+		require.Nil(suite.T(), err, "Create error")
+		bookingId, entryId := uuid.New(), uuid.New()
+		insert := "insert into bookings (id, room_id, host, start_at, end_at) values ($1, $2, 'ivan', $3, $4)"
+		_, err = suite.pool.Exec(suite.ctx, insert, bookingId, roomId, now.Add(time.Hour), now.Add(2*time.Hour))
+		require.Nil(suite.T(), err, "booking error")
+		insert = `insert into waitlist (id, person, criteria, start_at, end_at, status, created_at, booking_id)
+					values ($1, 'ivan', '{}', $2, $3, 'offered', $4, $5)`
+		_, err = suite.pool.Exec(suite.ctx, insert, entryId, now.Add(time.Hour), now.Add(2*time.Hour), now, bookingId)
+		require.Nil(suite.T(), err, "waitlist error")
+		return roomId, entryId
+	}
+	requireCancelled := func(entryId uuid.UUID) {
+		var status string
+		err := suite.pool.QueryRow(suite.ctx, "select status from waitlist where id = $1", entryId).Scan(&status)
+		require.Nil(suite.T(), err, "waitlist error")
+		require.Equal(suite.T(), "cancelled", status)
+	}
+
+	lockedRoom, lockedEntry := offered("Cheburechnaya")
+	lock := models.NewRoomLock{LockedBy: "facility", Reason: "flooded"}
+	_, err := (*suite.repository).Lock(suite.ctx, &lockedRoom, &lock, now)
+	require.Nil(suite.T(), err, "Lock error")
+	requireCancelled(lockedEntry)
+
+	deletedRoom, deletedEntry := offered("Blinnaya")
+	require.Nil(suite.T(), (*suite.repository).Delete(suite.ctx, &deletedRoom, 1), "Delete error")
+	requireCancelled(deletedEntry)
 }
 
 func TestAdministrationRepositoryTestSuite(t *testing.T) {
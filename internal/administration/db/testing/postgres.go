@@ -2,8 +2,12 @@ package testHelpers
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/tern/v2/migrate"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	"github.com/testcontainers/testcontainers-go/wait"
@@ -48,3 +52,44 @@ func CreatePostgresContainer(ctx context.Context) (*PostgresContainer, error) {
 		ConnectionString: connStr,
 	}, nil
 }
+
+// applies tern migrations from migrationsPath to the database
+func Migrate(ctx context.Context, connectionString string, migrationsPath string) error {
+	conn, err := pgx.Connect(ctx, connectionString)
+	if err != nil {
+		return fmt.Errorf("cannot connect tern to DB, %w", err)
+	}
+	defer conn.Close(ctx)
+
+	migrator, err := migrate.NewMigrator(ctx, conn, "public.schema_version")
+	if err != nil {
+		return fmt.Errorf("cannot create a migrator, %w", err)
+	}
+	if err := migrator.LoadMigrations(os.DirFS(migrationsPath)); err != nil {
+		return fmt.Errorf("error loading migrations, %w", err)
+	}
+	if len(migrator.Migrations) == 0 {
+		return fmt.Errorf("no migrations found in %v", migrationsPath)
+	}
+	if err := migrator.Migrate(ctx); err != nil {
+		return fmt.Errorf("migration failed, %w", err)
+	}
+	return nil
+}
+
+// rooms refer to offices, tests create offices in UTC with floors from -5 to 30
+func CreateOffices(ctx context.Context, connectionString string, names ...string) error {
+	conn, err := pgx.Connect(ctx, connectionString)
+	if err != nil {
+		return fmt.Errorf("cannot connect to DB, %w", err)
+	}
+	defer conn.Close(ctx)
+
+	query := `insert into offices (id, name, timezone, floors)
+				select gen_random_uuid(), name, 'UTC', array(select generate_series(-5, 30))
+				from unnest($1::text[]) name`
+	if _, err := conn.Exec(ctx, query, names); err != nil {
+		return fmt.Errorf("cannot create offices, %w", err)
+	}
+	return nil
+}
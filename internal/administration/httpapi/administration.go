@@ -1,12 +1,11 @@
 package httpapi
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/google/uuid"
 	"github.com/optician/meeting-room-booking/internal/administration/models"
 	"github.com/optician/meeting-room-booking/internal/administration/service"
 	"go.uber.org/zap"
@@ -30,117 +29,176 @@ func Make(logic *service.Logic, logger *zap.SugaredLogger) func(chi.Router) {
 
 func (ctrl *Controller) routes(r chi.Router) {
 	r.Route("/rooms", func(r chi.Router) {
-		r.Get("/", ctrl.getRoomsController)
-		r.Post("/create", ctrl.createRoomController)
-		r.Delete("/{id}", ctrl.deleteRoomController)
-		r.Post("/update", ctrl.updateRoomController)
+		r.Get("/", jsonHandler(ctrl, "listing of rooms", ctrl.getRooms))
+		r.Get("/free", jsonHandler(ctrl, "free room search", ctrl.findFreeRooms))
+		r.Post("/create", jsonHandler(ctrl, "creation of a room", ctrl.createRoom))
+		r.Get("/{id}", jsonHandler(ctrl, "room", ctrl.getRoom))
+		r.Delete("/{id}", ctrl.emptyHandler("deletion of a room", ctrl.deleteRoom))
+		r.Post("/update", ctrl.emptyHandler("update of a room", ctrl.updateRoom))
+		r.Get("/{id}/timetable", jsonHandler(ctrl, "timetable of a room", ctrl.getTimetable))
+		r.Get("/{id}/availability", jsonHandler(ctrl, "availability of a room", ctrl.getAvailability))
+		r.Post("/{id}/lock", jsonHandler(ctrl, "lock of a room", ctrl.lockRoom))
+		r.Post("/{id}/unlock", jsonHandler(ctrl, "unlock of a room", ctrl.unlockRoom))
+		r.Get("/{id}/locks", jsonHandler(ctrl, "locks of a room", ctrl.getLocks))
+		r.Get("/{id}/blackouts", jsonHandler(ctrl, "blackouts of a room", ctrl.getRoomBlackouts))
+		r.Post("/{id}/blackouts/import", jsonHandler(ctrl, "blackouts import of a room", ctrl.importRoomBlackouts))
 	})
+	r.Route("/offices", ctrl.officeRoutes)
+	r.Route("/blackouts", ctrl.blackoutRoutes)
 }
 
-func (ctrl *Controller) getRoomsController(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	logicChannel := make(chan []models.RoomInfo)
-	go ctrl.getRooms(ctx, &logicChannel)
+// rooms with timetables are a different response, they are asked by include=timetable
+func (ctrl *Controller) getRooms(r *http.Request) (any, error) {
+	if r.URL.Query().Get("include") == "timetable" {
+		return ctrl.getRoomsWithTimetable(r)
+	}
 
-	select {
-	case <-ctx.Done():
-		return
-	case result := <-logicChannel: // need to work with errors (right now all errors will wait timeout)
-		json, err := json.Marshal(result)
-		if err == nil {
-			w.Header().Add("content-type", "application/json")
-			w.Write(json)
-		} else {
-			ctrl.logger.Errorf("internal error: %v", err)
-			w.WriteHeader(500)
-		}
+	query, err := input(roomQueryFromQuery(r.URL.Query()))
+	if err != nil {
+		return nil, err
+	}
+	page, err := (*ctrl.logic).List(r.Context(), &query)
+	if err != nil {
+		return nil, err
+	}
+	response := RoomsResponse{Rooms: page.Rooms}
+	if page.Next != nil {
+		response.NextCursor = encodeRoomCursor(page.Next)
 	}
+	return response, nil
 }
 
-func (ctrl *Controller) getRooms(ctx context.Context, listener *chan []models.RoomInfo) {
-	if list, err := (*ctrl.logic).List(ctx); err != nil {
-		ctrl.logger.Errorf("internal error: %v", err)
-		// close(*listener)   // need to send error too
+func (ctrl *Controller) getRoomsWithTimetable(r *http.Request) ([]models.RoomTimetable, error) {
+	if window, err := input(timeWindowFromQuery(r.URL.Query())); err != nil {
+		return nil, err
 	} else {
-		*listener <- list
+		return (*ctrl.logic).ListWithTimetable(r.Context(), &window)
 	}
 }
 
-func (ctrl *Controller) createRoomController(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	logicChannel := make(chan any)
+func (ctrl *Controller) getTimetable(r *http.Request) (models.RoomTimetable, error) {
+	if id, err := pathId(r); err != nil {
+		return models.RoomTimetable{}, err
+	} else if window, err := input(timeWindowFromQuery(r.URL.Query())); err != nil {
+		return models.RoomTimetable{}, err
+	} else {
+		return (*ctrl.logic).Timetable(r.Context(), &id, &window)
+	}
+}
 
-	go func() {
-		defer func() { logicChannel <- struct{}{} }()
+func (ctrl *Controller) getAvailability(r *http.Request) (models.Availability, error) {
+	if id, err := pathId(r); err != nil {
+		return models.Availability{}, err
+	} else if window, err := input(timeWindowFromQuery(r.URL.Query())); err != nil {
+		return models.Availability{}, err
+	} else {
+		return (*ctrl.logic).Availability(r.Context(), &id, &window)
+	}
+}
 
-		if room, err := fromBytesNewRoom(r.Body); err != nil {
-			ctrl.logger.Errorf("Bad Request. Invalid NewRoomInfo: %v", err)
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(err.Error()))
-		} else if json, err := ctrl.createRoom(ctx, &room); err != nil {
-			ctrl.logger.Errorf("failed to create a new room: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-		} else {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(json))
-		}
-	}()
+func (ctrl *Controller) findFreeRooms(r *http.Request) ([]models.RoomInfo, error) {
+	if search, err := input(freeRoomQueryFromQuery(r.URL.Query())); err != nil {
+		return nil, err
+	} else {
+		return (*ctrl.logic).FindFree(r.Context(), &search)
+	}
+}
 
-	select {
-	case <-ctx.Done():
-		return
-	case <-logicChannel: // no cancelation, looks useless
+func (ctrl *Controller) lockRoom(r *http.Request) (models.LockResult, error) {
+	if id, err := pathId(r); err != nil {
+		return models.LockResult{}, err
+	} else if lock, err := input(fromBytesNewRoomLock(r.Body)); err != nil {
+		return models.LockResult{}, err
+	} else {
+		return (*ctrl.logic).Lock(r.Context(), &id, &lock)
 	}
 }
 
-// the idea is to prepare answer and errors
-// there is only common logic in the controller method
-// maybe it helps to generalize controllers and avoid repetition of a fragile error processing
-func (ctrl *Controller) createRoom(ctx context.Context, newRoom *models.NewRoomInfo) (string, error) {
-	if id, err := (*ctrl.logic).Create(ctx, newRoom); err != nil {
-		return "", err
+func (ctrl *Controller) unlockRoom(r *http.Request) (models.RoomLock, error) {
+	if id, err := pathId(r); err != nil {
+		return models.RoomLock{}, err
+	} else if unlock, err := input(fromBytesUnlock(r.Body)); err != nil {
+		return models.RoomLock{}, err
 	} else {
-		response := CreationResponse{Id: id}
-		if json, err := json.Marshal(response); err != nil {
-			ctrl.logger.Errorf("room creation failed, %v", err)
-			return "", err
-		} else {
-			return string(json), nil
-		}
+		return (*ctrl.logic).Unlock(r.Context(), &id, &unlock)
 	}
 }
 
-func (ctrl *Controller) deleteRoomController(w http.ResponseWriter, r *http.Request) {
-	strId := chi.URLParam(r, "id")
+func (ctrl *Controller) getLocks(r *http.Request) ([]models.RoomLock, error) {
+	if id, err := pathId(r); err != nil {
+		return nil, err
+	} else {
+		return (*ctrl.logic).Locks(r.Context(), &id)
+	}
+}
 
-	if id, err := uuid.Parse(strId); err != nil {
-		ctrl.logger.Error(`deletion of a room called with malformed id "%v"`, strId)
-		w.WriteHeader(http.StatusBadRequest)
-	} else if err := ctrl.deleteRoom(r.Context(), &id); err != nil {
-		ctrl.logger.Errorf("Deletion of %v room raised error: %v", id, err)
+func (ctrl *Controller) writeJSON(w http.ResponseWriter, payload any) {
+	if json, err := json.Marshal(payload); err != nil {
+		ctrl.logger.Errorf("internal error: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 	} else {
-		w.WriteHeader(http.StatusOK)
+		w.Header().Add("content-type", "application/json")
+		w.Write(json)
 	}
 }
 
-func (ctrl *Controller) deleteRoom(ctx context.Context, id *uuid.UUID) error {
-	return (*ctrl.logic).Delete(ctx, id)
+func (ctrl *Controller) createRoom(r *http.Request) (CreationResponse, error) {
+	if room, err := input(deserializeNewRoom(r.Body)); err != nil {
+		return CreationResponse{}, err
+	} else if id, err := (*ctrl.logic).Create(r.Context(), &room); err != nil {
+		return CreationResponse{}, err
+	} else {
+		return CreationResponse{Id: id}, nil
+	}
 }
 
-func (ctrl *Controller) updateRoomController(w http.ResponseWriter, r *http.Request) {
-	if room, err := fromBytesRoom(r.Body); err != nil {
-		ctrl.logger.Errorf("Bad Request. Invalid RoomInfo: %v", err)
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(err.Error()))
-	} else if err := ctrl.updateRoom(r.Context(), &room); err != nil {
-		ctrl.logger.Errorf("Update of %v room raised error: %v", room, err)
-		w.WriteHeader(http.StatusInternalServerError)
+func (ctrl *Controller) getRoom(r *http.Request) (RoomResponse, error) {
+	if id, err := pathId(r); err != nil {
+		return RoomResponse{}, err
+	} else if room, err := (*ctrl.logic).Room(r.Context(), &id); err != nil {
+		return RoomResponse{}, err
+	} else {
+		return RoomResponse{RoomInfo: room}, nil
+	}
+}
+
+// the version is given by If-Match or by the version parameter
+func (ctrl *Controller) deleteRoom(r *http.Request) error {
+	id, err := pathId(r)
+	if err != nil {
+		return err
+	}
+	version, err := input(optionalIntFromQuery(r.URL.Query(), "version"))
+	if err != nil {
+		return err
+	}
+	var given int64
+	if version != nil {
+		given = int64(*version)
+	}
+	if expected, err := versionOf(r, given); err != nil {
+		return err
 	} else {
-		w.WriteHeader(http.StatusOK)
+		return (*ctrl.logic).Delete(r.Context(), &id, expected)
+	}
+}
+
+// the version is given by If-Match or by the version field
+func (ctrl *Controller) updateRoom(r *http.Request) error {
+	room, err := input(deserializeRoom(r.Body))
+	if err != nil {
+		return err
 	}
+	if room.Version, err = versionOf(r, room.Version); err != nil {
+		return err
+	}
+	return (*ctrl.logic).Update(r.Context(), &room)
 }
 
-func (ctrl *Controller) updateRoom(ctx context.Context, room *models.RoomInfo) error {
-	return (*ctrl.logic).Update(ctx, room)
+func versionOf(r *http.Request, given int64) (int64, error) {
+	version, err := expectedVersion(r.Header.Get("if-match"), given)
+	if errors.Is(err, errVersionRequired) {
+		return 0, err
+	}
+	return input(version, err)
 }
@@ -2,13 +2,17 @@ package httpapi
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/google/uuid"
 	"github.com/optician/meeting-room-booking/internal/administration/models"
 	"github.com/optician/meeting-room-booking/internal/administration/service"
@@ -32,6 +36,16 @@ func checkResponseCode(t *testing.T, expected, actual int) {
 	}
 }
 
+func requireProblem(t *testing.T, response *httptest.ResponseRecorder, status int, detail string) {
+	checkResponseCode(t, status, response.Code)
+	require.Equal(t, "application/problem+json", response.Header().Get("content-type"))
+	var problem Problem
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &problem))
+	require.Equal(t, status, problem.Status)
+	require.Equal(t, http.StatusText(status), problem.Title)
+	require.Equal(t, detail, problem.Detail)
+}
+
 func TestListRoomsSuccessfully(t *testing.T) {
 	// Create a New Server Struct
 	r := chi.NewRouter()
@@ -44,12 +58,38 @@ func TestListRoomsSuccessfully(t *testing.T) {
 
 	checkResponseCode(t, http.StatusOK, response.Code)
 	expected := fmt.Sprintf(
-		`[{"id":"%v","name":"Belyash","capacity":5,"office":"BC Utopia","stage":20,"labels":["video","projector"]}]`,
+		`{"rooms":[{"id":"%v","name":"Belyash","capacity":5,"office":"BC Utopia","stage":20,"labels":["video","projector"],"bufferBefore":10,"bufferAfter":15,"version":3,"locked":false}]}`,
 		stubId,
 	)
 	require.Equal(t, expected, response.Body.String())
 }
 
+func TestListRoomsWithNextCursor(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	req, _ := http.NewRequest("GET", "/rooms?office=BC+Utopia&labels=video&labelsMatch=any&sort=-capacity&pageSize=1", nil)
+	response := executeRequest(req, r)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var body RoomsResponse
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+	cursor, err := decodeRoomCursor(body.NextCursor)
+	require.NoError(t, err)
+	require.Equal(t, models.RoomCursor{Sort: models.SortByCapacityDesc, Id: stubId.String(), Capacity: 5}, cursor)
+}
+
+func TestListRoomsWithCursorOfAnotherSort(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	cursor := encodeRoomCursor(&models.RoomCursor{Sort: models.SortByName, Id: stubId.String(), Name: "Belyash"})
+	req, _ := http.NewRequest("GET", "/rooms?sort=capacity&cursor="+cursor, nil)
+	response := executeRequest(req, r)
+
+	requireProblem(t, response, http.StatusBadRequest, "cursor belongs to another sort order")
+}
+
 func TestCreateRoomSuccessfully(t *testing.T) {
 	// Create a New Server Struct
 	r := chi.NewRouter()
@@ -94,8 +134,36 @@ func TestCreateRoomWithBadRequest(t *testing.T) {
 	// Execute Request
 	response := executeRequest(req, r)
 
-	checkResponseCode(t, http.StatusBadRequest, response.Code)
-	require.Equal(t, "room name can't be empty", response.Body.String())
+	requireProblem(t, response, http.StatusUnprocessableEntity, `unknown field "names"; name is required`)
+}
+
+func TestCreateRoomWithViolations(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	body := `{"name":"Belyash","capacity":0,"office":"BC Utopia","stage":3,"labels":["video","Video"]}`
+	req, _ := http.NewRequest("POST", "/rooms/create", strings.NewReader(body))
+	response := executeRequest(req, r)
+
+	checkResponseCode(t, http.StatusUnprocessableEntity, response.Code)
+	var problem Problem
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &problem))
+	require.Equal(t, []models.Violation{
+		{Field: "capacity", Code: models.CodeOutOfRange, Message: "room can't have 0 or less capacity"},
+		{Field: "labels[1]", Code: models.CodeInvalidCharset, Message: `label "Video" may only have lowercase letters, digits, - and _`},
+		{Field: "stage", Code: models.CodeNotFound, Message: `office "BC Utopia" has no floor 3`},
+	}, problem.Violations)
+}
+
+func TestCreateOversizedRoom(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	body := `{"name":"` + strings.Repeat("Belyash", maxBodySize/7) + `"}`
+	req, _ := http.NewRequest("POST", "/rooms/create", strings.NewReader(body))
+	response := executeRequest(req, r)
+
+	requireProblem(t, response, http.StatusRequestEntityTooLarge, "body is larger than 65536 bytes")
 }
 
 func TestUpdateRoomSuccessfully(t *testing.T) {
@@ -106,12 +174,13 @@ func TestUpdateRoomSuccessfully(t *testing.T) {
 	// Create a New Request
 	json := `
 	{
-		"id": "123",
+		"id": "6f1f5bd4-5d1e-4b8c-9a43-1d6f3c1b2e2a",
 		"name":"Belyash",
 		"capacity":5,
 		"office":"BC Utopia",
 		"stage":20,
-		"labels":["video","projector"]
+		"labels":["video","projector"],
+		"version":3
 	}`
 	req, _ := http.NewRequest("POST", "/rooms/update", strings.NewReader(json))
 	response := executeRequest(req, r)
@@ -133,15 +202,15 @@ func TestUpdateRoomWithBadRequest(t *testing.T) {
 		"capacity":5,
 		"office":"BC Utopia",
 		"stage":20,
-		"labels":["video","projector"]
+		"labels":["video","projector"],
+		"version":3
 	}`
 	req, _ := http.NewRequest("POST", "/rooms/update", strings.NewReader(json))
 
 	// Execute Request
 	response := executeRequest(req, r)
 
-	checkResponseCode(t, http.StatusBadRequest, response.Code)
-	require.Equal(t, "room id can't be empty", response.Body.String())
+	requireProblem(t, response, http.StatusUnprocessableEntity, `unknown field "names"; id is required; name is required`)
 }
 
 func TestDeleteRoomSuccessfully(t *testing.T) {
@@ -150,6 +219,7 @@ func TestDeleteRoomSuccessfully(t *testing.T) {
 	r.Route("/", Make(&logic, logger))
 
 	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/rooms/%v", stubId), nil)
+	req.Header.Set("If-Match", `"3"`)
 
 	// Execute Request
 	response := executeRequest(req, r)
@@ -158,23 +228,806 @@ func TestDeleteRoomSuccessfully(t *testing.T) {
 	require.Equal(t, "", response.Body.String())
 }
 
+func TestGetRoomWithETag(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/rooms/%v", stubId), nil)
+	response := executeRequest(req, r)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+	require.Equal(t, `"3"`, response.Header().Get("etag"))
+	var room models.RoomInfo
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &room))
+	require.Equal(t, stubRooms()[0], room)
+}
+
+func TestUpdateRoomWithStaleIfMatch(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	body := fmt.Sprintf(`{"id":"%v","name":"Belyash","capacity":5,"office":"BC Utopia","stage":20,"labels":[]}`, stubId)
+	req, _ := http.NewRequest("POST", "/rooms/update", strings.NewReader(body))
+	req.Header.Set("If-Match", `"2"`)
+	response := executeRequest(req, r)
+
+	requireProblem(t, response, http.StatusPreconditionFailed, "room was changed by someone else, read it again")
+}
+
+func TestUpdateRoomWithoutVersion(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	body := fmt.Sprintf(`{"id":"%v","name":"Belyash","capacity":5,"office":"BC Utopia","stage":20,"labels":[]}`, stubId)
+	req, _ := http.NewRequest("POST", "/rooms/update", strings.NewReader(body))
+	response := executeRequest(req, r)
+
+	requireProblem(t, response, http.StatusPreconditionRequired, "If-Match header or a version is required to change a room")
+}
+
+func TestDeleteRoomWithStaleVersion(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/rooms/%v?version=2", stubId), nil)
+	response := executeRequest(req, r)
+
+	requireProblem(t, response, http.StatusPreconditionFailed, "room was changed by someone else, read it again")
+}
+
+func TestListRoomsWithTimetable(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	req, _ := http.NewRequest("GET", "/rooms?include=timetable&from=2030-01-10T00:00:00Z&to=2030-01-11T00:00:00Z", nil)
+
+	response := executeRequest(req, r)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+	expected := fmt.Sprintf(
+		`[{"id":"%v","name":"Belyash","capacity":5,"office":"BC Utopia","stage":20,"labels":["video","projector"],"bufferBefore":0,"bufferAfter":0,"version":3,"locked":false,"timetable":[{"bookingId":"%v","start":"2030-01-10T14:00:00Z","end":"2030-01-10T15:00:00Z"}]}]`,
+		stubId, stubBookingId,
+	)
+	require.Equal(t, expected, response.Body.String())
+}
+
+func TestListRoomsWithTimetableWithoutWindow(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	req, _ := http.NewRequest("GET", "/rooms?include=timetable", nil)
+
+	response := executeRequest(req, r)
+
+	checkResponseCode(t, http.StatusBadRequest, response.Code)
+}
+
+func TestRoomTimetableSuccessfully(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/rooms/%v/timetable?from=2030-01-10T00:00:00Z&to=2030-01-11T00:00:00Z", stubId), nil)
+
+	response := executeRequest(req, r)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+	require.Contains(t, response.Body.String(), fmt.Sprintf(`"timetable":[{"bookingId":"%v"`, stubBookingId))
+}
+
+func TestRoomAvailabilityWithConflicts(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/rooms/%v/availability?from=2030-01-10T15:00:00Z&to=2030-01-10T16:00:00Z", stubId), nil)
+
+	response := executeRequest(req, r)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+	require.Contains(t, response.Body.String(), `"available":false`)
+	require.Contains(t, response.Body.String(), fmt.Sprintf(`"conflicts":[{"bookingId":"%v"`, stubBookingId))
+}
+
+func TestUnknownRoomAvailability(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/rooms/%v/availability?from=2030-01-10T15:00:00Z&to=2030-01-10T16:00:00Z", uuid.New()), nil)
+
+	response := executeRequest(req, r)
+
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+func TestRoomTimetableWithInvertedWindow(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/rooms/%v/timetable?from=2030-01-11T00:00:00Z&to=2030-01-10T00:00:00Z", stubId), nil)
+
+	response := executeRequest(req, r)
+
+	requireProblem(t, response, http.StatusBadRequest, "window start must be before its end")
+}
+
+func TestFindFreeRoomsSuccessfully(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	req, _ := http.NewRequest("GET", "/rooms/free?from=2030-01-10T14:00:00Z&to=2030-01-10T15:00:00Z&capacity=4&office=BC%20Utopia&labels=video", nil)
+
+	response := executeRequest(req, r)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+	expected := fmt.Sprintf(
+		`[{"id":"%v","name":"Belyash","capacity":5,"office":"BC Utopia","stage":20,"labels":["video","projector"],"bufferBefore":10,"bufferAfter":15,"version":3,"locked":false}]`,
+		stubId,
+	)
+	require.Equal(t, expected, response.Body.String())
+}
+
+func TestFindFreeRoomsWithoutOffice(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	req, _ := http.NewRequest("GET", "/rooms/free?from=2030-01-10T14:00:00Z&to=2030-01-10T15:00:00Z&capacity=4", nil)
+
+	response := executeRequest(req, r)
+
+	requireProblem(t, response, http.StatusBadRequest, "office can't be empty")
+}
+
+func TestLockRoomSuccessfully(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	json := `{"lockedBy":"facility","reason":"flooded"}`
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/rooms/%v/lock", stubId), strings.NewReader(json))
+
+	response := executeRequest(req, r)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+	require.Contains(t, response.Body.String(), fmt.Sprintf(`"cancelledBookings":["%v"]`, stubBookingId))
+}
+
+func TestLockRoomWithoutReason(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	json := `{"lockedBy":"facility"}`
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/rooms/%v/lock", stubId), strings.NewReader(json))
+
+	response := executeRequest(req, r)
+
+	requireProblem(t, response, http.StatusUnprocessableEntity, "lock reason can't be empty")
+}
+
+func TestUnlockNotLockedRoom(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	json := `{"unlockedBy":"facility"}`
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/rooms/%v/unlock", stubId), strings.NewReader(json))
+
+	response := executeRequest(req, r)
+
+	requireProblem(t, response, http.StatusConflict, "room is not locked")
+}
+
+func TestCreateRoomOnUnknownFloor(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	json := `
+	{
+		"name":"Belyash",
+		"capacity":5,
+		"office":"BC Utopia",
+		"stage":7,
+		"labels":["video","projector"]
+	}`
+	req, _ := http.NewRequest("POST", "/rooms/create", strings.NewReader(json))
+	response := executeRequest(req, r)
+
+	requireProblem(t, response, http.StatusUnprocessableEntity, `office "BC Utopia" has no floor 7`)
+}
+
+func TestCreateOfficeWithTakenName(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	json := `
+	{
+		"name":"BC Utopia",
+		"address":"Nevsky prospect, 1",
+		"timezone":"Europe/Moscow",
+		"floors":[1,20]
+	}`
+	req, _ := http.NewRequest("POST", "/offices/create", strings.NewReader(json))
+	response := executeRequest(req, r)
+
+	checkResponseCode(t, http.StatusConflict, response.Code)
+}
+
+func TestCreateOfficeWithUnknownTimezone(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	json := `
+	{
+		"name":"BC Dystopia",
+		"address":"Nevsky prospect, 2",
+		"timezone":"Mars/Olympus",
+		"floors":[1]
+	}`
+	req, _ := http.NewRequest("POST", "/offices/create", strings.NewReader(json))
+	response := executeRequest(req, r)
+
+	checkResponseCode(t, http.StatusUnprocessableEntity, response.Code)
+}
+
+func TestCreateOfficeWithViolations(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	body := `{"name":"","timezone":"Mars/Olympus","floors":[1,1],"openingHours":[{"day":1,"open":"18:00","close":"09:00"}]}`
+	req, _ := http.NewRequest("POST", "/offices/create", strings.NewReader(body))
+	response := executeRequest(req, r)
+
+	checkResponseCode(t, http.StatusUnprocessableEntity, response.Code)
+	var problem Problem
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &problem))
+	require.Equal(t, []models.Violation{
+		{Field: "name", Code: models.CodeRequired, Message: "office name can't be empty"},
+		{Field: "timezone", Code: models.CodeNotFound, Message: `unknown office timezone "Mars/Olympus"`},
+		{Field: "floors[1]", Code: models.CodeDuplicate, Message: "office floors must be unique"},
+		{Field: "openingHours[0].close", Code: models.CodeOutOfRange, Message: "opening hours of Monday must open before they close"},
+	}, problem.Violations)
+}
+
+func TestOfficeOpenInLocalTime(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	// 10:30 in Moscow on Thursday
+	url := fmt.Sprintf("/offices/%v/open?at=2030-01-10T07:30:00Z", stubOffice.Id)
+	req, _ := http.NewRequest("GET", url, nil)
+	response := executeRequest(req, r)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+	require.Contains(t, response.Body.String(), `"at":"2030-01-10T10:30:00+03:00"`)
+	require.Contains(t, response.Body.String(), `"open":true`)
+}
+
+func TestOfficeOpenOfUnknownOffice(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/offices/%v/open", uuid.New()), nil)
+	response := executeRequest(req, r)
+
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+func TestDeleteOfficeInUse(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/offices/%v", stubOffice.Id), nil)
+	response := executeRequest(req, r)
+
+	checkResponseCode(t, http.StatusConflict, response.Code)
+}
+
+func TestImportOfficeBlackouts(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	calendar := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" +
+		"BEGIN:VEVENT\r\nSUMMARY:New Year\r\nDTSTART;VALUE=DATE:20300101\r\nDTEND;VALUE=DATE:20300109\r\nRRULE:FREQ=YEARLY\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nSUMMARY:Power maintenance\r\nDTSTART:20300315T060000Z\r\nDTEND:20300315T120000Z\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	url := fmt.Sprintf("/offices/%v/blackouts/import", stubOffice.Id)
+	req, _ := http.NewRequest("POST", url, strings.NewReader(calendar))
+	response := executeRequest(req, r)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+	require.Equal(t, fmt.Sprintf(`{"ids":["%v","%v"]}`, stubId, stubId), response.Body.String())
+}
+
+func TestImportOfficeBlackoutsWithUnsupportedRecurrence(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	calendar := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nSUMMARY:Cleaning day\r\nDTSTART;VALUE=DATE:20300101\r\nRRULE:FREQ=MONTHLY\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	url := fmt.Sprintf("/offices/%v/blackouts/import", stubOffice.Id)
+	req, _ := http.NewRequest("POST", url, strings.NewReader(calendar))
+	response := executeRequest(req, r)
+
+	requireProblem(t, response, http.StatusBadRequest, `invalid event "Cleaning day": unsupported recurrence FREQ=MONTHLY, only YEARLY is`)
+}
+
+func TestImportOversizedCalendar(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	event := "BEGIN:VEVENT\r\nSUMMARY:Power maintenance\r\nDTSTART:20300315T060000Z\r\nDTEND:20300315T120000Z\r\nEND:VEVENT\r\n"
+	calendar := "BEGIN:VCALENDAR\r\n" + strings.Repeat(event, maxCalendarSize/len(event)+1) + "END:VCALENDAR\r\n"
+	url := fmt.Sprintf("/offices/%v/blackouts/import", stubOffice.Id)
+	req, _ := http.NewRequest("POST", url, strings.NewReader(calendar))
+	response := executeRequest(req, r)
+
+	requireProblem(t, response, http.StatusRequestEntityTooLarge, "body is larger than 1048576 bytes")
+}
+
+func TestRoomBlackoutsOfYearlyHoliday(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	url := fmt.Sprintf("/rooms/%v/blackouts?from=2031-12-25T00:00:00Z&to=2032-01-05T00:00:00Z", stubId)
+	req, _ := http.NewRequest("GET", url, nil)
+	response := executeRequest(req, r)
+
+	expected := fmt.Sprintf(
+		`[{"blackoutId":"%v","officeId":"%v","reason":"New Year","start":"2032-01-01T00:00:00+03:00","end":"2032-01-09T00:00:00+03:00","yearly":true}]`,
+		stubBookingId, stubOffice.Id,
+	)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	require.JSONEq(t, expected, response.Body.String())
+}
+
+func TestRoomBlackoutsOfUnknownRoom(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	url := fmt.Sprintf("/rooms/%v/blackouts?from=2031-12-25T00:00:00Z&to=2032-01-05T00:00:00Z", uuid.New())
+	req, _ := http.NewRequest("GET", url, nil)
+	response := executeRequest(req, r)
+
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+func TestDeleteMissingRoom(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/rooms/%v?version=1", uuid.New()), nil)
+	response := executeRequest(req, r)
+
+	requireProblem(t, response, http.StatusNotFound, "room not found")
+}
+
+func TestFindFreeRoomsWithUnavailableStorage(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	req, _ := http.NewRequest("GET", "/rooms/free?from=2030-01-10T14:00:00Z&to=2030-01-10T15:00:00Z&capacity=4&office=Offline", nil)
+	response := executeRequest(req, r)
+
+	requireProblem(t, response, http.StatusServiceUnavailable, "the service is temporarily unavailable, retry later")
+	require.Equal(t, "5", response.Header().Get("retry-after"))
+}
+
+func TestUnknownRouteProblem(t *testing.T) {
+	r := chi.NewRouter()
+	r.NotFound(NotFound)
+	r.MethodNotAllowed(MethodNotAllowed)
+	r.Route("/", Make(&logic, logger))
+
+	req, _ := http.NewRequest("GET", "/rooms/of/nowhere", nil)
+	response := executeRequest(req, r)
+
+	requireProblem(t, response, http.StatusNotFound, "no route for /rooms/of/nowhere")
+}
+
+func TestMethodNotAllowedProblem(t *testing.T) {
+	r := chi.NewRouter()
+	r.NotFound(NotFound)
+	r.MethodNotAllowed(MethodNotAllowed)
+	r.Route("/", Make(&logic, logger))
+
+	req, _ := http.NewRequest("PUT", "/rooms/create", nil)
+	response := executeRequest(req, r)
+
+	requireProblem(t, response, http.StatusMethodNotAllowed, "PUT isn't allowed for /rooms/create")
+	require.Equal(t, []string{"GET", "POST", "DELETE"}, response.Header().Values("allow"))
+}
+
+func TestRoutesPropagateLogicErrors(t *testing.T) {
+	id := uuid.NewString()
+	window := "from=2030-01-10T14:00:00Z&to=2030-01-10T15:00:00Z"
+	requests := []struct{ method, url, body string }{
+		{"GET", "/rooms", ""},
+		{"GET", "/rooms?include=timetable&" + window, ""},
+		{"GET", "/rooms/free?capacity=4&office=BC%20Utopia&" + window, ""},
+		{"POST", "/rooms/create", `{"name":"Belyash","capacity":5,"office":"BC Utopia","stage":20,"labels":[]}`},
+		{"POST", "/rooms/update", fmt.Sprintf(`{"id":"%v","name":"Belyash","capacity":5,"office":"BC Utopia","stage":20,"labels":[],"version":1}`, id)},
+		{"DELETE", "/rooms/" + id + "?version=1", ""},
+		{"GET", "/rooms/" + id, ""},
+		{"GET", "/rooms/" + id + "/timetable?" + window, ""},
+		{"GET", "/rooms/" + id + "/locks", ""},
+		{"GET", "/offices", ""},
+		{"DELETE", "/offices/" + id, ""},
+		{"DELETE", "/blackouts/" + id, ""},
+	}
+	failures := []struct {
+		err    error
+		status int
+	}{
+		{errors.New("connection reset by peer"), http.StatusInternalServerError},
+		{models.Unavailable(errors.New("connection refused")), http.StatusServiceUnavailable},
+		{models.ErrRoomNotFound, http.StatusNotFound},
+	}
+
+	for _, failure := range failures {
+		var failing service.Logic = failingLogic{err: failure.err}
+		r := chi.NewRouter()
+		r.Route("/", Make(&failing, logger))
+
+		for _, request := range requests {
+			// a failure has to be answered at once, a hanging handler would leave the recorder untouched
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			req, _ := http.NewRequestWithContext(ctx, request.method, request.url, strings.NewReader(request.body))
+			response := executeRequest(req, r)
+			cancel()
+
+			require.Equal(t, failure.status, response.Code, "%v %v", request.method, request.url)
+			require.Equal(t, "application/problem+json", response.Header().Get("content-type"))
+		}
+	}
+}
+
+func TestListRoomsTimedOut(t *testing.T) {
+	var blocking service.Logic = blockingLogic{}
+	r := chi.NewRouter()
+	r.Use(middleware.Timeout(20 * time.Millisecond))
+	r.Route("/", Make(&blocking, logger))
+
+	req, _ := http.NewRequest("GET", "/rooms", nil)
+	response := executeRequest(req, r)
+
+	checkResponseCode(t, http.StatusGatewayTimeout, response.Code)
+	require.Empty(t, response.Body.String())
+}
+
+func TestCreateRoomCancelledByClient(t *testing.T) {
+	var blocking service.Logic = blockingLogic{}
+	r := chi.NewRouter()
+	r.Route("/", Make(&blocking, logger))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	body := strings.NewReader(`{"name":"Belyash","capacity":5,"office":"BC Utopia","stage":20,"labels":[]}`)
+	req, _ := http.NewRequestWithContext(ctx, "POST", "/rooms/create", body)
+	response := executeRequest(req, r)
+
+	// the handler returns only after the logic and nothing is written to the gone client
+	require.False(t, response.Flushed)
+	require.Empty(t, response.Header())
+	require.Empty(t, response.Body.String())
+}
+
 type logicStub struct{}
 
 var stubId = uuid.New()
+var stubBookingId = uuid.New()
+
+const stubVersion = 3
+
+func stubTimetable() models.RoomTimetable {
+	return models.RoomTimetable{
+		RoomInfo: models.RoomInfo{Id: stubId.String(), Name: "Belyash", Capacity: 5, Office: "BC Utopia", Stage: 20, Labels: []string{"video", "projector"}, Version: stubVersion},
+		Timetable: []models.BusyInterval{{
+			BookingId: stubBookingId.String(),
+			Start:     time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC),
+			End:       time.Date(2030, 1, 10, 15, 0, 0, 0, time.UTC),
+		}},
+	}
+}
+
+var stubOffice = models.Office{
+	Id:       uuid.NewString(),
+	Name:     "BC Utopia",
+	Address:  "Nevsky prospect, 1",
+	Timezone: "Europe/Moscow",
+	OpeningHours: []models.OpeningHours{
+		{Day: time.Thursday, Open: "09:00", Close: "13:00"},
+		{Day: time.Thursday, Open: "14:00", Close: "18:00"},
+	},
+	Floors: []int{1, 20},
+}
 
 func (logicStub) Create(ctx context.Context, room *models.NewRoomInfo) (uuid.UUID, error) {
+	if _, err := models.ValidateNewRoomInfo(room, &stubOffice); err != nil {
+		return uuid.Nil, &models.ValidationError{Err: err}
+	}
 	return stubId, nil
 }
 
 func (logicStub) Update(ctx context.Context, room *models.RoomInfo) error {
+	if _, err := models.ValidateRoomInfo(room, &stubOffice); err != nil {
+		return &models.ValidationError{Err: err}
+	}
+	if room.Version != stubVersion {
+		return models.ErrRoomVersionStale
+	}
+	return nil
+}
+
+func (logicStub) Room(ctx context.Context, id *uuid.UUID) (models.RoomInfo, error) {
+	if *id != stubId {
+		return models.RoomInfo{}, models.ErrRoomNotFound
+	}
+	return stubRooms()[0], nil
+}
+
+func (logicStub) List(ctx context.Context, query *models.RoomQuery) (models.RoomPage, error) {
+	page := models.RoomPage{Rooms: stubRooms()}
+	if query.PageSize == 1 && query.Cursor == nil {
+		next := models.CursorAfter(&page.Rooms[0], query.Sort)
+		page.Next = &next
+	}
+	return page, nil
+}
+
+func stubRooms() []models.RoomInfo {
+	list := []models.RoomInfo{{
+		Id:           stubId.String(),
+		Name:         "Belyash",
+		Capacity:     5,
+		Office:       "BC Utopia",
+		Stage:        20,
+		Labels:       []string{"video", "projector"},
+		BufferBefore: 10,
+		BufferAfter:  15,
+		Version:      stubVersion,
+	}}
+	return list
+}
+
+func (logicStub) Delete(ctx context.Context, id *uuid.UUID, version int64) error {
+	if *id != stubId {
+		return models.ErrRoomNotFound
+	}
+	if version != stubVersion {
+		return models.ErrRoomVersionStale
+	}
 	return nil
 }
 
-func (logicStub) List(ctx context.Context) ([]models.RoomInfo, error) {
-	list := []models.RoomInfo{{Id: stubId.String(), Name: "Belyash", Capacity: 5, Office: "BC Utopia", Stage: 20, Labels: []string{"video", "projector"}}}
-	return list, nil
+func (logicStub) ListWithTimetable(ctx context.Context, window *models.TimeWindow) ([]models.RoomTimetable, error) {
+	return []models.RoomTimetable{stubTimetable()}, nil
+}
+
+func (logicStub) Timetable(ctx context.Context, id *uuid.UUID, window *models.TimeWindow) (models.RoomTimetable, error) {
+	return stubTimetable(), nil
+}
+
+func (logicStub) FindFree(ctx context.Context, query *models.FreeRoomQuery) ([]models.RoomInfo, error) {
+	if query.Office == "Offline" {
+		return nil, models.Unavailable(errors.New("connection refused"))
+	}
+	return stubRooms(), nil
+}
+
+func (logicStub) Availability(ctx context.Context, id *uuid.UUID, window *models.TimeWindow) (models.Availability, error) {
+	if *id != stubId {
+		return models.Availability{}, models.ErrRoomNotFound
+	}
+	timetable := stubTimetable()
+	availability := models.Availability{
+		RoomId:       id.String(),
+		From:         window.From,
+		To:           window.To,
+		BufferBefore: 10,
+		BufferAfter:  15,
+		Conflicts:    timetable.Timetable,
+	}
+	return availability, nil
+}
+
+func (logicStub) Lock(ctx context.Context, roomId *uuid.UUID, lock *models.NewRoomLock) (models.LockResult, error) {
+	result := models.LockResult{
+		Lock: models.RoomLock{
+			Id:       uuid.NewString(),
+			RoomId:   roomId.String(),
+			Reason:   lock.Reason,
+			LockedBy: lock.LockedBy,
+			LockedAt: time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC),
+		},
+		CancelledBookings: []string{stubBookingId.String()},
+	}
+	return result, nil
+}
+
+func (logicStub) Unlock(ctx context.Context, roomId *uuid.UUID, unlock *models.Unlock) (models.RoomLock, error) {
+	return models.RoomLock{}, models.ErrRoomNotLocked
+}
+
+func (logicStub) Locks(ctx context.Context, roomId *uuid.UUID) ([]models.RoomLock, error) {
+	return []models.RoomLock{}, nil
 }
 
-func (logicStub) Delete(ctx context.Context, id *uuid.UUID) error {
+func (logicStub) CreateOffice(ctx context.Context, office *models.NewOffice) (uuid.UUID, error) {
+	if office.Name == stubOffice.Name {
+		return uuid.Nil, models.ErrOfficeNameTaken
+	}
+	return uuid.MustParse(stubOffice.Id), nil
+}
+
+func (logicStub) UpdateOffice(ctx context.Context, office *models.Office) error {
 	return nil
 }
+
+func (logicStub) Offices(ctx context.Context) ([]models.Office, error) {
+	return []models.Office{stubOffice}, nil
+}
+
+func (logicStub) Office(ctx context.Context, id *uuid.UUID) (models.Office, error) {
+	if id.String() != stubOffice.Id {
+		return models.Office{}, models.ErrOfficeNotFound
+	}
+	return stubOffice, nil
+}
+
+func (logicStub) DeleteOffice(ctx context.Context, id *uuid.UUID) error {
+	return models.ErrOfficeInUse
+}
+
+func (stub logicStub) IsOfficeOpen(ctx context.Context, id *uuid.UUID, at time.Time) (models.OpenCheck, error) {
+	office, err := stub.Office(ctx, id)
+	if err != nil {
+		return models.OpenCheck{}, err
+	}
+	return office.IsOpen(at), nil
+}
+
+func (logicStub) CreateBlackout(ctx context.Context, blackout *models.NewBlackout) (uuid.UUID, error) {
+	return stubId, nil
+}
+
+func (logicStub) ImportBlackouts(ctx context.Context, target *models.BlackoutTarget, events []models.CalendarEvent) ([]uuid.UUID, error) {
+	if target.OfficeId == nil || *target.OfficeId != stubOffice.Id {
+		return nil, models.ErrOfficeNotFound
+	}
+	loc, _ := time.LoadLocation(stubOffice.Timezone)
+	ids := make([]uuid.UUID, 0, len(events))
+	for i, event := range events {
+		if _, err := event.ImportedBlackout(target, loc); err != nil {
+			return nil, &models.ValidationError{Err: fmt.Errorf("event %v: %w", i+1, err)}
+		}
+		ids = append(ids, stubId)
+	}
+	return ids, nil
+}
+
+func (logicStub) DeleteBlackout(ctx context.Context, id *uuid.UUID) error {
+	return models.ErrBlackoutNotFound
+}
+
+func (logicStub) RoomBlackouts(ctx context.Context, roomId *uuid.UUID, window *models.TimeWindow) ([]models.BlackoutPeriod, error) {
+	if *roomId != stubId {
+		return nil, models.ErrRoomNotFound
+	}
+	officeId := stubOffice.Id
+	blackout := models.Blackout{
+		Id:             stubBookingId.String(),
+		BlackoutTarget: models.BlackoutTarget{OfficeId: &officeId},
+		Reason:         "New Year",
+		Start:          time.Date(2029, 12, 31, 21, 0, 0, 0, time.UTC),
+		End:            time.Date(2030, 1, 8, 21, 0, 0, 0, time.UTC),
+		Yearly:         true,
+		Office:         stubOffice.Name,
+		Timezone:       stubOffice.Timezone,
+	}
+	return blackout.Occurrences(window), nil
+}
+
+// failingLogic fails every call with the same error
+type failingLogic struct {
+	err error
+}
+
+func (stub failingLogic) Create(ctx context.Context, room *models.NewRoomInfo) (uuid.UUID, error) {
+	return uuid.Nil, stub.err
+}
+
+func (stub failingLogic) Update(ctx context.Context, room *models.RoomInfo) error {
+	return stub.err
+}
+
+func (stub failingLogic) List(ctx context.Context, query *models.RoomQuery) (models.RoomPage, error) {
+	return models.RoomPage{}, stub.err
+}
+
+func (stub failingLogic) Delete(ctx context.Context, id *uuid.UUID, version int64) error {
+	return stub.err
+}
+
+func (stub failingLogic) ListWithTimetable(ctx context.Context, window *models.TimeWindow) ([]models.RoomTimetable, error) {
+	return nil, stub.err
+}
+
+func (stub failingLogic) Timetable(ctx context.Context, id *uuid.UUID, window *models.TimeWindow) (models.RoomTimetable, error) {
+	return models.RoomTimetable{}, stub.err
+}
+
+func (stub failingLogic) FindFree(ctx context.Context, query *models.FreeRoomQuery) ([]models.RoomInfo, error) {
+	return nil, stub.err
+}
+
+func (stub failingLogic) Availability(ctx context.Context, id *uuid.UUID, window *models.TimeWindow) (models.Availability, error) {
+	return models.Availability{}, stub.err
+}
+
+func (stub failingLogic) Lock(ctx context.Context, roomId *uuid.UUID, lock *models.NewRoomLock) (models.LockResult, error) {
+	return models.LockResult{}, stub.err
+}
+
+func (stub failingLogic) Unlock(ctx context.Context, roomId *uuid.UUID, unlock *models.Unlock) (models.RoomLock, error) {
+	return models.RoomLock{}, stub.err
+}
+
+func (stub failingLogic) Locks(ctx context.Context, roomId *uuid.UUID) ([]models.RoomLock, error) {
+	return nil, stub.err
+}
+
+func (stub failingLogic) CreateOffice(ctx context.Context, office *models.NewOffice) (uuid.UUID, error) {
+	return uuid.Nil, stub.err
+}
+
+func (stub failingLogic) UpdateOffice(ctx context.Context, office *models.Office) error {
+	return stub.err
+}
+
+func (stub failingLogic) Offices(ctx context.Context) ([]models.Office, error) {
+	return nil, stub.err
+}
+
+func (stub failingLogic) Office(ctx context.Context, id *uuid.UUID) (models.Office, error) {
+	return models.Office{}, stub.err
+}
+
+func (stub failingLogic) DeleteOffice(ctx context.Context, id *uuid.UUID) error {
+	return stub.err
+}
+
+func (stub failingLogic) IsOfficeOpen(ctx context.Context, id *uuid.UUID, at time.Time) (models.OpenCheck, error) {
+	return models.OpenCheck{}, stub.err
+}
+
+func (stub failingLogic) CreateBlackout(ctx context.Context, blackout *models.NewBlackout) (uuid.UUID, error) {
+	return uuid.Nil, stub.err
+}
+
+func (stub failingLogic) ImportBlackouts(ctx context.Context, target *models.BlackoutTarget, events []models.CalendarEvent) ([]uuid.UUID, error) {
+	return nil, stub.err
+}
+
+func (stub failingLogic) DeleteBlackout(ctx context.Context, id *uuid.UUID) error {
+	return stub.err
+}
+
+func (stub failingLogic) RoomBlackouts(ctx context.Context, roomId *uuid.UUID, window *models.TimeWindow) ([]models.BlackoutPeriod, error) {
+	return nil, stub.err
+}
+
+// blockingLogic lists and creates rooms until the request context is done, like a stuck database
+type blockingLogic struct {
+	failingLogic
+}
+
+func (blockingLogic) List(ctx context.Context, query *models.RoomQuery) (models.RoomPage, error) {
+	<-ctx.Done()
+	return models.RoomPage{}, ctx.Err()
+}
+
+func (blockingLogic) Create(ctx context.Context, room *models.NewRoomInfo) (uuid.UUID, error) {
+	<-ctx.Done()
+	return uuid.Nil, ctx.Err()
+}
+
+func (stub failingLogic) Room(ctx context.Context, id *uuid.UUID) (models.RoomInfo, error) {
+	return models.RoomInfo{}, stub.err
+}
@@ -0,0 +1,70 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/optician/meeting-room-booking/internal/administration/models"
+)
+
+func (ctrl *Controller) blackoutRoutes(r chi.Router) {
+	r.Post("/create", jsonHandler(ctrl, "creation of a blackout", ctrl.createBlackout))
+	r.Delete("/{id}", ctrl.emptyHandler("deletion of a blackout", ctrl.deleteBlackout))
+}
+
+func (ctrl *Controller) createBlackout(r *http.Request) (CreationResponse, error) {
+	if blackout, err := input(fromBytesNewBlackout(r.Body)); err != nil {
+		return CreationResponse{}, err
+	} else if id, err := (*ctrl.logic).CreateBlackout(r.Context(), &blackout); err != nil {
+		return CreationResponse{}, err
+	} else {
+		return CreationResponse{Id: id}, nil
+	}
+}
+
+func (ctrl *Controller) deleteBlackout(r *http.Request) error {
+	if id, err := pathId(r); err != nil {
+		return err
+	} else {
+		return (*ctrl.logic).DeleteBlackout(r.Context(), &id)
+	}
+}
+
+func (ctrl *Controller) getRoomBlackouts(r *http.Request) ([]models.BlackoutPeriod, error) {
+	if id, err := pathId(r); err != nil {
+		return nil, err
+	} else if window, err := input(timeWindowFromQuery(r.URL.Query())); err != nil {
+		return nil, err
+	} else {
+		return (*ctrl.logic).RoomBlackouts(r.Context(), &id, &window)
+	}
+}
+
+func (ctrl *Controller) importRoomBlackouts(r *http.Request) (ImportResponse, error) {
+	if id, err := pathId(r); err != nil {
+		return ImportResponse{}, err
+	} else {
+		strId := id.String()
+		return ctrl.importBlackouts(r, &models.BlackoutTarget{RoomId: &strId})
+	}
+}
+
+func (ctrl *Controller) importOfficeBlackouts(r *http.Request) (ImportResponse, error) {
+	if id, err := pathId(r); err != nil {
+		return ImportResponse{}, err
+	} else {
+		strId := id.String()
+		return ctrl.importBlackouts(r, &models.BlackoutTarget{OfficeId: &strId})
+	}
+}
+
+// the body is an iCalendar file, every event becomes a blackout
+func (ctrl *Controller) importBlackouts(r *http.Request, target *models.BlackoutTarget) (ImportResponse, error) {
+	if events, err := input(fromICalendar(r.Body)); err != nil {
+		return ImportResponse{}, err
+	} else if ids, err := (*ctrl.logic).ImportBlackouts(r.Context(), target, events); err != nil {
+		return ImportResponse{}, err
+	} else {
+		return ImportResponse{Ids: ids}, nil
+	}
+}
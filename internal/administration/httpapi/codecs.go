@@ -1,43 +1,297 @@
 package httpapi
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/optician/meeting-room-booking/internal/administration/models"
 )
 
+// validated tells invalid bodies apart from unreadable ones, they are answered with different statuses
+func validated[T any](value T, err error) (T, error) {
+	if err != nil {
+		return value, &models.ValidationError{Err: err}
+	}
+	return value, nil
+}
+
+// required fields of room bodies, version of an update may come as If-Match instead
+var (
+	newRoomRequired = []string{"name", "capacity", "office", "stage"}
+	roomRequired    = append([]string{"id"}, newRoomRequired...)
+)
+
+// rooms are validated in full by the service, it knows their offices.
+// Rules of fields are checked here only to report them along with violations of the body.
 func deserializeRoom(stream io.Reader) (models.RoomInfo, error) {
-	room := &models.RoomInfo{}
-	if err := json.NewDecoder(stream).Decode(room); err != nil {
-		return *room, fmt.Errorf("can't deserialize RoomInfo: %w", err)
+	room := models.RoomInfo{}
+	if err := decodeStrict(stream, &room, roomRequired...); err != nil {
+		return room, withFieldViolations(decodingError(err, "RoomInfo"), models.ValidateRoomFields(&room))
+	}
+	return room, nil
+}
+
+func deserializeNewRoom(stream io.Reader) (models.NewRoomInfo, error) {
+	room := models.NewRoomInfo{}
+	if err := decodeStrict(stream, &room, newRoomRequired...); err != nil {
+		return room, withFieldViolations(decodingError(err, "NewRoomInfo"), models.ValidateNewRoomFields(&room))
+	}
+	return room, nil
+}
+
+// violations of a body are a ValidationError, other errors mean the body can't be read
+func decodingError(err error, what string) error {
+	var violations models.Violations
+	if errors.As(err, &violations) {
+		return &models.ValidationError{Err: violations}
+	}
+	return fmt.Errorf("can't deserialize %v: %w", what, err)
+}
+
+// violations of fields which are fine in the body are added to violations of the body
+func withFieldViolations(err error, rules models.Violations) error {
+	var violations models.Violations
+	if !errors.As(err, &violations) {
+		return err
+	}
+	for _, rule := range rules {
+		if !violations.Has(rule.Field) {
+			violations = append(violations, rule)
+		}
+	}
+	return &models.ValidationError{Err: violations}
+}
+
+func deserializeNewOffice(stream io.Reader) (models.NewOffice, error) {
+	office := models.NewOffice{}
+	if err := decodeStrict(stream, &office); err != nil {
+		return office, withFieldViolations(decodingError(err, "NewOffice"), models.ValidateNewOfficeFields(&office))
+	}
+	return office, nil
+}
+
+func fromBytesNewOffice(stream io.Reader) (models.NewOffice, error) {
+	if office, err := deserializeNewOffice(stream); err != nil {
+		return office, err
 	} else {
-		return *room, nil
+		return validated(models.ValidateNewOffice(&office))
 	}
 }
 
-func fromBytesRoom(stream io.Reader) (models.RoomInfo, error) {
-	if room, err := deserializeRoom(stream); err != nil {
-		return room, err
+func deserializeOffice(stream io.Reader) (models.Office, error) {
+	office := models.Office{}
+	if err := decodeStrict(stream, &office); err != nil {
+		return office, withFieldViolations(decodingError(err, "Office"), models.ValidateOfficeFields(&office))
+	}
+	return office, nil
+}
+
+func fromBytesOffice(stream io.Reader) (models.Office, error) {
+	if office, err := deserializeOffice(stream); err != nil {
+		return office, err
 	} else {
-		return models.ValidateRoomInfo(&room)
+		return validated(models.ValidateOffice(&office))
 	}
 }
 
-func deserializeNewRoom(stream io.Reader) (models.NewRoomInfo, error) {
-	room := &models.NewRoomInfo{}
-	if err := json.NewDecoder(stream).Decode(room); err != nil {
-		return *room, fmt.Errorf("can't deserialize NewRoomInfo: %w", err)
+func deserializeNewBlackout(stream io.Reader) (models.NewBlackout, error) {
+	blackout := models.NewBlackout{}
+	if err := decodeStrict(stream, &blackout); err != nil {
+		return blackout, decodingError(err, "NewBlackout")
+	}
+	return blackout, nil
+}
+
+func fromBytesNewBlackout(stream io.Reader) (models.NewBlackout, error) {
+	if blackout, err := deserializeNewBlackout(stream); err != nil {
+		return blackout, err
+	} else {
+		return validated(models.ValidateNewBlackout(&blackout))
+	}
+}
+
+func deserializeNewRoomLock(stream io.Reader) (models.NewRoomLock, error) {
+	lock := models.NewRoomLock{}
+	if err := decodeStrict(stream, &lock); err != nil {
+		return lock, decodingError(err, "NewRoomLock")
+	}
+	return lock, nil
+}
+
+func fromBytesNewRoomLock(stream io.Reader) (models.NewRoomLock, error) {
+	if lock, err := deserializeNewRoomLock(stream); err != nil {
+		return lock, err
 	} else {
-		return *room, nil
+		return validated(models.ValidateNewRoomLock(&lock))
 	}
 }
 
-func fromBytesNewRoom(stream io.Reader) (models.NewRoomInfo, error) {
-	if room, err := deserializeNewRoom(stream); err != nil {
-		return room, err
+func deserializeUnlock(stream io.Reader) (models.Unlock, error) {
+	unlock := models.Unlock{}
+	if err := decodeStrict(stream, &unlock); err != nil {
+		return unlock, decodingError(err, "Unlock")
+	}
+	return unlock, nil
+}
+
+func fromBytesUnlock(stream io.Reader) (models.Unlock, error) {
+	if unlock, err := deserializeUnlock(stream); err != nil {
+		return unlock, err
 	} else {
-		return models.ValidateNewRoomInfo(&room)
+		return validated(models.ValidateUnlock(&unlock))
+	}
+}
+
+// both bounds are required and formatted as RFC 3339
+func timeWindowFromQuery(query url.Values) (models.TimeWindow, error) {
+	window := models.TimeWindow{}
+	from, err := time.Parse(time.RFC3339, query.Get("from"))
+	if err != nil {
+		return window, fmt.Errorf("invalid from parameter: %w", err)
+	}
+	to, err := time.Parse(time.RFC3339, query.Get("to"))
+	if err != nil {
+		return window, fmt.Errorf("invalid to parameter: %w", err)
+	}
+	window.From = from
+	window.To = to
+	return models.ValidateTimeWindow(&window)
+}
+
+// labels are passed as repeated parameters: labels=video&labels=projector
+func freeRoomQueryFromQuery(query url.Values) (models.FreeRoomQuery, error) {
+	search := models.FreeRoomQuery{}
+	window, err := timeWindowFromQuery(query)
+	if err != nil {
+		return search, err
+	}
+	search.Window = window
+
+	if search.Capacity, err = strconv.Atoi(query.Get("capacity")); err != nil {
+		return search, fmt.Errorf("invalid capacity parameter: %w", err)
+	}
+	search.Office = query.Get("office")
+	if query.Has("stage") {
+		stage, err := strconv.Atoi(query.Get("stage"))
+		if err != nil {
+			return search, fmt.Errorf("invalid stage parameter: %w", err)
+		}
+		search.Stage = &stage
+	}
+	search.Labels = query["labels"]
+
+	return models.ValidateFreeRoomQuery(&search)
+}
+
+// every parameter is optional: office, stage, minCapacity, maxCapacity,
+// labels (repeated) with labelsMatch=all|any, namePrefix, sort=name|-name|capacity|-capacity, cursor and pageSize
+func roomQueryFromQuery(query url.Values) (models.RoomQuery, error) {
+	search := models.RoomQuery{
+		Office:      query.Get("office"),
+		Labels:      query["labels"],
+		LabelsMatch: models.LabelsMatch(query.Get("labelsMatch")),
+		NamePrefix:  query.Get("namePrefix"),
+		Sort:        models.RoomSort(query.Get("sort")),
+	}
+	var err error
+	if search.Stage, err = optionalIntFromQuery(query, "stage"); err != nil {
+		return search, err
+	}
+	if search.MinCapacity, err = optionalIntFromQuery(query, "minCapacity"); err != nil {
+		return search, err
+	}
+	if search.MaxCapacity, err = optionalIntFromQuery(query, "maxCapacity"); err != nil {
+		return search, err
+	}
+	if pageSize, err := optionalIntFromQuery(query, "pageSize"); err != nil {
+		return search, err
+	} else if pageSize != nil {
+		search.PageSize = *pageSize
+	}
+	if query.Has("cursor") {
+		cursor, err := decodeRoomCursor(query.Get("cursor"))
+		if err != nil {
+			return search, err
+		}
+		search.Cursor = &cursor
+	}
+
+	return models.ValidateRoomQuery(&search)
+}
+
+func optionalIntFromQuery(query url.Values, name string) (*int, error) {
+	if !query.Has(name) {
+		return nil, nil
+	}
+	value, err := strconv.Atoi(query.Get(name))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v parameter: %w", name, err)
+	}
+	return &value, nil
+}
+
+// cursors are opaque for clients, it's base64 of the JSON of the last room of a page
+func encodeRoomCursor(cursor *models.RoomCursor) string {
+	bytes, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+func decodeRoomCursor(encoded string) (models.RoomCursor, error) {
+	cursor := models.RoomCursor{}
+	bytes, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err == nil {
+		err = json.Unmarshal(bytes, &cursor)
+	}
+	if err != nil {
+		return cursor, errors.New("invalid cursor")
+	}
+	return cursor, nil
+}
+
+// at is optional and formatted as RFC 3339, it's now by default
+func atFromQuery(query url.Values, now time.Time) (time.Time, error) {
+	if !query.Has("at") {
+		return now, nil
+	}
+	at, err := time.Parse(time.RFC3339, query.Get("at"))
+	if err != nil {
+		return at, fmt.Errorf("invalid at parameter: %w", err)
+	}
+	return at, nil
+}
+
+// a room ETag is its quoted version, it's strong as every change of a room changes the version
+func roomETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// errVersionRequired is answered with 428, a change of a room has to name the version it was made against
+var errVersionRequired = errors.New("If-Match header or a version is required to change a room")
+
+// expectedVersion is the version of If-Match or the given one, 0 means none is given, they must agree if both are given
+func expectedVersion(ifMatch string, version int64) (int64, error) {
+	if ifMatch == "" {
+		if version == 0 {
+			return 0, errVersionRequired
+		}
+		return version, nil
+	}
+	unquoted, err := strconv.Unquote(ifMatch)
+	if err != nil {
+		return 0, fmt.Errorf("If-Match %v isn't an ETag of a room", ifMatch)
+	}
+	tagged, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || tagged < 1 {
+		return 0, fmt.Errorf("If-Match %v isn't an ETag of a room", ifMatch)
+	}
+	if version != 0 && version != tagged {
+		return 0, fmt.Errorf("If-Match %v and version %v differ", ifMatch, version)
 	}
+	return tagged, nil
 }
@@ -1,13 +1,17 @@
 package httpapi
 
 import (
+	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/optician/meeting-room-booking/internal/administration/models"
 	"github.com/stretchr/testify/require"
 )
 
+var utopia = models.Office{Name: "BC Utopia", Floors: []int{20}}
+
 func TestNewRoomDeserialization(t *testing.T) {
 	json := `
 	{
@@ -40,7 +44,7 @@ func TestNewRoomCapacityValidationFailed(t *testing.T) {
 		Labels:   []string{"video", "projector"},
 	}
 	expected := "room can't have 0 or less capacity"
-	_, err := models.ValidateNewRoomInfo(&data)
+	_, err := models.ValidateNewRoomInfo(&data, &utopia)
 
 	require.EqualError(t, err, expected)
 }
@@ -54,7 +58,7 @@ func TestNewRoomNameValidationFailed(t *testing.T) {
 		Labels:   []string{"video", "projector"},
 	}
 	expected := "room name can't be empty"
-	_, err := models.ValidateNewRoomInfo(&data)
+	_, err := models.ValidateNewRoomInfo(&data, &utopia)
 
 	require.EqualError(t, err, expected)
 }
@@ -68,7 +72,7 @@ func TestNewRoomOfficeValidationFailed(t *testing.T) {
 		Labels:   []string{"video", "projector"},
 	}
 	expected := "room office can't be empty"
-	_, err := models.ValidateNewRoomInfo(&data)
+	_, err := models.ValidateNewRoomInfo(&data, &utopia)
 
 	require.EqualError(t, err, expected)
 }
@@ -82,7 +86,7 @@ func TestNewRoomValidationPassed(t *testing.T) {
 		Labels:   []string{"video", "projector"},
 	}
 	expected := data
-	actual, err := models.ValidateNewRoomInfo(&data)
+	actual, err := models.ValidateNewRoomInfo(&data, &utopia)
 
 	require.Nil(t, err)
 	require.Equal(t, expected, actual)
@@ -91,7 +95,7 @@ func TestNewRoomValidationPassed(t *testing.T) {
 func TestRoomDeserialization(t *testing.T) {
 	json := `
 	  {
-		"id": "123", 
+		"id": "6f1f5bd4-5d1e-4b8c-9a43-1d6f3c1b2e2a", 
 		"name":"Belyash",
 		"capacity":5,
 		"office":
@@ -100,7 +104,7 @@ func TestRoomDeserialization(t *testing.T) {
 		"labels":["video","projector"]
 	  }`
 	expected := models.RoomInfo{
-		Id:       "123",
+		Id:       "6f1f5bd4-5d1e-4b8c-9a43-1d6f3c1b2e2a",
 		Name:     "Belyash",
 		Capacity: 5,
 		Office:   "BC Utopia",
@@ -116,7 +120,7 @@ func TestRoomDeserialization(t *testing.T) {
 
 func TestRoomCapacityValidationFailed(t *testing.T) {
 	data := models.RoomInfo{
-		Id:       "123",
+		Id:       "6f1f5bd4-5d1e-4b8c-9a43-1d6f3c1b2e2a",
 		Name:     "Belyash",
 		Capacity: 0,
 		Office:   "BC Utopia",
@@ -124,7 +128,7 @@ func TestRoomCapacityValidationFailed(t *testing.T) {
 		Labels:   []string{"video", "projector"},
 	}
 	expected := "room can't have 0 or less capacity"
-	_, err := models.ValidateRoomInfo(&data)
+	_, err := models.ValidateRoomInfo(&data, &utopia)
 
 	require.EqualError(t, err, expected)
 }
@@ -139,14 +143,14 @@ func TestRoomIdValidationFailed(t *testing.T) {
 		Labels:   []string{"video", "projector"},
 	}
 	expected := "room id can't be empty"
-	_, err := models.ValidateRoomInfo(&data)
+	_, err := models.ValidateRoomInfo(&data, &utopia)
 
 	require.EqualError(t, err, expected)
 }
 
 func TestRoomNameValidationFailed(t *testing.T) {
 	data := models.RoomInfo{
-		Id:       "123",
+		Id:       "6f1f5bd4-5d1e-4b8c-9a43-1d6f3c1b2e2a",
 		Name:     "",
 		Capacity: 5,
 		Office:   "BC Utopia",
@@ -154,14 +158,14 @@ func TestRoomNameValidationFailed(t *testing.T) {
 		Labels:   []string{"video", "projector"},
 	}
 	expected := "room name can't be empty"
-	_, err := models.ValidateRoomInfo(&data)
+	_, err := models.ValidateRoomInfo(&data, &utopia)
 
 	require.EqualError(t, err, expected)
 }
 
 func TestRoomOfficeValidationFailed(t *testing.T) {
 	data := models.RoomInfo{
-		Id:       "123",
+		Id:       "6f1f5bd4-5d1e-4b8c-9a43-1d6f3c1b2e2a",
 		Name:     "Matnakash",
 		Capacity: 5,
 		Office:   "",
@@ -169,14 +173,14 @@ func TestRoomOfficeValidationFailed(t *testing.T) {
 		Labels:   []string{"video", "projector"},
 	}
 	expected := "room office can't be empty"
-	_, err := models.ValidateRoomInfo(&data)
+	_, err := models.ValidateRoomInfo(&data, &utopia)
 
 	require.EqualError(t, err, expected)
 }
 
 func TestRoomValidationPassed(t *testing.T) {
 	data := models.RoomInfo{
-		Id:       "123",
+		Id:       "6f1f5bd4-5d1e-4b8c-9a43-1d6f3c1b2e2a",
 		Name:     "Belyash",
 		Capacity: 1,
 		Office:   "BC Utopia",
@@ -184,8 +188,162 @@ func TestRoomValidationPassed(t *testing.T) {
 		Labels:   []string{"video", "projector"},
 	}
 	expected := data
-	actual, err := models.ValidateRoomInfo(&data)
+	actual, err := models.ValidateRoomInfo(&data, &utopia)
+
+	require.Nil(t, err)
+	require.Equal(t, expected, actual)
+}
+
+func TestFreeRoomQueryParsing(t *testing.T) {
+	query := url.Values{
+		"from":     {"2030-01-10T14:00:00Z"},
+		"to":       {"2030-01-10T15:00:00Z"},
+		"capacity": {"8"},
+		"office":   {"BC Utopia"},
+		"stage":    {"3"},
+		"labels":   {"video", "projector"},
+	}
+
+	actual, err := freeRoomQueryFromQuery(query)
+
+	require.Nil(t, err)
+	require.Equal(t, 8, actual.Capacity)
+	require.Equal(t, "BC Utopia", actual.Office)
+	require.Equal(t, 3, *actual.Stage)
+	require.Equal(t, []string{"video", "projector"}, actual.Labels)
+}
+
+func TestFreeRoomQueryWithoutStage(t *testing.T) {
+	query := url.Values{
+		"from":     {"2030-01-10T14:00:00Z"},
+		"to":       {"2030-01-10T15:00:00Z"},
+		"capacity": {"8"},
+		"office":   {"BC Utopia"},
+	}
+
+	actual, err := freeRoomQueryFromQuery(query)
 
 	require.Nil(t, err)
+	require.Nil(t, actual.Stage)
+	require.Nil(t, actual.Labels)
+}
+
+func TestICalendarDeserialization(t *testing.T) {
+	calendar := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"SUMMARY:Victory Day\\, parade",
+		"DTSTART;VALUE=DATE:20300509",
+		"RRULE:FREQ=YEARLY;BYMONTH=5;BYMONTHDAY=9",
+		"BEGIN:VALARM",
+		"TRIGGER:-PT15M",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Shutdown of the",
+		"  second floor",
+		`DTSTART;TZID="Europe/Moscow":20300701T090000`,
+		"DTEND;TZID=Europe/Moscow:20300702T180000",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	actual, err := fromICalendar(strings.NewReader(calendar))
+	require.NoError(t, err)
+
+	moscow, _ := time.LoadLocation("Europe/Moscow")
+	expected := []models.CalendarEvent{
+		{
+			Summary: "Victory Day, parade",
+			Start:   models.CalendarTime{Time: time.Date(2030, 5, 9, 0, 0, 0, 0, time.UTC), Floating: true},
+			End:     models.CalendarTime{Time: time.Date(2030, 5, 10, 0, 0, 0, 0, time.UTC), Floating: true},
+			Yearly:  true,
+		},
+		{
+			Summary: "Shutdown of the second floor",
+			Start:   models.CalendarTime{Time: time.Date(2030, 7, 1, 9, 0, 0, 0, moscow)},
+			End:     models.CalendarTime{Time: time.Date(2030, 7, 2, 18, 0, 0, 0, moscow)},
+		},
+	}
 	require.Equal(t, expected, actual)
 }
+
+func TestICalendarWithoutEventsFailed(t *testing.T) {
+	_, err := fromICalendar(strings.NewReader("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"))
+
+	require.EqualError(t, err, "calendar has no events")
+}
+
+func TestICalendarWithoutEventEndFailed(t *testing.T) {
+	calendar := "BEGIN:VEVENT\r\nSUMMARY:Drill\r\nDTSTART:20300101T100000Z\r\nEND:VEVENT\r\n"
+	_, err := fromICalendar(strings.NewReader(calendar))
+
+	require.EqualError(t, err, `invalid event "Drill": event has no DTEND`)
+}
+
+func TestExpectedVersion(t *testing.T) {
+	version, err := expectedVersion(`"7"`, 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), version)
+
+	version, err = expectedVersion("", 7)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), version)
+
+	_, err = expectedVersion("", 0)
+	require.ErrorIs(t, err, errVersionRequired)
+
+	_, err = expectedVersion(`"7"`, 6)
+	require.EqualError(t, err, `If-Match "7" and version 6 differ`)
+
+	_, err = expectedVersion(`W/"7"`, 0)
+	require.Error(t, err)
+}
+
+func TestNewRoomStrictDeserialization(t *testing.T) {
+	json := `{"name":"Belyash","capacity":"five","office":null,"floor":20,"labels":["video","video"]}`
+
+	_, err := deserializeNewRoom(strings.NewReader(json))
+
+	var violations models.Violations
+	require.ErrorAs(t, err, &violations)
+	require.Equal(t, models.KindValidation, models.KindOf(err))
+	require.Equal(t, models.Violations{
+		{Field: "capacity", Code: models.CodeInvalidType, Message: "capacity must be an integer"},
+		{Field: "floor", Code: models.CodeUnknown, Message: `unknown field "floor"`},
+		{Field: "office", Code: models.CodeRequired, Message: "office is required"},
+		{Field: "stage", Code: models.CodeRequired, Message: "stage is required"},
+		{Field: "labels[1]", Code: models.CodeDuplicate, Message: `label "video" is repeated`},
+	}, violations)
+}
+
+func TestRoomWithReadOnlyFields(t *testing.T) {
+	json := `{"id":"6f1f5bd4-5d1e-4b8c-9a43-1d6f3c1b2e2a","name":"Belyash","capacity":5,"office":"BC Utopia","stage":20,"labels":[],"version":3,
+		"locked":true,"blackout":{"reason":"renovation"}}`
+
+	_, err := deserializeRoom(strings.NewReader(json))
+
+	var violations models.Violations
+	require.ErrorAs(t, err, &violations)
+	require.Equal(t, models.Violations{
+		{Field: "blackout", Code: models.CodeUnknown, Message: `unknown field "blackout"`},
+		{Field: "locked", Code: models.CodeUnknown, Message: `unknown field "locked"`},
+	}, violations)
+}
+
+func TestNewRoomWithTrailingData(t *testing.T) {
+	json := `{"name":"Belyash","capacity":5,"office":"BC Utopia","stage":20,"labels":[]} {"name":"Chebupel"}`
+
+	_, err := deserializeNewRoom(strings.NewReader(json))
+
+	require.EqualError(t, err, "can't deserialize NewRoomInfo: body has data after the JSON object")
+	require.Equal(t, models.KindUnknown, models.KindOf(err))
+}
+
+func TestOversizedNewRoom(t *testing.T) {
+	json := `{"name":"` + strings.Repeat("Belyash", maxBodySize/7) + `"}`
+
+	_, err := deserializeNewRoom(strings.NewReader(json))
+
+	require.ErrorIs(t, err, errBodyTooLarge)
+}
@@ -0,0 +1,109 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// handler reads a request and runs the logic synchronously with the request context,
+// everything is written to the client by the adapter after the handler returns
+type handler[T any] func(r *http.Request) (T, error)
+
+// an error of a request which can't be read or is invalid, it never reaches the logic
+type inputError struct {
+	err error
+}
+
+func (e *inputError) Error() string { return e.err.Error() }
+func (e *inputError) Unwrap() error { return e.err }
+
+type malformedIdError struct {
+	strId string
+}
+
+func (e *malformedIdError) Error() string { return fmt.Sprintf("malformed id %q", e.strId) }
+
+// input marks a failure to read a request
+func input[T any](value T, err error) (T, error) {
+	if err != nil {
+		return value, &inputError{err: err}
+	}
+	return value, nil
+}
+
+// pathId parses the id parameter of a path
+func pathId(r *http.Request) (uuid.UUID, error) {
+	strId := chi.URLParam(r, "id")
+	if id, err := uuid.Parse(strId); err != nil {
+		return uuid.Nil, &malformedIdError{strId: strId}
+	} else {
+		return id, nil
+	}
+}
+
+// results which are tagged are answered with an ETag header
+type tagged interface {
+	etag() string
+}
+
+// jsonHandler answers with the json of the handler result
+func jsonHandler[T any](ctrl *Controller, what string, serve handler[T]) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, err := serve(r)
+		if ctrl.abandoned(r, what) {
+			return
+		}
+		if err != nil {
+			ctrl.writeHandlerError(w, r, err, what)
+			return
+		}
+		if tagged, ok := any(result).(tagged); ok {
+			w.Header().Set("etag", tagged.etag())
+		}
+		ctrl.writeJSON(w, result)
+	}
+}
+
+// emptyHandler answers with 200 and no body
+func (ctrl *Controller) emptyHandler(what string, serve func(r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := serve(r)
+		if ctrl.abandoned(r, what) {
+			return
+		}
+		if err != nil {
+			ctrl.writeHandlerError(w, r, err, what)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+	}
+}
+
+// nothing is written to a request which is cancelled or timed out, the timeout middleware answers instead
+func (ctrl *Controller) abandoned(r *http.Request, what string) bool {
+	if err := r.Context().Err(); err != nil {
+		ctrl.logger.Warnf("%v abandoned: %v", what, err)
+		return true
+	}
+	return false
+}
+
+func (ctrl *Controller) writeHandlerError(w http.ResponseWriter, r *http.Request, err error, what string) {
+	var inputErr *inputError
+	var idErr *malformedIdError
+	switch {
+	case errors.Is(err, errVersionRequired):
+		ctrl.logger.Errorf("%v: %v", what, err)
+		writeProblem(w, r, http.StatusPreconditionRequired, err.Error())
+	case errors.As(err, &idErr):
+		ctrl.writeMalformedId(w, r, idErr.strId, what)
+	case errors.As(err, &inputErr):
+		ctrl.writeInputError(w, r, inputErr.err, what)
+	default:
+		ctrl.writeError(w, r, err, what)
+	}
+}
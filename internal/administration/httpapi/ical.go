@@ -0,0 +1,222 @@
+package httpapi
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/optician/meeting-room-booking/internal/administration/models"
+)
+
+// the largest calendar which is read, holiday calendars are far smaller
+const maxCalendarSize = 1 << 20
+
+var errCalendarTooLarge = &bodyTooLargeError{limit: maxCalendarSize}
+
+// fromICalendar reads VEVENTs of an iCalendar (RFC 5545) file.
+// Only what holiday calendars use is supported: dates and date-times in UTC, with TZID or floating,
+// DTEND or an all-day event of a single day, and a yearly RRULE.
+func fromICalendar(stream io.Reader) ([]models.CalendarEvent, error) {
+	body, err := readBody(stream, errCalendarTooLarge)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := unfoldICalendar(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("can't read calendar: %w", err)
+	}
+
+	events := make([]models.CalendarEvent, 0)
+	var event *icalEvent
+	// components nested into an event, e.g. VALARM, are skipped
+	nested := 0
+	for _, line := range lines {
+		name, params, value, err := parseICalendarLine(line)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case name == "BEGIN" && value == "VEVENT" && event == nil:
+			event = &icalEvent{}
+		case event == nil:
+			continue
+		case name == "BEGIN":
+			nested++
+		case name == "END" && nested > 0:
+			nested--
+		case nested > 0:
+			continue
+		case name == "END" && value == "VEVENT":
+			calendarEvent, err := event.toModel()
+			if err != nil {
+				return nil, fmt.Errorf("invalid event %q: %w", event.summary, err)
+			}
+			events = append(events, calendarEvent)
+			event = nil
+		default:
+			if err := event.set(name, params, value); err != nil {
+				return nil, fmt.Errorf("invalid event %q: %w", event.summary, err)
+			}
+		}
+	}
+	if event != nil {
+		return nil, errors.New("calendar ends inside an event")
+	}
+	if len(events) == 0 {
+		return nil, errors.New("calendar has no events")
+	}
+	return events, nil
+}
+
+type icalEvent struct {
+	summary string
+	start   *models.CalendarTime
+	end     *models.CalendarTime
+	// all-day events have dates instead of date-times
+	allDay bool
+	rrule  map[string]string
+}
+
+func (event *icalEvent) set(name string, params map[string]string, value string) error {
+	var err error
+	switch name {
+	case "SUMMARY":
+		event.summary = unescapeICalendarText(value)
+	case "DTSTART":
+		event.allDay = params["VALUE"] == "DATE" || len(value) == len("20060102")
+		event.start, err = parseICalendarTime(params, value)
+	case "DTEND":
+		event.end, err = parseICalendarTime(params, value)
+	case "RRULE":
+		event.rrule = make(map[string]string)
+		for _, part := range strings.Split(value, ";") {
+			key, value, _ := strings.Cut(part, "=")
+			event.rrule[strings.ToUpper(key)] = strings.ToUpper(value)
+		}
+	}
+	return err
+}
+
+func (event *icalEvent) toModel() (models.CalendarEvent, error) {
+	if event.start == nil {
+		return models.CalendarEvent{}, errors.New("event has no DTSTART")
+	}
+	end := event.end
+	if end == nil {
+		if !event.allDay {
+			return models.CalendarEvent{}, errors.New("event has no DTEND")
+		}
+		// an all-day event without an end lasts a day
+		end = &models.CalendarTime{Time: event.start.Time.AddDate(0, 0, 1), Floating: true}
+	}
+	yearly, err := event.yearly()
+	if err != nil {
+		return models.CalendarEvent{}, err
+	}
+	return models.CalendarEvent{Summary: event.summary, Start: *event.start, End: *end, Yearly: yearly}, nil
+}
+
+// a plain yearly rule, BYMONTH and BYMONTHDAY are accepted only if they repeat DTSTART
+func (event *icalEvent) yearly() (bool, error) {
+	if event.rrule == nil {
+		return false, nil
+	}
+	if event.rrule["FREQ"] != "YEARLY" {
+		return false, fmt.Errorf("unsupported recurrence FREQ=%v, only YEARLY is", event.rrule["FREQ"])
+	}
+	for key, value := range event.rrule {
+		switch key {
+		case "FREQ", "WKST":
+		case "BYMONTH":
+			if value != strconv.Itoa(int(event.start.Time.Month())) {
+				return false, fmt.Errorf("BYMONTH=%v differs from the event start", value)
+			}
+		case "BYMONTHDAY":
+			if value != strconv.Itoa(event.start.Time.Day()) {
+				return false, fmt.Errorf("BYMONTHDAY=%v differs from the event start", value)
+			}
+		default:
+			return false, fmt.Errorf("unsupported recurrence rule part %v", key)
+		}
+	}
+	return true, nil
+}
+
+func parseICalendarTime(params map[string]string, value string) (*models.CalendarTime, error) {
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		date, err := time.Parse("20060102", value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q", value)
+		}
+		return &models.CalendarTime{Time: date, Floating: true}, nil
+	}
+	if utc, found := strings.CutSuffix(value, "Z"); found {
+		at, err := time.Parse("20060102T150405", utc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date-time %q", value)
+		}
+		return &models.CalendarTime{Time: at}, nil
+	}
+	if tzid, found := params["TZID"]; found {
+		loc, err := time.LoadLocation(tzid)
+		if err != nil {
+			return nil, fmt.Errorf("unknown timezone %q", tzid)
+		}
+		at, err := time.ParseInLocation("20060102T150405", value, loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date-time %q", value)
+		}
+		return &models.CalendarTime{Time: at}, nil
+	}
+	at, err := time.Parse("20060102T150405", value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date-time %q", value)
+	}
+	return &models.CalendarTime{Time: at, Floating: true}, nil
+}
+
+// long lines are folded into several ones starting with a space or a tab
+func unfoldICalendar(stream io.Reader) ([]string, error) {
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case line == "":
+		case (line[0] == ' ' || line[0] == '\t') && len(lines) > 0:
+			lines[len(lines)-1] += line[1:]
+		default:
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// NAME;PARAM=value;PARAM="quoted:value":VALUE
+func parseICalendarLine(line string) (string, map[string]string, string, error) {
+	quoted := false
+	for i, char := range line {
+		switch {
+		case char == '"':
+			quoted = !quoted
+		case char == ':' && !quoted:
+			parts := strings.Split(line[:i], ";")
+			params := make(map[string]string, len(parts)-1)
+			for _, param := range parts[1:] {
+				key, value, _ := strings.Cut(param, "=")
+				params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+			}
+			return strings.ToUpper(parts[0]), params, line[i+1:], nil
+		}
+	}
+	return "", nil, "", fmt.Errorf("invalid calendar line %q", line)
+}
+
+func unescapeICalendarText(text string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(text)
+}
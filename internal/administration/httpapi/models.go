@@ -1,7 +1,29 @@
 package httpapi
 
-import "github.com/google/uuid"
+import (
+	"github.com/google/uuid"
+	"github.com/optician/meeting-room-booking/internal/administration/models"
+)
 
 type CreationResponse struct {
 	Id uuid.UUID `json:"id"`
 }
+
+type ImportResponse struct {
+	Ids []uuid.UUID `json:"ids"`
+}
+
+// NextCursor is passed as the cursor parameter to get the next page, there is none on the last page
+type RoomsResponse struct {
+	Rooms      []models.RoomInfo `json:"rooms"`
+	NextCursor string            `json:"nextCursor,omitempty"`
+}
+
+// RoomResponse is a room with its version as the ETag
+type RoomResponse struct {
+	models.RoomInfo
+}
+
+func (response RoomResponse) etag() string {
+	return roomETag(response.Version)
+}
@@ -0,0 +1,67 @@
+package httpapi
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/optician/meeting-room-booking/internal/administration/models"
+)
+
+func (ctrl *Controller) officeRoutes(r chi.Router) {
+	r.Get("/", jsonHandler(ctrl, "listing of offices", ctrl.getOffices))
+	r.Post("/create", jsonHandler(ctrl, "creation of an office", ctrl.createOffice))
+	r.Post("/update", ctrl.emptyHandler("update of an office", ctrl.updateOffice))
+	r.Get("/{id}", jsonHandler(ctrl, "office", ctrl.getOffice))
+	r.Delete("/{id}", ctrl.emptyHandler("deletion of an office", ctrl.deleteOffice))
+	r.Get("/{id}/open", jsonHandler(ctrl, "open check of an office", ctrl.isOfficeOpen))
+	r.Post("/{id}/blackouts/import", jsonHandler(ctrl, "blackouts import of an office", ctrl.importOfficeBlackouts))
+}
+
+func (ctrl *Controller) getOffices(r *http.Request) ([]models.Office, error) {
+	return (*ctrl.logic).Offices(r.Context())
+}
+
+func (ctrl *Controller) createOffice(r *http.Request) (CreationResponse, error) {
+	if office, err := input(fromBytesNewOffice(r.Body)); err != nil {
+		return CreationResponse{}, err
+	} else if id, err := (*ctrl.logic).CreateOffice(r.Context(), &office); err != nil {
+		return CreationResponse{}, err
+	} else {
+		return CreationResponse{Id: id}, nil
+	}
+}
+
+func (ctrl *Controller) updateOffice(r *http.Request) error {
+	if office, err := input(fromBytesOffice(r.Body)); err != nil {
+		return err
+	} else {
+		return (*ctrl.logic).UpdateOffice(r.Context(), &office)
+	}
+}
+
+func (ctrl *Controller) getOffice(r *http.Request) (models.Office, error) {
+	if id, err := pathId(r); err != nil {
+		return models.Office{}, err
+	} else {
+		return (*ctrl.logic).Office(r.Context(), &id)
+	}
+}
+
+func (ctrl *Controller) deleteOffice(r *http.Request) error {
+	if id, err := pathId(r); err != nil {
+		return err
+	} else {
+		return (*ctrl.logic).DeleteOffice(r.Context(), &id)
+	}
+}
+
+func (ctrl *Controller) isOfficeOpen(r *http.Request) (models.OpenCheck, error) {
+	if id, err := pathId(r); err != nil {
+		return models.OpenCheck{}, err
+	} else if at, err := input(atFromQuery(r.URL.Query(), time.Now())); err != nil {
+		return models.OpenCheck{}, err
+	} else {
+		return (*ctrl.logic).IsOfficeOpen(r.Context(), &id, at)
+	}
+}
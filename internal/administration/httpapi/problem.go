@@ -0,0 +1,109 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/optician/meeting-room-booking/internal/administration/models"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details body, the type is always about:blank so the title is the status text.
+// Violations is an extension member of 422 answers to invalid bodies.
+type Problem struct {
+	Type       string             `json:"type"`
+	Title      string             `json:"title"`
+	Status     int                `json:"status"`
+	Detail     string             `json:"detail,omitempty"`
+	Instance   string             `json:"instance,omitempty"`
+	Violations []models.Violation `json:"violations,omitempty"`
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	writeProblemOf(w, r, Problem{Status: status, Detail: detail})
+}
+
+// every violation of an invalid body is listed
+func writeValidationProblem(w http.ResponseWriter, r *http.Request, err error) {
+	problem := Problem{Status: http.StatusUnprocessableEntity, Detail: err.Error()}
+	var violations models.Violations
+	if errors.As(err, &violations) {
+		problem.Violations = violations
+	}
+	writeProblemOf(w, r, problem)
+}
+
+func writeProblemOf(w http.ResponseWriter, r *http.Request, problem Problem) {
+	problem.Type = "about:blank"
+	problem.Title = http.StatusText(problem.Status)
+	problem.Instance = r.URL.Path
+	body, _ := json.Marshal(problem)
+	w.Header().Set("content-type", problemContentType)
+	w.WriteHeader(problem.Status)
+	w.Write(body)
+}
+
+var kindStatuses = map[models.ErrorKind]int{
+	models.KindNotFound:     http.StatusNotFound,
+	models.KindConflict:     http.StatusConflict,
+	models.KindValidation:   http.StatusUnprocessableEntity,
+	models.KindUnavailable:  http.StatusServiceUnavailable,
+	models.KindPrecondition: http.StatusPreconditionFailed,
+}
+
+// writeError answers with the status of the domain error kind, causes of other errors are only logged
+func (ctrl *Controller) writeError(w http.ResponseWriter, r *http.Request, err error, what string) {
+	switch status, known := kindStatuses[models.KindOf(err)]; {
+	case !known:
+		ctrl.logger.Errorf("%v: %v", what, err)
+		writeProblem(w, r, http.StatusInternalServerError, "")
+	case status == http.StatusUnprocessableEntity:
+		writeValidationProblem(w, r, err)
+	case status == http.StatusServiceUnavailable:
+		ctrl.logger.Errorf("%v: %v", what, err)
+		w.Header().Set("retry-after", "5")
+		writeProblem(w, r, status, "the service is temporarily unavailable, retry later")
+	default:
+		writeProblem(w, r, status, err.Error())
+	}
+}
+
+// writeInputError answers a request which can't be read with 400 or 413 and a read but invalid one with 422
+func (ctrl *Controller) writeInputError(w http.ResponseWriter, r *http.Request, err error, what string) {
+	ctrl.logger.Errorf("Bad Request. %v: %v", what, err)
+	var tooLarge *bodyTooLargeError
+	if errors.As(err, &tooLarge) {
+		writeProblem(w, r, http.StatusRequestEntityTooLarge, tooLarge.Error())
+	} else if models.KindOf(err) == models.KindValidation {
+		writeValidationProblem(w, r, err)
+	} else {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+	}
+}
+
+// malformed ids in paths are answered with 400
+func (ctrl *Controller) writeMalformedId(w http.ResponseWriter, r *http.Request, strId string, what string) {
+	ctrl.logger.Errorf(`%v called with malformed id "%v"`, what, strId)
+	writeProblem(w, r, http.StatusBadRequest, fmt.Sprintf("malformed id %q", strId))
+}
+
+// NotFound replaces the default 404 of chi routers
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	writeProblem(w, r, http.StatusNotFound, fmt.Sprintf("no route for %v", r.URL.Path))
+}
+
+// MethodNotAllowed replaces the default 405 of chi routers, it keeps the Allow header of the default
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.Routes != nil {
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+			if rctx.Routes.Match(chi.NewRouteContext(), method, r.URL.Path) {
+				w.Header().Add("allow", method)
+			}
+		}
+	}
+	writeProblem(w, r, http.StatusMethodNotAllowed, fmt.Sprintf("%v isn't allowed for %v", r.Method, r.URL.Path))
+}
@@ -0,0 +1,137 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/optician/meeting-room-booking/internal/administration/models"
+)
+
+// the largest JSON body which is read, rooms and offices are far smaller
+const maxBodySize = 64 << 10
+
+// bodyTooLargeError is answered with 413
+type bodyTooLargeError struct {
+	limit int
+}
+
+func (e *bodyTooLargeError) Error() string {
+	return fmt.Sprintf("body is larger than %v bytes", e.limit)
+}
+
+var errBodyTooLarge = &bodyTooLargeError{limit: maxBodySize}
+
+// readBody reads the whole body, one larger than the limit of tooLarge is that error
+func readBody(stream io.Reader, tooLarge *bodyTooLargeError) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(stream, int64(tooLarge.limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > tooLarge.limit {
+		return nil, tooLarge
+	}
+	return body, nil
+}
+
+// decodeStrict reads a single JSON object into target, a pointer to a struct.
+// Unknown fields, missing or null required fields and fields of a wrong type are models.Violations,
+// they are all reported at once. A body which isn't a JSON object or has data after it is an error.
+func decodeStrict(stream io.Reader, target any, required ...string) error {
+	body, err := readBody(stream, errBodyTooLarge)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	var object map[string]json.RawMessage
+	if err := decoder.Decode(&object); err != nil {
+		return err
+	}
+	if object == nil {
+		return errors.New("body must be a JSON object")
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("body has data after the JSON object")
+	}
+
+	violations := models.Violations{}
+	fields := jsonFields(reflect.ValueOf(target).Elem())
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		field, known := fields[name]
+		switch {
+		case !known:
+			violations.Add(name, models.CodeUnknown, fmt.Sprintf("unknown field %q", name))
+		case string(object[name]) == "null":
+		default:
+			if err := json.Unmarshal(object[name], field.Addr().Interface()); err != nil {
+				violations.Add(name, models.CodeInvalidType, fmt.Sprintf("%v must be %v", name, jsonType(field.Type())))
+			}
+		}
+	}
+	for _, name := range required {
+		if value, found := object[name]; !found || string(value) == "null" {
+			violations.Add(name, models.CodeRequired, fmt.Sprintf("%v is required", name))
+		}
+	}
+	return violations.Err()
+}
+
+// fields of a struct by their JSON names, fields of embedded structs are fields of the struct.
+// Fields tagged readonly:"true" are only answered, they are unknown in a body.
+func jsonFields(value reflect.Value) map[string]reflect.Value {
+	fields := make(map[string]reflect.Value)
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch {
+		case !field.IsExported() || tag == "-" || field.Tag.Get("readonly") == "true":
+		case field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct:
+			for name, embedded := range jsonFields(value.Field(i)) {
+				fields[name] = embedded
+			}
+		case tag == "":
+			fields[field.Name] = value.Field(i)
+		default:
+			fields[tag] = value.Field(i)
+		}
+	}
+	return fields
+}
+
+var textUnmarshaler = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// a type as clients see it, e.g. times are strings
+func jsonType(t reflect.Type) string {
+	if reflect.PointerTo(t).Implements(textUnmarshaler) {
+		return "a string"
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonType(t.Elem())
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
@@ -1,6 +1,17 @@
 package models
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
 
 type RoomInfo struct {
 	Id       string   `json:"id"`
@@ -9,23 +20,36 @@ type RoomInfo struct {
 	Office   string   `json:"office"`
 	Stage    int      `json:"stage"`
 	Labels   []string `json:"labels"`
+	// minutes of setup before and cleanup after every meeting
+	BufferBefore int `json:"bufferBefore"`
+	BufferAfter  int `json:"bufferAfter"`
+	// grows with every update, an update or a deletion must refer to the current one
+	Version int64 `json:"version"`
+	// read only, an open lock isn't changed by updates
+	Locked bool `json:"locked" readonly:"true"`
+	// read only, the blackout the room is in at the moment
+	Blackout *BlackoutPeriod `json:"blackout,omitempty" db:"-" readonly:"true"`
 }
 
-func ValidateRoomInfo(room *RoomInfo) (RoomInfo, error) {
-	if room.Capacity < 1 {
-		return *room, errors.New("room can't have 0 or less capacity")
-	}
+// ValidateRoomInfo checks the room against its office, office is nil if there is no such office.
+// The error lists all Violations of the room.
+func ValidateRoomInfo(room *RoomInfo, office *Office) (RoomInfo, error) {
+	violations := ValidateRoomFields(room)
+	validatePlacement(&violations, room.Office, room.Stage, office)
+	return *room, violations.Err()
+}
+
+// ValidateRoomFields checks the rules of room fields which don't depend on its office
+func ValidateRoomFields(room *RoomInfo) Violations {
+	violations := Violations{}
 	if room.Id == "" {
-		return *room, errors.New("room id can't be empty")
+		violations.Add("id", CodeRequired, "room id can't be empty")
+	} else if !isUUID(room.Id) {
+		violations.Add("id", CodeInvalidFormat, fmt.Sprintf("room id %q isn't a UUID", room.Id))
 	}
-	if room.Name == "" {
-		return *room, errors.New("room name can't be empty")
-	}
-	if room.Office == "" {
-		return *room, errors.New("room office can't be empty")
-	}
-
-	return *room, nil
+	validateRoomFields(&violations, room.Name, room.Capacity, room.Office, room.Stage, room.Labels)
+	validateBuffers(&violations, room.BufferBefore, room.BufferAfter)
+	return violations
 }
 
 type NewRoomInfo struct {
@@ -34,18 +58,784 @@ type NewRoomInfo struct {
 	Office   string   `json:"office"`
 	Stage    int      `json:"stage"`
 	Labels   []string `json:"labels"`
+	// optional, minutes of setup before and cleanup after every meeting
+	BufferBefore int `json:"bufferBefore"`
+	BufferAfter  int `json:"bufferAfter"`
+}
+
+// ValidateNewRoomInfo checks the room against its office, office is nil if there is no such office.
+// The error lists all Violations of the room.
+func ValidateNewRoomInfo(newRoom *NewRoomInfo, office *Office) (NewRoomInfo, error) {
+	violations := ValidateNewRoomFields(newRoom)
+	validatePlacement(&violations, newRoom.Office, newRoom.Stage, office)
+	return *newRoom, violations.Err()
+}
+
+// ValidateNewRoomFields checks the rules of room fields which don't depend on its office
+func ValidateNewRoomFields(newRoom *NewRoomInfo) Violations {
+	violations := Violations{}
+	validateRoomFields(&violations, newRoom.Name, newRoom.Capacity, newRoom.Office, newRoom.Stage, newRoom.Labels)
+	validateBuffers(&violations, newRoom.BufferBefore, newRoom.BufferAfter)
+	return violations
+}
+
+// limits of room fields, lengths are in characters
+const (
+	MaxRoomNameLength   = 100
+	MaxOfficeNameLength = 100
+	MaxRoomCapacity     = 10000
+	MinStage            = -10
+	MaxStage            = 200
+	MaxLabels           = 20
+	MaxLabelLength      = 32
+)
+
+func validateRoomFields(violations *Violations, name string, capacity int, office string, stage int, labels []string) {
+	if name == "" {
+		violations.Add("name", CodeRequired, "room name can't be empty")
+	} else if utf8.RuneCountInString(name) > MaxRoomNameLength {
+		violations.Add("name", CodeTooLong, fmt.Sprintf("room name can't be longer than %v characters", MaxRoomNameLength))
+	}
+	if capacity < 1 {
+		violations.Add("capacity", CodeOutOfRange, "room can't have 0 or less capacity")
+	} else if capacity > MaxRoomCapacity {
+		violations.Add("capacity", CodeOutOfRange, fmt.Sprintf("room can't have more than %v seats", MaxRoomCapacity))
+	}
+	if office == "" {
+		violations.Add("office", CodeRequired, "room office can't be empty")
+	} else if utf8.RuneCountInString(office) > MaxOfficeNameLength {
+		violations.Add("office", CodeTooLong, fmt.Sprintf("room office can't be longer than %v characters", MaxOfficeNameLength))
+	}
+	if stage < MinStage || stage > MaxStage {
+		violations.Add("stage", CodeOutOfRange, fmt.Sprintf("room stage must be from %v to %v", MinStage, MaxStage))
+	}
+	validateLabels(violations, labels)
+}
+
+// labels are short lowercase words, e.g. video or standing-desk
+func validateLabels(violations *Violations, labels []string) {
+	if len(labels) > MaxLabels {
+		violations.Add("labels", CodeTooLong, fmt.Sprintf("room can't have more than %v labels", MaxLabels))
+	}
+	seen := make(map[string]bool, len(labels))
+	for i, label := range labels {
+		field := fmt.Sprintf("labels[%v]", i)
+		switch {
+		case label == "":
+			violations.Add(field, CodeRequired, "label can't be empty")
+		case utf8.RuneCountInString(label) > MaxLabelLength:
+			violations.Add(field, CodeTooLong, fmt.Sprintf("label %q can't be longer than %v characters", label, MaxLabelLength))
+		case strings.IndexFunc(label, notLabelRune) >= 0:
+			violations.Add(field, CodeInvalidCharset, fmt.Sprintf("label %q may only have lowercase letters, digits, - and _", label))
+		case seen[label]:
+			violations.Add(field, CodeDuplicate, fmt.Sprintf("label %q is repeated", label))
+		}
+		seen[label] = true
+	}
+}
+
+func notLabelRune(char rune) bool {
+	return !unicode.IsLower(char) && !unicode.IsDigit(char) && char != '-' && char != '_'
+}
+
+// fields which already break a rule aren't checked against the office
+func validatePlacement(violations *Violations, officeName string, stage int, office *Office) {
+	switch {
+	case violations.Has("office"):
+	case office == nil || office.Name != officeName:
+		violations.Add("office", CodeNotFound, fmt.Sprintf("room office %q doesn't exist", officeName))
+	case violations.Has("stage"):
+	case !slices.Contains(office.Floors, stage):
+		violations.Add("stage", CodeNotFound, fmt.Sprintf("office %q has no floor %v", officeName, stage))
+	}
+}
+
+// Violation is a broken rule of a body field, fields are named as in JSON, e.g. labels[2]
+type Violation struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// codes of violations, clients may rely on them unlike on messages
+const (
+	CodeRequired       = "required"
+	CodeUnknown        = "unknown"
+	CodeInvalidType    = "invalid_type"
+	CodeInvalidFormat  = "invalid_format"
+	CodeInvalidCharset = "invalid_charset"
+	CodeTooLong        = "too_long"
+	CodeOutOfRange     = "out_of_range"
+	CodeDuplicate      = "duplicate"
+	CodeNotFound       = "not_found"
+)
+
+// Violations of a body, all of them are reported at once
+type Violations []Violation
+
+func (violations Violations) Error() string {
+	messages := make([]string, len(violations))
+	for i, violation := range violations {
+		messages[i] = violation.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (violations *Violations) Add(field, code, message string) {
+	*violations = append(*violations, Violation{Field: field, Code: code, Message: message})
+}
+
+// Has a violation of the field
+func (violations Violations) Has(field string) bool {
+	return slices.ContainsFunc(violations, func(violation Violation) bool { return violation.Field == field })
+}
+
+// Err is nil if there are no violations
+func (violations Violations) Err() error {
+	if len(violations) == 0 {
+		return nil
+	}
+	return violations
+}
+
+// ErrorKind of a domain error tells callers how to react, e.g. the http api picks a status by it
+type ErrorKind int
+
+const (
+	// not a domain error, e.g. a bug
+	KindUnknown ErrorKind = iota
+	KindNotFound
+	// the entity is fine but the stored state doesn't allow the change
+	KindConflict
+	KindValidation
+	// the storage can't be reached at the moment, a retry may succeed
+	KindUnavailable
+	// the change was made against a state which isn't current anymore
+	KindPrecondition
+)
+
+// DomainError has a kind, sentinel errors of the module are domain errors so errors.Is works with them
+type DomainError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (err *DomainError) Error() string {
+	return err.Err.Error()
+}
+
+func (err *DomainError) Unwrap() error {
+	return err.Err
+}
+
+func notFound(message string) error {
+	return &DomainError{Kind: KindNotFound, Err: errors.New(message)}
+}
+
+func conflict(message string) error {
+	return &DomainError{Kind: KindConflict, Err: errors.New(message)}
+}
+
+func stale(message string) error {
+	return &DomainError{Kind: KindPrecondition, Err: errors.New(message)}
+}
+
+func invalid(message string) error {
+	return &ValidationError{Err: errors.New(message)}
+}
+
+// Unavailable wraps a storage failure which may pass by itself
+func Unavailable(err error) error {
+	return &DomainError{Kind: KindUnavailable, Err: fmt.Errorf("storage is unavailable: %w", err)}
+}
+
+// ValidationError is an entity which breaks rules, e.g. a room in an unknown office
+type ValidationError struct {
+	Err error
+}
+
+func (err *ValidationError) Error() string {
+	return err.Err.Error()
+}
+
+func (err *ValidationError) Unwrap() error {
+	return err.Err
+}
+
+// KindOf the outermost domain error in the chain
+func KindOf(err error) ErrorKind {
+	for ; err != nil; err = errors.Unwrap(err) {
+		switch typed := err.(type) {
+		case *DomainError:
+			return typed.Kind
+		case *ValidationError:
+			return KindValidation
+		}
+	}
+	return KindUnknown
+}
+
+// the longest buffer around a meeting, in minutes
+const MaxBufferMinutes = 120
+
+func validateBuffers(violations *Violations, before, after int) {
+	for _, buffer := range []struct {
+		field   string
+		minutes int
+	}{{"bufferBefore", before}, {"bufferAfter", after}} {
+		if buffer.minutes < 0 {
+			violations.Add(buffer.field, CodeOutOfRange, "room buffers can't be negative")
+		} else if buffer.minutes > MaxBufferMinutes {
+			violations.Add(buffer.field, CodeOutOfRange, fmt.Sprintf("room buffers can't be longer than %v minutes", MaxBufferMinutes))
+		}
+	}
+}
+
+var (
+	ErrRoomNotFound  = notFound("room not found")
+	ErrRoomNameTaken = conflict("room name is already taken")
+	// the room was changed after the version a client has read
+	ErrRoomVersionStale = stale("room was changed by someone else, read it again")
+)
+
+var (
+	ErrOfficeNotFound  = notFound("office not found")
+	ErrOfficeNameTaken = conflict("office name is already taken")
+	ErrOfficeInUse     = conflict("office has rooms")
+	ErrFloorInUse      = conflict("removed floors have rooms")
+)
+
+// OpeningHours of a day of the week by the office clock, "09:00" to "18:00".
+// A day can have several, e.g. around a lunch break.
+type OpeningHours struct {
+	Day   time.Weekday `json:"day"`
+	Open  string       `json:"open"`
+	Close string       `json:"close"`
+}
+
+// minutes since midnight of "hh:mm", "24:00" is the end of a day
+func parseClock(clock string) (int, error) {
+	hours, minutes, ok := strings.Cut(clock, ":")
+	h, hErr := strconv.Atoi(hours)
+	m, mErr := strconv.Atoi(minutes)
+	if !ok || len(hours) != 2 || len(minutes) != 2 || hErr != nil || mErr != nil ||
+		h < 0 || m < 0 || m > 59 || h > 24 || h == 24 && m != 0 {
+		return 0, fmt.Errorf("invalid time %q, expected hh:mm", clock)
+	}
+	return h*60 + m, nil
+}
+
+func validateOpeningHours(violations *Violations, hours []OpeningHours) {
+	for i, day := range hours {
+		field := fmt.Sprintf("openingHours[%v]", i)
+		if day.Day < time.Sunday || day.Day > time.Saturday {
+			violations.Add(field+".day", CodeOutOfRange, fmt.Sprintf("invalid day %v, expected 0 (Sunday) to 6", int(day.Day)))
+		}
+		open, openErr := parseClock(day.Open)
+		if openErr != nil {
+			violations.Add(field+".open", CodeInvalidFormat, openErr.Error())
+		}
+		close, closeErr := parseClock(day.Close)
+		if closeErr != nil {
+			violations.Add(field+".close", CodeInvalidFormat, closeErr.Error())
+		}
+		if openErr == nil && closeErr == nil && open >= close {
+			violations.Add(field+".close", CodeOutOfRange, fmt.Sprintf("opening hours of %v must open before they close", day.Day))
+		}
+	}
+}
+
+type Office struct {
+	Id      string `json:"id"`
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	// IANA name, e.g. Europe/Berlin
+	Timezone     string         `json:"timezone"`
+	OpeningHours []OpeningHours `json:"openingHours"`
+	// stages rooms can be on
+	Floors []int `json:"floors"`
+}
+
+// ValidateOffice lists all Violations of the office in the error
+func ValidateOffice(office *Office) (Office, error) {
+	violations := ValidateOfficeFields(office)
+	if office.OpeningHours == nil {
+		office.OpeningHours = []OpeningHours{}
+	}
+	return *office, violations.Err()
+}
+
+func ValidateOfficeFields(office *Office) Violations {
+	violations := Violations{}
+	if office.Id == "" {
+		violations.Add("id", CodeRequired, "office id can't be empty")
+	}
+	validateOfficeFields(&violations, office.Name, office.Timezone, office.OpeningHours, office.Floors)
+	return violations
+}
+
+type NewOffice struct {
+	Name         string         `json:"name"`
+	Address      string         `json:"address"`
+	Timezone     string         `json:"timezone"`
+	OpeningHours []OpeningHours `json:"openingHours"`
+	Floors       []int          `json:"floors"`
+}
+
+// ValidateNewOffice lists all Violations of the office in the error
+func ValidateNewOffice(office *NewOffice) (NewOffice, error) {
+	violations := ValidateNewOfficeFields(office)
+	if office.OpeningHours == nil {
+		office.OpeningHours = []OpeningHours{}
+	}
+	return *office, violations.Err()
+}
+
+func ValidateNewOfficeFields(office *NewOffice) Violations {
+	violations := Violations{}
+	validateOfficeFields(&violations, office.Name, office.Timezone, office.OpeningHours, office.Floors)
+	return violations
+}
+
+func validateOfficeFields(violations *Violations, name string, timezone string, hours []OpeningHours, floors []int) {
+	if name == "" {
+		violations.Add("name", CodeRequired, "office name can't be empty")
+	}
+	if timezone == "" {
+		violations.Add("timezone", CodeRequired, "office timezone can't be empty")
+	} else if _, err := time.LoadLocation(timezone); err != nil {
+		violations.Add("timezone", CodeNotFound, fmt.Sprintf("unknown office timezone %q", timezone))
+	}
+	if len(floors) == 0 {
+		violations.Add("floors", CodeRequired, "office must have floors")
+	}
+	seen := make(map[int]bool, len(floors))
+	for i, floor := range floors {
+		if seen[floor] {
+			violations.Add(fmt.Sprintf("floors[%v]", i), CodeDuplicate, "office floors must be unique")
+		}
+		seen[floor] = true
+	}
+	validateOpeningHours(violations, hours)
+}
+
+// OpenCheck tells whether an office is open at a moment, At is by the office clock
+type OpenCheck struct {
+	OfficeId string    `json:"officeId"`
+	At       time.Time `json:"at"`
+	Timezone string    `json:"timezone"`
+	Open     bool      `json:"open"`
+	// of the day of At
+	Hours []OpeningHours `json:"hours"`
+}
+
+// IsOpen checks at by the office clock, the office is valid
+func (office *Office) IsOpen(at time.Time) OpenCheck {
+	loc, _ := time.LoadLocation(office.Timezone)
+	local := at.In(loc)
+	check := OpenCheck{OfficeId: office.Id, At: local, Timezone: office.Timezone, Hours: make([]OpeningHours, 0)}
+	minutes := local.Hour()*60 + local.Minute()
+	for _, hours := range office.OpeningHours {
+		if hours.Day != local.Weekday() {
+			continue
+		}
+		check.Hours = append(check.Hours, hours)
+		open, _ := parseClock(hours.Open)
+		close, _ := parseClock(hours.Close)
+		if open <= minutes && minutes < close {
+			check.Open = true
+		}
+	}
+	return check
+}
+
+// the longest window a timetable can be requested for
+const MaxTimetableWindow = 31 * 24 * time.Hour
+
+type TimeWindow struct {
+	From time.Time
+	To   time.Time
+}
+
+func ValidateTimeWindow(window *TimeWindow) (TimeWindow, error) {
+	if !window.From.Before(window.To) {
+		return *window, errors.New("window start must be before its end")
+	}
+	if window.To.Sub(window.From) > MaxTimetableWindow {
+		return *window, errors.New("window can't be longer than 31 days")
+	}
+
+	return *window, nil
+}
+
+// BusyInterval is a part of a room timetable occupied by a booking
+type BusyInterval struct {
+	BookingId string    `json:"bookingId"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+}
+
+type RoomTimetable struct {
+	RoomInfo
+	Timetable []BusyInterval `json:"timetable"`
+}
+
+// Availability of a room in a window, buffers before and after bookings are occupied as well as bookings
+// themselves. Conflicts are the bookings with their own intervals.
+type Availability struct {
+	RoomId       string         `json:"roomId"`
+	From         time.Time      `json:"from"`
+	To           time.Time      `json:"to"`
+	BufferBefore int            `json:"bufferBefore"`
+	BufferAfter  int            `json:"bufferAfter"`
+	Available    bool           `json:"available" db:"-"`
+	Locked       bool           `json:"locked"`
+	Conflicts    []BusyInterval `json:"conflicts"`
+	// the earliest blackout of the room in the window
+	Blackout *BlackoutPeriod `json:"blackout,omitempty" db:"-"`
+}
+
+type FreeRoomQuery struct {
+	Window   TimeWindow
+	Capacity int
+	Office   string
+	Stage    *int
+	Labels   []string
+}
+
+func ValidateFreeRoomQuery(query *FreeRoomQuery) (FreeRoomQuery, error) {
+	if _, err := ValidateTimeWindow(&query.Window); err != nil {
+		return *query, err
+	}
+	if query.Capacity < 1 {
+		return *query, errors.New("required capacity can't be 0 or less")
+	}
+	if query.Office == "" {
+		return *query, errors.New("office can't be empty")
+	}
+
+	return *query, nil
+}
+
+type RoomSort string
+
+const (
+	SortByName         RoomSort = "name"
+	SortByNameDesc     RoomSort = "-name"
+	SortByCapacity     RoomSort = "capacity"
+	SortByCapacityDesc RoomSort = "-capacity"
+)
+
+type LabelsMatch string
+
+const (
+	// rooms with every label
+	MatchAllLabels LabelsMatch = "all"
+	// rooms with at least one of the labels
+	MatchAnyLabel LabelsMatch = "any"
+)
+
+const (
+	DefaultRoomPageSize = 50
+	MaxRoomPageSize     = 200
+)
+
+// RoomQuery filters rooms, empty and nil filters match every room
+type RoomQuery struct {
+	Office      string
+	Stage       *int
+	MinCapacity *int
+	MaxCapacity *int
+	Labels      []string
+	LabelsMatch LabelsMatch
+	NamePrefix  string
+	Sort        RoomSort
+	// the page after the cursor, the first page if nil
+	Cursor   *RoomCursor
+	PageSize int
 }
 
-func ValidateNewRoomInfo(newRoom *NewRoomInfo) (NewRoomInfo, error) {
-	if newRoom.Capacity < 1 {
-		return *newRoom, errors.New("room can't have 0 or less capacity")
+// RoomCursor is the last room of a page in the sort order of the query, the room id breaks ties
+type RoomCursor struct {
+	Sort     RoomSort `json:"s"`
+	Id       string   `json:"i"`
+	Name     string   `json:"n,omitempty"`
+	Capacity int      `json:"c,omitempty"`
+}
+
+func ValidateRoomQuery(query *RoomQuery) (RoomQuery, error) {
+	if query.Sort == "" {
+		query.Sort = SortByName
+	}
+	if query.LabelsMatch == "" {
+		query.LabelsMatch = MatchAllLabels
+	}
+	if query.PageSize == 0 {
+		query.PageSize = DefaultRoomPageSize
+	}
+
+	switch query.Sort {
+	case SortByName, SortByNameDesc, SortByCapacity, SortByCapacityDesc:
+	default:
+		return *query, fmt.Errorf("unknown sort order %q", query.Sort)
+	}
+	if query.LabelsMatch != MatchAllLabels && query.LabelsMatch != MatchAnyLabel {
+		return *query, fmt.Errorf("unknown labels match %q, expected all or any", query.LabelsMatch)
+	}
+	if query.MinCapacity != nil && query.MaxCapacity != nil && *query.MinCapacity > *query.MaxCapacity {
+		return *query, errors.New("min capacity can't be greater than max capacity")
+	}
+	if query.PageSize < 1 || query.PageSize > MaxRoomPageSize {
+		return *query, fmt.Errorf("page size must be from 1 to %v", MaxRoomPageSize)
+	}
+	if query.Cursor != nil && query.Cursor.Sort != query.Sort {
+		return *query, errors.New("cursor belongs to another sort order")
+	}
+	if query.Cursor != nil && !isUUID(query.Cursor.Id) {
+		return *query, errors.New("invalid cursor")
 	}
-	if newRoom.Name == "" {
-		return *newRoom, errors.New("room name can't be empty")
+
+	return *query, nil
+}
+
+// RoomPage has no Next on the last page
+type RoomPage struct {
+	Rooms []RoomInfo
+	Next  *RoomCursor
+}
+
+// CursorAfter points past the room in the sort order
+func CursorAfter(room *RoomInfo, sort RoomSort) RoomCursor {
+	cursor := RoomCursor{Sort: sort, Id: room.Id}
+	switch sort {
+	case SortByCapacity, SortByCapacityDesc:
+		cursor.Capacity = room.Capacity
+	default:
+		cursor.Name = room.Name
+	}
+	return cursor
+}
+
+var (
+	ErrRoomAlreadyLocked = conflict("room is already locked")
+	ErrRoomNotLocked     = conflict("room is not locked")
+	ErrLockUntilInPast   = invalid("lock end must be in the future")
+)
+
+// RoomLock takes a room out of service, e.g. because of a flood or a broken projector.
+// Locks are kept as a history, an open lock has no UnlockedAt.
+type RoomLock struct {
+	Id         string     `json:"id"`
+	RoomId     string     `json:"roomId"`
+	Reason     string     `json:"reason"`
+	LockedBy   string     `json:"lockedBy"`
+	LockedAt   time.Time  `json:"lockedAt"`
+	Until      *time.Time `json:"until,omitempty"`
+	UnlockedAt *time.Time `json:"unlockedAt,omitempty"`
+	UnlockedBy *string    `json:"unlockedBy,omitempty"`
+}
+
+// a lock without Until lasts until an explicit unlock
+type NewRoomLock struct {
+	LockedBy string     `json:"lockedBy"`
+	Reason   string     `json:"reason"`
+	Until    *time.Time `json:"until"`
+}
+
+func ValidateNewRoomLock(lock *NewRoomLock) (NewRoomLock, error) {
+	if lock.LockedBy == "" {
+		return *lock, errors.New("lock author can't be empty")
 	}
-	if newRoom.Office == "" {
-		return *newRoom, errors.New("room office can't be empty")
+	if lock.Reason == "" {
+		return *lock, errors.New("lock reason can't be empty")
 	}
 
-	return *newRoom, nil
+	return *lock, nil
+}
+
+type Unlock struct {
+	UnlockedBy string `json:"unlockedBy"`
+}
+
+func ValidateUnlock(unlock *Unlock) (Unlock, error) {
+	if unlock.UnlockedBy == "" {
+		return *unlock, errors.New("unlock author can't be empty")
+	}
+
+	return *unlock, nil
+}
+
+// LockResult lists bookings cancelled by the lock
+type LockResult struct {
+	Lock              RoomLock `json:"lock"`
+	CancelledBookings []string `json:"cancelledBookings"`
+}
+
+var (
+	ErrBlackoutNotFound       = notFound("blackout not found")
+	ErrBlackoutTargetNotFound = notFound("blackout office or room not found")
+)
+
+// the longest yearly blackout, longer ones are rather one-off shutdowns
+const MaxYearlyBlackout = 31 * 24 * time.Hour
+
+// BlackoutTarget is either a whole office or a single room
+type BlackoutTarget struct {
+	OfficeId *string `json:"officeId,omitempty"`
+	RoomId   *string `json:"roomId,omitempty"`
+}
+
+// Blackout closes an office or a room, e.g. for a public holiday or a planned shutdown.
+// A yearly blackout repeats on the same dates by the office clock.
+type Blackout struct {
+	Id string `json:"id"`
+	BlackoutTarget
+	Reason string    `json:"reason"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Yearly bool      `json:"yearly"`
+	// name and timezone of the office the blackout applies to, directly or through its room
+	Office   string `json:"-"`
+	Timezone string `json:"-"`
+}
+
+type NewBlackout struct {
+	BlackoutTarget
+	Reason string    `json:"reason"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Yearly bool      `json:"yearly"`
+}
+
+func ValidateNewBlackout(blackout *NewBlackout) (NewBlackout, error) {
+	if err := validateBlackoutTarget(&blackout.BlackoutTarget); err != nil {
+		return *blackout, err
+	}
+	if !blackout.Start.Before(blackout.End) {
+		return *blackout, errors.New("blackout start must be before its end")
+	}
+	if blackout.Yearly && blackout.End.Sub(blackout.Start) > MaxYearlyBlackout {
+		return *blackout, errors.New("yearly blackout can't be longer than 31 days")
+	}
+
+	return *blackout, nil
+}
+
+func validateBlackoutTarget(target *BlackoutTarget) error {
+	if (target.OfficeId == nil) == (target.RoomId == nil) {
+		return errors.New("blackout must belong to either an office or a room")
+	}
+	if target.OfficeId != nil && !isUUID(*target.OfficeId) {
+		return fmt.Errorf("invalid blackout office id %q", *target.OfficeId)
+	}
+	if target.RoomId != nil && !isUUID(*target.RoomId) {
+		return fmt.Errorf("invalid blackout room id %q", *target.RoomId)
+	}
+	return nil
+}
+
+func isUUID(value string) bool {
+	_, err := uuid.Parse(value)
+	return err == nil
+}
+
+// BlackoutPeriod is an occurrence of a blackout, a yearly one occurs every year
+type BlackoutPeriod struct {
+	BlackoutId string `json:"blackoutId"`
+	BlackoutTarget
+	Reason string    `json:"reason"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Yearly bool      `json:"yearly"`
+}
+
+// Occurrences overlapping the window by the office clock, the earliest first
+func (blackout *Blackout) Occurrences(window *TimeWindow) []BlackoutPeriod {
+	periods := make([]BlackoutPeriod, 0)
+	occur := func(start, end time.Time) {
+		if start.Before(window.To) && end.After(window.From) {
+			periods = append(periods, BlackoutPeriod{
+				BlackoutId:     blackout.Id,
+				BlackoutTarget: blackout.BlackoutTarget,
+				Reason:         blackout.Reason,
+				Start:          start,
+				End:            end,
+				Yearly:         blackout.Yearly,
+			})
+		}
+	}
+	// occurrences are on the office clock, years are counted by it so a holiday stays on its local date
+	loc, err := time.LoadLocation(blackout.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	start, end := blackout.Start.In(loc), blackout.End.In(loc)
+	if !blackout.Yearly {
+		occur(start, end)
+		return periods
+	}
+
+	// an occurrence of the previous year can last into the window
+	for year := window.From.In(loc).Year() - 1; year <= window.To.In(loc).Year(); year++ {
+		if shift := year - start.Year(); shift >= 0 {
+			occur(start.AddDate(shift, 0, 0), end.AddDate(shift, 0, 0))
+		}
+	}
+	return periods
+}
+
+// ActiveBlackout is the occurrence the room is in at the moment, nil if there is none
+func ActiveBlackout(room *RoomInfo, blackouts []Blackout, at time.Time) *BlackoutPeriod {
+	return WindowBlackout(room, blackouts, &TimeWindow{From: at, To: at.Add(time.Nanosecond)})
+}
+
+// WindowBlackout is the earliest occurrence the room is in during the window, nil if there is none
+func WindowBlackout(room *RoomInfo, blackouts []Blackout, window *TimeWindow) *BlackoutPeriod {
+	var earliest *BlackoutPeriod
+	for i := range blackouts {
+		blackout := &blackouts[i]
+		applies := blackout.RoomId != nil && *blackout.RoomId == room.Id ||
+			blackout.OfficeId != nil && blackout.Office == room.Office
+		if !applies {
+			continue
+		}
+		if periods := blackout.Occurrences(window); len(periods) > 0 && (earliest == nil || periods[0].Start.Before(earliest.Start)) {
+			earliest = &periods[0]
+		}
+	}
+	return earliest
+}
+
+// CalendarEvent is an imported VEVENT, a yearly one has a yearly recurrence rule
+type CalendarEvent struct {
+	Summary string
+	Start   CalendarTime
+	End     CalendarTime
+	Yearly  bool
+}
+
+// CalendarTime is floating when a calendar has neither UTC nor a timezone for it, e.g. dates of all-day events
+type CalendarTime struct {
+	Time     time.Time
+	Floating bool
+}
+
+// In places a floating time on the clock of loc
+func (t CalendarTime) In(loc *time.Location) time.Time {
+	if !t.Floating {
+		return t.Time
+	}
+	return time.Date(t.Time.Year(), t.Time.Month(), t.Time.Day(), t.Time.Hour(), t.Time.Minute(), t.Time.Second(), 0, loc)
+}
+
+// ImportedBlackout turns the event into a blackout of the target, floating times are read by the office clock
+func (event *CalendarEvent) ImportedBlackout(target *BlackoutTarget, loc *time.Location) (NewBlackout, error) {
+	blackout := NewBlackout{
+		BlackoutTarget: *target,
+		Reason:         event.Summary,
+		Start:          event.Start.In(loc),
+		End:            event.End.In(loc),
+		Yearly:         event.Yearly,
+	}
+	return ValidateNewBlackout(&blackout)
 }
@@ -1,11 +1,17 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
 
+var utopia = Office{Name: "BC Utopia", Floors: []int{20}}
+
 func TestNewRoomCapacityValidationFailed(t *testing.T) {
 	data := NewRoomInfo{
 		Name:     "Belyash",
@@ -15,7 +21,7 @@ func TestNewRoomCapacityValidationFailed(t *testing.T) {
 		Labels:   []string{"video", "projector"},
 	}
 	expected := "room can't have 0 or less capacity"
-	_, err := ValidateNewRoomInfo(&data)
+	_, err := ValidateNewRoomInfo(&data, &utopia)
 
 	require.EqualError(t, err, expected)
 }
@@ -29,7 +35,7 @@ func TestNewRoomNameValidationFailed(t *testing.T) {
 		Labels:   []string{"video", "projector"},
 	}
 	expected := "room name can't be empty"
-	_, err := ValidateNewRoomInfo(&data)
+	_, err := ValidateNewRoomInfo(&data, &utopia)
 
 	require.EqualError(t, err, expected)
 }
@@ -43,7 +49,7 @@ func TestNewRoomOfficeValidationFailed(t *testing.T) {
 		Labels:   []string{"video", "projector"},
 	}
 	expected := "room office can't be empty"
-	_, err := ValidateNewRoomInfo(&data)
+	_, err := ValidateNewRoomInfo(&data, &utopia)
 
 	require.EqualError(t, err, expected)
 }
@@ -57,15 +63,46 @@ func TestNewRoomValidationPassed(t *testing.T) {
 		Labels:   []string{"video", "projector"},
 	}
 	expected := data
-	actual, err := ValidateNewRoomInfo(&data)
+	actual, err := ValidateNewRoomInfo(&data, &utopia)
 
 	require.Nil(t, err)
 	require.Equal(t, expected, actual)
 }
 
+func TestNewRoomBufferValidationFailed(t *testing.T) {
+	data := NewRoomInfo{
+		Name:         "Belyash",
+		Capacity:     10,
+		Office:       "BC Utopia",
+		Stage:        20,
+		Labels:       []string{"video", "projector"},
+		BufferBefore: -5,
+	}
+	expected := "room buffers can't be negative"
+	_, err := ValidateNewRoomInfo(&data, &utopia)
+
+	require.EqualError(t, err, expected)
+}
+
+func TestRoomBufferValidationFailed(t *testing.T) {
+	data := RoomInfo{
+		Id:          "6f1f5bd4-5d1e-4b8c-9a43-1d6f3c1b2e2a",
+		Name:        "Belyash",
+		Capacity:    10,
+		Office:      "BC Utopia",
+		Stage:       20,
+		Labels:      []string{"video", "projector"},
+		BufferAfter: 121,
+	}
+	expected := "room buffers can't be longer than 120 minutes"
+	_, err := ValidateRoomInfo(&data, &utopia)
+
+	require.EqualError(t, err, expected)
+}
+
 func TestRoomCapacityValidationFailed(t *testing.T) {
 	data := RoomInfo{
-		Id:       "123",
+		Id:       "6f1f5bd4-5d1e-4b8c-9a43-1d6f3c1b2e2a",
 		Name:     "Belyash",
 		Capacity: 0,
 		Office:   "BC Utopia",
@@ -73,7 +110,7 @@ func TestRoomCapacityValidationFailed(t *testing.T) {
 		Labels:   []string{"video", "projector"},
 	}
 	expected := "room can't have 0 or less capacity"
-	_, err := ValidateRoomInfo(&data)
+	_, err := ValidateRoomInfo(&data, &utopia)
 
 	require.EqualError(t, err, expected)
 }
@@ -88,14 +125,14 @@ func TestRoomIdValidationFailed(t *testing.T) {
 		Labels:   []string{"video", "projector"},
 	}
 	expected := "room id can't be empty"
-	_, err := ValidateRoomInfo(&data)
+	_, err := ValidateRoomInfo(&data, &utopia)
 
 	require.EqualError(t, err, expected)
 }
 
 func TestRoomNameValidationFailed(t *testing.T) {
 	data := RoomInfo{
-		Id:       "123",
+		Id:       "6f1f5bd4-5d1e-4b8c-9a43-1d6f3c1b2e2a",
 		Name:     "",
 		Capacity: 5,
 		Office:   "BC Utopia",
@@ -103,14 +140,14 @@ func TestRoomNameValidationFailed(t *testing.T) {
 		Labels:   []string{"video", "projector"},
 	}
 	expected := "room name can't be empty"
-	_, err := ValidateRoomInfo(&data)
+	_, err := ValidateRoomInfo(&data, &utopia)
 
 	require.EqualError(t, err, expected)
 }
 
 func TestRoomOfficeValidationFailed(t *testing.T) {
 	data := RoomInfo{
-		Id:       "123",
+		Id:       "6f1f5bd4-5d1e-4b8c-9a43-1d6f3c1b2e2a",
 		Name:     "Matnakash",
 		Capacity: 5,
 		Office:   "",
@@ -118,14 +155,14 @@ func TestRoomOfficeValidationFailed(t *testing.T) {
 		Labels:   []string{"video", "projector"},
 	}
 	expected := "room office can't be empty"
-	_, err := ValidateRoomInfo(&data)
+	_, err := ValidateRoomInfo(&data, &utopia)
 
 	require.EqualError(t, err, expected)
 }
 
 func TestRoomValidationPassed(t *testing.T) {
 	data := RoomInfo{
-		Id:       "123",
+		Id:       "6f1f5bd4-5d1e-4b8c-9a43-1d6f3c1b2e2a",
 		Name:     "Belyash",
 		Capacity: 1,
 		Office:   "BC Utopia",
@@ -133,8 +170,259 @@ func TestRoomValidationPassed(t *testing.T) {
 		Labels:   []string{"video", "projector"},
 	}
 	expected := data
-	actual, err := ValidateRoomInfo(&data)
+	actual, err := ValidateRoomInfo(&data, &utopia)
 
 	require.Nil(t, err)
 	require.Equal(t, expected, actual)
 }
+
+func TestTimeWindowOrderValidationFailed(t *testing.T) {
+	from := time.Date(2030, 1, 10, 0, 0, 0, 0, time.UTC)
+	data := TimeWindow{From: from, To: from}
+	expected := "window start must be before its end"
+	_, err := ValidateTimeWindow(&data)
+
+	require.EqualError(t, err, expected)
+}
+
+func TestTimeWindowLengthValidationFailed(t *testing.T) {
+	from := time.Date(2030, 1, 10, 0, 0, 0, 0, time.UTC)
+	data := TimeWindow{From: from, To: from.Add(MaxTimetableWindow + time.Hour)}
+	expected := "window can't be longer than 31 days"
+	_, err := ValidateTimeWindow(&data)
+
+	require.EqualError(t, err, expected)
+}
+
+func TestNewRoomOnUnknownOfficeValidationFailed(t *testing.T) {
+	data := NewRoomInfo{Name: "Belyash", Capacity: 5, Office: "BC Dystopia", Stage: 20, Labels: []string{}}
+	_, err := ValidateNewRoomInfo(&data, nil)
+
+	require.EqualError(t, err, `room office "BC Dystopia" doesn't exist`)
+}
+
+func TestRoomOnUnknownFloorValidationFailed(t *testing.T) {
+	data := RoomInfo{Id: "6f1f5bd4-5d1e-4b8c-9a43-1d6f3c1b2e2a", Name: "Belyash", Capacity: 5, Office: "BC Utopia", Stage: 3, Labels: []string{}}
+	_, err := ValidateRoomInfo(&data, &utopia)
+
+	require.EqualError(t, err, `office "BC Utopia" has no floor 3`)
+}
+
+func TestNewOfficeTimezoneValidationFailed(t *testing.T) {
+	data := NewOffice{Name: "BC Utopia", Timezone: "Mars/Olympus", Floors: []int{1}}
+	_, err := ValidateNewOffice(&data)
+
+	require.EqualError(t, err, `unknown office timezone "Mars/Olympus"`)
+}
+
+func TestNewOfficeFloorsValidationFailed(t *testing.T) {
+	data := NewOffice{Name: "BC Utopia", Timezone: "UTC", Floors: []int{1, 2, 1}}
+	_, err := ValidateNewOffice(&data)
+
+	require.EqualError(t, err, "office floors must be unique")
+}
+
+func TestNewOfficeOpeningHoursValidationFailed(t *testing.T) {
+	data := NewOffice{
+		Name:         "BC Utopia",
+		Timezone:     "UTC",
+		Floors:       []int{1},
+		OpeningHours: []OpeningHours{{Day: time.Monday, Open: "18:00", Close: "09:00"}},
+	}
+	_, err := ValidateNewOffice(&data)
+
+	require.EqualError(t, err, "opening hours of Monday must open before they close")
+}
+
+func TestNewOfficeValidationPassed(t *testing.T) {
+	data := NewOffice{Name: "BC Utopia", Address: "Nevsky prospect, 1", Timezone: "Europe/Moscow", Floors: []int{1, 20}}
+	actual, err := ValidateNewOffice(&data)
+
+	require.NoError(t, err)
+	require.Equal(t, []OpeningHours{}, actual.OpeningHours)
+}
+
+func TestOfficeIsOpenByLocalClock(t *testing.T) {
+	office := Office{
+		Id:       "6f1f5bd4-5d1e-4b8c-9a43-1d6f3c1b2e2a",
+		Timezone: "Europe/Moscow",
+		OpeningHours: []OpeningHours{
+			{Day: time.Thursday, Open: "09:00", Close: "13:00"},
+			{Day: time.Thursday, Open: "14:00", Close: "18:00"},
+			{Day: time.Friday, Open: "00:00", Close: "24:00"},
+		},
+	}
+
+	// 07:30 UTC is 10:30 in Moscow
+	morning := office.IsOpen(time.Date(2030, 1, 10, 7, 30, 0, 0, time.UTC))
+	require.True(t, morning.Open)
+	require.Equal(t, 10, morning.At.Hour())
+	require.Len(t, morning.Hours, 2)
+
+	// lunch break
+	lunch := office.IsOpen(time.Date(2030, 1, 10, 10, 30, 0, 0, time.UTC))
+	require.False(t, lunch.Open)
+
+	// 21:30 UTC on Thursday is already Friday in Moscow
+	night := office.IsOpen(time.Date(2030, 1, 10, 21, 30, 0, 0, time.UTC))
+	require.True(t, night.Open)
+	require.Equal(t, time.Friday, night.At.Weekday())
+}
+
+func TestNewBlackoutTargetValidationFailed(t *testing.T) {
+	officeId, roomId := "6f1c3b3e-7a49-4d35-9a3a-0f2f2a1f6d11", "0b6e8f5c-3a8e-4d6e-8f36-62f6b7e0f2a4"
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := NewBlackout{
+		BlackoutTarget: BlackoutTarget{OfficeId: &officeId, RoomId: &roomId},
+		Start:          start,
+		End:            start.Add(24 * time.Hour),
+	}
+	_, err := ValidateNewBlackout(&data)
+
+	require.EqualError(t, err, "blackout must belong to either an office or a room")
+}
+
+func TestNewYearlyBlackoutLengthValidationFailed(t *testing.T) {
+	officeId := "6f1c3b3e-7a49-4d35-9a3a-0f2f2a1f6d11"
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := NewBlackout{
+		BlackoutTarget: BlackoutTarget{OfficeId: &officeId},
+		Start:          start,
+		End:            start.AddDate(0, 2, 0),
+		Yearly:         true,
+	}
+	_, err := ValidateNewBlackout(&data)
+
+	require.EqualError(t, err, "yearly blackout can't be longer than 31 days")
+}
+
+func TestYearlyBlackoutOccurrences(t *testing.T) {
+	moscow, _ := time.LoadLocation("Europe/Moscow")
+	blackout := Blackout{
+		Id:       "6f1f5bd4-5d1e-4b8c-9a43-1d6f3c1b2e2a",
+		Reason:   "New Year",
+		Start:    time.Date(2030, 1, 1, 0, 0, 0, 0, moscow),
+		End:      time.Date(2030, 1, 9, 0, 0, 0, 0, moscow),
+		Yearly:   true,
+		Timezone: "Europe/Moscow",
+	}
+
+	// the occurrence of 2032 lasts into the window, the one of 2033 starts in it
+	window := TimeWindow{From: time.Date(2032, 1, 5, 0, 0, 0, 0, time.UTC), To: time.Date(2033, 1, 2, 0, 0, 0, 0, time.UTC)}
+	periods := blackout.Occurrences(&window)
+
+	require.Len(t, periods, 2)
+	require.Equal(t, time.Date(2032, 1, 1, 0, 0, 0, 0, moscow), periods[0].Start)
+	require.Equal(t, time.Date(2033, 1, 1, 0, 0, 0, 0, moscow), periods[1].Start)
+
+	// nothing before the first year
+	window = TimeWindow{From: time.Date(2029, 1, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2029, 1, 20, 0, 0, 0, 0, time.UTC)}
+	require.Empty(t, blackout.Occurrences(&window))
+}
+
+func TestActiveBlackoutOfRoomOffice(t *testing.T) {
+	officeId, otherRoomId := "6f1c3b3e-7a49-4d35-9a3a-0f2f2a1f6d11", "0b6e8f5c-3a8e-4d6e-8f36-62f6b7e0f2a4"
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	blackouts := []Blackout{
+		{Id: "1", BlackoutTarget: BlackoutTarget{RoomId: &otherRoomId}, Start: start, End: start.Add(time.Hour), Timezone: "UTC"},
+		{Id: "2", BlackoutTarget: BlackoutTarget{OfficeId: &officeId}, Start: start, End: start.Add(time.Hour), Office: "BC Utopia", Timezone: "UTC"},
+	}
+	room := RoomInfo{Id: "3", Office: "BC Utopia"}
+
+	active := ActiveBlackout(&room, blackouts, start.Add(30*time.Minute))
+	require.NotNil(t, active)
+	require.Equal(t, "2", active.BlackoutId)
+
+	require.Nil(t, ActiveBlackout(&room, blackouts, start.Add(time.Hour)))
+}
+
+func TestWindowBlackoutIsTheEarliest(t *testing.T) {
+	roomId := "0b6e8f5c-3a8e-4d6e-8f36-62f6b7e0f2a4"
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	blackouts := []Blackout{
+		{Id: "1", BlackoutTarget: BlackoutTarget{RoomId: &roomId}, Start: start.Add(2 * time.Hour), End: start.Add(3 * time.Hour), Timezone: "UTC"},
+		{Id: "2", BlackoutTarget: BlackoutTarget{RoomId: &roomId}, Start: start.Add(time.Hour), End: start.Add(2 * time.Hour), Timezone: "UTC"},
+	}
+	room := RoomInfo{Id: roomId, Office: "BC Utopia"}
+
+	blackout := WindowBlackout(&room, blackouts, &TimeWindow{From: start, To: start.Add(4 * time.Hour)})
+	require.NotNil(t, blackout)
+	require.Equal(t, "2", blackout.BlackoutId)
+
+	require.Nil(t, WindowBlackout(&room, blackouts, &TimeWindow{From: start, To: start.Add(time.Hour)}))
+}
+
+func TestRoomQueryDefaults(t *testing.T) {
+	actual, err := ValidateRoomQuery(&RoomQuery{})
+
+	require.NoError(t, err)
+	require.Equal(t, SortByName, actual.Sort)
+	require.Equal(t, MatchAllLabels, actual.LabelsMatch)
+	require.Equal(t, DefaultRoomPageSize, actual.PageSize)
+}
+
+func TestRoomQueryCapacityValidationFailed(t *testing.T) {
+	min, max := 10, 5
+	_, err := ValidateRoomQuery(&RoomQuery{MinCapacity: &min, MaxCapacity: &max})
+
+	require.EqualError(t, err, "min capacity can't be greater than max capacity")
+}
+
+func TestRoomQueryPageSizeValidationFailed(t *testing.T) {
+	_, err := ValidateRoomQuery(&RoomQuery{PageSize: MaxRoomPageSize + 1})
+
+	require.EqualError(t, err, "page size must be from 1 to 200")
+}
+
+func TestKindOfWrappedErrors(t *testing.T) {
+	require.Equal(t, KindNotFound, KindOf(fmt.Errorf("lock: %w", ErrRoomNotFound)))
+	require.Equal(t, KindConflict, KindOf(ErrRoomNameTaken))
+	require.Equal(t, KindValidation, KindOf(&ValidationError{Err: errors.New("invalid")}))
+	require.Equal(t, KindUnavailable, KindOf(Unavailable(errors.New("connection refused"))))
+	require.Equal(t, KindUnknown, KindOf(errors.New("boom")))
+	require.Equal(t, KindUnknown, KindOf(nil))
+}
+
+func TestRoomViolationsReportedAtOnce(t *testing.T) {
+	data := RoomInfo{
+		Id:       "123",
+		Name:     strings.Repeat("Belyash", 20),
+		Capacity: 0,
+		Office:   "BC Utopia",
+		Stage:    500,
+		Labels:   []string{"video", "Projector", "video", "standing-desk"},
+	}
+	_, err := ValidateRoomInfo(&data, &utopia)
+
+	var violations Violations
+	require.ErrorAs(t, err, &violations)
+	require.Equal(t, Violations{
+		{Field: "id", Code: CodeInvalidFormat, Message: `room id "123" isn't a UUID`},
+		{Field: "name", Code: CodeTooLong, Message: "room name can't be longer than 100 characters"},
+		{Field: "capacity", Code: CodeOutOfRange, Message: "room can't have 0 or less capacity"},
+		{Field: "stage", Code: CodeOutOfRange, Message: "room stage must be from -10 to 200"},
+		{Field: "labels[1]", Code: CodeInvalidCharset, Message: `label "Projector" may only have lowercase letters, digits, - and _`},
+		{Field: "labels[2]", Code: CodeDuplicate, Message: `label "video" is repeated`},
+	}, violations)
+}
+
+func TestOfficeViolationsReportedAtOnce(t *testing.T) {
+	data := Office{
+		Timezone:     "Mars/Olympus",
+		Floors:       []int{1, 2, 1},
+		OpeningHours: []OpeningHours{{Day: 7, Open: "9:00", Close: "18:00"}, {Day: time.Monday, Open: "18:00", Close: "09:00"}},
+	}
+	_, err := ValidateOffice(&data)
+
+	var violations Violations
+	require.ErrorAs(t, err, &violations)
+	require.Equal(t, Violations{
+		{Field: "id", Code: CodeRequired, Message: "office id can't be empty"},
+		{Field: "name", Code: CodeRequired, Message: "office name can't be empty"},
+		{Field: "timezone", Code: CodeNotFound, Message: `unknown office timezone "Mars/Olympus"`},
+		{Field: "floors[2]", Code: CodeDuplicate, Message: "office floors must be unique"},
+		{Field: "openingHours[0].day", Code: CodeOutOfRange, Message: "invalid day 7, expected 0 (Sunday) to 6"},
+		{Field: "openingHours[0].open", Code: CodeInvalidFormat, Message: `invalid time "9:00", expected hh:mm`},
+		{Field: "openingHours[1].close", Code: CodeOutOfRange, Message: "opening hours of Monday must open before they close"},
+	}, violations)
+}
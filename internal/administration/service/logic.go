@@ -2,6 +2,9 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/optician/meeting-room-booking/internal/administration/db"
@@ -10,13 +13,63 @@ import (
 )
 
 type Logic interface {
+	// the room must be on a floor of an existing office, otherwise it's a ValidationError
 	Create(ctx context.Context, room *models.NewRoomInfo) (uuid.UUID, error)
 
+	// the room must be on a floor of an existing office, otherwise it's a ValidationError,
+	// its version must be the current one, otherwise it's ErrRoomVersionStale
 	Update(ctx context.Context, room *models.RoomInfo) error
 
-	List(ctx context.Context) ([]models.RoomInfo, error)
+	// the room is in a blackout if it's in one at the moment
+	Room(ctx context.Context, id *uuid.UUID) (models.RoomInfo, error)
 
-	Delete(ctx context.Context, id *uuid.UUID) error
+	// a page of rooms matching the query, rooms in a blackout at the moment have it set
+	List(ctx context.Context, query *models.RoomQuery) (models.RoomPage, error)
+
+	// the version must be the current one, otherwise it's ErrRoomVersionStale
+	Delete(ctx context.Context, id *uuid.UUID, version int64) error
+
+	// rooms in a blackout at the moment have it set
+	ListWithTimetable(ctx context.Context, window *models.TimeWindow) ([]models.RoomTimetable, error)
+
+	Timetable(ctx context.Context, id *uuid.UUID, window *models.TimeWindow) (models.RoomTimetable, error)
+
+	// rooms without bookings in the window, the best capacity fit first
+	FindFree(ctx context.Context, query *models.FreeRoomQuery) ([]models.RoomInfo, error)
+
+	// whether the room is free in the window taking its buffers around bookings into account
+	Availability(ctx context.Context, id *uuid.UUID, window *models.TimeWindow) (models.Availability, error)
+
+	// takes the room out of service and cancels its current and future bookings overlapping the lock
+	Lock(ctx context.Context, roomId *uuid.UUID, lock *models.NewRoomLock) (models.LockResult, error)
+
+	Unlock(ctx context.Context, roomId *uuid.UUID, unlock *models.Unlock) (models.RoomLock, error)
+
+	Locks(ctx context.Context, roomId *uuid.UUID) ([]models.RoomLock, error)
+
+	CreateOffice(ctx context.Context, office *models.NewOffice) (uuid.UUID, error)
+
+	UpdateOffice(ctx context.Context, office *models.Office) error
+
+	Offices(ctx context.Context) ([]models.Office, error)
+
+	Office(ctx context.Context, id *uuid.UUID) (models.Office, error)
+
+	// an office with rooms can't be deleted
+	DeleteOffice(ctx context.Context, id *uuid.UUID) error
+
+	// whether the office is open at the moment by its clock
+	IsOfficeOpen(ctx context.Context, id *uuid.UUID, at time.Time) (models.OpenCheck, error)
+
+	CreateBlackout(ctx context.Context, blackout *models.NewBlackout) (uuid.UUID, error)
+
+	// events become blackouts of the target, all of them or none
+	ImportBlackouts(ctx context.Context, target *models.BlackoutTarget, events []models.CalendarEvent) ([]uuid.UUID, error)
+
+	DeleteBlackout(ctx context.Context, id *uuid.UUID) error
+
+	// occurrences of blackouts of the room and of its office in the window, the earliest first
+	RoomBlackouts(ctx context.Context, roomId *uuid.UUID, window *models.TimeWindow) ([]models.BlackoutPeriod, error)
 }
 
 type impl struct {
@@ -44,12 +97,211 @@ func (impl impl) Update(ctx context.Context, room *models.RoomInfo) error {
 	return (*impl.db).Update(ctx, room) // wrap error
 }
 
-func (impl impl) List(ctx context.Context) ([]models.RoomInfo, error) {
-	list, err := (*impl.db).List(ctx) // wrap error
+func (impl impl) Room(ctx context.Context, id *uuid.UUID) (models.RoomInfo, error) {
+	room, err := (*impl.db).Room(ctx, id)
+	if err != nil {
+		return room, err // wrap error
+	}
+	return room, impl.markBlackouts(ctx, []*models.RoomInfo{&room})
+}
+
+func (impl impl) List(ctx context.Context, query *models.RoomQuery) (models.RoomPage, error) {
+	page, err := (*impl.db).List(ctx, query)
+	if err != nil {
+		return page, err // wrap error
+	}
+	rooms := make([]*models.RoomInfo, len(page.Rooms))
+	for i := range page.Rooms {
+		rooms[i] = &page.Rooms[i]
+	}
+	return page, impl.markBlackouts(ctx, rooms)
+}
+
+func (impl impl) Delete(ctx context.Context, id *uuid.UUID, version int64) error {
+	impl.logger.Infof("delete %v room of version %v", id, version)
+	return (*impl.db).Delete(ctx, id, version) // wrap error
+}
+
+func (impl impl) ListWithTimetable(ctx context.Context, window *models.TimeWindow) ([]models.RoomTimetable, error) {
+	list, err := (*impl.db).ListWithTimetable(ctx, window)
+	if err != nil {
+		return list, err // wrap error
+	}
+	rooms := make([]*models.RoomInfo, len(list))
+	for i := range list {
+		rooms[i] = &list[i].RoomInfo
+	}
+	return list, impl.markBlackouts(ctx, rooms)
+}
+
+// sets blackouts active at the moment
+func (impl impl) markBlackouts(ctx context.Context, rooms []*models.RoomInfo) error {
+	at := time.Now()
+	blackouts, err := (*impl.db).Blackouts(ctx, &models.TimeWindow{From: at, To: at.Add(time.Nanosecond)})
+	if err != nil {
+		return err // wrap error
+	}
+	for _, room := range rooms {
+		room.Blackout = models.ActiveBlackout(room, blackouts, at)
+	}
+	return nil
+}
+
+func (impl impl) Timetable(ctx context.Context, id *uuid.UUID, window *models.TimeWindow) (models.RoomTimetable, error) {
+	timetable, err := (*impl.db).Timetable(ctx, id, window) // wrap error
+	return timetable, err
+}
+
+func (impl impl) FindFree(ctx context.Context, query *models.FreeRoomQuery) ([]models.RoomInfo, error) {
+	list, err := (*impl.db).FindFree(ctx, query)
+	if err != nil {
+		return list, err // wrap error
+	}
+	// yearly blackouts occur by the office clock, so they are matched here rather than in the query
+	blackouts, err := (*impl.db).Blackouts(ctx, &query.Window)
+	if err != nil {
+		return nil, err // wrap error
+	}
+	return slices.DeleteFunc(list, func(room models.RoomInfo) bool {
+		return models.WindowBlackout(&room, blackouts, &query.Window) != nil
+	}), nil
+}
+
+func (impl impl) Availability(ctx context.Context, id *uuid.UUID, window *models.TimeWindow) (models.Availability, error) {
+	availability, err := (*impl.db).Availability(ctx, id, window)
+	if err != nil {
+		return availability, err // wrap error
+	}
+	periods, err := impl.RoomBlackouts(ctx, id, window)
+	if err != nil {
+		return availability, err
+	}
+	if len(periods) > 0 {
+		availability.Blackout = &periods[0]
+		availability.Available = false
+	}
+	return availability, nil
+}
+
+func (impl impl) Lock(ctx context.Context, roomId *uuid.UUID, lock *models.NewRoomLock) (models.LockResult, error) {
+	now := time.Now()
+	if lock.Until != nil && !lock.Until.After(now) {
+		return models.LockResult{}, models.ErrLockUntilInPast
+	}
+	impl.logger.Infof("lock %v room by %v: %v", roomId, lock.LockedBy, lock.Reason)
+	result, err := (*impl.db).Lock(ctx, roomId, lock, now) // wrap error
+	if err == nil && len(result.CancelledBookings) > 0 {
+		impl.logger.Infof("lock of %v room cancelled bookings %v", roomId, result.CancelledBookings)
+	}
+	return result, err
+}
+
+func (impl impl) Unlock(ctx context.Context, roomId *uuid.UUID, unlock *models.Unlock) (models.RoomLock, error) {
+	impl.logger.Infof("unlock %v room by %v", roomId, unlock.UnlockedBy)
+	lock, err := (*impl.db).Unlock(ctx, roomId, unlock, time.Now()) // wrap error
+	return lock, err
+}
+
+func (impl impl) Locks(ctx context.Context, roomId *uuid.UUID) ([]models.RoomLock, error) {
+	list, err := (*impl.db).Locks(ctx, roomId) // wrap error
 	return list, err
 }
 
-func (impl impl) Delete(ctx context.Context, id *uuid.UUID) error {
-	impl.logger.Infof("delete %v room", id)
-	return (*impl.db).Delete(ctx, id) // wrap error
+func (impl impl) CreateOffice(ctx context.Context, office *models.NewOffice) (uuid.UUID, error) {
+	impl.logger.Infof("recieved a new office %v", *office)
+	id, err := (*impl.db).CreateOffice(ctx, office) // wrap error
+	return id, err
+}
+
+func (impl impl) UpdateOffice(ctx context.Context, office *models.Office) error {
+	impl.logger.Infof("recieved an updated office %v", *office)
+	return (*impl.db).UpdateOffice(ctx, office) // wrap error
+}
+
+func (impl impl) Offices(ctx context.Context) ([]models.Office, error) {
+	list, err := (*impl.db).Offices(ctx) // wrap error
+	return list, err
+}
+
+func (impl impl) Office(ctx context.Context, id *uuid.UUID) (models.Office, error) {
+	office, err := (*impl.db).Office(ctx, id) // wrap error
+	return office, err
+}
+
+func (impl impl) DeleteOffice(ctx context.Context, id *uuid.UUID) error {
+	impl.logger.Infof("delete %v office", id)
+	return (*impl.db).DeleteOffice(ctx, id) // wrap error
+}
+
+func (impl impl) IsOfficeOpen(ctx context.Context, id *uuid.UUID, at time.Time) (models.OpenCheck, error) {
+	office, err := (*impl.db).Office(ctx, id)
+	if err != nil {
+		return models.OpenCheck{}, err // wrap error
+	}
+	return office.IsOpen(at), nil
+}
+
+func (impl impl) CreateBlackout(ctx context.Context, blackout *models.NewBlackout) (uuid.UUID, error) {
+	impl.logger.Infof("recieved a new blackout %v", *blackout)
+	ids, err := (*impl.db).CreateBlackouts(ctx, []models.NewBlackout{*blackout})
+	if err != nil {
+		return uuid.Nil, err // wrap error
+	}
+	return ids[0], nil
+}
+
+func (impl impl) ImportBlackouts(ctx context.Context, target *models.BlackoutTarget, events []models.CalendarEvent) ([]uuid.UUID, error) {
+	impl.logger.Infof("import %v blackouts of %v", len(events), *target)
+	office, err := impl.targetOffice(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	// the office timezone is known to be valid
+	loc, _ := time.LoadLocation(office.Timezone)
+	blackouts := make([]models.NewBlackout, 0, len(events))
+	for i, event := range events {
+		blackout, err := event.ImportedBlackout(target, loc)
+		if err != nil {
+			return nil, &models.ValidationError{Err: fmt.Errorf("event %v %q: %w", i+1, event.Summary, err)}
+		}
+		blackouts = append(blackouts, blackout)
+	}
+	ids, err := (*impl.db).CreateBlackouts(ctx, blackouts) // wrap error
+	return ids, err
+}
+
+// the office whose clock the target follows
+func (impl impl) targetOffice(ctx context.Context, target *models.BlackoutTarget) (models.Office, error) {
+	if target.RoomId != nil {
+		id, err := uuid.Parse(*target.RoomId)
+		if err != nil {
+			return models.Office{}, models.ErrRoomNotFound
+		}
+		return (*impl.db).RoomOffice(ctx, &id) // wrap error
+	}
+	id, err := uuid.Parse(*target.OfficeId)
+	if err != nil {
+		return models.Office{}, models.ErrOfficeNotFound
+	}
+	return (*impl.db).Office(ctx, &id) // wrap error
+}
+
+func (impl impl) DeleteBlackout(ctx context.Context, id *uuid.UUID) error {
+	impl.logger.Infof("delete %v blackout", id)
+	return (*impl.db).DeleteBlackout(ctx, id) // wrap error
+}
+
+func (impl impl) RoomBlackouts(ctx context.Context, roomId *uuid.UUID, window *models.TimeWindow) ([]models.BlackoutPeriod, error) {
+	blackouts, err := (*impl.db).RoomBlackouts(ctx, roomId, window)
+	if err != nil {
+		return nil, err // wrap error
+	}
+	periods := make([]models.BlackoutPeriod, 0, len(blackouts))
+	for i := range blackouts {
+		periods = append(periods, blackouts[i].Occurrences(window)...)
+	}
+	slices.SortFunc(periods, func(a, b models.BlackoutPeriod) int {
+		return a.Start.Compare(b.Start)
+	})
+	return periods, nil
 }
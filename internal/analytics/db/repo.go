@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/optician/meeting-room-booking/internal/analytics/models"
+	"go.uber.org/zap"
+)
+
+type DB interface {
+	// the latest state of bookings overlapping [start, end) read from the reservation log, of all offices if office is empty
+	Reservations(ctx context.Context, start time.Time, end time.Time, office string) ([]models.Reservation, error)
+	// rooms of all offices if office is empty
+	Rooms(ctx context.Context, office string) ([]models.Room, error)
+	// all offices if office is empty
+	Offices(ctx context.Context, office string) ([]models.Office, error)
+}
+
+type impl struct {
+	logger *zap.SugaredLogger
+	dbpool *pgxpool.Pool
+}
+
+func New(dbPool *pgxpool.Pool, logger *zap.SugaredLogger) DB {
+	return &impl{
+		logger: logger,
+		dbpool: dbPool,
+	}
+}
+
+func (impl *impl) Reservations(ctx context.Context, start time.Time, end time.Time, office string) ([]models.Reservation, error) {
+	reservations := make([]models.Reservation, 0)
+	// the latest logged event of a booking has its state, the room snapshot is the latest one taken
+	query := `with history as (
+					select booking_id,
+						(array_agg(room_id order by event_id desc))[1] as room_id,
+						coalesce((array_agg(room_name order by event_id desc) filter (where room_name is not null))[1], '') as room_name,
+						coalesce((array_agg(office order by event_id desc) filter (where office is not null))[1], '') as office,
+						coalesce((array_agg(capacity order by event_id desc) filter (where capacity is not null))[1], 0) as capacity,
+						(array_agg(host order by event_id desc))[1] as host,
+						(array_agg(start_at order by event_id desc))[1] as start_at,
+						(array_agg(end_at order by event_id desc))[1] as end_at,
+						(array_agg(attendees order by event_id desc))[1] as attendees,
+						bool_or(checked_in) as checked_in,
+						(array_agg(cancelled_at order by event_id desc))[1] as cancelled_at,
+						(array_agg(cancel_reason order by event_id desc))[1] as cancel_reason
+					from reservation_log
+					where booking_id in (select booking_id from reservation_log where start_at < @end and end_at > @start)
+					group by booking_id
+				)
+				select booking_id, room_id, room_name, office, capacity, host, start_at, end_at,
+					attendees, checked_in, cancelled_at, cancel_reason
+				from history
+				where start_at < @end and end_at > @start
+				  and (@office = '' or office = @office)
+				order by start_at`
+	args := pgx.NamedArgs{"start": start, "end": end, "office": office}
+	err := pgxscan.Select(ctx, impl.dbpool, &reservations, query, args)
+	return reservations, err // wrap error
+}
+
+func (impl *impl) Rooms(ctx context.Context, office string) ([]models.Room, error) {
+	rooms := make([]models.Room, 0)
+	query := `select id, coalesce(name, '') as name, coalesce(office, '') as office, coalesce(capacity, 0) as capacity
+				from meeting_rooms
+				where @office = '' or office = @office
+				order by office, name`
+	err := pgxscan.Select(ctx, impl.dbpool, &rooms, query, pgx.NamedArgs{"office": office})
+	return rooms, err // wrap error
+}
+
+func (impl *impl) Offices(ctx context.Context, office string) ([]models.Office, error) {
+	offices := make([]models.Office, 0)
+	query := `select name, timezone, opening_hours
+				from offices
+				where @office = '' or name = @office
+				order by name`
+	err := pgxscan.Select(ctx, impl.dbpool, &offices, query, pgx.NamedArgs{"office": office})
+	return offices, err // wrap error
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	testHelpers "github.com/optician/meeting-room-booking/internal/administration/db/testing"
+	"github.com/optician/meeting-room-booking/internal/analytics/models"
+	"github.com/optician/meeting-room-booking/internal/dbPool"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"go.uber.org/zap"
+)
+
+type AnalyticsRepositoryTestSuite struct {
+	suite.Suite
+	pgContainer *postgres.PostgresContainer
+	pool        *pgxpool.Pool
+	repository  *DB
+	ctx         context.Context
+	logger      *zap.SugaredLogger
+}
+
+func (suite *AnalyticsRepositoryTestSuite) SetupTest() {
+	ctx := context.Background()
+	logger := zap.NewExample().Sugar()
+	container, err := testHelpers.CreatePostgresContainer(ctx)
+	if err != nil {
+		logger.Fatalf("cannot setup postgres container in AnalyticsRepositoryTestSuite, %v", err)
+	}
+	migrationsPath := "../../../migrations/" // better to use env instead
+	if err := testHelpers.Migrate(ctx, container.ConnectionString, migrationsPath); err != nil {
+		logger.Fatalf("%v", err)
+	}
+	if err := testHelpers.CreateOffices(ctx, container.ConnectionString, "FoodCourt"); err != nil {
+		logger.Fatalf("%v", err)
+	}
+
+	config := dbPool.DBConfig{Url: container.ConnectionString}
+	dbPool, dbPoolErr := dbPool.NewDBPool(&config, logger)
+	if dbPoolErr != nil {
+		logger.Fatalf("application terminated: %v", dbPoolErr)
+	}
+
+	reportsDB := New(dbPool.GetPool(), logger)
+
+	suite.pgContainer = container.Container
+	suite.pool = dbPool.GetPool()
+	suite.repository = &reportsDB
+	suite.ctx = ctx
+	suite.logger = logger
+}
+
+func (suite *AnalyticsRepositoryTestSuite) TearDownTest() {
+	if err := suite.pgContainer.Terminate(suite.ctx); err != nil {
+		suite.logger.Fatalf("error terminating postgres container: %s", err)
+	}
+}
+
+func (suite *AnalyticsRepositoryTestSuite) TestReservationsKeepLatestStateOfLog() {
+	roomId, bookingId := uuid.New(), uuid.New()
+	start := time.Date(2030, 1, 7, 14, 0, 0, 0, time.UTC)
+	log := func(eventId int64, eventType string, roomName *string, checkedIn bool, reason *string) {
+		var cancelledAt *time.Time
+		if reason != nil {
+			at := start.Add(10 * time.Minute)
+			cancelledAt = &at
+		}
+		query := `insert into reservation_log
+					(event_id, type, booking_id, room_id, host, start_at, end_at, occurred_at,
+					 room_name, office, capacity, attendees, checked_in, cancelled_at, cancel_reason)
+					values ($1, $2, $3, $4, 'ivan', $5, $6, $5, $7, 'FoodCourt', 6, 2, $8, $9, $10)`
+		_, err := suite.pool.Exec(suite.ctx, query, eventId, eventType, bookingId, roomId, start, start.Add(time.Hour), roomName, checkedIn, cancelledAt, reason)
+		require.Nil(suite.T(), err, "reservation log error")
+	}
+	name, reason := "Belyash", "flooded"
+	log(1, "booking_created", &name, false, nil)
+	log(2, "booking_checked_in", &name, true, nil)
+	// the room is gone when the cancellation is logged
+	log(3, "booking_cancelled", nil, false, &reason)
+
+	reservations, err := (*suite.repository).Reservations(suite.ctx, start.Add(-time.Hour), start.Add(time.Hour), "")
+	require.Nil(suite.T(), err, "Reservations error")
+	require.Len(suite.T(), reservations, 1)
+	require.Equal(suite.T(), "Belyash", reservations[0].RoomName, "the room snapshot outlives the room")
+	require.Equal(suite.T(), 6, reservations[0].Capacity)
+	require.Equal(suite.T(), 2, reservations[0].Attendees)
+	require.True(suite.T(), reservations[0].CheckedIn)
+	require.Equal(suite.T(), reason, *reservations[0].CancelReason)
+
+	reservations, err = (*suite.repository).Reservations(suite.ctx, start.Add(-time.Hour), start.Add(time.Hour), "BC Utopia")
+	require.Nil(suite.T(), err, "Reservations error")
+	require.Empty(suite.T(), reservations)
+	reservations, err = (*suite.repository).Reservations(suite.ctx, start.Add(time.Hour), start.Add(2*time.Hour), "FoodCourt")
+	require.Nil(suite.T(), err, "Reservations error")
+	require.Empty(suite.T(), reservations)
+}
+
+func (suite *AnalyticsRepositoryTestSuite) TestOfficesHaveOpeningHours() {
+	query := `update offices set timezone = 'Asia/Tokyo', opening_hours = '[{"day":1,"open":"09:00","close":"18:00"}]' where name = 'FoodCourt'`
+	_, err := suite.pool.Exec(suite.ctx, query)
+	require.Nil(suite.T(), err, "office update error")
+
+	offices, err := (*suite.repository).Offices(suite.ctx, "FoodCourt")
+
+	require.Nil(suite.T(), err, "Offices error")
+	require.Len(suite.T(), offices, 1)
+	require.Equal(suite.T(), "Asia/Tokyo", offices[0].Timezone)
+	require.Equal(suite.T(), []models.OfficeDay{{Day: time.Monday, Open: "09:00", Close: "18:00"}}, offices[0].OpeningHours)
+}
+
+func TestAnalyticsRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(AnalyticsRepositoryTestSuite))
+}
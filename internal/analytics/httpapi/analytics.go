@@ -0,0 +1,112 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/optician/meeting-room-booking/internal/analytics/service"
+	"go.uber.org/zap"
+)
+
+type Controller struct {
+	logger *zap.SugaredLogger
+	logic  *service.Logic
+}
+
+// mutates router
+func Make(logic *service.Logic, logger *zap.SugaredLogger) func(chi.Router) {
+	defer logger.Sync()
+
+	controller := Controller{
+		logger: logger,
+		logic:  logic,
+	}
+	return controller.routes
+}
+
+// every report takes ?from=&to=&tz=&office=&format=json|csv
+func (ctrl *Controller) routes(r chi.Router) {
+	r.Route("/analytics", func(r chi.Router) {
+		r.Get("/rooms", ctrl.roomsController)
+		r.Get("/offices", ctrl.officesController)
+		r.Get("/heatmap", ctrl.heatmapController)
+	})
+}
+
+func (ctrl *Controller) roomsController(w http.ResponseWriter, r *http.Request) {
+	query, err := queryFromQuery(r.URL.Query())
+	if err != nil {
+		ctrl.badRequest(w, "Invalid report query", err)
+		return
+	}
+	if rooms, err := (*ctrl.logic).Rooms(r.Context(), &query); err != nil {
+		ctrl.internalError(w, err, "Room utilization raised error")
+	} else {
+		ctrl.write(w, r, "rooms", rooms, func(stream io.Writer) error { return utilizationToCsv(stream, rooms) })
+	}
+}
+
+func (ctrl *Controller) officesController(w http.ResponseWriter, r *http.Request) {
+	query, err := queryFromQuery(r.URL.Query())
+	if err != nil {
+		ctrl.badRequest(w, "Invalid report query", err)
+		return
+	}
+	if offices, err := (*ctrl.logic).Offices(r.Context(), &query); err != nil {
+		ctrl.internalError(w, err, "Office utilization raised error")
+	} else {
+		ctrl.write(w, r, "offices", offices, func(stream io.Writer) error { return utilizationToCsv(stream, offices) })
+	}
+}
+
+func (ctrl *Controller) heatmapController(w http.ResponseWriter, r *http.Request) {
+	query, err := queryFromQuery(r.URL.Query())
+	if err != nil {
+		ctrl.badRequest(w, "Invalid report query", err)
+		return
+	}
+	if cells, err := (*ctrl.logic).Heatmap(r.Context(), &query); err != nil {
+		ctrl.internalError(w, err, "Heatmap raised error")
+	} else {
+		ctrl.write(w, r, "heatmap", cells, func(stream io.Writer) error { return heatmapToCsv(stream, cells) })
+	}
+}
+
+// JSON unless ?format=csv, a CSV report is a file download
+func (ctrl *Controller) write(w http.ResponseWriter, r *http.Request, name string, payload any, toCsv func(io.Writer) error) {
+	var body bytes.Buffer
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		if err := json.NewEncoder(&body).Encode(payload); err != nil {
+			ctrl.internalError(w, err, "internal error")
+			return
+		}
+		w.Header().Add("content-type", "application/json")
+	case "csv":
+		if err := toCsv(&body); err != nil {
+			ctrl.internalError(w, err, "internal error")
+			return
+		}
+		w.Header().Add("content-type", "text/csv")
+		w.Header().Add("content-disposition", fmt.Sprintf(`attachment; filename="%v.csv"`, name))
+	default:
+		ctrl.badRequest(w, "Invalid format", fmt.Errorf("unknown format %q", format))
+		return
+	}
+	w.Write(body.Bytes())
+}
+
+func (ctrl *Controller) badRequest(w http.ResponseWriter, what string, err error) {
+	ctrl.logger.Errorf("Bad Request. %v: %v", what, err)
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write([]byte(err.Error()))
+}
+
+func (ctrl *Controller) internalError(w http.ResponseWriter, err error, what string) {
+	ctrl.logger.Errorf("%v: %v", what, err)
+	w.WriteHeader(http.StatusInternalServerError)
+}
@@ -0,0 +1,106 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/optician/meeting-room-booking/internal/analytics/models"
+	"github.com/optician/meeting-room-booking/internal/analytics/service"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var logger = zap.NewExample().Sugar()
+
+var stubRoomId = uuid.MustParse("9f1c1a4e-3a5b-4e4c-9a7e-1d2f3b4c5d6e")
+
+func router(logic service.Logic) *chi.Mux {
+	r := chi.NewRouter()
+	r.Group(Make(&logic, logger))
+	return r
+}
+
+func TestRoomsAsJson(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/analytics/rooms?from=2030-01-07&to=2030-01-13&tz=Europe/Berlin", nil)
+	rr := httptest.NewRecorder()
+
+	router(&logicStub{}).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "application/json", rr.Header().Get("content-type"))
+	require.Contains(t, rr.Body.String(), `"roomName":"Belyash"`)
+	require.Contains(t, rr.Body.String(), `"utilization":0.5`)
+}
+
+func TestRoomsAsCsv(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/analytics/rooms?from=2030-01-07&to=2030-01-13&format=csv", nil)
+	rr := httptest.NewRecorder()
+
+	router(&logicStub{}).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "text/csv", rr.Header().Get("content-type"))
+	lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+	require.Equal(t, "room_id,room_name,office,capacity,open_hours,booked_hours,utilization,bookings,average_attendees,occupancy,no_shows,no_show_rate", lines[0])
+	require.Equal(t, stubRoomId.String()+",Belyash,FoodCourt,6,45.0000,22.5000,0.5000,10,3.0000,0.5000,1,0.1000", lines[1])
+}
+
+func TestHeatmapAsCsv(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/analytics/heatmap?from=2030-01-07&to=2030-01-13&format=csv", nil)
+	rr := httptest.NewRecorder()
+
+	router(&logicStub{}).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "weekday,hour,booked_hours,utilization\nMonday,9,1.5000,0.7500\n", rr.Body.String())
+}
+
+func TestInvalidReportQueries(t *testing.T) {
+	queries := []string{
+		"/analytics/rooms?from=2030-01-13&to=2030-01-07",
+		"/analytics/rooms?from=2030-01-07",
+		"/analytics/rooms?from=2030-01-07&to=2030-01-13&tz=Mars/Olympus",
+		"/analytics/offices?from=2030-01-07&to=2030-01-13&format=xml",
+		"/analytics/heatmap?from=2030-01-01&to=2031-06-01",
+	}
+	for _, query := range queries {
+		req, _ := http.NewRequest("GET", query, nil)
+		rr := httptest.NewRecorder()
+
+		router(&logicStub{}).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+}
+
+type logicStub struct{}
+
+func (*logicStub) Rooms(ctx context.Context, query *models.Query) ([]models.Utilization, error) {
+	return []models.Utilization{{
+		RoomId:           &stubRoomId,
+		RoomName:         "Belyash",
+		Office:           "FoodCourt",
+		Capacity:         6,
+		OpenHours:        45,
+		BookedHours:      22.5,
+		Utilization:      0.5,
+		Bookings:         10,
+		AverageAttendees: 3,
+		Occupancy:        0.5,
+		NoShows:          1,
+		NoShowRate:       0.1,
+	}}, nil
+}
+
+func (stub *logicStub) Offices(ctx context.Context, query *models.Query) ([]models.Utilization, error) {
+	return stub.Rooms(ctx, query)
+}
+
+func (*logicStub) Heatmap(ctx context.Context, query *models.Query) ([]models.HeatmapCell, error) {
+	return []models.HeatmapCell{{Weekday: 1, Hour: 9, BookedHours: 1.5, Utilization: 0.75}}, nil
+}
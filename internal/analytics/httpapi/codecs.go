@@ -0,0 +1,77 @@
+package httpapi
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/optician/meeting-room-booking/internal/analytics/models"
+)
+
+const dateLayout = "2006-01-02"
+
+// from and to are dates, both included; tz is an IANA timezone of the dates, UTC by default
+func queryFromQuery(query url.Values) (models.Query, error) {
+	report := models.Query{Office: query.Get("office")}
+	loc, err := time.LoadLocation(query.Get("tz"))
+	if err != nil {
+		return report, fmt.Errorf("invalid tz parameter: %w", err)
+	}
+	from, err := time.ParseInLocation(dateLayout, query.Get("from"), loc)
+	if err != nil {
+		return report, fmt.Errorf("invalid from parameter: %w", err)
+	}
+	to, err := time.ParseInLocation(dateLayout, query.Get("to"), loc)
+	if err != nil {
+		return report, fmt.Errorf("invalid to parameter: %w", err)
+	}
+	report.Range, err = models.ValidateRange(&models.Range{From: from, To: to, Location: loc})
+	return report, err
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', 4, 64)
+}
+
+func utilizationToCsv(stream io.Writer, utilization []models.Utilization) error {
+	writer := csv.NewWriter(stream)
+	writer.Write([]string{
+		"room_id", "room_name", "office", "capacity", "open_hours", "booked_hours", "utilization",
+		"bookings", "average_attendees", "occupancy", "no_shows", "no_show_rate",
+	})
+	for _, row := range utilization {
+		roomId := ""
+		if row.RoomId != nil {
+			roomId = row.RoomId.String()
+		}
+		writer.Write([]string{
+			roomId,
+			row.RoomName,
+			row.Office,
+			strconv.Itoa(row.Capacity),
+			formatFloat(row.OpenHours),
+			formatFloat(row.BookedHours),
+			formatFloat(row.Utilization),
+			strconv.Itoa(row.Bookings),
+			formatFloat(row.AverageAttendees),
+			formatFloat(row.Occupancy),
+			strconv.Itoa(row.NoShows),
+			formatFloat(row.NoShowRate),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func heatmapToCsv(stream io.Writer, cells []models.HeatmapCell) error {
+	writer := csv.NewWriter(stream)
+	writer.Write([]string{"weekday", "hour", "booked_hours", "utilization"})
+	for _, cell := range cells {
+		writer.Write([]string{cell.Weekday.String(), strconv.Itoa(cell.Hour), formatFloat(cell.BookedHours), formatFloat(cell.Utilization)})
+	}
+	writer.Flush()
+	return writer.Error()
+}
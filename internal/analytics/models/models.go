@@ -0,0 +1,419 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// the longest range of a report
+const MaxRangeDays = 366
+
+// the cancel reason of bookings released by the no-show releaser
+const NoShowReason = "no-show"
+
+// Range is whole days from From to To inclusive in Location
+type Range struct {
+	From     time.Time
+	To       time.Time
+	Location *time.Location
+}
+
+func ValidateRange(period *Range) (Range, error) {
+	if period.To.Before(period.From) {
+		return *period, errors.New("range start must not be after its end")
+	}
+	if period.To.Sub(period.From) >= MaxRangeDays*24*time.Hour {
+		return *period, errors.New("range can't be longer than 366 days")
+	}
+	return *period, nil
+}
+
+// Start is the first moment of the range
+func (period *Range) Start() time.Time {
+	return time.Date(period.From.Year(), period.From.Month(), period.From.Day(), 0, 0, 0, 0, period.Location)
+}
+
+// End is the moment after the range
+func (period *Range) End() time.Time {
+	return time.Date(period.To.Year(), period.To.Month(), period.To.Day()+1, 0, 0, 0, 0, period.Location)
+}
+
+// OpeningHours are the configured ones in the report timezone, they are used for offices without their own
+type OpeningHours struct {
+	// since midnight
+	Open  time.Duration
+	Close time.Duration
+	Days  []time.Weekday
+}
+
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Windows are the opening hours within the range
+func (hours *OpeningHours) Windows(period *Range) []Interval {
+	open := make(map[time.Weekday]bool, len(hours.Days))
+	for _, day := range hours.Days {
+		open[day] = true
+	}
+	windows := make([]Interval, 0)
+	start, end := period.Start(), period.End()
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		if !open[day.Weekday()] {
+			continue
+		}
+		windows = append(windows, Interval{Start: wallClock(day, hours.Open), End: wallClock(day, hours.Close)})
+	}
+	return windows
+}
+
+// Office is read with its opening hours by the office clock, "09:00" to "18:00"
+type Office struct {
+	Name         string
+	Timezone     string
+	OpeningHours []OfficeDay `db:"opening_hours"`
+}
+
+type OfficeDay struct {
+	Day   time.Weekday `json:"day"`
+	Open  string       `json:"open"`
+	Close string       `json:"close"`
+}
+
+// OfficeHours are opening hours of an office in its timezone, a day can have several
+type OfficeHours struct {
+	Location *time.Location
+	Days     []DayHours
+}
+
+// DayHours are since midnight
+type DayHours struct {
+	Day   time.Weekday
+	Open  time.Duration
+	Close time.Duration
+}
+
+func NewOfficeHours(office *Office) (OfficeHours, error) {
+	loc, err := time.LoadLocation(office.Timezone)
+	if err != nil {
+		return OfficeHours{}, fmt.Errorf("unknown timezone %q of office %v", office.Timezone, office.Name)
+	}
+	hours := OfficeHours{Location: loc, Days: make([]DayHours, 0, len(office.OpeningHours))}
+	for _, day := range office.OpeningHours {
+		open, openErr := parseClock(day.Open)
+		close, closeErr := parseClock(day.Close)
+		if err := errors.Join(openErr, closeErr); err != nil {
+			return OfficeHours{}, fmt.Errorf("opening hours of office %v: %w", office.Name, err)
+		}
+		hours.Days = append(hours.Days, DayHours{Day: day.Day, Open: open, Close: close})
+	}
+	return hours, nil
+}
+
+// "hh:mm" since midnight, "24:00" is the end of a day
+func parseClock(clock string) (time.Duration, error) {
+	hours, minutes, ok := strings.Cut(clock, ":")
+	h, hErr := strconv.Atoi(hours)
+	m, mErr := strconv.Atoi(minutes)
+	if !ok || hErr != nil || mErr != nil {
+		return 0, fmt.Errorf("invalid time %q", clock)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// Windows are the opening hours by the office clock within the range, which may be in another timezone
+func (hours *OfficeHours) Windows(period *Range) []Interval {
+	whole := Interval{Start: period.Start(), End: period.End()}
+	first := whole.Start.In(hours.Location)
+	windows := make([]Interval, 0)
+	for day := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, hours.Location); day.Before(whole.End); day = day.AddDate(0, 0, 1) {
+		for _, open := range hours.Days {
+			if open.Day != day.Weekday() {
+				continue
+			}
+			window := intersect(Interval{Start: wallClock(day, open.Open), End: wallClock(day, open.Close)}, whole)
+			if window.Start.Before(window.End) {
+				windows = append(windows, window)
+			}
+		}
+	}
+	return windows
+}
+
+// Schedule has opening hours of offices, the configured ones are for the rest
+type Schedule struct {
+	Default OpeningHours
+	Offices map[string]OfficeHours
+}
+
+func (schedule *Schedule) Windows(office string, period *Range) []Interval {
+	if hours, ok := schedule.Offices[office]; ok {
+		return hours.Windows(period)
+	}
+	return schedule.Default.Windows(period)
+}
+
+// the time of the day by the clock, so opening hours are the same on days of a DST change
+func wallClock(day time.Time, sinceMidnight time.Duration) time.Time {
+	hours := int(sinceMidnight.Hours())
+	minutes := int(sinceMidnight.Minutes()) % 60
+	return time.Date(day.Year(), day.Month(), day.Day(), hours, minutes, 0, 0, day.Location())
+}
+
+// Reservation is the latest state of a booking with its room at the booking time
+type Reservation struct {
+	BookingId    uuid.UUID
+	RoomId       uuid.UUID
+	RoomName     string
+	Office       string
+	Capacity     int
+	Host         string
+	Start        time.Time `db:"start_at"`
+	End          time.Time `db:"end_at"`
+	Attendees    int
+	CheckedIn    bool
+	CancelledAt  *time.Time
+	CancelReason *string
+}
+
+// Held tells whether the room was really reserved: a booking cancelled before its start wasn't
+func (reservation *Reservation) Held() bool {
+	return reservation.CancelledAt == nil || !reservation.CancelledAt.Before(reservation.Start) || reservation.NoShow()
+}
+
+func (reservation *Reservation) NoShow() bool {
+	return reservation.CancelReason != nil && *reservation.CancelReason == NoShowReason
+}
+
+// Occupied is the time the room was taken, until the cancellation of a started booking
+func (reservation *Reservation) Occupied() Interval {
+	end := reservation.End
+	if reservation.CancelledAt != nil && reservation.CancelledAt.Before(end) {
+		end = *reservation.CancelledAt
+	}
+	if end.Before(reservation.Start) {
+		end = reservation.Start
+	}
+	return Interval{Start: reservation.Start, End: end}
+}
+
+// empty intervals have End not after Start
+func intersect(a Interval, b Interval) Interval {
+	start := a.Start
+	if b.Start.After(start) {
+		start = b.Start
+	}
+	end := a.End
+	if b.End.Before(end) {
+		end = b.End
+	}
+	return Interval{Start: start, End: end}
+}
+
+func overlap(a Interval, b Interval) time.Duration {
+	common := intersect(a, b)
+	if !common.Start.Before(common.End) {
+		return 0
+	}
+	return common.End.Sub(common.Start)
+}
+
+type Room struct {
+	Id       uuid.UUID
+	Name     string
+	Office   string
+	Capacity int
+}
+
+// Utilization of a room or an office over a range
+type Utilization struct {
+	RoomId   *uuid.UUID `json:"roomId,omitempty"`
+	RoomName string     `json:"roomName,omitempty"`
+	Office   string     `json:"office"`
+	Capacity int        `json:"capacity"`
+	// of all rooms for an office
+	OpenHours   float64 `json:"openHours"`
+	BookedHours float64 `json:"bookedHours"`
+	// booked hours within opening hours / open hours
+	Utilization      float64 `json:"utilization"`
+	Bookings         int     `json:"bookings"`
+	AverageAttendees float64 `json:"averageAttendees"`
+	// attendees / capacity on average
+	Occupancy  float64 `json:"occupancy"`
+	NoShows    int     `json:"noShows"`
+	NoShowRate float64 `json:"noShowRate"`
+
+	attendees     int
+	seats         int
+	openDuration  time.Duration
+	bookedInHours time.Duration
+}
+
+func (utilization *Utilization) add(reservation *Reservation, windows []Interval) {
+	if !reservation.Held() {
+		return
+	}
+	occupied := reservation.Occupied()
+	for _, window := range windows {
+		utilization.bookedInHours += overlap(occupied, window)
+	}
+	utilization.Bookings++
+	utilization.attendees += reservation.Attendees
+	utilization.seats += reservation.Capacity
+	if reservation.NoShow() {
+		utilization.NoShows++
+	}
+}
+
+func (utilization *Utilization) merge(other *Utilization) {
+	utilization.Capacity += other.Capacity
+	utilization.openDuration += other.openDuration
+	utilization.bookedInHours += other.bookedInHours
+	utilization.Bookings += other.Bookings
+	utilization.attendees += other.attendees
+	utilization.seats += other.seats
+	utilization.NoShows += other.NoShows
+}
+
+func (utilization *Utilization) finish() {
+	utilization.OpenHours = utilization.openDuration.Hours()
+	utilization.BookedHours = utilization.bookedInHours.Hours()
+	utilization.Utilization = ratio(utilization.bookedInHours.Hours(), utilization.openDuration.Hours())
+	utilization.AverageAttendees = ratio(float64(utilization.attendees), float64(utilization.Bookings))
+	utilization.Occupancy = ratio(float64(utilization.attendees), float64(utilization.seats))
+	utilization.NoShowRate = ratio(float64(utilization.NoShows), float64(utilization.Bookings))
+}
+
+func ratio(part float64, whole float64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return part / whole
+}
+
+// RoomUtilization has every room, rooms deleted since are taken from reservations.
+// Opening hours of a room are the ones of its office.
+func RoomUtilization(rooms []Room, reservations []Reservation, schedule *Schedule, period *Range) []Utilization {
+	windows := make(map[string][]Interval)
+	officeWindows := func(office string) []Interval {
+		if _, ok := windows[office]; !ok {
+			windows[office] = schedule.Windows(office, period)
+		}
+		return windows[office]
+	}
+
+	byRoom := make(map[uuid.UUID]*Utilization, len(rooms))
+	order := make([]uuid.UUID, 0, len(rooms))
+	addRoom := func(id uuid.UUID, name string, office string, capacity int) *Utilization {
+		if utilization, ok := byRoom[id]; ok {
+			return utilization
+		}
+		var open time.Duration
+		for _, window := range officeWindows(office) {
+			open += window.End.Sub(window.Start)
+		}
+		roomId := id
+		utilization := &Utilization{RoomId: &roomId, RoomName: name, Office: office, Capacity: capacity, openDuration: open}
+		byRoom[id] = utilization
+		order = append(order, id)
+		return utilization
+	}
+	for _, room := range rooms {
+		addRoom(room.Id, room.Name, room.Office, room.Capacity)
+	}
+	for i := range reservations {
+		reservation := &reservations[i]
+		room := addRoom(reservation.RoomId, reservation.RoomName, reservation.Office, reservation.Capacity)
+		room.add(reservation, officeWindows(room.Office))
+	}
+
+	result := make([]Utilization, 0, len(order))
+	for _, id := range order {
+		byRoom[id].finish()
+		result = append(result, *byRoom[id])
+	}
+	return result
+}
+
+// OfficeUtilization sums up rooms of every office, capacity is the total of rooms
+func OfficeUtilization(rooms []Utilization) []Utilization {
+	byOffice := make(map[string]*Utilization)
+	order := make([]string, 0)
+	for i := range rooms {
+		office, ok := byOffice[rooms[i].Office]
+		if !ok {
+			office = &Utilization{Office: rooms[i].Office}
+			byOffice[rooms[i].Office] = office
+			order = append(order, rooms[i].Office)
+		}
+		office.merge(&rooms[i])
+	}
+
+	result := make([]Utilization, 0, len(order))
+	for _, name := range order {
+		byOffice[name].finish()
+		result = append(result, *byOffice[name])
+	}
+	return result
+}
+
+// HeatmapCell is an hour of a weekday
+type HeatmapCell struct {
+	Weekday     time.Weekday `json:"weekday"`
+	Hour        int          `json:"hour"`
+	BookedHours float64      `json:"bookedHours"`
+	// booked hours / hours of all rooms in this cell over the range
+	Utilization float64 `json:"utilization"`
+}
+
+// Heatmap has 7*24 cells from Sunday 0h, hours are local to the range location
+func Heatmap(roomCount int, reservations []Reservation, period *Range) []HeatmapCell {
+	booked := make([]time.Duration, 7*24)
+	available := make([]time.Duration, 7*24)
+	start, end := period.Start(), period.End()
+	for hour := start; hour.Before(end); hour = hour.Add(time.Hour) {
+		available[cell(hour, period.Location)] += time.Duration(roomCount) * time.Hour
+	}
+	whole := Interval{Start: start, End: end}
+	for i := range reservations {
+		if !reservations[i].Held() {
+			continue
+		}
+		occupied := intersect(reservations[i].Occupied(), whole)
+		// hour by hour from the hour of the start
+		local := occupied.Start.In(period.Location)
+		hour := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, period.Location)
+		for ; hour.Before(occupied.End); hour = hour.Add(time.Hour) {
+			slot := Interval{Start: hour, End: hour.Add(time.Hour)}
+			booked[cell(hour, period.Location)] += overlap(occupied, slot)
+		}
+	}
+
+	cells := make([]HeatmapCell, 0, len(booked))
+	for i := range booked {
+		cells = append(cells, HeatmapCell{
+			Weekday:     time.Weekday(i / 24),
+			Hour:        i % 24,
+			BookedHours: booked[i].Hours(),
+			Utilization: ratio(booked[i].Hours(), available[i].Hours()),
+		})
+	}
+	return cells
+}
+
+func cell(hour time.Time, loc *time.Location) int {
+	local := hour.In(loc)
+	return int(local.Weekday())*24 + local.Hour()
+}
+
+// Query of a report, of all offices if Office is empty
+type Query struct {
+	Range  Range
+	Office string
+}
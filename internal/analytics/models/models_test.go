@@ -0,0 +1,108 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+var weekdays = OpeningHours{
+	Open:  9 * time.Hour,
+	Close: 18 * time.Hour,
+	Days:  []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+}
+
+// Monday and Tuesday
+var twoDays = Range{
+	From:     time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC),
+	To:       time.Date(2030, 1, 8, 0, 0, 0, 0, time.UTC),
+	Location: time.UTC,
+}
+
+func TestWindowsKeepWallClockOverDstChange(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	// Sunday 2030-03-31 is the change, Monday is the next day
+	period := Range{From: time.Date(2030, 3, 29, 0, 0, 0, 0, berlin), To: time.Date(2030, 4, 1, 0, 0, 0, 0, berlin), Location: berlin}
+
+	windows := weekdays.Windows(&period)
+
+	require.Len(t, windows, 2)
+	require.Equal(t, 9, windows[1].Start.In(berlin).Hour())
+	require.Equal(t, 9*time.Hour, windows[1].End.Sub(windows[1].Start))
+}
+
+func TestRoomUtilization(t *testing.T) {
+	roomId := uuid.New()
+	monday := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	noShow := NoShowReason
+	releasedAt := monday.Add(14*time.Hour + 10*time.Minute)
+	cancelledAt := monday
+	reservations := []Reservation{
+		// half out of opening hours
+		{RoomId: roomId, Capacity: 10, Start: monday.Add(17 * time.Hour), End: monday.Add(19 * time.Hour), Attendees: 6},
+		{RoomId: roomId, Capacity: 10, Start: monday.Add(14 * time.Hour), End: monday.Add(15 * time.Hour), Attendees: 2, CancelledAt: &releasedAt, CancelReason: &noShow},
+		// cancelled in advance, the room was free
+		{RoomId: roomId, Capacity: 10, Start: monday.Add(10 * time.Hour), End: monday.Add(11 * time.Hour), Attendees: 9, CancelledAt: &cancelledAt},
+	}
+	rooms := []Room{{Id: roomId, Name: "Belyash", Office: "FoodCourt", Capacity: 10}, {Id: uuid.New(), Name: "Pyshka", Office: "FoodCourt", Capacity: 4}}
+
+	utilization := RoomUtilization(rooms, reservations, &Schedule{Default: weekdays}, &twoDays)
+
+	require.Len(t, utilization, 2)
+	belyash := utilization[0]
+	require.Equal(t, 18.0, belyash.OpenHours)
+	require.InDelta(t, 1+10.0/60, belyash.BookedHours, 0.0001)
+	require.Equal(t, 2, belyash.Bookings)
+	require.Equal(t, 4.0, belyash.AverageAttendees)
+	require.Equal(t, 0.4, belyash.Occupancy)
+	require.Equal(t, 1, belyash.NoShows)
+	require.Equal(t, 0.5, belyash.NoShowRate)
+	require.Equal(t, 0, utilization[1].Bookings)
+
+	offices := OfficeUtilization(utilization)
+	require.Len(t, offices, 1)
+	require.Equal(t, 14, offices[0].Capacity)
+	require.Equal(t, 36.0, offices[0].OpenHours)
+}
+
+func TestOfficeHoursByOfficeClock(t *testing.T) {
+	office := Office{
+		Name:     "Tokyo",
+		Timezone: "Asia/Tokyo",
+		OpeningHours: []OfficeDay{
+			{Day: time.Monday, Open: "09:00", Close: "12:00"},
+			{Day: time.Monday, Open: "13:00", Close: "18:00"},
+		},
+	}
+	hours, err := NewOfficeHours(&office)
+	require.Nil(t, err)
+	roomId := uuid.New()
+	monday := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	// 10:00 to 11:00 in Tokyo on Monday
+	reservations := []Reservation{{RoomId: roomId, Office: "Tokyo", Start: monday.Add(time.Hour), End: monday.Add(2 * time.Hour)}}
+	schedule := Schedule{Default: weekdays, Offices: map[string]OfficeHours{"Tokyo": hours}}
+
+	utilization := RoomUtilization([]Room{{Id: roomId, Office: "Tokyo"}}, reservations, &schedule, &twoDays)
+
+	// 9:00 to 12:00 and 13:00 to 18:00 in Tokyo are 0:00 to 3:00 and 4:00 to 9:00 in UTC
+	require.Len(t, utilization, 1)
+	require.Equal(t, 8.0, utilization[0].OpenHours)
+	require.Equal(t, 1.0, utilization[0].BookedHours)
+	require.Len(t, hours.Windows(&Range{From: monday, To: monday, Location: time.FixedZone("UTC+9", 9*60*60)}), 2)
+}
+
+func TestHeatmap(t *testing.T) {
+	monday := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	reservations := []Reservation{{Start: monday.Add(9*time.Hour + 30*time.Minute), End: monday.Add(11 * time.Hour)}}
+
+	cells := Heatmap(2, reservations, &twoDays)
+
+	require.Len(t, cells, 7*24)
+	nine := cells[int(time.Monday)*24+9]
+	require.Equal(t, 0.5, nine.BookedHours)
+	require.Equal(t, 0.25, nine.Utilization)
+	require.Equal(t, 1.0, cells[int(time.Monday)*24+10].BookedHours)
+	require.Equal(t, 0.0, cells[int(time.Tuesday)*24+10].BookedHours)
+}
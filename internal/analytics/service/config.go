@@ -0,0 +1,11 @@
+package service
+
+import "time"
+
+type Config struct {
+	// opening hours since midnight in the report timezone of offices which have no opening hours of their own
+	OpenAt  time.Duration `koanf:"open_at"`
+	CloseAt time.Duration `koanf:"close_at"`
+	// 0 is Sunday
+	WorkingDays []time.Weekday `koanf:"working_days"`
+}
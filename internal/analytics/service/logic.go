@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+
+	"github.com/optician/meeting-room-booking/internal/analytics/db"
+	"github.com/optician/meeting-room-booking/internal/analytics/models"
+	"go.uber.org/zap"
+)
+
+type Logic interface {
+	Rooms(ctx context.Context, query *models.Query) ([]models.Utilization, error)
+	Offices(ctx context.Context, query *models.Query) ([]models.Utilization, error)
+	Heatmap(ctx context.Context, query *models.Query) ([]models.HeatmapCell, error)
+}
+
+type impl struct {
+	logger *zap.SugaredLogger
+	db     *db.DB
+	hours  models.OpeningHours
+}
+
+func Make(db *db.DB, config *Config, logger *zap.SugaredLogger) Logic {
+	defer logger.Sync()
+
+	return impl{
+		logger: logger,
+		db:     db,
+		hours:  models.OpeningHours{Open: config.OpenAt, Close: config.CloseAt, Days: config.WorkingDays},
+	}
+}
+
+func (impl impl) Rooms(ctx context.Context, query *models.Query) ([]models.Utilization, error) {
+	rooms, err := (*impl.db).Rooms(ctx, query.Office) // wrap error
+	if err != nil {
+		return nil, err
+	}
+	reservations, err := (*impl.db).Reservations(ctx, query.Range.Start(), query.Range.End(), query.Office) // wrap error
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := impl.schedule(ctx, query.Office)
+	if err != nil {
+		return nil, err
+	}
+	return models.RoomUtilization(rooms, reservations, &schedule, &query.Range), nil
+}
+
+// offices without opening hours of their own have the configured ones
+func (impl impl) schedule(ctx context.Context, office string) (models.Schedule, error) {
+	schedule := models.Schedule{Default: impl.hours, Offices: make(map[string]models.OfficeHours)}
+	offices, err := (*impl.db).Offices(ctx, office) // wrap error
+	if err != nil {
+		return schedule, err
+	}
+	for i := range offices {
+		if len(offices[i].OpeningHours) == 0 {
+			continue
+		}
+		hours, err := models.NewOfficeHours(&offices[i])
+		if err != nil {
+			return schedule, err
+		}
+		schedule.Offices[offices[i].Name] = hours
+	}
+	return schedule, nil
+}
+
+func (impl impl) Offices(ctx context.Context, query *models.Query) ([]models.Utilization, error) {
+	rooms, err := impl.Rooms(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return models.OfficeUtilization(rooms), nil
+}
+
+// utilization is relative to rooms existing now
+func (impl impl) Heatmap(ctx context.Context, query *models.Query) ([]models.HeatmapCell, error) {
+	rooms, err := (*impl.db).Rooms(ctx, query.Office) // wrap error
+	if err != nil {
+		return nil, err
+	}
+	reservations, err := (*impl.db).Reservations(ctx, query.Range.Start(), query.Range.End(), query.Office) // wrap error
+	if err != nil {
+		return nil, err
+	}
+	return models.Heatmap(len(rooms), reservations, &query.Range), nil
+}
@@ -0,0 +1,861 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/optician/meeting-room-booking/internal/booking/models"
+	"github.com/optician/meeting-room-booking/internal/booking/recurrence"
+	outboxDB "github.com/optician/meeting-room-booking/internal/outbox/db"
+	outbox "github.com/optician/meeting-room-booking/internal/outbox/models"
+	"go.uber.org/zap"
+)
+
+// postgres error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	foreignKeyViolation = "23503"
+	exclusionViolation  = "23P01"
+)
+
+type DB interface {
+	Create(context.Context, *models.NewBooking) (uuid.UUID, error)
+	Get(context.Context, *uuid.UUID) (models.Booking, error)
+	Cancel(ctx context.Context, id *uuid.UUID, cancellation *models.Cancellation, at time.Time) (models.Booking, error)
+	CreateSeries(ctx context.Context, series *models.NewSeries, occurrences []models.NewBooking) (models.SeriesResult, error)
+	CancelSeries(ctx context.Context, id *uuid.UUID, scope models.Scope, cancellation *models.Cancellation, at time.Time) ([]models.Booking, error)
+	Update(ctx context.Context, id *uuid.UUID, scope models.Scope, change *models.BookingChange, at time.Time) ([]models.Booking, error)
+	CheckIn(ctx context.Context, id *uuid.UUID, checkIn *models.CheckIn, at time.Time) (models.Booking, error)
+	ReleaseNoShows(ctx context.Context, startedBefore time.Time, at time.Time) ([]uuid.UUID, error)
+	Events(ctx context.Context, id *uuid.UUID) ([]models.Event, error)
+	Preempt(ctx context.Context, booking *models.NewBooking, at time.Time) (models.PreemptionResult, error)
+	// books the slot until expiresAt unless it's confirmed
+	Hold(ctx context.Context, booking *models.NewBooking, expiresAt time.Time) (models.Booking, error)
+	Confirm(ctx context.Context, id *uuid.UUID, at time.Time) (models.Booking, error)
+	// cancels holds expired by at
+	ReleaseExpiredHolds(ctx context.Context, at time.Time) ([]uuid.UUID, error)
+	SetPriority(context.Context, *models.HostPriority) error
+	JoinWaitlist(ctx context.Context, entry *models.NewWaitlistEntry, at time.Time) (models.WaitlistEntry, error)
+	WaitlistEntry(context.Context, *uuid.UUID) (models.WaitlistEntry, error)
+	// entries of the person, the oldest first
+	Waitlist(ctx context.Context, person string) ([]models.WaitlistEntry, error)
+	LeaveWaitlist(context.Context, *uuid.UUID) (models.WaitlistEntry, error)
+	// books the freed slot for the first matching waitlist entries
+	OfferFreedSlot(ctx context.Context, slot *models.FreedSlot, at time.Time, window time.Duration) ([]models.WaitlistEntry, error)
+}
+
+type impl struct {
+	logger *zap.SugaredLogger
+	dbpool *pgxpool.Pool
+}
+
+func New(dbPool *pgxpool.Pool, logger *zap.SugaredLogger) DB {
+	return &impl{
+		logger: logger,
+		dbpool: dbPool,
+	}
+}
+
+// both a pool and a transaction
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+const bookingColumns = `id, room_id, host, start_at, end_at, attendees, agenda, booked_at,
+	cancelled_at, cancelled_by, cancel_reason, series_id, checked_in_at, checked_in_by, host_priority, hold_expires_at`
+
+// overlaps are prevented by the bookings_no_overlap exclusion constraint,
+// so concurrent requests can't book the same slot twice
+func (impl *impl) Create(ctx context.Context, booking *models.NewBooking) (uuid.UUID, error) {
+	id := uuid.New()
+	err := pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		_, err := impl.book(ctx, tx, id, booking, nil, nil)
+		return err
+	})
+	return id, err
+}
+
+// book inserts a booking in a savepoint, so tx stays usable if the slot is taken.
+// A booking with holdExpiresAt is a hold.
+func (impl *impl) book(ctx context.Context, tx pgx.Tx, id uuid.UUID, booking *models.NewBooking, seriesId *uuid.UUID, holdExpiresAt *time.Time) (models.Booking, error) {
+	var created models.Booking
+	if err := checkRoomLock(ctx, tx, booking.RoomId, booking.Start, booking.End); err != nil {
+		return created, err
+	}
+	err := pgx.BeginFunc(ctx, tx, func(savepoint pgx.Tx) error {
+		return insertBooking(ctx, savepoint, id, booking, seriesId, holdExpiresAt)
+	})
+	if err != nil {
+		return created, impl.translate(ctx, tx, err, booking.RoomId, booking.Start, booking.End, nil)
+	}
+
+	query := "select " + bookingColumns + " from bookings where id = @id"
+	if err := pgxscan.Get(ctx, tx, &created, query, pgx.NamedArgs{"id": id}); err != nil {
+		return created, err
+	}
+	return created, outboxDB.Write(ctx, tx, outbox.BookingCreated, id.String(), created.BookedAt, created)
+}
+
+// the outbox payload of every cancellation
+func cancelledBooking(booking *models.Booking) outbox.CancelledBooking {
+	return outbox.CancelledBooking{
+		BookingId:   booking.Id,
+		RoomId:      booking.RoomId,
+		Host:        booking.Host,
+		Attendees:   booking.Attendees,
+		Start:       booking.Start,
+		End:         booking.End,
+		CancelledBy: *booking.CancelledBy,
+		Reason:      *booking.CancelReason,
+	}
+}
+
+// checkRoomLock fails if an open lock of the room overlaps the interval.
+// The room row is share locked till the end of tx, so a concurrent lock waits and then sees the booking.
+func checkRoomLock(ctx context.Context, tx pgx.Tx, roomId uuid.UUID, start, end time.Time) error {
+	query := `select exists (
+					select 1 from room_locks
+					where room_id = @room_id
+					  and unlocked_at is null
+					  and tstzrange(locked_at, until) && tstzrange(@start_at, @end_at)
+				)
+				from meeting_rooms
+				where id = @room_id
+				for key share`
+	args := pgx.NamedArgs{"room_id": roomId, "start_at": start, "end_at": end}
+	var locked bool
+	switch err := tx.QueryRow(ctx, query, args).Scan(&locked); {
+	case errors.Is(err, pgx.ErrNoRows):
+		return models.ErrRoomNotFound
+	case err != nil:
+		return err
+	case locked:
+		return models.ErrRoomLocked
+	default:
+		return nil
+	}
+}
+
+func insertBooking(ctx context.Context, q querier, id uuid.UUID, booking *models.NewBooking, seriesId *uuid.UUID, holdExpiresAt *time.Time) error {
+	query := `insert into bookings
+				(
+					id,
+					room_id,
+					host,
+					start_at,
+					end_at,
+					attendees,
+					agenda,
+					series_id,
+					host_priority,
+					hold_expires_at
+				)
+				values (
+					@id,
+					@room_id,
+					@host,
+					@start_at,
+					@end_at,
+					@attendees,
+					@agenda,
+					@series_id,
+					coalesce((select priority from host_priorities where host = @host), 0),
+					@hold_expires_at
+				)
+				`
+	attendees := booking.Attendees
+	if attendees == nil {
+		attendees = []string{}
+	}
+	args := pgx.NamedArgs{
+		"id":        id,
+		"room_id":   booking.RoomId,
+		"host":      booking.Host,
+		"start_at":  booking.Start,
+		"end_at":    booking.End,
+		"attendees": attendees,
+		"agenda":    booking.Agenda,
+		"series_id": seriesId,
+
+		"hold_expires_at": holdExpiresAt,
+	}
+	_, err := q.Exec(ctx, query, args)
+	return err
+}
+
+// translate turns constraint violations of a booking into domain errors.
+// q must be usable after the failure, i.e. the failed statement ran in a rolled back savepoint or outside a transaction.
+func (impl *impl) translate(ctx context.Context, q querier, err error, roomId uuid.UUID, start, end time.Time, exclude *uuid.UUID) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case foreignKeyViolation:
+			return models.ErrRoomNotFound
+		case exclusionViolation:
+			return &models.ConflictError{Conflicts: impl.findConflicts(ctx, q, roomId, start, end, exclude)}
+		}
+	}
+	return err // wrap error
+}
+
+// looks up bookings that made a change fail, exclude is the changed booking itself
+func (impl *impl) findConflicts(ctx context.Context, q querier, roomId uuid.UUID, start, end time.Time, exclude *uuid.UUID) []models.Booking {
+	conflicts := make([]models.Booking, 0)
+	query := "select " + bookingColumns + ` from bookings
+				where room_id = @room_id
+				  and cancelled_at is null
+				  and tstzrange(start_at, end_at) && tstzrange(@start_at, @end_at)
+				  and (@exclude::uuid is null or id <> @exclude::uuid)
+				order by start_at`
+	args := pgx.NamedArgs{
+		"room_id":  roomId,
+		"start_at": start,
+		"end_at":   end,
+		"exclude":  exclude,
+	}
+	if err := pgxscan.Select(ctx, q, &conflicts, query, args); err != nil {
+		impl.logger.Errorf("cannot look up conflicting bookings: %v", err)
+	}
+	return conflicts
+}
+
+func (impl *impl) Get(ctx context.Context, id *uuid.UUID) (models.Booking, error) {
+	var booking models.Booking
+	query := "select " + bookingColumns + " from bookings where id = @id"
+	args := pgx.NamedArgs{"id": id}
+	err := pgxscan.Get(ctx, impl.dbpool, &booking, query, args)
+	if pgxscan.NotFound(err) {
+		return booking, models.ErrBookingNotFound
+	}
+	return booking, err // wrap error
+}
+
+// lockActive locks a booking which can still be changed
+func lockActive(ctx context.Context, tx pgx.Tx, id *uuid.UUID, at time.Time) (models.Booking, error) {
+	var booking models.Booking
+	query := "select " + bookingColumns + " from bookings where id = @id for update"
+	if err := pgxscan.Get(ctx, tx, &booking, query, pgx.NamedArgs{"id": id}); err != nil {
+		if pgxscan.NotFound(err) {
+			return booking, models.ErrBookingNotFound
+		}
+		return booking, err
+	}
+	if booking.IsCancelled() {
+		return booking, models.ErrAlreadyCancelled
+	}
+	if !booking.End.After(at) {
+		return booking, models.ErrBookingEnded
+	}
+	return booking, nil
+}
+
+// lockScope locks active occurrences of the booking series selected by scope, origin goes first
+func lockScope(ctx context.Context, tx pgx.Tx, origin *models.Booking, scope models.Scope, at time.Time) ([]models.Booking, error) {
+	if scope == models.ScopeThis {
+		return []models.Booking{*origin}, nil
+	}
+	if origin.SeriesId == nil {
+		return nil, models.ErrNotInSeries
+	}
+
+	bookings := make([]models.Booking, 0)
+	query := "select " + bookingColumns + ` from bookings
+				where series_id = @series_id
+				  and cancelled_at is null
+				  and end_at > @at
+				  and (@all::boolean or start_at >= @start_at)
+				order by id = @id desc, start_at
+				for update`
+	args := pgx.NamedArgs{
+		"id":        origin.Id,
+		"series_id": origin.SeriesId,
+		"at":        at,
+		"all":       scope == models.ScopeAll,
+		"start_at":  origin.Start,
+	}
+	err := pgxscan.Select(ctx, tx, &bookings, query, args)
+	return bookings, err
+}
+
+func cancelBooking(ctx context.Context, tx pgx.Tx, booking *models.Booking, cancellation *models.Cancellation, at time.Time) error {
+	update := `update bookings
+				set
+					cancelled_at = @cancelled_at,
+					cancelled_by = @cancelled_by,
+					cancel_reason = @cancel_reason
+				where id = @id`
+	args := pgx.NamedArgs{
+		"id":            booking.Id,
+		"cancelled_at":  at,
+		"cancelled_by":  cancellation.CancelledBy,
+		"cancel_reason": cancellation.Reason,
+	}
+	if _, err := tx.Exec(ctx, update, args); err != nil {
+		return err
+	}
+	booking.CancelledAt = &at
+	booking.CancelledBy = &cancellation.CancelledBy
+	booking.CancelReason = &cancellation.Reason
+	if err := settleOffer(ctx, tx, booking.Id, models.WaitlistCancelled); err != nil {
+		return err
+	}
+	return outboxDB.Write(ctx, tx, outbox.BookingCancelled, booking.Id.String(), at, cancelledBooking(booking))
+}
+
+// cancelled bookings are kept, they just stop occupying the slot
+func (impl *impl) Cancel(ctx context.Context, id *uuid.UUID, cancellation *models.Cancellation, at time.Time) (models.Booking, error) {
+	var booking models.Booking
+	err := pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		var err error
+		if booking, err = lockActive(ctx, tx, id, at); err != nil {
+			return err
+		}
+		return cancelBooking(ctx, tx, &booking, cancellation, at)
+	})
+	return booking, err // wrap error
+}
+
+// occurrences that already ended are left untouched
+func (impl *impl) CancelSeries(ctx context.Context, id *uuid.UUID, scope models.Scope, cancellation *models.Cancellation, at time.Time) ([]models.Booking, error) {
+	var bookings []models.Booking
+	err := pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		origin, err := lockActive(ctx, tx, id, at)
+		if err != nil {
+			return err
+		}
+		if bookings, err = lockScope(ctx, tx, &origin, scope, at); err != nil {
+			return err
+		}
+		for i := range bookings {
+			if err := cancelBooking(ctx, tx, &bookings[i], cancellation, at); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return bookings, err // wrap error
+}
+
+// occurrences are booked independently: conflicting ones are skipped and reported.
+// Nothing is stored if every occurrence conflicts.
+func (impl *impl) CreateSeries(ctx context.Context, series *models.NewSeries, occurrences []models.NewBooking) (models.SeriesResult, error) {
+	result := models.SeriesResult{
+		Id:        uuid.New(),
+		Bookings:  make([]models.Booking, 0, len(occurrences)),
+		Conflicts: make([]models.OccurrenceConflict, 0),
+	}
+	err := pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		query := `insert into booking_series
+					(id, room_id, host, rrule, timezone, start_at, end_at, exdates)
+					values (@id, @room_id, @host, @rrule, @timezone, @start_at, @end_at, @exdates)`
+		exdates := series.ExDates
+		if exdates == nil {
+			exdates = []time.Time{}
+		}
+		args := pgx.NamedArgs{
+			"id":       result.Id,
+			"room_id":  series.RoomId,
+			"host":     series.Host,
+			"rrule":    series.RRule,
+			"timezone": series.Timezone,
+			"start_at": series.Start,
+			"end_at":   series.End,
+			"exdates":  exdates,
+		}
+		if _, err := tx.Exec(ctx, query, args); err != nil {
+			return impl.translate(ctx, tx, err, series.RoomId, series.Start, series.End, nil)
+		}
+
+		for _, occurrence := range occurrences {
+			_, err := impl.book(ctx, tx, uuid.New(), &occurrence, &result.Id, nil)
+			var conflict *models.ConflictError
+			if errors.As(err, &conflict) {
+				result.Conflicts = append(result.Conflicts, models.OccurrenceConflict{
+					Start:     occurrence.Start,
+					End:       occurrence.End,
+					Conflicts: conflict.Conflicts,
+				})
+			} else if errors.Is(err, models.ErrRoomLocked) {
+				result.Conflicts = append(result.Conflicts, models.OccurrenceConflict{
+					Start:     occurrence.Start,
+					End:       occurrence.End,
+					Conflicts: make([]models.Booking, 0),
+					Locked:    true,
+				})
+			} else if err != nil {
+				return err
+			}
+		}
+
+		query = "select " + bookingColumns + " from bookings where series_id = @series_id order by start_at"
+		if err := pgxscan.Select(ctx, tx, &result.Bookings, query, pgx.NamedArgs{"series_id": result.Id}); err != nil {
+			return err
+		}
+		if len(result.Bookings) == 0 {
+			return models.ErrNoFreeOccurrence
+		}
+		return nil
+	})
+	return result, err // wrap error
+}
+
+// all selected occurrences are changed or none of them
+func (impl *impl) Update(ctx context.Context, id *uuid.UUID, scope models.Scope, change *models.BookingChange, at time.Time) ([]models.Booking, error) {
+	var bookings []models.Booking
+	err := pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		origin, err := lockActive(ctx, tx, id, at)
+		if err != nil {
+			return err
+		}
+		before, err := lockScope(ctx, tx, &origin, scope, at)
+		if err != nil {
+			return err
+		}
+		loc, err := seriesLocation(ctx, tx, origin.SeriesId)
+		if err != nil {
+			return err
+		}
+
+		bookings = make([]models.Booking, len(before))
+		for i := range before {
+			bookings[i] = change.Apply(before[i], &origin, loc)
+		}
+		moved := change.Start != nil && scope != models.ScopeThis
+		if moved {
+			// occurrences moved by the interval of the series or more overlap its not yet moved ones
+			if _, err := tx.Exec(ctx, "set constraints bookings_no_overlap deferred"); err != nil {
+				return err
+			}
+		}
+		// the series row describes its occurrences, so it follows their time and room
+		if scope != models.ScopeThis && (change.Start != nil || change.End != nil || change.RoomId != nil) {
+			seriesId, err := moveSeries(ctx, tx, *origin.SeriesId, before, bookings, loc)
+			if err != nil {
+				return err
+			}
+			for i := range bookings {
+				bookings[i].SeriesId = &seriesId
+			}
+		}
+
+		for i := range bookings {
+			// the same checks as for a new booking
+			if change.Start != nil || change.RoomId != nil {
+				if err := checkRoomLock(ctx, tx, bookings[i].RoomId, bookings[i].Start, bookings[i].End); err != nil {
+					return err
+				}
+			}
+			err := pgx.BeginFunc(ctx, tx, func(savepoint pgx.Tx) error {
+				return updateBooking(ctx, savepoint, &bookings[i])
+			})
+			if err != nil {
+				return impl.translate(ctx, tx, err, bookings[i].RoomId, bookings[i].Start, bookings[i].End, &bookings[i].Id)
+			}
+			modification := models.Modification{Booking: bookings[i], Before: before[i]}
+			if err := insertModification(ctx, tx, &modification, at); err != nil {
+				return err
+			}
+			if err := outboxDB.Write(ctx, tx, outbox.BookingUpdated, bookings[i].Id.String(), at, modification); err != nil {
+				return err
+			}
+		}
+		if moved {
+			return impl.checkOverlaps(ctx, tx, bookings)
+		}
+		return nil
+	})
+	return bookings, err // wrap error
+}
+
+// checkOverlaps runs the overlap checks deferred while occurrences were moved one by one
+func (impl *impl) checkOverlaps(ctx context.Context, tx pgx.Tx, bookings []models.Booking) error {
+	err := pgx.BeginFunc(ctx, tx, func(savepoint pgx.Tx) error {
+		_, err := savepoint.Exec(ctx, "set constraints bookings_no_overlap immediate")
+		return err
+	})
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != exclusionViolation {
+		return err
+	}
+	for i := range bookings {
+		conflicts := impl.findConflicts(ctx, tx, bookings[i].RoomId, bookings[i].Start, bookings[i].End, &bookings[i].Id)
+		if len(conflicts) > 0 {
+			return &models.ConflictError{Conflicts: conflicts}
+		}
+	}
+	return &models.ConflictError{Conflicts: make([]models.Booking, 0)}
+}
+
+// moveSeries keeps the rule, the room and the time of a series in line with its changed occurrences,
+// before are the occurrences as they were. Occurrences earlier than the changed ones keep the series,
+// the changed ones make a new series then.
+// Returns the series of the moved occurrences.
+func moveSeries(ctx context.Context, tx pgx.Tx, seriesId uuid.UUID, before, after []models.Booking, loc *time.Location) (uuid.UUID, error) {
+	var series struct {
+		RRule   string      `db:"rrule"`
+		Start   time.Time   `db:"start_at"`
+		ExDates []time.Time `db:"exdates"`
+	}
+	query := "select rrule, start_at, exdates from booking_series where id = @id for update"
+	if err := pgxscan.Get(ctx, tx, &series, query, pgx.NamedArgs{"id": seriesId}); err != nil {
+		return uuid.Nil, err
+	}
+	rule, err := recurrence.Parse(series.RRule, loc)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	// a shift keeps the order of occurrences, so the first and the last are the same before and after it
+	first, last := 0, 0
+	for i := range before {
+		if before[i].Start.Before(before[first].Start) {
+			first = i
+		}
+		if before[i].Start.After(before[last].Start) {
+			last = i
+		}
+	}
+	from, to := before[first].Start, after[first].Start
+	moved := rule.Moved(from, to, loc)
+	moved.Count, moved.Until = 0, after[last].Start
+	kept, shifted := make([]time.Time, 0), make([]time.Time, 0)
+	for _, exdate := range series.ExDates {
+		if exdate.Before(from) {
+			kept = append(kept, exdate)
+		} else {
+			shifted = append(shifted, recurrence.Shift(exdate, from, to, loc))
+		}
+	}
+	args := pgx.NamedArgs{
+		"id":       seriesId,
+		"room_id":  after[first].RoomId,
+		"rrule":    moved.String(),
+		"start_at": after[first].Start,
+		"end_at":   after[first].End,
+		"exdates":  shifted,
+	}
+
+	if !from.After(series.Start) {
+		query = `update booking_series
+					set room_id = @room_id, rrule = @rrule, start_at = @start_at, end_at = @end_at, exdates = @exdates
+					where id = @id`
+		_, err := tx.Exec(ctx, query, args)
+		return seriesId, err
+	}
+
+	rule.Count, rule.Until = 0, from.Add(-time.Second)
+	query = "update booking_series set rrule = @rrule, exdates = @exdates where id = @id"
+	if _, err := tx.Exec(ctx, query, pgx.NamedArgs{"id": seriesId, "rrule": rule.String(), "exdates": kept}); err != nil {
+		return uuid.Nil, err
+	}
+	newId := uuid.New()
+	args["new_id"] = newId
+	query = `insert into booking_series (id, room_id, host, rrule, timezone, start_at, end_at, exdates)
+				select @new_id, @room_id, host, @rrule, timezone, @start_at, @end_at, @exdates
+				from booking_series where id = @id`
+	_, err = tx.Exec(ctx, query, args)
+	return newId, err
+}
+
+// series are expanded in their own timezone, standalone bookings don't need one
+func seriesLocation(ctx context.Context, tx pgx.Tx, seriesId *uuid.UUID) (*time.Location, error) {
+	if seriesId == nil {
+		return time.UTC, nil
+	}
+	var timezone string
+	query := "select timezone from booking_series where id = @id"
+	if err := tx.QueryRow(ctx, query, pgx.NamedArgs{"id": seriesId}).Scan(&timezone); err != nil {
+		return nil, err
+	}
+	return time.LoadLocation(timezone)
+}
+
+func updateBooking(ctx context.Context, q querier, booking *models.Booking) error {
+	query := `update bookings
+				set
+					room_id = @room_id,
+					start_at = @start_at,
+					end_at = @end_at,
+					attendees = @attendees,
+					agenda = @agenda,
+					series_id = @series_id
+				where id = @id`
+	attendees := booking.Attendees
+	if attendees == nil {
+		attendees = []string{}
+	}
+	args := pgx.NamedArgs{
+		"id":        booking.Id,
+		"room_id":   booking.RoomId,
+		"start_at":  booking.Start,
+		"end_at":    booking.End,
+		"attendees": attendees,
+		"agenda":    booking.Agenda,
+		"series_id": booking.SeriesId,
+	}
+	_, err := q.Exec(ctx, query, args)
+	return err
+}
+
+// repeated check-ins keep the first one
+func (impl *impl) CheckIn(ctx context.Context, id *uuid.UUID, checkIn *models.CheckIn, at time.Time) (models.Booking, error) {
+	var booking models.Booking
+	err := pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		var err error
+		if booking, err = lockActive(ctx, tx, id, at); err != nil {
+			return err
+		}
+		if booking.IsHeld() {
+			return models.ErrHoldNotConfirmed
+		}
+		if at.Before(booking.Start.Add(-models.EarlyCheckIn)) {
+			return models.ErrCheckInNotOpen
+		}
+		if !booking.IsParticipant(checkIn.Person) {
+			return models.ErrNotParticipant
+		}
+		if booking.IsCheckedIn() {
+			return nil
+		}
+
+		update := "update bookings set checked_in_at = @at, checked_in_by = @person where id = @id"
+		args := pgx.NamedArgs{"id": id, "at": at, "person": checkIn.Person}
+		if _, err := tx.Exec(ctx, update, args); err != nil {
+			return err
+		}
+		booking.CheckedInAt = &at
+		booking.CheckedInBy = &checkIn.Person
+		if err := outboxDB.Write(ctx, tx, outbox.BookingCheckedIn, booking.Id.String(), at, booking); err != nil {
+			return err
+		}
+		return insertEvent(ctx, tx, booking.Id, models.EventCheckedIn, checkIn.Person, at, nil)
+	})
+	return booking, err // wrap error
+}
+
+// cancels running bookings which started before startedBefore and nobody checked in
+func (impl *impl) ReleaseNoShows(ctx context.Context, startedBefore time.Time, at time.Time) ([]uuid.UUID, error) {
+	released := make([]uuid.UUID, 0)
+	err := pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		bookings := make([]models.Booking, 0)
+		query := `with released as (
+						update bookings
+						set
+							cancelled_at = @at,
+							cancelled_by = @actor,
+							cancel_reason = 'no-show'
+						where cancelled_at is null
+						  and checked_in_at is null
+						  and hold_expires_at is null
+						  and start_at <= @started_before
+						  and end_at > @at
+						returning ` + bookingColumns + `
+					), events as (
+						insert into booking_events (booking_id, type, actor, occurred_at)
+						select id, @type, @actor, @at from released
+					)
+					select * from released order by start_at`
+		args := pgx.NamedArgs{
+			"at":             at,
+			"started_before": startedBefore,
+			"actor":          models.SystemActor,
+			"type":           models.EventNoShow,
+		}
+		if err := pgxscan.Select(ctx, tx, &bookings, query, args); err != nil {
+			return err
+		}
+		for i := range bookings {
+			released = append(released, bookings[i].Id)
+			if err := outboxDB.Write(ctx, tx, outbox.BookingCancelled, bookings[i].Id.String(), at, cancelledBooking(&bookings[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return released, err // wrap error
+}
+
+// slice can't be nil if error is nil
+func (impl *impl) Events(ctx context.Context, id *uuid.UUID) ([]models.Event, error) {
+	events := make([]models.Event, 0)
+	query := `select id, booking_id, type, actor, occurred_at, related_booking_id, before, after from booking_events
+				where booking_id = @id
+				order by occurred_at, id`
+	err := pgxscan.Select(ctx, impl.dbpool, &events, query, pgx.NamedArgs{"id": id})
+	return events, err // wrap error
+}
+
+// the host is the actor, only they change their bookings
+func insertModification(ctx context.Context, q querier, modification *models.Modification, at time.Time) error {
+	query := `insert into booking_events (booking_id, type, actor, occurred_at, before, after)
+				values (@booking_id, @type, @actor, @at, @before, @after)`
+	args := pgx.NamedArgs{
+		"booking_id": modification.Id,
+		"type":       models.EventModified,
+		"actor":      modification.Before.Host,
+		"at":         at,
+		"before":     modification.Before,
+		"after":      modification.Booking,
+	}
+	_, err := q.Exec(ctx, query, args)
+	return err
+}
+
+func insertEvent(ctx context.Context, q querier, bookingId uuid.UUID, eventType models.EventType, actor string, at time.Time, related *uuid.UUID) error {
+	query := `insert into booking_events (booking_id, type, actor, occurred_at, related_booking_id)
+				values (@booking_id, @type, @actor, @at, @related_booking_id)`
+	args := pgx.NamedArgs{
+		"booking_id":         bookingId,
+		"type":               eventType,
+		"actor":              actor,
+		"at":                 at,
+		"related_booking_id": related,
+	}
+	_, err := q.Exec(ctx, query, args)
+	return err
+}
+
+// overlapping bookings are cancelled in favour of the new one if all of them have a lower host priority,
+// otherwise nothing changes and the blocking bookings are reported as a conflict
+func (impl *impl) Preempt(ctx context.Context, booking *models.NewBooking, at time.Time) (models.PreemptionResult, error) {
+	result := models.PreemptionResult{Id: uuid.New(), Preempted: make([]models.Booking, 0)}
+	err := pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		var priority int
+		query := "select coalesce((select priority from host_priorities where host = @host), 0)"
+		if err := tx.QueryRow(ctx, query, pgx.NamedArgs{"host": booking.Host}).Scan(&priority); err != nil {
+			return err
+		}
+
+		overlapping := make([]models.Booking, 0)
+		query = "select " + bookingColumns + ` from bookings
+					where room_id = @room_id
+					  and cancelled_at is null
+					  and tstzrange(start_at, end_at) && tstzrange(@start_at, @end_at)
+					order by start_at
+					for update`
+		args := pgx.NamedArgs{"room_id": booking.RoomId, "start_at": booking.Start, "end_at": booking.End}
+		if err := pgxscan.Select(ctx, tx, &overlapping, query, args); err != nil {
+			return err
+		}
+		blocking := slices.DeleteFunc(slices.Clone(overlapping), func(other models.Booking) bool {
+			return other.HostPriority < priority
+		})
+		if len(blocking) > 0 {
+			return &models.ConflictError{Conflicts: blocking}
+		}
+
+		cancellation := models.Cancellation{CancelledBy: booking.Host, Reason: models.PreemptionReason}
+		for i := range overlapping {
+			if err := cancelBooking(ctx, tx, &overlapping[i], &cancellation, at); err != nil {
+				return err
+			}
+			if err := insertEvent(ctx, tx, overlapping[i].Id, models.EventPreempted, booking.Host, at, &result.Id); err != nil {
+				return err
+			}
+		}
+		result.Preempted = overlapping
+
+		_, err := impl.book(ctx, tx, result.Id, booking, nil, nil)
+		return err
+	})
+	return result, err // wrap error
+}
+
+func (impl *impl) Hold(ctx context.Context, booking *models.NewBooking, expiresAt time.Time) (models.Booking, error) {
+	var held models.Booking
+	err := pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		var err error
+		held, err = impl.book(ctx, tx, uuid.New(), booking, nil, &expiresAt)
+		return err
+	})
+	return held, err // wrap error
+}
+
+// an expired hold can't be confirmed even if the sweeper hasn't released it yet
+func (impl *impl) Confirm(ctx context.Context, id *uuid.UUID, at time.Time) (models.Booking, error) {
+	var booking models.Booking
+	err := pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		var err error
+		if booking, err = lockActive(ctx, tx, id, at); err != nil {
+			return err
+		}
+		if !booking.IsHeld() {
+			return models.ErrNotHeld
+		}
+		if !booking.HoldExpiresAt.After(at) {
+			return models.ErrHoldExpired
+		}
+
+		if _, err := tx.Exec(ctx, "update bookings set hold_expires_at = null where id = @id", pgx.NamedArgs{"id": id}); err != nil {
+			return err
+		}
+		booking.HoldExpiresAt = nil
+		if err := settleOffer(ctx, tx, booking.Id, models.WaitlistAccepted); err != nil {
+			return err
+		}
+		if err := outboxDB.Write(ctx, tx, outbox.BookingConfirmed, booking.Id.String(), at, booking); err != nil {
+			return err
+		}
+		return insertEvent(ctx, tx, booking.Id, models.EventConfirmed, booking.Host, at, nil)
+	})
+	return booking, err // wrap error
+}
+
+func (impl *impl) ReleaseExpiredHolds(ctx context.Context, at time.Time) ([]uuid.UUID, error) {
+	released := make([]uuid.UUID, 0)
+	err := pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		bookings := make([]models.Booking, 0)
+		query := `with released as (
+						update bookings
+						set
+							cancelled_at = @at,
+							cancelled_by = @actor,
+							cancel_reason = @reason
+						where cancelled_at is null
+						  and hold_expires_at <= @at
+						returning ` + bookingColumns + `
+					), events as (
+						insert into booking_events (booking_id, type, actor, occurred_at)
+						select id, @type, @actor, @at from released
+					), offers as (
+						update waitlist
+						set status = @expired
+						where status = 'offered'
+						  and booking_id in (select id from released)
+					)
+					select * from released order by start_at`
+		args := pgx.NamedArgs{
+			"at":      at,
+			"actor":   models.SystemActor,
+			"reason":  models.HoldExpiredReason,
+			"type":    models.EventHoldExpired,
+			"expired": models.WaitlistExpired,
+		}
+		if err := pgxscan.Select(ctx, tx, &bookings, query, args); err != nil {
+			return err
+		}
+		for i := range bookings {
+			released = append(released, bookings[i].Id)
+			if err := outboxDB.Write(ctx, tx, outbox.BookingHoldExpired, bookings[i].Id.String(), at, cancelledBooking(&bookings[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return released, err // wrap error
+}
+
+func (impl *impl) SetPriority(ctx context.Context, priority *models.HostPriority) error {
+	query := `insert into host_priorities (host, priority) values (@host, @priority)
+				on conflict (host) do update set priority = excluded.priority`
+	args := pgx.NamedArgs{"host": priority.Host, "priority": priority.Priority}
+	_, err := impl.dbpool.Exec(ctx, query, args)
+	return err // wrap error
+}
@@ -0,0 +1,585 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	testHelpers "github.com/optician/meeting-room-booking/internal/administration/db/testing"
+	"github.com/optician/meeting-room-booking/internal/booking/models"
+	"github.com/optician/meeting-room-booking/internal/dbPool"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"go.uber.org/zap"
+)
+
+type BookingRepositoryTestSuite struct {
+	suite.Suite
+	pgContainer *postgres.PostgresContainer
+	pool        *pgxpool.Pool
+	repository  *DB
+	ctx         context.Context
+	logger      *zap.SugaredLogger
+}
+
+func (suite *BookingRepositoryTestSuite) SetupSuite() {
+	ctx := context.Background()
+	logger := zap.NewExample().Sugar()
+	container, err := testHelpers.CreatePostgresContainer(ctx)
+	if err != nil {
+		logger.Fatalf("cannot setup postgres container in BookingRepositoryTestSuite, %v", err)
+	}
+	migrationsPath := "../../../migrations/" // better to use env instead
+	if err := testHelpers.Migrate(ctx, container.ConnectionString, migrationsPath); err != nil {
+		logger.Fatalf("%v", err)
+	}
+	if err := testHelpers.CreateOffices(ctx, container.ConnectionString, "FoodCourt"); err != nil {
+		logger.Fatalf("%v", err)
+	}
+
+	config := dbPool.DBConfig{Url: container.ConnectionString}
+	dbPool, dbPoolErr := dbPool.NewDBPool(&config, logger)
+	if dbPoolErr != nil {
+		logger.Fatalf("application terminated: %v", dbPoolErr)
+	}
+
+	bookingsDB := New(dbPool.GetPool(), logger)
+
+	suite.pgContainer = container.Container
+	suite.pool = dbPool.GetPool()
+	suite.repository = &bookingsDB
+	suite.ctx = ctx
+	suite.logger = logger
+}
+
+func (suite *BookingRepositoryTestSuite) TearDownSuite() {
+	if err := suite.pgContainer.Terminate(suite.ctx); err != nil {
+		suite.logger.Fatalf("error terminating postgres container: %s", err)
+	}
+}
+
+func (suite *BookingRepositoryTestSuite) createRoom() uuid.UUID {
+	id := uuid.New()
+	query := "insert into meeting_rooms (id, name, capacity, office, stage, labels) values ($1, $2, 6, 'FoodCourt', -2, '{}')"
+	_, err := suite.pool.Exec(suite.ctx, query, id, id.String())
+	require.Nil(suite.T(), err, "room creation error")
+	return id
+}
+
+func (suite *BookingRepositoryTestSuite) TestCreateGetBooking() {
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	newBooking := models.NewBooking{
+		RoomId:    suite.createRoom(),
+		Host:      "ivan",
+		Start:     start,
+		End:       start.Add(time.Hour),
+		Attendees: []string{"petr", "olga"},
+		Agenda:    "plov recipe review",
+	}
+
+	id, err := (*suite.repository).Create(suite.ctx, &newBooking)
+	require.Nil(suite.T(), err, "Create error")
+
+	booking, err := (*suite.repository).Get(suite.ctx, &id)
+	require.Nil(suite.T(), err, "Get error")
+	require.Equal(suite.T(), id, booking.Id)
+	require.Equal(suite.T(), newBooking.RoomId, booking.RoomId)
+	require.True(suite.T(), newBooking.Start.Equal(booking.Start))
+	require.True(suite.T(), newBooking.End.Equal(booking.End))
+	require.Equal(suite.T(), newBooking.Attendees, booking.Attendees)
+	require.Equal(suite.T(), newBooking.Agenda, booking.Agenda)
+}
+
+func (suite *BookingRepositoryTestSuite) TestCreateForMissingRoom() {
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	newBooking := models.NewBooking{RoomId: uuid.New(), Host: "ivan", Start: start, End: start.Add(time.Hour)}
+
+	_, err := (*suite.repository).Create(suite.ctx, &newBooking)
+	require.ErrorIs(suite.T(), err, models.ErrRoomNotFound)
+}
+
+func (suite *BookingRepositoryTestSuite) TestAdjacentBookingsDoNotConflict() {
+	roomId := suite.createRoom()
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	first := models.NewBooking{RoomId: roomId, Host: "ivan", Start: start, End: start.Add(time.Hour)}
+	second := models.NewBooking{RoomId: roomId, Host: "olga", Start: first.End, End: first.End.Add(time.Hour)}
+
+	_, err := (*suite.repository).Create(suite.ctx, &first)
+	require.Nil(suite.T(), err, "first booking error")
+	_, err = (*suite.repository).Create(suite.ctx, &second)
+	require.Nil(suite.T(), err, "second booking error")
+}
+
+func (suite *BookingRepositoryTestSuite) TestConcurrentOverlappingBookings() {
+	roomId := suite.createRoom()
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+
+	attempts := 10
+	errs := make([]error, attempts)
+	var wg sync.WaitGroup
+	for i := range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			booking := models.NewBooking{
+				RoomId: roomId,
+				Host:   "host",
+				Start:  start.Add(time.Duration(i) * time.Minute),
+				End:    start.Add(time.Hour),
+			}
+			_, errs[i] = (*suite.repository).Create(suite.ctx, &booking)
+		}()
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		var conflict *models.ConflictError
+		if err == nil {
+			succeeded++
+		} else {
+			require.True(suite.T(), errors.As(err, &conflict), "unexpected error %v", err)
+			require.Len(suite.T(), conflict.Conflicts, 1)
+		}
+	}
+	require.Equal(suite.T(), 1, succeeded)
+}
+
+func (suite *BookingRepositoryTestSuite) TestCancelFreesSlot() {
+	roomId := suite.createRoom()
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	newBooking := models.NewBooking{RoomId: roomId, Host: "ivan", Start: start, End: start.Add(time.Hour)}
+
+	id, err := (*suite.repository).Create(suite.ctx, &newBooking)
+	require.Nil(suite.T(), err, "Create error")
+
+	cancellation := models.Cancellation{CancelledBy: "olga", Reason: "moved online"}
+	cancelledAt := start.Add(-time.Hour)
+	cancelled, err := (*suite.repository).Cancel(suite.ctx, &id, &cancellation, cancelledAt)
+	require.Nil(suite.T(), err, "Cancel error")
+	require.True(suite.T(), cancelled.IsCancelled())
+	require.Equal(suite.T(), "olga", *cancelled.CancelledBy)
+
+	_, err = (*suite.repository).Cancel(suite.ctx, &id, &cancellation, cancelledAt)
+	require.ErrorIs(suite.T(), err, models.ErrAlreadyCancelled)
+
+	_, err = (*suite.repository).Create(suite.ctx, &newBooking)
+	require.Nil(suite.T(), err, "slot must be free after cancellation")
+}
+
+func (suite *BookingRepositoryTestSuite) TestCancelEndedBooking() {
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	newBooking := models.NewBooking{RoomId: suite.createRoom(), Host: "ivan", Start: start, End: start.Add(time.Hour)}
+
+	id, err := (*suite.repository).Create(suite.ctx, &newBooking)
+	require.Nil(suite.T(), err, "Create error")
+
+	cancellation := models.Cancellation{CancelledBy: "olga"}
+	_, err = (*suite.repository).Cancel(suite.ctx, &id, &cancellation, newBooking.End)
+	require.ErrorIs(suite.T(), err, models.ErrBookingEnded)
+}
+
+func (suite *BookingRepositoryTestSuite) createSeries(roomId uuid.UUID, start time.Time, count int) models.SeriesResult {
+	series := models.NewSeries{
+		NewBooking: models.NewBooking{RoomId: roomId, Host: "ivan", Start: start, End: start.Add(time.Hour)},
+		Timezone:   "Europe/Berlin",
+		RRule:      fmt.Sprintf("FREQ=WEEKLY;COUNT=%d", count),
+	}
+	occurrences, err := series.Occurrences()
+	require.Nil(suite.T(), err, "Occurrences error")
+	result, err := (*suite.repository).CreateSeries(suite.ctx, &series, occurrences)
+	require.Nil(suite.T(), err, "CreateSeries error")
+	return result
+}
+
+func (suite *BookingRepositoryTestSuite) TestCreateSeriesReportsConflicts() {
+	roomId := suite.createRoom()
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	blocker := models.NewBooking{RoomId: roomId, Host: "olga", Start: start.AddDate(0, 0, 7), End: start.AddDate(0, 0, 7).Add(time.Hour)}
+	blockerId, err := (*suite.repository).Create(suite.ctx, &blocker)
+	require.Nil(suite.T(), err, "Create error")
+
+	result := suite.createSeries(roomId, start, 3)
+
+	require.Len(suite.T(), result.Bookings, 2)
+	require.Len(suite.T(), result.Conflicts, 1)
+	require.True(suite.T(), blocker.Start.Equal(result.Conflicts[0].Start))
+	require.Equal(suite.T(), blockerId, result.Conflicts[0].Conflicts[0].Id)
+	require.Equal(suite.T(), result.Id, *result.Bookings[0].SeriesId)
+}
+
+func (suite *BookingRepositoryTestSuite) TestCancelFollowingOccurrences() {
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	result := suite.createSeries(suite.createRoom(), start, 4)
+
+	cancellation := models.Cancellation{CancelledBy: "olga"}
+	cancelled, err := (*suite.repository).CancelSeries(suite.ctx, &result.Bookings[1].Id, models.ScopeFollowing, &cancellation, start)
+	require.Nil(suite.T(), err, "CancelSeries error")
+	require.Len(suite.T(), cancelled, 3)
+
+	first, err := (*suite.repository).Get(suite.ctx, &result.Bookings[0].Id)
+	require.Nil(suite.T(), err, "Get error")
+	require.False(suite.T(), first.IsCancelled())
+}
+
+func (suite *BookingRepositoryTestSuite) TestUpdateWholeSeries() {
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	result := suite.createSeries(suite.createRoom(), start, 3)
+
+	newStart := result.Bookings[1].Start.Add(time.Hour)
+	newEnd := newStart.Add(30 * time.Minute)
+	change := models.BookingChange{Start: &newStart, End: &newEnd}
+	updated, err := (*suite.repository).Update(suite.ctx, &result.Bookings[1].Id, models.ScopeAll, &change, start.Add(-time.Hour))
+	require.Nil(suite.T(), err, "Update error")
+	require.Len(suite.T(), updated, 3)
+
+	first, err := (*suite.repository).Get(suite.ctx, &result.Bookings[0].Id)
+	require.Nil(suite.T(), err, "Get error")
+	require.True(suite.T(), start.Add(time.Hour).Equal(first.Start))
+	require.True(suite.T(), start.Add(90*time.Minute).Equal(first.End))
+}
+
+func (suite *BookingRepositoryTestSuite) TestShiftSeriesByItsInterval() {
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	result := suite.createSeries(suite.createRoom(), start, 3)
+
+	// every occurrence takes the slot of the next one
+	newStart := start.AddDate(0, 0, 7)
+	newEnd := newStart.Add(time.Hour)
+	change := models.BookingChange{Start: &newStart, End: &newEnd}
+	updated, err := (*suite.repository).Update(suite.ctx, &result.Bookings[0].Id, models.ScopeAll, &change, start.Add(-time.Hour))
+	require.Nil(suite.T(), err, "Update error")
+	require.Len(suite.T(), updated, 3)
+
+	last, err := (*suite.repository).Get(suite.ctx, &result.Bookings[2].Id)
+	require.Nil(suite.T(), err, "Get error")
+	require.True(suite.T(), start.AddDate(0, 0, 21).Equal(last.Start))
+
+	var rrule string
+	var seriesStart time.Time
+	query := "select rrule, start_at from booking_series where id = $1"
+	require.Nil(suite.T(), suite.pool.QueryRow(suite.ctx, query, result.Id).Scan(&rrule, &seriesStart))
+	require.Equal(suite.T(), "FREQ=WEEKLY;UNTIL=20300131T140000Z", rrule)
+	require.True(suite.T(), newStart.Equal(seriesStart))
+}
+
+func (suite *BookingRepositoryTestSuite) TestShiftFollowingSplitsSeries() {
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	result := suite.createSeries(suite.createRoom(), start, 4)
+
+	newStart := result.Bookings[1].Start.AddDate(0, 0, 1)
+	newEnd := newStart.Add(time.Hour)
+	change := models.BookingChange{Start: &newStart, End: &newEnd}
+	updated, err := (*suite.repository).Update(suite.ctx, &result.Bookings[1].Id, models.ScopeFollowing, &change, start.Add(-time.Hour))
+	require.Nil(suite.T(), err, "Update error")
+	require.Len(suite.T(), updated, 3)
+	require.NotEqual(suite.T(), result.Id, *updated[0].SeriesId)
+
+	first, err := (*suite.repository).Get(suite.ctx, &result.Bookings[0].Id)
+	require.Nil(suite.T(), err, "Get error")
+	require.Equal(suite.T(), result.Id, *first.SeriesId)
+	moved, err := (*suite.repository).Get(suite.ctx, &result.Bookings[3].Id)
+	require.Nil(suite.T(), err, "Get error")
+	require.Equal(suite.T(), *updated[0].SeriesId, *moved.SeriesId)
+
+	var rrule string
+	query := "select rrule from booking_series where id = $1"
+	require.Nil(suite.T(), suite.pool.QueryRow(suite.ctx, query, result.Id).Scan(&rrule))
+	require.Equal(suite.T(), "FREQ=WEEKLY;UNTIL=20300117T135959Z", rrule)
+	require.Nil(suite.T(), suite.pool.QueryRow(suite.ctx, query, *moved.SeriesId).Scan(&rrule))
+	require.Equal(suite.T(), "FREQ=WEEKLY;UNTIL=20300201T140000Z", rrule)
+}
+
+func (suite *BookingRepositoryTestSuite) TestMoveFollowingToAnotherRoomSplitsSeries() {
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	roomId, otherRoomId := suite.createRoom(), suite.createRoom()
+	result := suite.createSeries(roomId, start, 4)
+
+	// same start, longer occurrences in another room
+	sameStart, laterEnd := result.Bookings[1].Start, result.Bookings[1].End.Add(30*time.Minute)
+	change := models.BookingChange{RoomId: &otherRoomId, Start: &sameStart, End: &laterEnd}
+	updated, err := (*suite.repository).Update(suite.ctx, &result.Bookings[1].Id, models.ScopeFollowing, &change, start.Add(-time.Hour))
+	require.Nil(suite.T(), err, "Update error")
+	require.Len(suite.T(), updated, 3)
+	require.NotEqual(suite.T(), result.Id, *updated[0].SeriesId)
+
+	var rrule string
+	var seriesRoomId uuid.UUID
+	var seriesEnd time.Time
+	query := "select rrule, room_id, end_at from booking_series where id = $1"
+	require.Nil(suite.T(), suite.pool.QueryRow(suite.ctx, query, result.Id).Scan(&rrule, &seriesRoomId, &seriesEnd))
+	require.Equal(suite.T(), "FREQ=WEEKLY;UNTIL=20300117T135959Z", rrule)
+	require.Equal(suite.T(), roomId, seriesRoomId)
+	require.True(suite.T(), start.Add(time.Hour).Equal(seriesEnd))
+	require.Nil(suite.T(), suite.pool.QueryRow(suite.ctx, query, *updated[0].SeriesId).Scan(&rrule, &seriesRoomId, &seriesEnd))
+	require.Equal(suite.T(), otherRoomId, seriesRoomId)
+	require.True(suite.T(), laterEnd.Equal(seriesEnd))
+}
+
+func (suite *BookingRepositoryTestSuite) TestUpdateIntoOccupiedSlotFails() {
+	roomId := suite.createRoom()
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	first := models.NewBooking{RoomId: roomId, Host: "ivan", Start: start, End: start.Add(time.Hour)}
+	second := models.NewBooking{RoomId: roomId, Host: "olga", Start: first.End, End: first.End.Add(time.Hour)}
+	firstId, err := (*suite.repository).Create(suite.ctx, &first)
+	require.Nil(suite.T(), err, "Create error")
+	secondId, err := (*suite.repository).Create(suite.ctx, &second)
+	require.Nil(suite.T(), err, "Create error")
+
+	newEnd := first.End.Add(30 * time.Minute)
+	change := models.BookingChange{Start: &first.Start, End: &newEnd}
+	_, err = (*suite.repository).Update(suite.ctx, &firstId, models.ScopeThis, &change, start.Add(-time.Hour))
+
+	var conflict *models.ConflictError
+	require.True(suite.T(), errors.As(err, &conflict), "unexpected error %v", err)
+	require.Equal(suite.T(), secondId, conflict.Conflicts[0].Id)
+}
+
+func (suite *BookingRepositoryTestSuite) TestMoveToAnotherRoom() {
+	roomId, otherRoomId := suite.createRoom(), suite.createRoom()
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	booking := models.NewBooking{RoomId: roomId, Host: "ivan", Start: start, End: start.Add(time.Hour), Agenda: "plov"}
+	occupying := models.NewBooking{RoomId: otherRoomId, Host: "olga", Start: start.Add(time.Hour), End: start.Add(2 * time.Hour)}
+	id, err := (*suite.repository).Create(suite.ctx, &booking)
+	require.Nil(suite.T(), err, "Create error")
+	_, err = (*suite.repository).Create(suite.ctx, &occupying)
+	require.Nil(suite.T(), err, "Create error")
+
+	// the other room is busy at the new time, so nothing changes
+	later, laterEnd := start.Add(time.Hour), start.Add(2*time.Hour)
+	change := models.BookingChange{RoomId: &otherRoomId, Start: &later, End: &laterEnd}
+	_, err = (*suite.repository).Update(suite.ctx, &id, models.ScopeThis, &change, start.Add(-time.Hour))
+	var conflict *models.ConflictError
+	require.True(suite.T(), errors.As(err, &conflict), "unexpected error %v", err)
+
+	missing := uuid.New()
+	_, err = (*suite.repository).Update(suite.ctx, &id, models.ScopeThis, &models.BookingChange{RoomId: &missing}, start.Add(-time.Hour))
+	require.ErrorIs(suite.T(), err, models.ErrRoomNotFound)
+
+	agenda := "lagman"
+	change = models.BookingChange{RoomId: &otherRoomId, Agenda: &agenda}
+	updated, err := (*suite.repository).Update(suite.ctx, &id, models.ScopeThis, &change, start.Add(-time.Hour))
+	require.Nil(suite.T(), err, "Update error")
+	require.Equal(suite.T(), id, updated[0].Id)
+	require.Equal(suite.T(), otherRoomId, updated[0].RoomId)
+
+	events, err := (*suite.repository).Events(suite.ctx, &id)
+	require.Nil(suite.T(), err, "Events error")
+	require.Len(suite.T(), events, 1, "failed changes leave no events")
+	require.Equal(suite.T(), models.EventModified, events[0].Type)
+	require.Equal(suite.T(), roomId, events[0].Before.RoomId)
+	require.Equal(suite.T(), "plov", events[0].Before.Agenda)
+	require.Equal(suite.T(), otherRoomId, events[0].After.RoomId)
+	require.Equal(suite.T(), "lagman", events[0].After.Agenda)
+}
+
+func (suite *BookingRepositoryTestSuite) TestCheckIn() {
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	newBooking := models.NewBooking{RoomId: suite.createRoom(), Host: "ivan", Start: start, End: start.Add(time.Hour), Attendees: []string{"petr"}}
+	id, err := (*suite.repository).Create(suite.ctx, &newBooking)
+	require.Nil(suite.T(), err, "Create error")
+
+	checkIn := models.CheckIn{Person: "petr"}
+	_, err = (*suite.repository).CheckIn(suite.ctx, &id, &checkIn, start.Add(-time.Hour))
+	require.ErrorIs(suite.T(), err, models.ErrCheckInNotOpen)
+
+	stranger := models.CheckIn{Person: "olga"}
+	_, err = (*suite.repository).CheckIn(suite.ctx, &id, &stranger, start)
+	require.ErrorIs(suite.T(), err, models.ErrNotParticipant)
+
+	booking, err := (*suite.repository).CheckIn(suite.ctx, &id, &checkIn, start.Add(-10*time.Minute))
+	require.Nil(suite.T(), err, "CheckIn error")
+	require.True(suite.T(), booking.IsCheckedIn())
+
+	events, err := (*suite.repository).Events(suite.ctx, &id)
+	require.Nil(suite.T(), err, "Events error")
+	require.Len(suite.T(), events, 1)
+	require.Equal(suite.T(), models.EventCheckedIn, events[0].Type)
+}
+
+func (suite *BookingRepositoryTestSuite) TestReleaseNoShows() {
+	roomId := suite.createRoom()
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	ghost := models.NewBooking{RoomId: roomId, Host: "ivan", Start: start, End: start.Add(time.Hour)}
+	confirmed := models.NewBooking{RoomId: roomId, Host: "olga", Start: ghost.End, End: ghost.End.Add(time.Hour)}
+	ghostId, err := (*suite.repository).Create(suite.ctx, &ghost)
+	require.Nil(suite.T(), err, "Create error")
+	confirmedId, err := (*suite.repository).Create(suite.ctx, &confirmed)
+	require.Nil(suite.T(), err, "Create error")
+	checkIn := models.CheckIn{Person: "olga"}
+	_, err = (*suite.repository).CheckIn(suite.ctx, &confirmedId, &checkIn, confirmed.Start)
+	require.Nil(suite.T(), err, "CheckIn error")
+
+	at := confirmed.Start.Add(15 * time.Minute)
+	released, err := (*suite.repository).ReleaseNoShows(suite.ctx, at.Add(-10*time.Minute), at)
+	require.Nil(suite.T(), err, "ReleaseNoShows error")
+	// the ghost booking has already ended, the confirmed one is checked in
+	require.Empty(suite.T(), released)
+
+	at = start.Add(15 * time.Minute)
+	released, err = (*suite.repository).ReleaseNoShows(suite.ctx, at.Add(-10*time.Minute), at)
+	require.Nil(suite.T(), err, "ReleaseNoShows error")
+	require.Equal(suite.T(), []uuid.UUID{ghostId}, released)
+
+	events, err := (*suite.repository).Events(suite.ctx, &ghostId)
+	require.Nil(suite.T(), err, "Events error")
+	require.Equal(suite.T(), models.EventNoShow, events[0].Type)
+}
+
+func (suite *BookingRepositoryTestSuite) TestHoldConfirmAndExpire() {
+	roomId := suite.createRoom()
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	at := start.Add(-24 * time.Hour)
+	first := models.NewBooking{RoomId: roomId, Host: "ivan", Start: start, End: start.Add(time.Hour)}
+	second := models.NewBooking{RoomId: roomId, Host: "olga", Start: first.End, End: first.End.Add(time.Hour)}
+	confirmed, err := (*suite.repository).Hold(suite.ctx, &first, at.Add(10*time.Minute))
+	require.Nil(suite.T(), err, "Hold error")
+	require.True(suite.T(), confirmed.IsHeld())
+	expired, err := (*suite.repository).Hold(suite.ctx, &second, at.Add(10*time.Minute))
+	require.Nil(suite.T(), err, "Hold error")
+
+	// a hold blocks the slot as a booking does
+	var conflict *models.ConflictError
+	_, err = (*suite.repository).Create(suite.ctx, &first)
+	require.ErrorAs(suite.T(), err, &conflict)
+
+	booking, err := (*suite.repository).Confirm(suite.ctx, &confirmed.Id, at.Add(5*time.Minute))
+	require.Nil(suite.T(), err, "Confirm error")
+	require.False(suite.T(), booking.IsHeld())
+	_, err = (*suite.repository).Confirm(suite.ctx, &confirmed.Id, at.Add(5*time.Minute))
+	require.ErrorIs(suite.T(), err, models.ErrNotHeld)
+	_, err = (*suite.repository).Confirm(suite.ctx, &expired.Id, at.Add(10*time.Minute))
+	require.ErrorIs(suite.T(), err, models.ErrHoldExpired)
+
+	released, err := (*suite.repository).ReleaseExpiredHolds(suite.ctx, at.Add(10*time.Minute))
+	require.Nil(suite.T(), err, "ReleaseExpiredHolds error")
+	require.Equal(suite.T(), []uuid.UUID{expired.Id}, released)
+
+	events, err := (*suite.repository).Events(suite.ctx, &expired.Id)
+	require.Nil(suite.T(), err, "Events error")
+	require.Equal(suite.T(), models.EventHoldExpired, events[0].Type)
+
+	// the released slot can be booked again
+	_, err = (*suite.repository).Create(suite.ctx, &second)
+	require.Nil(suite.T(), err, "Create error")
+}
+
+func (suite *BookingRepositoryTestSuite) TestWaitlistTakesFreedSlotInTurn() {
+	roomId := suite.createRoom()
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	at := start.Add(-24 * time.Hour)
+	newBooking := models.NewBooking{RoomId: roomId, Host: "ivan", Start: start, End: start.Add(time.Hour)}
+	id, err := (*suite.repository).Create(suite.ctx, &newBooking)
+	require.Nil(suite.T(), err, "Create error")
+
+	first := models.NewWaitlistEntry{Person: "olga", RoomId: &roomId, Start: start, End: newBooking.End, Attendees: []string{}}
+	second := models.NewWaitlistEntry{
+		Person:     "petr",
+		Criteria:   &models.RoomCriteria{Capacity: 4, Labels: []string{}},
+		Start:      start.Add(30 * time.Minute),
+		End:        newBooking.End,
+		Attendees:  []string{},
+		AutoAssign: true,
+	}
+	offered, err := (*suite.repository).JoinWaitlist(suite.ctx, &first, at)
+	require.Nil(suite.T(), err, "JoinWaitlist error")
+	assigned, err := (*suite.repository).JoinWaitlist(suite.ctx, &second, at.Add(time.Minute))
+	require.Nil(suite.T(), err, "JoinWaitlist error")
+
+	cancellation := models.Cancellation{CancelledBy: "ivan", Reason: "moved online"}
+	_, err = (*suite.repository).Cancel(suite.ctx, &id, &cancellation, at)
+	require.Nil(suite.T(), err, "Cancel error")
+	slot := models.FreedSlot{RoomId: roomId, Start: newBooking.Start, End: newBooking.End}
+	entries, err := (*suite.repository).OfferFreedSlot(suite.ctx, &slot, at, 30*time.Minute)
+	require.Nil(suite.T(), err, "OfferFreedSlot error")
+	// the second entry overlaps the hold of the first one and keeps waiting
+	require.Len(suite.T(), entries, 1)
+	require.Equal(suite.T(), offered.Id, entries[0].Id)
+	require.Equal(suite.T(), models.WaitlistOffered, entries[0].Status)
+
+	hold, err := (*suite.repository).Get(suite.ctx, entries[0].BookingId)
+	require.Nil(suite.T(), err, "Get error")
+	require.Equal(suite.T(), "olga", hold.Host)
+	require.True(suite.T(), hold.IsHeld())
+
+	released, err := (*suite.repository).ReleaseExpiredHolds(suite.ctx, at.Add(30*time.Minute))
+	require.Nil(suite.T(), err, "ReleaseExpiredHolds error")
+	require.Equal(suite.T(), []uuid.UUID{hold.Id}, released)
+	expired, err := (*suite.repository).WaitlistEntry(suite.ctx, &offered.Id)
+	require.Nil(suite.T(), err, "WaitlistEntry error")
+	require.Equal(suite.T(), models.WaitlistExpired, expired.Status)
+
+	entries, err = (*suite.repository).OfferFreedSlot(suite.ctx, &slot, at.Add(30*time.Minute), 30*time.Minute)
+	require.Nil(suite.T(), err, "OfferFreedSlot error")
+	require.Len(suite.T(), entries, 1)
+	require.Equal(suite.T(), assigned.Id, entries[0].Id)
+	require.Equal(suite.T(), models.WaitlistAssigned, entries[0].Status)
+
+	var offers int
+	query := "select count(*) from outbox where type = 'waitlist_offered' and aggregate_id in ($1, $2)"
+	err = suite.pool.QueryRow(suite.ctx, query, offered.Id.String(), assigned.Id.String()).Scan(&offers)
+	require.Nil(suite.T(), err, "outbox error")
+	require.Equal(suite.T(), 2, offers)
+
+	_, err = (*suite.repository).LeaveWaitlist(suite.ctx, &assigned.Id)
+	require.ErrorIs(suite.T(), err, models.ErrNotWaiting)
+}
+
+func (suite *BookingRepositoryTestSuite) TestPreempt() {
+	roomId := suite.createRoom()
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	require.Nil(suite.T(), (*suite.repository).SetPriority(suite.ctx, &models.HostPriority{Host: "director", Priority: 10}))
+	require.Nil(suite.T(), (*suite.repository).SetPriority(suite.ctx, &models.HostPriority{Host: "manager", Priority: 5}))
+
+	regular := models.NewBooking{RoomId: roomId, Host: "ivan", Start: start, End: start.Add(time.Hour)}
+	regularId, err := (*suite.repository).Create(suite.ctx, &regular)
+	require.Nil(suite.T(), err, "Create error")
+
+	managers := models.NewBooking{RoomId: roomId, Host: "manager", Start: start.Add(30 * time.Minute), End: start.Add(90 * time.Minute)}
+	result, err := (*suite.repository).Preempt(suite.ctx, &managers, start.Add(-time.Hour))
+	require.Nil(suite.T(), err, "Preempt error")
+	require.Len(suite.T(), result.Preempted, 1)
+	require.Equal(suite.T(), regularId, result.Preempted[0].Id)
+
+	events, err := (*suite.repository).Events(suite.ctx, &regularId)
+	require.Nil(suite.T(), err, "Events error")
+	require.Equal(suite.T(), models.EventPreempted, events[0].Type)
+	require.Equal(suite.T(), "manager", events[0].Actor)
+	require.Equal(suite.T(), result.Id, *events[0].RelatedBookingId)
+
+	// equal priority can't preempt
+	anotherManager := managers
+	_, err = (*suite.repository).Preempt(suite.ctx, &anotherManager, start.Add(-time.Hour))
+	var conflict *models.ConflictError
+	require.True(suite.T(), errors.As(err, &conflict), "unexpected error %v", err)
+
+	directors := models.NewBooking{RoomId: roomId, Host: "director", Start: start, End: start.Add(2 * time.Hour)}
+	_, err = (*suite.repository).Preempt(suite.ctx, &directors, start.Add(-time.Hour))
+	require.Nil(suite.T(), err, "Preempt error")
+}
+
+func (suite *BookingRepositoryTestSuite) TestCreateInLockedRoomFails() {
+	roomId := suite.createRoom()
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	lock := "insert into room_locks (id, room_id, reason, locked_by, locked_at, until) values ($1, $2, 'flooded', 'facility', $3, $4)"
+	_, err := suite.pool.Exec(suite.ctx, lock, uuid.New(), roomId, start, start.Add(2*time.Hour))
+	require.Nil(suite.T(), err, "lock error")
+
+	during := models.NewBooking{RoomId: roomId, Host: "ivan", Start: start.Add(time.Hour), End: start.Add(3 * time.Hour)}
+	_, err = (*suite.repository).Create(suite.ctx, &during)
+	require.ErrorIs(suite.T(), err, models.ErrRoomLocked)
+
+	after := models.NewBooking{RoomId: roomId, Host: "ivan", Start: start.Add(2 * time.Hour), End: start.Add(3 * time.Hour)}
+	_, err = (*suite.repository).Create(suite.ctx, &after)
+	require.Nil(suite.T(), err, "Create error")
+}
+
+func TestBookingRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(BookingRepositoryTestSuite))
+}
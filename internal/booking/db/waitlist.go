@@ -0,0 +1,205 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/optician/meeting-room-booking/internal/booking/models"
+	outboxDB "github.com/optician/meeting-room-booking/internal/outbox/db"
+	outbox "github.com/optician/meeting-room-booking/internal/outbox/models"
+)
+
+const waitlistColumns = `id, person, room_id, criteria, start_at, end_at, attendees, agenda, auto_assign,
+	status, created_at, booking_id, offer_expires_at`
+
+func (impl *impl) JoinWaitlist(ctx context.Context, entry *models.NewWaitlistEntry, at time.Time) (models.WaitlistEntry, error) {
+	var joined models.WaitlistEntry
+	query := `insert into waitlist
+				(
+					id,
+					person,
+					room_id,
+					criteria,
+					start_at,
+					end_at,
+					attendees,
+					agenda,
+					auto_assign,
+					created_at
+				)
+				values (
+					@id,
+					@person,
+					@room_id,
+					@criteria,
+					@start_at,
+					@end_at,
+					@attendees,
+					@agenda,
+					@auto_assign,
+					@created_at
+				)
+				returning ` + waitlistColumns
+	args := pgx.NamedArgs{
+		"id":          uuid.New(),
+		"person":      entry.Person,
+		"room_id":     entry.RoomId,
+		"criteria":    entry.Criteria,
+		"start_at":    entry.Start,
+		"end_at":      entry.End,
+		"attendees":   entry.Attendees,
+		"agenda":      entry.Agenda,
+		"auto_assign": entry.AutoAssign,
+		"created_at":  at,
+	}
+	err := pgxscan.Get(ctx, impl.dbpool, &joined, query, args)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return joined, models.ErrRoomNotFound
+	}
+	return joined, err // wrap error
+}
+
+func (impl *impl) WaitlistEntry(ctx context.Context, id *uuid.UUID) (models.WaitlistEntry, error) {
+	var entry models.WaitlistEntry
+	query := "select " + waitlistColumns + " from waitlist where id = @id"
+	err := pgxscan.Get(ctx, impl.dbpool, &entry, query, pgx.NamedArgs{"id": id})
+	if pgxscan.NotFound(err) {
+		return entry, models.ErrEntryNotFound
+	}
+	return entry, err // wrap error
+}
+
+func (impl *impl) Waitlist(ctx context.Context, person string) ([]models.WaitlistEntry, error) {
+	entries := make([]models.WaitlistEntry, 0)
+	query := "select " + waitlistColumns + " from waitlist where person = @person order by created_at, id"
+	err := pgxscan.Select(ctx, impl.dbpool, &entries, query, pgx.NamedArgs{"person": person})
+	return entries, err // wrap error
+}
+
+// only a waiting entry can be left, an offer is declined by cancelling its booking
+func (impl *impl) LeaveWaitlist(ctx context.Context, id *uuid.UUID) (models.WaitlistEntry, error) {
+	var entry models.WaitlistEntry
+	err := pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		query := "select " + waitlistColumns + " from waitlist where id = @id for update"
+		if err := pgxscan.Get(ctx, tx, &entry, query, pgx.NamedArgs{"id": id}); err != nil {
+			if pgxscan.NotFound(err) {
+				return models.ErrEntryNotFound
+			}
+			return err
+		}
+		if entry.Status != models.WaitlistWaiting {
+			return models.ErrNotWaiting
+		}
+
+		entry.Status = models.WaitlistLeft
+		update := "update waitlist set status = @status where id = @id"
+		_, err := tx.Exec(ctx, update, pgx.NamedArgs{"id": id, "status": entry.Status})
+		return err
+	})
+	return entry, err // wrap error
+}
+
+// OfferFreedSlot books the slot for waiting entries in the order they joined, an entry gets a hold
+// expiring after window unless it's auto assigned. Entries which still conflict with other bookings keep waiting.
+// Entries are skip locked, so concurrent offers of overlapping slots don't wait for each other.
+func (impl *impl) OfferFreedSlot(ctx context.Context, slot *models.FreedSlot, at time.Time, window time.Duration) ([]models.WaitlistEntry, error) {
+	offered := make([]models.WaitlistEntry, 0)
+	err := pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		candidates := make([]models.WaitlistEntry, 0)
+		query := `select ` + waitlistColumns + `
+					from waitlist w
+					where status = 'waiting'
+					  and start_at > @at
+					  and tstzrange(start_at, end_at) && tstzrange(@start_at, @end_at)
+					  and (
+						room_id = @room_id
+						or room_id is null and exists (
+							select 1 from meeting_rooms r
+							where r.id = @room_id
+							  and r.capacity >= (w.criteria->>'capacity')::int
+							  and r.office = coalesce(w.criteria->>'office', r.office)
+							  and coalesce(r.labels, '{}') @> array(select jsonb_array_elements_text(w.criteria->'labels'))
+						)
+					  )
+					order by created_at, id
+					for update skip locked`
+		args := pgx.NamedArgs{"room_id": slot.RoomId, "start_at": slot.Start, "end_at": slot.End, "at": at}
+		if err := pgxscan.Select(ctx, tx, &candidates, query, args); err != nil {
+			return err
+		}
+
+		for i := range candidates {
+			entry := &candidates[i]
+			ok, err := impl.offer(ctx, tx, entry, slot.RoomId, at, window)
+			if err != nil {
+				return err
+			}
+			if ok {
+				offered = append(offered, *entry)
+			}
+		}
+		return nil
+	})
+	return offered, err // wrap error
+}
+
+// offer is false if the entry doesn't fit the room anymore
+func (impl *impl) offer(ctx context.Context, tx pgx.Tx, entry *models.WaitlistEntry, roomId uuid.UUID, at time.Time, window time.Duration) (bool, error) {
+	var expiresAt *time.Time
+	status := models.WaitlistAssigned
+	if !entry.AutoAssign {
+		expiry := at.Add(window)
+		status, expiresAt = models.WaitlistOffered, &expiry
+	}
+
+	booking := entry.Booking(roomId)
+	created, err := impl.book(ctx, tx, uuid.New(), &booking, nil, expiresAt)
+	var conflict *models.ConflictError
+	switch {
+	case errors.As(err, &conflict), errors.Is(err, models.ErrRoomLocked):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+
+	entry.Status, entry.BookingId, entry.OfferExpiresAt = status, &created.Id, expiresAt
+	update := `update waitlist
+				set
+					status = @status,
+					booking_id = @booking_id,
+					offer_expires_at = @offer_expires_at
+				where id = @id`
+	args := pgx.NamedArgs{
+		"id":               entry.Id,
+		"status":           entry.Status,
+		"booking_id":       entry.BookingId,
+		"offer_expires_at": entry.OfferExpiresAt,
+	}
+	if _, err := tx.Exec(ctx, update, args); err != nil {
+		return false, err
+	}
+
+	payload := outbox.WaitlistOffer{
+		EntryId:   entry.Id,
+		Person:    entry.Person,
+		BookingId: created.Id,
+		RoomId:    created.RoomId,
+		Start:     created.Start,
+		End:       created.End,
+		ExpiresAt: expiresAt,
+	}
+	return true, outboxDB.Write(ctx, tx, outbox.WaitlistOffered, entry.Id.String(), at, payload)
+}
+
+// settleOffer moves an entry offered the booking to status
+func settleOffer(ctx context.Context, q querier, bookingId uuid.UUID, status models.WaitlistStatus) error {
+	query := "update waitlist set status = @status where booking_id = @booking_id and status = 'offered'"
+	_, err := q.Exec(ctx, query, pgx.NamedArgs{"booking_id": bookingId, "status": status})
+	return err
+}
@@ -0,0 +1,289 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/optician/meeting-room-booking/internal/booking/models"
+	"github.com/optician/meeting-room-booking/internal/booking/service"
+	"go.uber.org/zap"
+)
+
+type Controller struct {
+	logger *zap.SugaredLogger
+	logic  *service.Logic
+}
+
+// mutates router
+func Make(logic *service.Logic, logger *zap.SugaredLogger) func(chi.Router) {
+	defer logger.Sync()
+
+	controller := Controller{
+		logger: logger,
+		logic:  logic,
+	}
+	return controller.routes
+}
+
+func (ctrl *Controller) routes(r chi.Router) {
+	r.Route("/bookings", func(r chi.Router) {
+		r.Post("/create", ctrl.createBookingController)
+		r.Post("/series/create", ctrl.createSeriesController)
+		r.Post("/hold", ctrl.holdController)
+		r.Post("/priorities/update", ctrl.setPriorityController)
+		r.Get("/{id}", ctrl.getBookingController)
+		r.Post("/{id}/cancel", ctrl.cancelBookingController)
+		r.Post("/{id}/update", ctrl.updateBookingController)
+		r.Post("/{id}/confirm", ctrl.confirmController)
+		r.Post("/{id}/checkin", ctrl.checkInController)
+		r.Get("/{id}/events", ctrl.getEventsController)
+	})
+	// an offered slot is a hold, it's accepted by /bookings/{id}/confirm
+	r.Route("/waitlist", ctrl.waitlistRoutes)
+}
+
+// ?mode=preempt cancels overlapping bookings of hosts with a lower priority
+func (ctrl *Controller) createBookingController(w http.ResponseWriter, r *http.Request) {
+	booking, err := fromBytesNewBooking(r.Body)
+	if err != nil {
+		ctrl.badRequest(w, "Invalid NewBooking", err)
+		return
+	}
+
+	switch mode := r.URL.Query().Get("mode"); mode {
+	case "":
+	case "preempt":
+		if result, err := (*ctrl.logic).Preempt(r.Context(), &booking); err != nil {
+			ctrl.writeError(w, err, "failed to preempt a booking")
+		} else {
+			ctrl.writeJSON(w, http.StatusOK, result)
+		}
+		return
+	default:
+		ctrl.badRequest(w, "Invalid mode", fmt.Errorf("unknown mode %q", mode))
+		return
+	}
+
+	if id, err := (*ctrl.logic).Create(r.Context(), &booking); err != nil {
+		ctrl.writeError(w, err, "failed to create a new booking")
+	} else {
+		ctrl.writeJSON(w, http.StatusOK, CreationResponse{Id: id})
+	}
+}
+
+func (ctrl *Controller) createSeriesController(w http.ResponseWriter, r *http.Request) {
+	series, err := fromBytesNewSeries(r.Body)
+	if err != nil {
+		ctrl.badRequest(w, "Invalid NewSeries", err)
+		return
+	}
+
+	result, err := (*ctrl.logic).CreateSeries(r.Context(), &series)
+	switch {
+	case err == nil:
+		ctrl.writeJSON(w, http.StatusOK, result)
+	case errors.Is(err, models.ErrNoFreeOccurrence):
+		ctrl.writeJSON(w, http.StatusConflict, result)
+	default:
+		ctrl.writeError(w, err, "failed to create a new booking series")
+	}
+}
+
+func (ctrl *Controller) setPriorityController(w http.ResponseWriter, r *http.Request) {
+	priority, err := fromBytesHostPriority(r.Body)
+	if err != nil {
+		ctrl.badRequest(w, "Invalid HostPriority", err)
+		return
+	}
+
+	if err := (*ctrl.logic).SetPriority(r.Context(), &priority); err != nil {
+		ctrl.writeError(w, err, "Update of a host priority raised error")
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (ctrl *Controller) getBookingController(w http.ResponseWriter, r *http.Request) {
+	id, ok := ctrl.bookingId(w, r)
+	if !ok {
+		return
+	}
+
+	if booking, err := (*ctrl.logic).Get(r.Context(), &id); err != nil {
+		ctrl.writeError(w, err, "Reading of a booking raised error")
+	} else {
+		ctrl.writeJSON(w, http.StatusOK, booking)
+	}
+}
+
+// ?scope=following|all cancels other occurrences of a series too and responds with a list
+func (ctrl *Controller) cancelBookingController(w http.ResponseWriter, r *http.Request) {
+	id, ok := ctrl.bookingId(w, r)
+	if !ok {
+		return
+	}
+	scope, err := models.ParseScope(r.URL.Query().Get("scope"))
+	if err != nil {
+		ctrl.badRequest(w, "Invalid scope", err)
+		return
+	}
+	cancellation, err := fromBytesCancellation(r.Body)
+	if err != nil {
+		ctrl.badRequest(w, "Invalid Cancellation", err)
+		return
+	}
+
+	if scope == models.ScopeThis {
+		if booking, err := (*ctrl.logic).Cancel(r.Context(), &id, &cancellation); err != nil {
+			ctrl.writeError(w, err, "Cancellation of a booking raised error")
+		} else {
+			ctrl.writeJSON(w, http.StatusOK, booking)
+		}
+	} else if bookings, err := (*ctrl.logic).CancelSeries(r.Context(), &id, scope, &cancellation); err != nil {
+		ctrl.writeError(w, err, "Cancellation of a booking series raised error")
+	} else {
+		ctrl.writeJSON(w, http.StatusOK, bookings)
+	}
+}
+
+func (ctrl *Controller) updateBookingController(w http.ResponseWriter, r *http.Request) {
+	id, ok := ctrl.bookingId(w, r)
+	if !ok {
+		return
+	}
+	scope, err := models.ParseScope(r.URL.Query().Get("scope"))
+	if err != nil {
+		ctrl.badRequest(w, "Invalid scope", err)
+		return
+	}
+	change, err := fromBytesBookingChange(r.Body)
+	if err != nil {
+		ctrl.badRequest(w, "Invalid BookingChange", err)
+		return
+	}
+
+	if bookings, err := (*ctrl.logic).Update(r.Context(), &id, scope, &change); err != nil {
+		ctrl.writeError(w, err, "Update of a booking raised error")
+	} else {
+		ctrl.writeJSON(w, http.StatusOK, bookings)
+	}
+}
+
+// a hold blocks the slot like a booking until it's confirmed or expires
+func (ctrl *Controller) holdController(w http.ResponseWriter, r *http.Request) {
+	hold, err := fromBytesNewHold(r.Body)
+	if err != nil {
+		ctrl.badRequest(w, "Invalid NewHold", err)
+		return
+	}
+
+	if booking, err := (*ctrl.logic).Hold(r.Context(), &hold); err != nil {
+		ctrl.writeError(w, err, "failed to hold a slot")
+	} else {
+		ctrl.writeJSON(w, http.StatusOK, booking)
+	}
+}
+
+func (ctrl *Controller) confirmController(w http.ResponseWriter, r *http.Request) {
+	id, ok := ctrl.bookingId(w, r)
+	if !ok {
+		return
+	}
+
+	if booking, err := (*ctrl.logic).Confirm(r.Context(), &id); err != nil {
+		ctrl.writeError(w, err, "Confirmation of a hold raised error")
+	} else {
+		ctrl.writeJSON(w, http.StatusOK, booking)
+	}
+}
+
+func (ctrl *Controller) checkInController(w http.ResponseWriter, r *http.Request) {
+	id, ok := ctrl.bookingId(w, r)
+	if !ok {
+		return
+	}
+	checkIn, err := fromBytesCheckIn(r.Body)
+	if err != nil {
+		ctrl.badRequest(w, "Invalid CheckIn", err)
+		return
+	}
+
+	if booking, err := (*ctrl.logic).CheckIn(r.Context(), &id, &checkIn); err != nil {
+		ctrl.writeError(w, err, "Check-in raised error")
+	} else {
+		ctrl.writeJSON(w, http.StatusOK, booking)
+	}
+}
+
+func (ctrl *Controller) getEventsController(w http.ResponseWriter, r *http.Request) {
+	id, ok := ctrl.bookingId(w, r)
+	if !ok {
+		return
+	}
+
+	if events, err := (*ctrl.logic).Events(r.Context(), &id); err != nil {
+		ctrl.writeError(w, err, "Reading of booking events raised error")
+	} else {
+		ctrl.writeJSON(w, http.StatusOK, events)
+	}
+}
+
+func (ctrl *Controller) bookingId(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	strId := chi.URLParam(r, "id")
+	id, err := uuid.Parse(strId)
+	if err != nil {
+		ctrl.logger.Errorf(`booking called with malformed id "%v"`, strId)
+		w.WriteHeader(http.StatusBadRequest)
+		return id, false
+	}
+	return id, true
+}
+
+func (ctrl *Controller) badRequest(w http.ResponseWriter, what string, err error) {
+	ctrl.logger.Errorf("Bad Request. %v: %v", what, err)
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write([]byte(err.Error()))
+}
+
+// maps domain errors to statuses, unknown errors are internal
+func (ctrl *Controller) writeError(w http.ResponseWriter, err error, what string) {
+	var conflict *models.ConflictError
+	switch {
+	case errors.As(err, &conflict):
+		ctrl.logger.Infof("%v: %v", what, err)
+		ctrl.writeJSON(w, http.StatusConflict, ConflictResponse{Message: err.Error(), Conflicts: conflict.Conflicts})
+	case errors.Is(err, models.ErrRoomNotFound), errors.Is(err, models.ErrBookingNotFound), errors.Is(err, models.ErrEntryNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+	case errors.Is(err, models.ErrAlreadyCancelled), errors.Is(err, models.ErrBookingEnded), errors.Is(err, models.ErrCheckInNotOpen),
+		errors.Is(err, models.ErrRoomLocked), errors.Is(err, models.ErrNotHeld), errors.Is(err, models.ErrHoldExpired),
+		errors.Is(err, models.ErrHoldNotConfirmed), errors.Is(err, models.ErrNotWaiting):
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(err.Error()))
+	case errors.Is(err, models.ErrNotParticipant):
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(err.Error()))
+	case errors.Is(err, models.ErrNotInSeries):
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+	default:
+		ctrl.logger.Errorf("%v: %v", what, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (ctrl *Controller) writeJSON(w http.ResponseWriter, status int, payload any) {
+	json, err := json.Marshal(payload)
+	if err != nil {
+		ctrl.logger.Errorf("internal error: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(status)
+	w.Write(json)
+}
@@ -0,0 +1,474 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/optician/meeting-room-booking/internal/booking/models"
+	"github.com/optician/meeting-room-booking/internal/booking/service"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var logger = zap.NewExample().Sugar()
+
+func executeRequest(req *http.Request, logic service.Logic) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	return rr
+}
+
+const newBookingJson = `
+	{
+		"roomId":"6f1f5bd4-5d1e-4b8c-9a43-1d6f3c1b2e2a",
+		"host":"ivan",
+		"start":"2030-01-10T14:00:00Z",
+		"end":"2030-01-10T15:00:00Z",
+		"attendees":["petr","olga"],
+		"agenda":"retro"
+	}`
+
+func TestCreateBookingSuccessfully(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/bookings/create", strings.NewReader(newBookingJson))
+
+	response := executeRequest(req, logicStub{})
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Equal(t, fmt.Sprintf(`{"id":"%v"}`, stubBooking.Id), response.Body.String())
+}
+
+func TestCreateBookingWithBadRequest(t *testing.T) {
+	json := `{"roomId":"6f1f5bd4-5d1e-4b8c-9a43-1d6f3c1b2e2a","start":"2030-01-10T14:00:00Z","end":"2030-01-10T15:00:00Z"}`
+	req, _ := http.NewRequest("POST", "/bookings/create", strings.NewReader(json))
+
+	response := executeRequest(req, logicStub{})
+
+	require.Equal(t, http.StatusBadRequest, response.Code)
+	require.Equal(t, "booking host can't be empty", response.Body.String())
+}
+
+func TestCreateBookingWithConflict(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/bookings/create", strings.NewReader(newBookingJson))
+	conflict := &models.ConflictError{Conflicts: []models.Booking{stubBooking}}
+
+	response := executeRequest(req, logicStub{err: conflict})
+
+	require.Equal(t, http.StatusConflict, response.Code)
+	expected := fmt.Sprintf(
+		`{"message":"booking overlaps 1 existing booking(s)","conflicts":[{"id":"%v","roomId":"%v","host":"ivan","start":"2030-01-10T14:00:00Z","end":"2030-01-10T15:00:00Z","attendees":["petr"],"agenda":"retro","bookedAt":"2030-01-01T00:00:00Z","hostPriority":0}]}`,
+		stubBooking.Id, stubBooking.RoomId,
+	)
+	require.Equal(t, expected, response.Body.String())
+}
+
+func TestCreateBookingForMissingRoom(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/bookings/create", strings.NewReader(newBookingJson))
+
+	response := executeRequest(req, logicStub{err: models.ErrRoomNotFound})
+
+	require.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestGetBookingSuccessfully(t *testing.T) {
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/bookings/%v", stubBooking.Id), nil)
+
+	response := executeRequest(req, logicStub{})
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Contains(t, response.Body.String(), fmt.Sprintf(`"id":"%v"`, stubBooking.Id))
+}
+
+func TestGetMissingBooking(t *testing.T) {
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/bookings/%v", uuid.New()), nil)
+
+	response := executeRequest(req, logicStub{err: models.ErrBookingNotFound})
+
+	require.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestCancelBookingSuccessfully(t *testing.T) {
+	json := `{"cancelledBy":"olga","reason":"moved online"}`
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/bookings/%v/cancel", stubBooking.Id), strings.NewReader(json))
+
+	response := executeRequest(req, logicStub{})
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Contains(t, response.Body.String(), `"cancelledBy":"olga","cancelReason":"moved online"`)
+}
+
+func TestCancelBookingWithoutAuthor(t *testing.T) {
+	json := `{"reason":"moved online"}`
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/bookings/%v/cancel", stubBooking.Id), strings.NewReader(json))
+
+	response := executeRequest(req, logicStub{})
+
+	require.Equal(t, http.StatusBadRequest, response.Code)
+	require.Equal(t, "cancellation author can't be empty", response.Body.String())
+}
+
+func TestCancelEndedBooking(t *testing.T) {
+	json := `{"cancelledBy":"olga","reason":"moved online"}`
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/bookings/%v/cancel", stubBooking.Id), strings.NewReader(json))
+
+	response := executeRequest(req, logicStub{err: models.ErrBookingEnded})
+
+	require.Equal(t, http.StatusConflict, response.Code)
+	require.Equal(t, "booking has already ended", response.Body.String())
+}
+
+func TestCreateSeriesSuccessfully(t *testing.T) {
+	json := `
+	{
+		"roomId":"6f1f5bd4-5d1e-4b8c-9a43-1d6f3c1b2e2a",
+		"host":"ivan",
+		"start":"2030-01-10T14:00:00Z",
+		"end":"2030-01-10T15:00:00Z",
+		"timezone":"Europe/Berlin",
+		"rrule":"FREQ=WEEKLY;COUNT=4",
+		"exdates":["2030-01-17T14:00:00Z"]
+	}`
+	req, _ := http.NewRequest("POST", "/bookings/series/create", strings.NewReader(json))
+
+	response := executeRequest(req, logicStub{})
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Contains(t, response.Body.String(), fmt.Sprintf(`"id":"%v"`, stubSeriesId))
+}
+
+func TestCreateSeriesWithUnboundedRule(t *testing.T) {
+	json := `
+	{
+		"roomId":"6f1f5bd4-5d1e-4b8c-9a43-1d6f3c1b2e2a",
+		"host":"ivan",
+		"start":"2030-01-10T14:00:00Z",
+		"end":"2030-01-10T15:00:00Z",
+		"timezone":"Europe/Berlin",
+		"rrule":"FREQ=WEEKLY"
+	}`
+	req, _ := http.NewRequest("POST", "/bookings/series/create", strings.NewReader(json))
+
+	response := executeRequest(req, logicStub{})
+
+	require.Equal(t, http.StatusBadRequest, response.Code)
+	require.Equal(t, "recurrence rule must be bounded by COUNT or UNTIL", response.Body.String())
+}
+
+func TestCreateSeriesWithoutFreeOccurrences(t *testing.T) {
+	json := `
+	{
+		"roomId":"6f1f5bd4-5d1e-4b8c-9a43-1d6f3c1b2e2a",
+		"host":"ivan",
+		"start":"2030-01-10T14:00:00Z",
+		"end":"2030-01-10T15:00:00Z",
+		"timezone":"UTC",
+		"rrule":"FREQ=DAILY;COUNT=2"
+	}`
+	req, _ := http.NewRequest("POST", "/bookings/series/create", strings.NewReader(json))
+
+	response := executeRequest(req, logicStub{err: models.ErrNoFreeOccurrence})
+
+	require.Equal(t, http.StatusConflict, response.Code)
+}
+
+func TestCancelFollowingOccurrences(t *testing.T) {
+	json := `{"cancelledBy":"olga","reason":"project is over"}`
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/bookings/%v/cancel?scope=following", stubBooking.Id), strings.NewReader(json))
+
+	response := executeRequest(req, logicStub{})
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.True(t, strings.HasPrefix(response.Body.String(), "["))
+}
+
+func TestCancelWithUnknownScope(t *testing.T) {
+	json := `{"cancelledBy":"olga"}`
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/bookings/%v/cancel?scope=previous", stubBooking.Id), strings.NewReader(json))
+
+	response := executeRequest(req, logicStub{})
+
+	require.Equal(t, http.StatusBadRequest, response.Code)
+	require.Equal(t, `unknown scope "previous"`, response.Body.String())
+}
+
+func TestUpdateStandaloneBookingSeries(t *testing.T) {
+	json := `{"agenda":"new agenda"}`
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/bookings/%v/update?scope=all", stubBooking.Id), strings.NewReader(json))
+
+	response := executeRequest(req, logicStub{err: models.ErrNotInSeries})
+
+	require.Equal(t, http.StatusBadRequest, response.Code)
+	require.Equal(t, "booking isn't a part of a series", response.Body.String())
+}
+
+func TestUpdateBookingWithConflict(t *testing.T) {
+	json := `{"start":"2030-01-10T15:00:00Z","end":"2030-01-10T16:00:00Z"}`
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/bookings/%v/update", stubBooking.Id), strings.NewReader(json))
+	conflict := &models.ConflictError{Conflicts: []models.Booking{stubBooking}}
+
+	response := executeRequest(req, logicStub{err: conflict})
+
+	require.Equal(t, http.StatusConflict, response.Code)
+}
+
+func TestCheckInSuccessfully(t *testing.T) {
+	json := `{"person":"petr"}`
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/bookings/%v/checkin", stubBooking.Id), strings.NewReader(json))
+
+	response := executeRequest(req, logicStub{})
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Contains(t, response.Body.String(), `"checkedInBy":"petr"`)
+}
+
+func TestCheckInByStranger(t *testing.T) {
+	json := `{"person":"stranger"}`
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/bookings/%v/checkin", stubBooking.Id), strings.NewReader(json))
+
+	response := executeRequest(req, logicStub{err: models.ErrNotParticipant})
+
+	require.Equal(t, http.StatusForbidden, response.Code)
+}
+
+func TestCheckInTooEarly(t *testing.T) {
+	json := `{"person":"petr"}`
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/bookings/%v/checkin", stubBooking.Id), strings.NewReader(json))
+
+	response := executeRequest(req, logicStub{err: models.ErrCheckInNotOpen})
+
+	require.Equal(t, http.StatusConflict, response.Code)
+}
+
+func TestGetBookingEvents(t *testing.T) {
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/bookings/%v/events", stubBooking.Id), nil)
+
+	response := executeRequest(req, logicStub{})
+
+	require.Equal(t, http.StatusOK, response.Code)
+	expected := fmt.Sprintf(`[{"id":1,"bookingId":"%v","type":"no_show","actor":"system","occurredAt":"2030-01-10T14:10:00Z"}]`, stubBooking.Id)
+	require.Equal(t, expected, response.Body.String())
+}
+
+func TestPreemptBookingSuccessfully(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/bookings/create?mode=preempt", strings.NewReader(newBookingJson))
+
+	response := executeRequest(req, logicStub{})
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Contains(t, response.Body.String(), fmt.Sprintf(`"preempted":[{"id":"%v"`, stubBooking.Id))
+}
+
+func TestPreemptHigherPriorityBooking(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/bookings/create?mode=preempt", strings.NewReader(newBookingJson))
+	conflict := &models.ConflictError{Conflicts: []models.Booking{stubBooking}}
+
+	response := executeRequest(req, logicStub{err: conflict})
+
+	require.Equal(t, http.StatusConflict, response.Code)
+}
+
+func TestCreateBookingWithUnknownMode(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/bookings/create?mode=force", strings.NewReader(newBookingJson))
+
+	response := executeRequest(req, logicStub{})
+
+	require.Equal(t, http.StatusBadRequest, response.Code)
+	require.Equal(t, `unknown mode "force"`, response.Body.String())
+}
+
+func TestSetNegativePriority(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/bookings/priorities/update", strings.NewReader(`{"host":"ivan","priority":-1}`))
+
+	response := executeRequest(req, logicStub{})
+
+	require.Equal(t, http.StatusBadRequest, response.Code)
+	require.Equal(t, "host priority can't be negative", response.Body.String())
+}
+
+func TestHoldSuccessfully(t *testing.T) {
+	json := strings.Replace(newBookingJson, "{", `{"ttlSeconds":600,`, 1)
+	req, _ := http.NewRequest("POST", "/bookings/hold", strings.NewReader(json))
+
+	response := executeRequest(req, logicStub{})
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Contains(t, response.Body.String(), `"holdExpiresAt":"2030-01-10T13:10:00Z"`)
+}
+
+func TestHoldTooLong(t *testing.T) {
+	json := strings.Replace(newBookingJson, "{", `{"ttlSeconds":86401,`, 1)
+	req, _ := http.NewRequest("POST", "/bookings/hold", strings.NewReader(json))
+
+	response := executeRequest(req, logicStub{})
+
+	require.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestConfirmExpiredHold(t *testing.T) {
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/bookings/%v/confirm", stubBooking.Id), nil)
+
+	response := executeRequest(req, logicStub{err: models.ErrHoldExpired})
+
+	require.Equal(t, http.StatusConflict, response.Code)
+}
+
+func TestJoinWaitlistSuccessfully(t *testing.T) {
+	json := `{"person":"olga","criteria":{"capacity":4,"labels":["video"]},"start":"2030-01-10T14:00:00Z","end":"2030-01-10T15:00:00Z"}`
+	req, _ := http.NewRequest("POST", "/waitlist/join", strings.NewReader(json))
+
+	response := executeRequest(req, logicStub{})
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Contains(t, response.Body.String(), `"status":"waiting"`)
+}
+
+func TestJoinWaitlistWithRoomAndCriteria(t *testing.T) {
+	json := `{"person":"olga","roomId":"6f1f5bd4-5d1e-4b8c-9a43-1d6f3c1b2e2a","criteria":{"capacity":4},"start":"2030-01-10T14:00:00Z","end":"2030-01-10T15:00:00Z"}`
+	req, _ := http.NewRequest("POST", "/waitlist/join", strings.NewReader(json))
+
+	response := executeRequest(req, logicStub{})
+
+	require.Equal(t, http.StatusBadRequest, response.Code)
+	require.Equal(t, "waitlist entry needs either a room id or room criteria", response.Body.String())
+}
+
+func TestGetWaitlistWithoutPerson(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/waitlist", nil)
+
+	response := executeRequest(req, logicStub{})
+
+	require.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestLeaveOfferedWaitlistEntry(t *testing.T) {
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/waitlist/%v/leave", uuid.New()), nil)
+
+	response := executeRequest(req, logicStub{err: models.ErrNotWaiting})
+
+	require.Equal(t, http.StatusConflict, response.Code)
+}
+
+type logicStub struct {
+	err error
+}
+
+var stubSeriesId = uuid.New()
+
+var stubBooking = models.Booking{
+	Id:        uuid.New(),
+	RoomId:    uuid.New(),
+	Host:      "ivan",
+	Start:     time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC),
+	End:       time.Date(2030, 1, 10, 15, 0, 0, 0, time.UTC),
+	Attendees: []string{"petr"},
+	Agenda:    "retro",
+	BookedAt:  time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+}
+
+func (stub logicStub) Create(ctx context.Context, booking *models.NewBooking) (uuid.UUID, error) {
+	return stubBooking.Id, stub.err
+}
+
+func (stub logicStub) Get(ctx context.Context, id *uuid.UUID) (models.Booking, error) {
+	return stubBooking, stub.err
+}
+
+func (stub logicStub) Cancel(ctx context.Context, id *uuid.UUID, cancellation *models.Cancellation) (models.Booking, error) {
+	booking := stubBooking
+	cancelledAt := time.Date(2030, 1, 10, 13, 0, 0, 0, time.UTC)
+	booking.CancelledAt = &cancelledAt
+	booking.CancelledBy = &cancellation.CancelledBy
+	booking.CancelReason = &cancellation.Reason
+	return booking, stub.err
+}
+
+func (stub logicStub) CreateSeries(ctx context.Context, series *models.NewSeries) (models.SeriesResult, error) {
+	return models.SeriesResult{Id: stubSeriesId, Bookings: []models.Booking{stubBooking}}, stub.err
+}
+
+func (stub logicStub) CancelSeries(ctx context.Context, id *uuid.UUID, scope models.Scope, cancellation *models.Cancellation) ([]models.Booking, error) {
+	booking, err := stub.Cancel(ctx, id, cancellation)
+	return []models.Booking{booking}, err
+}
+
+func (stub logicStub) Update(ctx context.Context, id *uuid.UUID, scope models.Scope, change *models.BookingChange) ([]models.Booking, error) {
+	return []models.Booking{stubBooking}, stub.err
+}
+
+func (stub logicStub) CheckIn(ctx context.Context, id *uuid.UUID, checkIn *models.CheckIn) (models.Booking, error) {
+	booking := stubBooking
+	checkedInAt := time.Date(2030, 1, 10, 14, 5, 0, 0, time.UTC)
+	booking.CheckedInAt = &checkedInAt
+	booking.CheckedInBy = &checkIn.Person
+	return booking, stub.err
+}
+
+func (stub logicStub) Events(ctx context.Context, id *uuid.UUID) ([]models.Event, error) {
+	event := models.Event{
+		Id:         1,
+		BookingId:  stubBooking.Id,
+		Type:       models.EventNoShow,
+		Actor:      models.SystemActor,
+		OccurredAt: time.Date(2030, 1, 10, 14, 10, 0, 0, time.UTC),
+	}
+	return []models.Event{event}, stub.err
+}
+
+func (stub logicStub) Preempt(ctx context.Context, booking *models.NewBooking) (models.PreemptionResult, error) {
+	return models.PreemptionResult{Id: uuid.New(), Preempted: []models.Booking{stubBooking}}, stub.err
+}
+
+func (stub logicStub) Hold(ctx context.Context, hold *models.NewHold) (models.Booking, error) {
+	booking := stubBooking
+	expiresAt := time.Date(2030, 1, 10, 13, 10, 0, 0, time.UTC)
+	booking.HoldExpiresAt = &expiresAt
+	return booking, stub.err
+}
+
+func (stub logicStub) Confirm(ctx context.Context, id *uuid.UUID) (models.Booking, error) {
+	return stubBooking, stub.err
+}
+
+func (stub logicStub) JoinWaitlist(ctx context.Context, entry *models.NewWaitlistEntry) (models.WaitlistEntry, error) {
+	return stubEntry(entry.Person), stub.err
+}
+
+func (stub logicStub) WaitlistEntry(ctx context.Context, id *uuid.UUID) (models.WaitlistEntry, error) {
+	return stubEntry("olga"), stub.err
+}
+
+func (stub logicStub) Waitlist(ctx context.Context, person string) ([]models.WaitlistEntry, error) {
+	return []models.WaitlistEntry{stubEntry(person)}, stub.err
+}
+
+func (stub logicStub) LeaveWaitlist(ctx context.Context, id *uuid.UUID) (models.WaitlistEntry, error) {
+	entry := stubEntry("olga")
+	entry.Status = models.WaitlistLeft
+	return entry, stub.err
+}
+
+func stubEntry(person string) models.WaitlistEntry {
+	return models.WaitlistEntry{
+		Id:        uuid.New(),
+		Person:    person,
+		RoomId:    &stubBooking.RoomId,
+		Start:     stubBooking.Start,
+		End:       stubBooking.End,
+		Attendees: []string{},
+		Status:    models.WaitlistWaiting,
+	}
+}
+
+func (stub logicStub) SetPriority(ctx context.Context, priority *models.HostPriority) error {
+	return stub.err
+}
@@ -0,0 +1,145 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/optician/meeting-room-booking/internal/booking/models"
+)
+
+func deserializeNewBooking(stream io.Reader) (models.NewBooking, error) {
+	booking := &models.NewBooking{}
+	if err := json.NewDecoder(stream).Decode(booking); err != nil {
+		return *booking, fmt.Errorf("can't deserialize NewBooking: %w", err)
+	} else {
+		return *booking, nil
+	}
+}
+
+func fromBytesNewBooking(stream io.Reader) (models.NewBooking, error) {
+	if booking, err := deserializeNewBooking(stream); err != nil {
+		return booking, err
+	} else {
+		return models.ValidateNewBooking(&booking)
+	}
+}
+
+func deserializeCancellation(stream io.Reader) (models.Cancellation, error) {
+	cancellation := &models.Cancellation{}
+	if err := json.NewDecoder(stream).Decode(cancellation); err != nil {
+		return *cancellation, fmt.Errorf("can't deserialize Cancellation: %w", err)
+	} else {
+		return *cancellation, nil
+	}
+}
+
+func fromBytesCancellation(stream io.Reader) (models.Cancellation, error) {
+	if cancellation, err := deserializeCancellation(stream); err != nil {
+		return cancellation, err
+	} else {
+		return models.ValidateCancellation(&cancellation)
+	}
+}
+
+func deserializeNewSeries(stream io.Reader) (models.NewSeries, error) {
+	newSeries := &models.NewSeries{}
+	if err := json.NewDecoder(stream).Decode(newSeries); err != nil {
+		return *newSeries, fmt.Errorf("can't deserialize NewSeries: %w", err)
+	} else {
+		return *newSeries, nil
+	}
+}
+
+func fromBytesNewSeries(stream io.Reader) (models.NewSeries, error) {
+	if newSeries, err := deserializeNewSeries(stream); err != nil {
+		return newSeries, err
+	} else {
+		return models.ValidateNewSeries(&newSeries)
+	}
+}
+
+func deserializeBookingChange(stream io.Reader) (models.BookingChange, error) {
+	bookingChange := &models.BookingChange{}
+	if err := json.NewDecoder(stream).Decode(bookingChange); err != nil {
+		return *bookingChange, fmt.Errorf("can't deserialize BookingChange: %w", err)
+	} else {
+		return *bookingChange, nil
+	}
+}
+
+func fromBytesBookingChange(stream io.Reader) (models.BookingChange, error) {
+	if bookingChange, err := deserializeBookingChange(stream); err != nil {
+		return bookingChange, err
+	} else {
+		return models.ValidateBookingChange(&bookingChange)
+	}
+}
+
+func deserializeCheckIn(stream io.Reader) (models.CheckIn, error) {
+	checkIn := &models.CheckIn{}
+	if err := json.NewDecoder(stream).Decode(checkIn); err != nil {
+		return *checkIn, fmt.Errorf("can't deserialize CheckIn: %w", err)
+	} else {
+		return *checkIn, nil
+	}
+}
+
+func fromBytesCheckIn(stream io.Reader) (models.CheckIn, error) {
+	if checkIn, err := deserializeCheckIn(stream); err != nil {
+		return checkIn, err
+	} else {
+		return models.ValidateCheckIn(&checkIn)
+	}
+}
+
+func deserializeHostPriority(stream io.Reader) (models.HostPriority, error) {
+	priority := &models.HostPriority{}
+	if err := json.NewDecoder(stream).Decode(priority); err != nil {
+		return *priority, fmt.Errorf("can't deserialize HostPriority: %w", err)
+	} else {
+		return *priority, nil
+	}
+}
+
+func fromBytesHostPriority(stream io.Reader) (models.HostPriority, error) {
+	if priority, err := deserializeHostPriority(stream); err != nil {
+		return priority, err
+	} else {
+		return models.ValidateHostPriority(&priority)
+	}
+}
+
+func deserializeNewHold(stream io.Reader) (models.NewHold, error) {
+	hold := &models.NewHold{}
+	if err := json.NewDecoder(stream).Decode(hold); err != nil {
+		return *hold, fmt.Errorf("can't deserialize NewHold: %w", err)
+	} else {
+		return *hold, nil
+	}
+}
+
+func fromBytesNewHold(stream io.Reader) (models.NewHold, error) {
+	if hold, err := deserializeNewHold(stream); err != nil {
+		return hold, err
+	} else {
+		return models.ValidateNewHold(&hold)
+	}
+}
+
+func deserializeNewWaitlistEntry(stream io.Reader) (models.NewWaitlistEntry, error) {
+	entry := &models.NewWaitlistEntry{}
+	if err := json.NewDecoder(stream).Decode(entry); err != nil {
+		return *entry, fmt.Errorf("can't deserialize NewWaitlistEntry: %w", err)
+	} else {
+		return *entry, nil
+	}
+}
+
+func fromBytesNewWaitlistEntry(stream io.Reader) (models.NewWaitlistEntry, error) {
+	if entry, err := deserializeNewWaitlistEntry(stream); err != nil {
+		return entry, err
+	} else {
+		return models.ValidateNewWaitlistEntry(&entry)
+	}
+}
@@ -0,0 +1,15 @@
+package httpapi
+
+import (
+	"github.com/google/uuid"
+	"github.com/optician/meeting-room-booking/internal/booking/models"
+)
+
+type CreationResponse struct {
+	Id uuid.UUID `json:"id"`
+}
+
+type ConflictResponse struct {
+	Message   string           `json:"message"`
+	Conflicts []models.Booking `json:"conflicts"`
+}
@@ -0,0 +1,70 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (ctrl *Controller) waitlistRoutes(r chi.Router) {
+	r.Get("/", ctrl.getWaitlistController)
+	r.Post("/join", ctrl.joinWaitlistController)
+	r.Get("/{id}", ctrl.getWaitlistEntryController)
+	r.Post("/{id}/leave", ctrl.leaveWaitlistController)
+}
+
+func (ctrl *Controller) joinWaitlistController(w http.ResponseWriter, r *http.Request) {
+	entry, err := fromBytesNewWaitlistEntry(r.Body)
+	if err != nil {
+		ctrl.badRequest(w, "Invalid NewWaitlistEntry", err)
+		return
+	}
+
+	if joined, err := (*ctrl.logic).JoinWaitlist(r.Context(), &entry); err != nil {
+		ctrl.writeError(w, err, "failed to join a waitlist")
+	} else {
+		ctrl.writeJSON(w, http.StatusOK, joined)
+	}
+}
+
+// ?person= is required
+func (ctrl *Controller) getWaitlistController(w http.ResponseWriter, r *http.Request) {
+	person := r.URL.Query().Get("person")
+	if person == "" {
+		ctrl.badRequest(w, "Invalid waitlist query", errors.New("person is required"))
+		return
+	}
+
+	if entries, err := (*ctrl.logic).Waitlist(r.Context(), person); err != nil {
+		ctrl.writeError(w, err, "Reading of a waitlist raised error")
+	} else {
+		ctrl.writeJSON(w, http.StatusOK, entries)
+	}
+}
+
+func (ctrl *Controller) getWaitlistEntryController(w http.ResponseWriter, r *http.Request) {
+	id, ok := ctrl.bookingId(w, r)
+	if !ok {
+		return
+	}
+
+	if entry, err := (*ctrl.logic).WaitlistEntry(r.Context(), &id); err != nil {
+		ctrl.writeError(w, err, "Reading of a waitlist entry raised error")
+	} else {
+		ctrl.writeJSON(w, http.StatusOK, entry)
+	}
+}
+
+func (ctrl *Controller) leaveWaitlistController(w http.ResponseWriter, r *http.Request) {
+	id, ok := ctrl.bookingId(w, r)
+	if !ok {
+		return
+	}
+
+	if entry, err := (*ctrl.logic).LeaveWaitlist(r.Context(), &id); err != nil {
+		ctrl.writeError(w, err, "Leaving a waitlist raised error")
+	} else {
+		ctrl.writeJSON(w, http.StatusOK, entry)
+	}
+}
@@ -0,0 +1,463 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/optician/meeting-room-booking/internal/booking/recurrence"
+)
+
+type Booking struct {
+	Id        uuid.UUID `json:"id"`
+	RoomId    uuid.UUID `json:"roomId"`
+	Host      string    `json:"host"`
+	Start     time.Time `json:"start" db:"start_at"`
+	End       time.Time `json:"end" db:"end_at"`
+	Attendees []string  `json:"attendees"`
+	Agenda    string    `json:"agenda"`
+	BookedAt  time.Time `json:"bookedAt"`
+
+	CancelledAt  *time.Time `json:"cancelledAt,omitempty"`
+	CancelledBy  *string    `json:"cancelledBy,omitempty"`
+	CancelReason *string    `json:"cancelReason,omitempty"`
+
+	SeriesId *uuid.UUID `json:"seriesId,omitempty"`
+
+	CheckedInAt *time.Time `json:"checkedInAt,omitempty"`
+	CheckedInBy *string    `json:"checkedInBy,omitempty"`
+
+	HostPriority int `json:"hostPriority"`
+
+	// set while the booking is a tentative hold
+	HoldExpiresAt *time.Time `json:"holdExpiresAt,omitempty"`
+}
+
+func (booking *Booking) IsHeld() bool {
+	return booking.HoldExpiresAt != nil
+}
+
+func (booking *Booking) IsCancelled() bool {
+	return booking.CancelledAt != nil
+}
+
+func (booking *Booking) IsCheckedIn() bool {
+	return booking.CheckedInAt != nil
+}
+
+// only the host and attendees take part in a meeting
+func (booking *Booking) IsParticipant(person string) bool {
+	return booking.Host == person || slices.Contains(booking.Attendees, person)
+}
+
+// check-in opens a bit earlier than a meeting starts
+const EarlyCheckIn = 15 * time.Minute
+
+type CheckIn struct {
+	Person string `json:"person"`
+}
+
+func ValidateCheckIn(checkIn *CheckIn) (CheckIn, error) {
+	if checkIn.Person == "" {
+		return *checkIn, errors.New("check-in person can't be empty")
+	}
+
+	return *checkIn, nil
+}
+
+type EventType string
+
+const (
+	EventConfirmed   EventType = "confirmed"
+	EventHoldExpired EventType = "hold_expired"
+	EventModified    EventType = "modified"
+	EventCheckedIn   EventType = "checked_in"
+	EventNoShow      EventType = "no_show"
+	EventPreempted   EventType = "preempted"
+	// written by the administration when a room lock cancels a booking
+	EventRoomLocked EventType = "room_locked"
+)
+
+// actor of events produced by the application itself
+const SystemActor = "system"
+
+type Event struct {
+	Id         int64     `json:"id"`
+	BookingId  uuid.UUID `json:"bookingId"`
+	Type       EventType `json:"type"`
+	Actor      string    `json:"actor"`
+	OccurredAt time.Time `json:"occurredAt"`
+
+	RelatedBookingId *uuid.UUID `json:"relatedBookingId,omitempty"`
+	// of modifications
+	Before *Booking `json:"before,omitempty"`
+	After  *Booking `json:"after,omitempty"`
+}
+
+// Modification is the booking after a change with its state before.
+// Fields of the booking are on top, so consumers of booking events read it as any other booking.
+type Modification struct {
+	Booking
+	Before Booking `json:"before"`
+}
+
+// HostPriority lets a host preempt bookings of hosts with a lower priority
+type HostPriority struct {
+	Host     string `json:"host"`
+	Priority int    `json:"priority"`
+}
+
+func ValidateHostPriority(priority *HostPriority) (HostPriority, error) {
+	if priority.Host == "" {
+		return *priority, errors.New("host can't be empty")
+	}
+	if priority.Priority < 0 {
+		return *priority, errors.New("host priority can't be negative")
+	}
+
+	return *priority, nil
+}
+
+// PreemptionResult is a created booking and bookings cancelled in its favour
+type PreemptionResult struct {
+	Id        uuid.UUID `json:"id"`
+	Preempted []Booking `json:"preempted"`
+}
+
+const PreemptionReason = "preempted by a booking of a higher priority host"
+
+type NewBooking struct {
+	RoomId    uuid.UUID `json:"roomId"`
+	Host      string    `json:"host"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Attendees []string  `json:"attendees"`
+	Agenda    string    `json:"agenda"`
+}
+
+func ValidateNewBooking(booking *NewBooking) (NewBooking, error) {
+	if booking.RoomId == uuid.Nil {
+		return *booking, errors.New("booking room id can't be empty")
+	}
+	if booking.Host == "" {
+		return *booking, errors.New("booking host can't be empty")
+	}
+	if booking.Start.IsZero() || booking.End.IsZero() {
+		return *booking, errors.New("booking start and end can't be empty")
+	}
+	if !booking.Start.Before(booking.End) {
+		return *booking, errors.New("booking start must be before its end")
+	}
+
+	return *booking, nil
+}
+
+const (
+	DefaultHoldTTL = 15 * time.Minute
+	MaxHoldTTL     = 24 * time.Hour
+	// the cancel reason of holds released by the sweeper
+	HoldExpiredReason = "hold expired"
+)
+
+// NewHold is a booking blocking its slot for TTLSeconds until it's confirmed
+type NewHold struct {
+	NewBooking
+	TTLSeconds int `json:"ttlSeconds"`
+}
+
+// TTL is DefaultHoldTTL if it's not set
+func (hold *NewHold) TTL() time.Duration {
+	if hold.TTLSeconds == 0 {
+		return DefaultHoldTTL
+	}
+	return time.Duration(hold.TTLSeconds) * time.Second
+}
+
+func ValidateNewHold(hold *NewHold) (NewHold, error) {
+	if _, err := ValidateNewBooking(&hold.NewBooking); err != nil {
+		return *hold, err
+	}
+	if hold.TTLSeconds < 0 || hold.TTL() > MaxHoldTTL {
+		return *hold, fmt.Errorf("hold ttl must be from 1 second to %v", MaxHoldTTL)
+	}
+
+	return *hold, nil
+}
+
+type WaitlistStatus string
+
+const (
+	WaitlistWaiting WaitlistStatus = "waiting"
+	// the slot is held for the person until the offer expires
+	WaitlistOffered WaitlistStatus = "offered"
+	// the slot is booked for the person at once
+	WaitlistAssigned WaitlistStatus = "assigned"
+	WaitlistAccepted WaitlistStatus = "accepted"
+	// the offered hold was cancelled
+	WaitlistCancelled WaitlistStatus = "cancelled"
+	WaitlistExpired   WaitlistStatus = "expired"
+	WaitlistLeft      WaitlistStatus = "left"
+)
+
+// RoomCriteria matches a room with enough capacity in the office, if it's set, with all labels
+type RoomCriteria struct {
+	Capacity int      `json:"capacity"`
+	Office   *string  `json:"office,omitempty"`
+	Labels   []string `json:"labels"`
+}
+
+// NewWaitlistEntry queues a person for an interval of a room or of any room matching criteria
+type NewWaitlistEntry struct {
+	Person    string        `json:"person"`
+	RoomId    *uuid.UUID    `json:"roomId,omitempty"`
+	Criteria  *RoomCriteria `json:"criteria,omitempty"`
+	Start     time.Time     `json:"start"`
+	End       time.Time     `json:"end"`
+	Attendees []string      `json:"attendees"`
+	Agenda    string        `json:"agenda"`
+	// a freed slot is booked at once instead of being offered
+	AutoAssign bool `json:"autoAssign"`
+}
+
+func ValidateNewWaitlistEntry(entry *NewWaitlistEntry) (NewWaitlistEntry, error) {
+	if entry.Person == "" {
+		return *entry, errors.New("waitlist person can't be empty")
+	}
+	if (entry.RoomId == nil) == (entry.Criteria == nil) {
+		return *entry, errors.New("waitlist entry needs either a room id or room criteria")
+	}
+	if entry.RoomId != nil && *entry.RoomId == uuid.Nil {
+		return *entry, errors.New("waitlist room id can't be empty")
+	}
+	if entry.Criteria != nil {
+		if entry.Criteria.Capacity < 0 {
+			return *entry, errors.New("waitlist capacity can't be negative")
+		}
+		if entry.Criteria.Labels == nil {
+			entry.Criteria.Labels = []string{}
+		}
+	}
+	if entry.Start.IsZero() || entry.End.IsZero() {
+		return *entry, errors.New("waitlist start and end can't be empty")
+	}
+	if !entry.Start.Before(entry.End) {
+		return *entry, errors.New("waitlist start must be before its end")
+	}
+	if entry.Attendees == nil {
+		entry.Attendees = []string{}
+	}
+
+	return *entry, nil
+}
+
+type WaitlistEntry struct {
+	Id         uuid.UUID      `json:"id"`
+	Person     string         `json:"person"`
+	RoomId     *uuid.UUID     `json:"roomId,omitempty"`
+	Criteria   *RoomCriteria  `json:"criteria,omitempty"`
+	Start      time.Time      `json:"start" db:"start_at"`
+	End        time.Time      `json:"end" db:"end_at"`
+	Attendees  []string       `json:"attendees"`
+	Agenda     string         `json:"agenda"`
+	AutoAssign bool           `json:"autoAssign"`
+	Status     WaitlistStatus `json:"status"`
+	CreatedAt  time.Time      `json:"createdAt"`
+
+	// the booking made for the person when a slot was freed
+	BookingId      *uuid.UUID `json:"bookingId,omitempty"`
+	OfferExpiresAt *time.Time `json:"offerExpiresAt,omitempty"`
+}
+
+// Booking is what the entry books in a room
+func (entry *WaitlistEntry) Booking(roomId uuid.UUID) NewBooking {
+	return NewBooking{
+		RoomId:    roomId,
+		Host:      entry.Person,
+		Start:     entry.Start,
+		End:       entry.End,
+		Attendees: entry.Attendees,
+		Agenda:    entry.Agenda,
+	}
+}
+
+// FreedSlot is an interval of a room which became free after a cancellation or a release
+type FreedSlot struct {
+	RoomId uuid.UUID
+	Start  time.Time
+	End    time.Time
+}
+
+type Cancellation struct {
+	CancelledBy string `json:"cancelledBy"`
+	Reason      string `json:"reason"`
+}
+
+func ValidateCancellation(cancellation *Cancellation) (Cancellation, error) {
+	if cancellation.CancelledBy == "" {
+		return *cancellation, errors.New("cancellation author can't be empty")
+	}
+
+	return *cancellation, nil
+}
+
+// NewSeries describes the first occurrence of a recurring booking and its recurrence
+type NewSeries struct {
+	NewBooking
+	Timezone string      `json:"timezone"`
+	RRule    string      `json:"rrule"`
+	ExDates  []time.Time `json:"exdates"`
+}
+
+func ValidateNewSeries(series *NewSeries) (NewSeries, error) {
+	if _, err := ValidateNewBooking(&series.NewBooking); err != nil {
+		return *series, err
+	}
+	if series.Timezone == "" {
+		return *series, errors.New("series timezone can't be empty")
+	}
+	loc, err := time.LoadLocation(series.Timezone)
+	if err != nil {
+		return *series, fmt.Errorf("unknown series timezone %q", series.Timezone)
+	}
+	if _, err := recurrence.Parse(series.RRule, loc); err != nil {
+		return *series, err
+	}
+
+	return *series, nil
+}
+
+// Occurrences materializes a series into bookings, wall-clock time is kept in the series timezone
+func (series *NewSeries) Occurrences() ([]NewBooking, error) {
+	loc, err := time.LoadLocation(series.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	rule, err := recurrence.Parse(series.RRule, loc)
+	if err != nil {
+		return nil, err
+	}
+	starts, err := rule.Occurrences(series.Start.In(loc), series.ExDates)
+	if err != nil {
+		return nil, err
+	}
+
+	duration := series.End.Sub(series.Start)
+	occurrences := make([]NewBooking, 0, len(starts))
+	for _, start := range starts {
+		occurrence := series.NewBooking
+		occurrence.Start = start
+		occurrence.End = start.Add(duration)
+		occurrences = append(occurrences, occurrence)
+	}
+	return occurrences, nil
+}
+
+type OccurrenceConflict struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Conflicts []Booking `json:"conflicts"`
+	// the room is locked at the occurrence time
+	Locked bool `json:"locked,omitempty"`
+}
+
+// SeriesResult lists booked occurrences and occurrences skipped because of conflicts
+type SeriesResult struct {
+	Id        uuid.UUID            `json:"id"`
+	Bookings  []Booking            `json:"bookings"`
+	Conflicts []OccurrenceConflict `json:"conflicts"`
+}
+
+// Scope selects occurrences of a series affected by a change or a cancellation
+type Scope string
+
+const (
+	ScopeThis      Scope = "this"
+	ScopeFollowing Scope = "following"
+	ScopeAll       Scope = "all"
+)
+
+func ParseScope(scope string) (Scope, error) {
+	switch Scope(scope) {
+	case "", ScopeThis:
+		return ScopeThis, nil
+	case ScopeFollowing, ScopeAll:
+		return Scope(scope), nil
+	default:
+		return ScopeThis, fmt.Errorf("unknown scope %q", scope)
+	}
+}
+
+// BookingChange contains only changed fields. Start and end are changed together.
+type BookingChange struct {
+	RoomId    *uuid.UUID `json:"roomId"`
+	Start     *time.Time `json:"start"`
+	End       *time.Time `json:"end"`
+	Attendees *[]string  `json:"attendees"`
+	Agenda    *string    `json:"agenda"`
+}
+
+func ValidateBookingChange(change *BookingChange) (BookingChange, error) {
+	if (change.Start == nil) != (change.End == nil) {
+		return *change, errors.New("booking start and end must be changed together")
+	}
+	if change.Start != nil && !change.Start.Before(*change.End) {
+		return *change, errors.New("booking start must be before its end")
+	}
+	if change.RoomId == nil && change.Start == nil && change.Attendees == nil && change.Agenda == nil {
+		return *change, errors.New("booking change can't be empty")
+	}
+
+	return *change, nil
+}
+
+// Apply changes an occurrence of a series the same way as origin occurrence is changed.
+// A new time is applied as a wall-clock shift in loc, so it's stable across DST changes.
+func (change *BookingChange) Apply(booking Booking, origin *Booking, loc *time.Location) Booking {
+	if change.RoomId != nil {
+		booking.RoomId = *change.RoomId
+	}
+	if change.Start != nil {
+		if booking.Id == origin.Id {
+			booking.Start = *change.Start
+		} else {
+			booking.Start = recurrence.Shift(booking.Start, origin.Start, *change.Start, loc)
+		}
+		booking.End = booking.Start.Add(change.End.Sub(*change.Start))
+	}
+	if change.Attendees != nil {
+		booking.Attendees = *change.Attendees
+	}
+	if change.Agenda != nil {
+		booking.Agenda = *change.Agenda
+	}
+	return booking
+}
+
+var (
+	ErrRoomNotFound     = errors.New("room not found")
+	ErrRoomLocked       = errors.New("room is locked")
+	ErrBookingNotFound  = errors.New("booking not found")
+	ErrAlreadyCancelled = errors.New("booking is already cancelled")
+	ErrBookingEnded     = errors.New("booking has already ended")
+	ErrNotInSeries      = errors.New("booking isn't a part of a series")
+	ErrNoFreeOccurrence = errors.New("all occurrences of the series conflict with existing bookings")
+	ErrCheckInNotOpen   = errors.New("check-in opens 15 minutes before the booking start")
+	ErrNotParticipant   = errors.New("only the host or attendees can check in")
+	ErrNotHeld          = errors.New("booking isn't a hold")
+	ErrHoldExpired      = errors.New("hold has expired")
+	ErrHoldNotConfirmed = errors.New("hold must be confirmed before check-in")
+	ErrEntryNotFound    = errors.New("waitlist entry not found")
+	ErrNotWaiting       = errors.New("waitlist entry isn't waiting")
+)
+
+// ConflictError is returned when a booking overlaps already existing bookings of the same room.
+// Conflicts can be empty if the conflicting booking disappeared before it was looked up.
+type ConflictError struct {
+	Conflicts []Booking
+}
+
+func (err *ConflictError) Error() string {
+	return fmt.Sprintf("booking overlaps %d existing booking(s)", len(err.Conflicts))
+}
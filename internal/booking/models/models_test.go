@@ -0,0 +1,228 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+
+func TestNewBookingRoomValidationFailed(t *testing.T) {
+	data := NewBooking{
+		Host:  "ivan",
+		Start: start,
+		End:   start.Add(time.Hour),
+	}
+	expected := "booking room id can't be empty"
+	_, err := ValidateNewBooking(&data)
+
+	require.EqualError(t, err, expected)
+}
+
+func TestNewBookingHostValidationFailed(t *testing.T) {
+	data := NewBooking{
+		RoomId: uuid.New(),
+		Host:   "",
+		Start:  start,
+		End:    start.Add(time.Hour),
+	}
+	expected := "booking host can't be empty"
+	_, err := ValidateNewBooking(&data)
+
+	require.EqualError(t, err, expected)
+}
+
+func TestNewBookingIntervalValidationFailed(t *testing.T) {
+	data := NewBooking{
+		RoomId: uuid.New(),
+		Host:   "ivan",
+		Start:  start,
+		End:    start,
+	}
+	expected := "booking start must be before its end"
+	_, err := ValidateNewBooking(&data)
+
+	require.EqualError(t, err, expected)
+}
+
+func TestNewBookingValidationPassed(t *testing.T) {
+	data := NewBooking{
+		RoomId:    uuid.New(),
+		Host:      "ivan",
+		Start:     start,
+		End:       start.Add(time.Hour),
+		Attendees: []string{"petr"},
+		Agenda:    "retro",
+	}
+	expected := data
+	actual, err := ValidateNewBooking(&data)
+
+	require.Nil(t, err)
+	require.Equal(t, expected, actual)
+}
+
+func TestCancellationAuthorValidationFailed(t *testing.T) {
+	data := Cancellation{CancelledBy: "", Reason: "moved online"}
+	expected := "cancellation author can't be empty"
+	_, err := ValidateCancellation(&data)
+
+	require.EqualError(t, err, expected)
+}
+
+func TestNewSeriesTimezoneValidationFailed(t *testing.T) {
+	data := NewSeries{
+		NewBooking: NewBooking{RoomId: uuid.New(), Host: "ivan", Start: start, End: start.Add(time.Hour)},
+		Timezone:   "Mars/Olympus",
+		RRule:      "FREQ=DAILY;COUNT=2",
+	}
+	expected := `unknown series timezone "Mars/Olympus"`
+	_, err := ValidateNewSeries(&data)
+
+	require.EqualError(t, err, expected)
+}
+
+func TestNewSeriesOccurrencesKeepDuration(t *testing.T) {
+	data := NewSeries{
+		NewBooking: NewBooking{RoomId: uuid.New(), Host: "ivan", Start: start, End: start.Add(30 * time.Minute), Agenda: "standup"},
+		Timezone:   "UTC",
+		RRule:      "FREQ=DAILY;COUNT=3",
+		ExDates:    []time.Time{start.AddDate(0, 0, 1)},
+	}
+
+	occurrences, err := data.Occurrences()
+
+	require.Nil(t, err)
+	require.Len(t, occurrences, 2)
+	require.True(t, start.AddDate(0, 0, 2).Equal(occurrences[1].Start))
+	require.True(t, start.AddDate(0, 0, 2).Add(30*time.Minute).Equal(occurrences[1].End))
+	require.Equal(t, "standup", occurrences[1].Agenda)
+}
+
+func TestParseScope(t *testing.T) {
+	scope, err := ParseScope("")
+	require.Nil(t, err)
+	require.Equal(t, ScopeThis, scope)
+
+	_, err = ParseScope("previous")
+	require.EqualError(t, err, `unknown scope "previous"`)
+}
+
+func TestBookingChangeValidationFailed(t *testing.T) {
+	newStart := start.Add(time.Hour)
+	data := BookingChange{Start: &newStart}
+	expected := "booking start and end must be changed together"
+	_, err := ValidateBookingChange(&data)
+
+	require.EqualError(t, err, expected)
+}
+
+func TestEmptyBookingChangeValidationFailed(t *testing.T) {
+	data := BookingChange{}
+	expected := "booking change can't be empty"
+	_, err := ValidateBookingChange(&data)
+
+	require.EqualError(t, err, expected)
+}
+
+func TestBookingChangeMovesToAnotherRoom(t *testing.T) {
+	roomId := uuid.New()
+	origin := Booking{Id: uuid.New(), RoomId: uuid.New(), Start: start, End: start.Add(time.Hour)}
+	change, err := ValidateBookingChange(&BookingChange{RoomId: &roomId})
+	require.Nil(t, err)
+
+	changed := change.Apply(origin, &origin, time.UTC)
+
+	require.Equal(t, roomId, changed.RoomId)
+	require.Equal(t, origin.Start, changed.Start)
+}
+
+func TestModificationKeepsBookingOnTop(t *testing.T) {
+	before := Booking{Id: uuid.New(), RoomId: uuid.New(), Host: "ivan"}
+	after := before
+	after.RoomId = uuid.New()
+
+	payload, err := json.Marshal(Modification{Booking: after, Before: before})
+	require.Nil(t, err)
+
+	var read struct {
+		RoomId uuid.UUID `json:"roomId"`
+		Before Booking   `json:"before"`
+	}
+	require.Nil(t, json.Unmarshal(payload, &read))
+	require.Equal(t, after.RoomId, read.RoomId)
+	require.Equal(t, before.RoomId, read.Before.RoomId)
+}
+
+func TestBookingChangeShiftsFollowingOccurrences(t *testing.T) {
+	loc, _ := time.LoadLocation("Europe/Berlin")
+	origin := Booking{Id: uuid.New(), Start: time.Date(2030, 3, 25, 10, 0, 0, 0, loc), End: time.Date(2030, 3, 25, 11, 0, 0, 0, loc)}
+	following := Booking{Id: uuid.New(), Start: time.Date(2030, 4, 1, 10, 0, 0, 0, loc), End: time.Date(2030, 4, 1, 11, 0, 0, 0, loc)}
+	newStart := time.Date(2030, 3, 25, 9, 0, 0, 0, loc)
+	newEnd := newStart.Add(90 * time.Minute)
+	agenda := "planning"
+	change := BookingChange{Start: &newStart, End: &newEnd, Agenda: &agenda}
+
+	changedOrigin := change.Apply(origin, &origin, loc)
+	changedFollowing := change.Apply(following, &origin, loc)
+
+	require.Equal(t, newStart, changedOrigin.Start)
+	require.True(t, time.Date(2030, 4, 1, 9, 0, 0, 0, loc).Equal(changedFollowing.Start))
+	require.True(t, time.Date(2030, 4, 1, 10, 30, 0, 0, loc).Equal(changedFollowing.End))
+	require.Equal(t, "planning", changedFollowing.Agenda)
+}
+
+func TestCheckInPersonValidationFailed(t *testing.T) {
+	data := CheckIn{}
+	expected := "check-in person can't be empty"
+	_, err := ValidateCheckIn(&data)
+
+	require.EqualError(t, err, expected)
+}
+
+func TestBookingParticipants(t *testing.T) {
+	booking := Booking{Host: "ivan", Attendees: []string{"petr"}}
+
+	require.True(t, booking.IsParticipant("ivan"))
+	require.True(t, booking.IsParticipant("petr"))
+	require.False(t, booking.IsParticipant("olga"))
+}
+
+func TestHoldTTL(t *testing.T) {
+	require.Equal(t, DefaultHoldTTL, (&NewHold{}).TTL())
+	require.Equal(t, 10*time.Minute, (&NewHold{TTLSeconds: 600}).TTL())
+}
+
+func TestHoldTTLValidationFailed(t *testing.T) {
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	data := NewHold{
+		NewBooking: NewBooking{RoomId: uuid.New(), Host: "ivan", Start: start, End: start.Add(time.Hour)},
+		TTLSeconds: -1,
+	}
+	expected := "hold ttl must be from 1 second to 24h0m0s"
+	_, err := ValidateNewHold(&data)
+
+	require.EqualError(t, err, expected)
+}
+
+func TestWaitlistEntryWithoutTargetValidationFailed(t *testing.T) {
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	data := NewWaitlistEntry{Person: "olga", Start: start, End: start.Add(time.Hour)}
+	expected := "waitlist entry needs either a room id or room criteria"
+	_, err := ValidateNewWaitlistEntry(&data)
+
+	require.EqualError(t, err, expected)
+}
+
+func TestWaitlistEntryDefaultsLabelsAndAttendees(t *testing.T) {
+	start := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	data := NewWaitlistEntry{Person: "olga", Criteria: &RoomCriteria{Capacity: 4}, Start: start, End: start.Add(time.Hour)}
+	entry, err := ValidateNewWaitlistEntry(&data)
+
+	require.Nil(t, err)
+	require.Equal(t, []string{}, entry.Criteria.Labels)
+	require.Equal(t, []string{}, entry.Attendees)
+}
@@ -0,0 +1,279 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// supported subset of RFC 5545 recurrence rules:
+// FREQ=DAILY|WEEKLY|MONTHLY, INTERVAL, COUNT, UNTIL, BYDAY (weekly, without ordinals), BYMONTHDAY (monthly)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+// MaxOccurrences bounds materialization of a single rule
+const MaxOccurrences = 500
+
+// rules like BYMONTHDAY=30 in february every 12 months never match, expansion gives up after this many periods
+const maxPeriods = 1200
+
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []time.Weekday
+	ByMonthDay []int
+}
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Parse accepts a rule with or without the "RRULE:" prefix, a date UNTIL ends in loc which is the series timezone
+func Parse(rrule string, loc *time.Location) (Rule, error) {
+	rule := Rule{Interval: 1}
+	rrule = strings.TrimPrefix(strings.TrimSpace(rrule), "RRULE:")
+	if rrule == "" {
+		return rule, errors.New("recurrence rule can't be empty")
+	}
+
+	for _, part := range strings.Split(rrule, ";") {
+		name, value, found := strings.Cut(part, "=")
+		if !found || value == "" {
+			return rule, fmt.Errorf("malformed recurrence rule part %q", part)
+		}
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			switch freq := Frequency(strings.ToUpper(value)); freq {
+			case Daily, Weekly, Monthly:
+				rule.Freq = freq
+			default:
+				return rule, fmt.Errorf("unsupported recurrence frequency %q", value)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 {
+				return rule, fmt.Errorf("invalid recurrence interval %q", value)
+			}
+			rule.Interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				return rule, fmt.Errorf("invalid recurrence count %q", value)
+			}
+			rule.Count = count
+		case "UNTIL":
+			until, err := parseUntil(value, loc)
+			if err != nil {
+				return rule, err
+			}
+			rule.Until = until
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				weekday, ok := weekdays[strings.ToUpper(day)]
+				if !ok {
+					return rule, fmt.Errorf("unsupported recurrence weekday %q", day)
+				}
+				rule.ByDay = append(rule.ByDay, weekday)
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(value, ",") {
+				monthDay, err := strconv.Atoi(day)
+				if err != nil || monthDay < 1 || monthDay > 31 {
+					return rule, fmt.Errorf("unsupported recurrence month day %q", day)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, monthDay)
+			}
+		case "WKST":
+			if strings.ToUpper(value) != "MO" {
+				return rule, errors.New("only WKST=MO is supported")
+			}
+		default:
+			return rule, fmt.Errorf("unsupported recurrence rule part %q", name)
+		}
+	}
+
+	switch {
+	case rule.Freq == "":
+		return rule, errors.New("recurrence frequency is required")
+	case rule.Count > 0 && !rule.Until.IsZero():
+		return rule, errors.New("recurrence rule can't have both COUNT and UNTIL")
+	case rule.Count == 0 && rule.Until.IsZero():
+		return rule, errors.New("recurrence rule must be bounded by COUNT or UNTIL")
+	case rule.Count > MaxOccurrences:
+		return rule, fmt.Errorf("recurrence rule can't have more than %d occurrences", MaxOccurrences)
+	case len(rule.ByDay) > 0 && rule.Freq != Weekly:
+		return rule, errors.New("BYDAY is supported only for WEEKLY rules")
+	case len(rule.ByMonthDay) > 0 && rule.Freq != Monthly:
+		return rule, errors.New("BYMONTHDAY is supported only for MONTHLY rules")
+	}
+	slices.SortFunc(rule.ByDay, func(a, b time.Weekday) int { return mondayOffset(a) - mondayOffset(b) })
+	slices.Sort(rule.ByMonthDay)
+	return rule, nil
+}
+
+// UNTIL is either a UTC date-time or a date, the latter includes the whole day in loc
+func parseUntil(value string, loc *time.Location) (time.Time, error) {
+	if until, err := time.Parse("20060102T150405Z", value); err == nil {
+		return until, nil
+	}
+	if date, err := time.Parse("20060102", value); err == nil {
+		y, m, d := date.Date()
+		return time.Date(y, m, d+1, 0, 0, 0, 0, loc).Add(-time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid recurrence until %q", value)
+}
+
+// String formats the rule as RFC 5545 does, UNTIL as a UTC date-time
+func (rule Rule) String() string {
+	parts := []string{"FREQ=" + string(rule.Freq)}
+	if rule.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(rule.Interval))
+	}
+	if rule.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(rule.Count))
+	}
+	if !rule.Until.IsZero() {
+		parts = append(parts, "UNTIL="+rule.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(rule.ByDay) > 0 {
+		days := make([]string, len(rule.ByDay))
+		for i, weekday := range rule.ByDay {
+			days[i] = strings.ToUpper(weekday.String()[:2])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(rule.ByMonthDay) > 0 {
+		days := make([]string, len(rule.ByMonthDay))
+		for i, day := range rule.ByMonthDay {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Moved is the rule of occurrences moved by Shift from `from` to `to`, days of the week and of the month follow the move
+func (rule Rule) Moved(from, to time.Time, loc *time.Location) Rule {
+	from, to = from.In(loc), to.In(loc)
+	moved := rule
+	moved.ByDay, moved.ByMonthDay = nil, nil
+	for _, weekday := range rule.ByDay {
+		moved.ByDay = append(moved.ByDay, time.Weekday((int(weekday)+int(to.Weekday())-int(from.Weekday())+7)%7))
+	}
+	for _, day := range rule.ByMonthDay {
+		moved.ByMonthDay = append(moved.ByMonthDay, ((day-1+to.Day()-from.Day())%31+31)%31+1)
+	}
+	slices.SortFunc(moved.ByDay, func(a, b time.Weekday) int { return mondayOffset(a) - mondayOffset(b) })
+	slices.Sort(moved.ByMonthDay)
+	return moved
+}
+
+// Occurrences expands the rule starting at dtstart. Wall-clock time of dtstart is kept in its location,
+// so occurrences don't drift across DST changes. Excluded dates still count towards COUNT as RFC 5545 requires.
+func (rule Rule) Occurrences(dtstart time.Time, exdates []time.Time) ([]time.Time, error) {
+	occurrences := make([]time.Time, 0)
+	generated := 0
+	for candidate := range rule.candidates(dtstart) {
+		if rule.Count > 0 && generated == rule.Count {
+			break
+		}
+		if !rule.Until.IsZero() && candidate.After(rule.Until) {
+			break
+		}
+		generated++
+		if generated > MaxOccurrences {
+			return nil, fmt.Errorf("recurrence rule can't have more than %d occurrences", MaxOccurrences)
+		}
+		if !slices.ContainsFunc(exdates, candidate.Equal) {
+			occurrences = append(occurrences, candidate)
+		}
+	}
+	return occurrences, nil
+}
+
+// weeks start on monday
+func mondayOffset(weekday time.Weekday) int {
+	return (int(weekday) + 6) % 7
+}
+
+// candidates yields ordered dates matching the rule from dtstart on, up to maxPeriods periods
+func (rule Rule) candidates(dtstart time.Time) func(yield func(time.Time) bool) {
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, dtstart.Hour(), dtstart.Minute(), dtstart.Second(), 0, dtstart.Location())
+	}
+
+	return func(yield func(time.Time) bool) {
+		switch rule.Freq {
+		case Daily:
+			for i := 0; i < maxPeriods*rule.Interval; i += rule.Interval {
+				if !yield(at(dtstart.Year(), dtstart.Month(), dtstart.Day()+i)) {
+					return
+				}
+			}
+		case Weekly:
+			byDay := rule.ByDay
+			if len(byDay) == 0 {
+				byDay = []time.Weekday{dtstart.Weekday()}
+			}
+			monday := dtstart.Day() - mondayOffset(dtstart.Weekday())
+			for week := 0; week < maxPeriods*rule.Interval; week += rule.Interval {
+				for _, weekday := range byDay {
+					candidate := at(dtstart.Year(), dtstart.Month(), monday+7*week+mondayOffset(weekday))
+					if candidate.Before(dtstart) {
+						continue
+					}
+					if !yield(candidate) {
+						return
+					}
+				}
+			}
+		case Monthly:
+			byMonthDay := rule.ByMonthDay
+			if len(byMonthDay) == 0 {
+				byMonthDay = []int{dtstart.Day()}
+			}
+			for month := 0; month < maxPeriods*rule.Interval; month += rule.Interval {
+				first := time.Date(dtstart.Year(), dtstart.Month()+time.Month(month), 1, 0, 0, 0, 0, dtstart.Location())
+				for _, day := range byMonthDay {
+					candidate := at(first.Year(), first.Month(), day)
+					// invalid dates like February 30 are skipped
+					if candidate.Month() != first.Month() || candidate.Before(dtstart) {
+						continue
+					}
+					if !yield(candidate) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// Shift moves an occurrence of a series the same way as one of its occurrences was moved from `from` to `to`.
+// The move is done in wall-clock time of loc, e.g. 10:00 -> 11:00 stays 11:00 after a DST change.
+func Shift(occurrence, from, to time.Time, loc *time.Location) time.Time {
+	occurrence, from, to = occurrence.In(loc), from.In(loc), to.In(loc)
+	days := civilDay(to) - civilDay(from)
+	return time.Date(occurrence.Year(), occurrence.Month(), occurrence.Day()+days, to.Hour(), to.Minute(), to.Second(), 0, loc)
+}
+
+func civilDay(t time.Time) int {
+	return int(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
@@ -0,0 +1,136 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func berlin(t *testing.T) *time.Location {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	require.Nil(t, err)
+	return loc
+}
+
+func TestParseWeeklyRule(t *testing.T) {
+	rule, err := Parse("RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=SU,MO,WE;COUNT=5", time.UTC)
+
+	require.Nil(t, err)
+	expected := Rule{
+		Freq:     Weekly,
+		Interval: 2,
+		Count:    5,
+		ByDay:    []time.Weekday{time.Monday, time.Wednesday, time.Sunday},
+	}
+	require.Equal(t, expected, rule)
+}
+
+func TestParseUnboundedRuleFailed(t *testing.T) {
+	_, err := Parse("FREQ=DAILY", time.UTC)
+
+	require.EqualError(t, err, "recurrence rule must be bounded by COUNT or UNTIL")
+}
+
+func TestParseUnsupportedFrequencyFailed(t *testing.T) {
+	_, err := Parse("FREQ=YEARLY;COUNT=2", time.UTC)
+
+	require.EqualError(t, err, `unsupported recurrence frequency "YEARLY"`)
+}
+
+func TestDailyOccurrencesWithExdate(t *testing.T) {
+	rule, _ := Parse("FREQ=DAILY;COUNT=3", time.UTC)
+	start := time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC)
+
+	actual, err := rule.Occurrences(start, []time.Time{start.AddDate(0, 0, 1)})
+
+	require.Nil(t, err)
+	require.Equal(t, []time.Time{start, start.AddDate(0, 0, 2)}, actual)
+}
+
+func TestWeeklyOccurrencesKeepWallClockAcrossDST(t *testing.T) {
+	loc := berlin(t)
+	rule, _ := Parse("FREQ=WEEKLY;COUNT=3", loc)
+	// DST starts on 2030-03-31 in Berlin
+	start := time.Date(2030, 3, 25, 10, 0, 0, 0, loc)
+
+	actual, err := rule.Occurrences(start, nil)
+
+	require.Nil(t, err)
+	require.Len(t, actual, 3)
+	require.Equal(t, time.Date(2030, 3, 25, 9, 0, 0, 0, time.UTC), actual[0].UTC())
+	require.Equal(t, time.Date(2030, 4, 1, 8, 0, 0, 0, time.UTC), actual[1].UTC())
+	require.Equal(t, time.Date(2030, 4, 8, 8, 0, 0, 0, time.UTC), actual[2].UTC())
+}
+
+func TestWeeklyByDayOccurrencesUntil(t *testing.T) {
+	rule, _ := Parse("FREQ=WEEKLY;BYDAY=MO,FR;UNTIL=20300118", time.UTC)
+	// wednesday
+	start := time.Date(2030, 1, 9, 10, 0, 0, 0, time.UTC)
+
+	actual, err := rule.Occurrences(start, nil)
+
+	require.Nil(t, err)
+	expected := []time.Time{
+		time.Date(2030, 1, 11, 10, 0, 0, 0, time.UTC),
+		time.Date(2030, 1, 14, 10, 0, 0, 0, time.UTC),
+		time.Date(2030, 1, 18, 10, 0, 0, 0, time.UTC),
+	}
+	require.Equal(t, expected, actual)
+}
+
+func TestDateUntilEndsInSeriesTimezone(t *testing.T) {
+	loc := berlin(t)
+	rule, _ := Parse("FREQ=DAILY;UNTIL=20300118", loc)
+	// half past midnight in Berlin is still the previous day in UTC
+	start := time.Date(2030, 1, 16, 0, 30, 0, 0, loc)
+
+	actual, err := rule.Occurrences(start, nil)
+
+	require.Nil(t, err)
+	require.Equal(t, time.Date(2030, 1, 18, 23, 59, 59, 0, loc), rule.Until)
+	require.Equal(t, []time.Time{start, start.AddDate(0, 0, 1), start.AddDate(0, 0, 2)}, actual)
+}
+
+func TestMonthlyOccurrencesSkipInvalidDates(t *testing.T) {
+	rule, _ := Parse("FREQ=MONTHLY;BYMONTHDAY=30;COUNT=3", time.UTC)
+	start := time.Date(2030, 1, 30, 10, 0, 0, 0, time.UTC)
+
+	actual, err := rule.Occurrences(start, nil)
+
+	require.Nil(t, err)
+	expected := []time.Time{
+		time.Date(2030, 1, 30, 10, 0, 0, 0, time.UTC),
+		time.Date(2030, 3, 30, 10, 0, 0, 0, time.UTC),
+		time.Date(2030, 4, 30, 10, 0, 0, 0, time.UTC),
+	}
+	require.Equal(t, expected, actual)
+}
+
+func TestShiftAcrossDST(t *testing.T) {
+	loc := berlin(t)
+	from := time.Date(2030, 3, 25, 10, 0, 0, 0, loc)
+	to := time.Date(2030, 3, 26, 11, 30, 0, 0, loc)
+	occurrence := time.Date(2030, 4, 1, 10, 0, 0, 0, loc)
+
+	actual := Shift(occurrence, from, to, loc)
+
+	require.Equal(t, time.Date(2030, 4, 2, 11, 30, 0, 0, loc), actual)
+	require.Equal(t, time.Date(2030, 4, 2, 9, 30, 0, 0, time.UTC), actual.UTC())
+}
+
+func TestMovedRuleFollowsDays(t *testing.T) {
+	loc := berlin(t)
+	rule, err := Parse("FREQ=WEEKLY;INTERVAL=2;BYDAY=FR,SU;COUNT=6", loc)
+	require.Nil(t, err)
+	from := time.Date(2030, 1, 11, 10, 0, 0, 0, loc)
+	to := time.Date(2030, 1, 12, 9, 0, 0, 0, loc)
+
+	moved := rule.Moved(from, to, loc)
+
+	require.Equal(t, []time.Weekday{time.Monday, time.Saturday}, moved.ByDay)
+	require.Equal(t, "FREQ=WEEKLY;INTERVAL=2;COUNT=6;BYDAY=MO,SA", moved.String())
+	parsed, err := Parse(moved.String(), loc)
+	require.Nil(t, err)
+	require.Equal(t, moved, parsed)
+}
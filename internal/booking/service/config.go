@@ -0,0 +1,14 @@
+package service
+
+import "time"
+
+type Config struct {
+	// a booking nobody checked in is released this long after its start
+	CheckInGracePeriod time.Duration `koanf:"checkin_grace_period"`
+	// how often the no-show releaser looks for unconfirmed bookings
+	NoShowCheckInterval time.Duration `koanf:"noshow_check_interval"`
+	// how often expired holds are released, an expired hold blocks its slot till then
+	HoldSweepInterval time.Duration `koanf:"hold_sweep_interval"`
+	// how long a waitlisted person has to confirm a freed slot offered to them
+	WaitlistOfferWindow time.Duration `koanf:"waitlist_offer_window"`
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/optician/meeting-room-booking/internal/booking/db"
+	"go.uber.org/zap"
+)
+
+// HoldSweeper periodically releases holds which were not confirmed in time
+type HoldSweeper struct {
+	logger *zap.SugaredLogger
+	db     *db.DB
+	config *Config
+	now    func() time.Time
+}
+
+func MakeHoldSweeper(db *db.DB, config *Config, logger *zap.SugaredLogger) HoldSweeper {
+	return HoldSweeper{
+		logger: logger,
+		db:     db,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// Run blocks until ctx is done
+func (sweeper HoldSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(sweeper.config.HoldSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := sweeper.SweepOnce(ctx); err != nil {
+				sweeper.logger.Errorf("hold sweep failed: %v", err)
+			}
+		}
+	}
+}
+
+// SweepOnce returns the number of released holds
+func (sweeper HoldSweeper) SweepOnce(ctx context.Context) (int, error) {
+	released, err := (*sweeper.db).ReleaseExpiredHolds(ctx, sweeper.now())
+	if err != nil {
+		return 0, err
+	}
+	if len(released) > 0 {
+		sweeper.logger.Infof("released expired holds %v", released)
+	}
+	return len(released), nil
+}
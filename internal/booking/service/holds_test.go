@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/optician/meeting-room-booking/internal/booking/db"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSweepOnceReleasesExpiredHolds(t *testing.T) {
+	stub := &dbStub{}
+	var repository db.DB = stub
+	config := Config{HoldSweepInterval: time.Minute}
+	sweeper := MakeHoldSweeper(&repository, &config, zap.NewExample().Sugar())
+	now := time.Date(2030, 1, 10, 14, 10, 0, 0, time.UTC)
+	sweeper.now = func() time.Time { return now }
+
+	released, err := sweeper.SweepOnce(context.Background())
+
+	require.Nil(t, err)
+	require.Equal(t, 1, released)
+	require.Equal(t, now, stub.at)
+}
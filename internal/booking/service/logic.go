@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/optician/meeting-room-booking/internal/booking/db"
+	"github.com/optician/meeting-room-booking/internal/booking/models"
+	"go.uber.org/zap"
+)
+
+type Logic interface {
+	Create(ctx context.Context, booking *models.NewBooking) (uuid.UUID, error)
+
+	Get(ctx context.Context, id *uuid.UUID) (models.Booking, error)
+
+	Cancel(ctx context.Context, id *uuid.UUID, cancellation *models.Cancellation) (models.Booking, error)
+
+	// materializes occurrences of the series, conflicting occurrences are skipped and reported
+	CreateSeries(ctx context.Context, series *models.NewSeries) (models.SeriesResult, error)
+
+	// cancels the booking and, depending on scope, other occurrences of its series
+	CancelSeries(ctx context.Context, id *uuid.UUID, scope models.Scope, cancellation *models.Cancellation) ([]models.Booking, error)
+
+	// changes the booking and, depending on scope, other occurrences of its series
+	Update(ctx context.Context, id *uuid.UUID, scope models.Scope, change *models.BookingChange) ([]models.Booking, error)
+
+	CheckIn(ctx context.Context, id *uuid.UUID, checkIn *models.CheckIn) (models.Booking, error)
+
+	Events(ctx context.Context, id *uuid.UUID) ([]models.Event, error)
+
+	// books a slot cancelling overlapping bookings of hosts with a lower priority
+	Preempt(ctx context.Context, booking *models.NewBooking) (models.PreemptionResult, error)
+
+	SetPriority(ctx context.Context, priority *models.HostPriority) error
+
+	// books the slot tentatively, it's released unless confirmed in the hold ttl
+	Hold(ctx context.Context, hold *models.NewHold) (models.Booking, error)
+
+	Confirm(ctx context.Context, id *uuid.UUID) (models.Booking, error)
+
+	// queues the person for a slot, it's offered to them when a matching booking is cancelled or released
+	JoinWaitlist(ctx context.Context, entry *models.NewWaitlistEntry) (models.WaitlistEntry, error)
+
+	WaitlistEntry(ctx context.Context, id *uuid.UUID) (models.WaitlistEntry, error)
+
+	Waitlist(ctx context.Context, person string) ([]models.WaitlistEntry, error)
+
+	LeaveWaitlist(ctx context.Context, id *uuid.UUID) (models.WaitlistEntry, error)
+}
+
+type impl struct {
+	logger *zap.SugaredLogger
+	db     *db.DB
+}
+
+func Make(db *db.DB, logger *zap.SugaredLogger) Logic {
+	defer logger.Sync()
+
+	return impl{
+		logger: logger,
+		db:     db,
+	}
+}
+
+func (impl impl) Create(ctx context.Context, booking *models.NewBooking) (uuid.UUID, error) {
+	impl.logger.Infof("recieved a new booking %v", *booking)
+	id, err := (*impl.db).Create(ctx, booking) // wrap error
+	return id, err
+}
+
+func (impl impl) Get(ctx context.Context, id *uuid.UUID) (models.Booking, error) {
+	booking, err := (*impl.db).Get(ctx, id) // wrap error
+	return booking, err
+}
+
+func (impl impl) Cancel(ctx context.Context, id *uuid.UUID, cancellation *models.Cancellation) (models.Booking, error) {
+	impl.logger.Infof("cancel %v booking: %v", id, *cancellation)
+	booking, err := (*impl.db).Cancel(ctx, id, cancellation, time.Now()) // wrap error
+	return booking, err
+}
+
+func (impl impl) CreateSeries(ctx context.Context, series *models.NewSeries) (models.SeriesResult, error) {
+	impl.logger.Infof("recieved a new booking series %v", *series)
+	occurrences, err := series.Occurrences()
+	if err != nil {
+		return models.SeriesResult{}, err
+	}
+	result, err := (*impl.db).CreateSeries(ctx, series, occurrences) // wrap error
+	return result, err
+}
+
+func (impl impl) CancelSeries(ctx context.Context, id *uuid.UUID, scope models.Scope, cancellation *models.Cancellation) ([]models.Booking, error) {
+	impl.logger.Infof("cancel %v of %v booking: %v", scope, id, *cancellation)
+	bookings, err := (*impl.db).CancelSeries(ctx, id, scope, cancellation, time.Now()) // wrap error
+	return bookings, err
+}
+
+func (impl impl) Update(ctx context.Context, id *uuid.UUID, scope models.Scope, change *models.BookingChange) ([]models.Booking, error) {
+	impl.logger.Infof("update %v of %v booking: %v", scope, id, *change)
+	bookings, err := (*impl.db).Update(ctx, id, scope, change, time.Now()) // wrap error
+	return bookings, err
+}
+
+func (impl impl) CheckIn(ctx context.Context, id *uuid.UUID, checkIn *models.CheckIn) (models.Booking, error) {
+	impl.logger.Infof("check-in of %v booking: %v", id, *checkIn)
+	booking, err := (*impl.db).CheckIn(ctx, id, checkIn, time.Now()) // wrap error
+	return booking, err
+}
+
+func (impl impl) Events(ctx context.Context, id *uuid.UUID) ([]models.Event, error) {
+	events, err := (*impl.db).Events(ctx, id) // wrap error
+	return events, err
+}
+
+func (impl impl) Preempt(ctx context.Context, booking *models.NewBooking) (models.PreemptionResult, error) {
+	impl.logger.Infof("recieved a new preempting booking %v", *booking)
+	result, err := (*impl.db).Preempt(ctx, booking, time.Now()) // wrap error
+	if err == nil && len(result.Preempted) > 0 {
+		impl.logger.Infof("booking %v preempted %v", result.Id, result.Preempted)
+	}
+	return result, err
+}
+
+func (impl impl) SetPriority(ctx context.Context, priority *models.HostPriority) error {
+	impl.logger.Infof("set host priority %v", *priority)
+	return (*impl.db).SetPriority(ctx, priority) // wrap error
+}
+
+func (impl impl) Hold(ctx context.Context, hold *models.NewHold) (models.Booking, error) {
+	impl.logger.Infof("recieved a new hold %v", *hold)
+	booking, err := (*impl.db).Hold(ctx, &hold.NewBooking, time.Now().Add(hold.TTL())) // wrap error
+	return booking, err
+}
+
+func (impl impl) Confirm(ctx context.Context, id *uuid.UUID) (models.Booking, error) {
+	impl.logger.Infof("confirm %v hold", id)
+	booking, err := (*impl.db).Confirm(ctx, id, time.Now()) // wrap error
+	return booking, err
+}
+
+func (impl impl) JoinWaitlist(ctx context.Context, entry *models.NewWaitlistEntry) (models.WaitlistEntry, error) {
+	impl.logger.Infof("recieved a new waitlist entry %v", *entry)
+	joined, err := (*impl.db).JoinWaitlist(ctx, entry, time.Now()) // wrap error
+	return joined, err
+}
+
+func (impl impl) WaitlistEntry(ctx context.Context, id *uuid.UUID) (models.WaitlistEntry, error) {
+	entry, err := (*impl.db).WaitlistEntry(ctx, id) // wrap error
+	return entry, err
+}
+
+func (impl impl) Waitlist(ctx context.Context, person string) ([]models.WaitlistEntry, error) {
+	entries, err := (*impl.db).Waitlist(ctx, person) // wrap error
+	return entries, err
+}
+
+func (impl impl) LeaveWaitlist(ctx context.Context, id *uuid.UUID) (models.WaitlistEntry, error) {
+	impl.logger.Infof("leave %v waitlist entry", id)
+	entry, err := (*impl.db).LeaveWaitlist(ctx, id) // wrap error
+	return entry, err
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/optician/meeting-room-booking/internal/booking/db"
+	"go.uber.org/zap"
+)
+
+// NoShowReleaser periodically cancels bookings nobody checked in within the grace period,
+// so ghost meetings don't block rooms
+type NoShowReleaser struct {
+	logger *zap.SugaredLogger
+	db     *db.DB
+	config *Config
+	now    func() time.Time
+}
+
+func MakeNoShowReleaser(db *db.DB, config *Config, logger *zap.SugaredLogger) NoShowReleaser {
+	return NoShowReleaser{
+		logger: logger,
+		db:     db,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// Run blocks until ctx is done
+func (releaser NoShowReleaser) Run(ctx context.Context) {
+	ticker := time.NewTicker(releaser.config.NoShowCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := releaser.ReleaseOnce(ctx); err != nil {
+				releaser.logger.Errorf("no-show release failed: %v", err)
+			}
+		}
+	}
+}
+
+// ReleaseOnce returns the number of released bookings
+func (releaser NoShowReleaser) ReleaseOnce(ctx context.Context) (int, error) {
+	at := releaser.now()
+	released, err := (*releaser.db).ReleaseNoShows(ctx, at.Add(-releaser.config.CheckInGracePeriod), at)
+	if err != nil {
+		return 0, err
+	}
+	if len(released) > 0 {
+		releaser.logger.Infof("released no-show bookings %v", released)
+	}
+	return len(released), nil
+}
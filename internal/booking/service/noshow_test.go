@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/optician/meeting-room-booking/internal/booking/db"
+	"github.com/optician/meeting-room-booking/internal/booking/models"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// overrides only methods used by a test, others panic
+type dbStub struct {
+	db.DB
+	startedBefore time.Time
+	at            time.Time
+	slots         []models.FreedSlot
+}
+
+func (stub *dbStub) ReleaseExpiredHolds(ctx context.Context, at time.Time) ([]uuid.UUID, error) {
+	stub.at = at
+	return []uuid.UUID{uuid.New()}, nil
+}
+
+func (stub *dbStub) ReleaseNoShows(ctx context.Context, startedBefore time.Time, at time.Time) ([]uuid.UUID, error) {
+	stub.startedBefore = startedBefore
+	stub.at = at
+	return []uuid.UUID{uuid.New(), uuid.New()}, nil
+}
+
+func (stub *dbStub) OfferFreedSlot(ctx context.Context, slot *models.FreedSlot, at time.Time, window time.Duration) ([]models.WaitlistEntry, error) {
+	stub.slots = append(stub.slots, *slot)
+	stub.at = at
+	return []models.WaitlistEntry{}, nil
+}
+
+func TestReleaseOnceUsesGracePeriod(t *testing.T) {
+	stub := &dbStub{}
+	var repository db.DB = stub
+	config := Config{CheckInGracePeriod: 10 * time.Minute, NoShowCheckInterval: time.Minute}
+	releaser := MakeNoShowReleaser(&repository, &config, zap.NewExample().Sugar())
+	now := time.Date(2030, 1, 10, 14, 10, 0, 0, time.UTC)
+	releaser.now = func() time.Time { return now }
+
+	released, err := releaser.ReleaseOnce(context.Background())
+
+	require.Nil(t, err)
+	require.Equal(t, 2, released)
+	require.Equal(t, now, stub.at)
+	require.Equal(t, time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC), stub.startedBefore)
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/optician/meeting-room-booking/internal/booking/db"
+	"github.com/optician/meeting-room-booking/internal/booking/models"
+	outbox "github.com/optician/meeting-room-booking/internal/outbox/models"
+	"github.com/optician/meeting-room-booking/internal/outbox/publisher"
+	"go.uber.org/zap"
+)
+
+// WaitlistOfferer is an outbox publisher which offers slots freed by cancellations and expired holds
+// to waitlisted people. An event published twice finds the slot already taken.
+type WaitlistOfferer struct {
+	logger *zap.SugaredLogger
+	db     *db.DB
+	config *Config
+	now    func() time.Time
+}
+
+func MakeWaitlistOfferer(db *db.DB, config *Config, logger *zap.SugaredLogger) WaitlistOfferer {
+	return WaitlistOfferer{
+		logger: logger,
+		db:     db,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+func (offerer WaitlistOfferer) Publish(ctx context.Context, events []outbox.Event) error {
+	for _, booking := range publisher.Decode(events, freedBooking, offerer.logger) {
+		now := offerer.now()
+		if !booking.End.After(now) {
+			continue
+		}
+		slot := models.FreedSlot{RoomId: booking.RoomId, Start: booking.Start, End: booking.End}
+		offered, err := (*offerer.db).OfferFreedSlot(ctx, &slot, now, offerer.config.WaitlistOfferWindow)
+		if err != nil {
+			return err
+		}
+		for _, entry := range offered {
+			offerer.logger.Infof("slot of %v offered to %v waitlist entry as %v", booking.BookingId, entry.Id, entry.BookingId)
+		}
+	}
+	return nil
+}
+
+// freedBooking reads cancellations and expired holds, ok is false for other events
+func freedBooking(event *outbox.Event) (booking outbox.CancelledBooking, ok bool, err error) {
+	if event.Type != outbox.BookingCancelled && event.Type != outbox.BookingHoldExpired {
+		return booking, false, nil
+	}
+	if err := json.Unmarshal(event.Payload, &booking); err != nil {
+		return booking, false, fmt.Errorf("can't read %v event %v: %w", event.Type, event.Id, err)
+	}
+	return booking, true, nil
+}
+
+func (offerer WaitlistOfferer) Close() error {
+	return nil
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/optician/meeting-room-booking/internal/booking/db"
+	outbox "github.com/optician/meeting-room-booking/internal/outbox/models"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func freedEvent(t *testing.T, eventType outbox.EventType, start time.Time) outbox.Event {
+	booking := outbox.CancelledBooking{BookingId: uuid.New(), RoomId: uuid.New(), Start: start, End: start.Add(time.Hour)}
+	payload, err := json.Marshal(booking)
+	require.Nil(t, err)
+	return outbox.Event{Type: eventType, AggregateId: booking.BookingId.String(), Payload: payload}
+}
+
+func TestOffererOffersFutureFreedSlots(t *testing.T) {
+	stub := &dbStub{}
+	var repository db.DB = stub
+	config := Config{WaitlistOfferWindow: 30 * time.Minute}
+	offerer := MakeWaitlistOfferer(&repository, &config, zap.NewExample().Sugar())
+	now := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	offerer.now = func() time.Time { return now }
+	cancelled := freedEvent(t, outbox.BookingCancelled, now.Add(time.Hour))
+	expired := freedEvent(t, outbox.BookingHoldExpired, now.Add(2*time.Hour))
+	ended := freedEvent(t, outbox.BookingCancelled, now.Add(-time.Hour))
+	created := outbox.Event{Type: outbox.BookingCreated, Payload: []byte("{}")}
+
+	err := offerer.Publish(context.Background(), []outbox.Event{cancelled, created, ended, expired})
+
+	require.Nil(t, err)
+	require.Len(t, stub.slots, 2)
+	require.Equal(t, now.Add(time.Hour), stub.slots[0].Start)
+	require.Equal(t, now.Add(2*time.Hour), stub.slots[1].Start)
+	require.Equal(t, now, stub.at)
+}
@@ -1,7 +1,21 @@
 package internal
 
-import "github.com/optician/meeting-room-booking/internal/dbPool"
+import (
+	analyticsService "github.com/optician/meeting-room-booking/internal/analytics/service"
+	bookingService "github.com/optician/meeting-room-booking/internal/booking/service"
+	"github.com/optician/meeting-room-booking/internal/dbPool"
+	displayService "github.com/optician/meeting-room-booking/internal/display/service"
+	notificationService "github.com/optician/meeting-room-booking/internal/notification/service"
+	outboxService "github.com/optician/meeting-room-booking/internal/outbox/service"
+	retentionService "github.com/optician/meeting-room-booking/internal/retention/service"
+)
 
 type Config struct {
-	DB dbPool.DBConfig `koanf:"db"`
+	DB           dbPool.DBConfig            `koanf:"db"`
+	Booking      bookingService.Config      `koanf:"booking"`
+	Outbox       outboxService.Config       `koanf:"outbox"`
+	Notification notificationService.Config `koanf:"notification"`
+	Display      displayService.Config      `koanf:"display"`
+	Retention    retentionService.Config    `koanf:"retention"`
+	Analytics    analyticsService.Config    `koanf:"analytics"`
 }
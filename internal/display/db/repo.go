@@ -0,0 +1,162 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/optician/meeting-room-booking/internal/display/models"
+	"go.uber.org/zap"
+)
+
+type DB interface {
+	// meetings until midnight in loc, by the clock of the room office if loc is nil
+	RoomState(ctx context.Context, roomId *uuid.UUID, at time.Time, loc *time.Location) (models.RoomState, error)
+	// creates a pending image unless one exists, a failed one or one pending longer than timeout is made pending again.
+	// generate tells whether the caller has to generate the image.
+	RequestImage(ctx context.Context, roomId *uuid.UUID, hash string, at time.Time, timeout time.Duration) (image models.AgendaImage, generate bool, err error)
+	StoreImage(ctx context.Context, roomId *uuid.UUID, hash string, png []byte, at time.Time) error
+	FailImage(ctx context.Context, roomId *uuid.UUID, hash string, reason string, at time.Time) error
+	// with the PNG if it's ready
+	Image(ctx context.Context, roomId *uuid.UUID, hash string) (models.AgendaImage, error)
+}
+
+type impl struct {
+	logger *zap.SugaredLogger
+	dbpool *pgxpool.Pool
+}
+
+func New(dbPool *pgxpool.Pool, logger *zap.SugaredLogger) DB {
+	return &impl{
+		logger: logger,
+		dbpool: dbPool,
+	}
+}
+
+// a single snapshot, so a pad doesn't show a meeting next to the lock which cancelled it
+func (impl *impl) RoomState(ctx context.Context, roomId *uuid.UUID, at time.Time, loc *time.Location) (models.RoomState, error) {
+	state := models.RoomState{Meetings: make([]models.Meeting, 0)}
+	options := pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}
+	err := pgx.BeginTxFunc(ctx, impl.dbpool, options, func(tx pgx.Tx) error {
+		args := pgx.NamedArgs{"room_id": roomId, "at": at}
+		var timezone string
+		query := "select r.name, o.timezone from meeting_rooms r join offices o on o.name = r.office where r.id = @room_id"
+		if err := tx.QueryRow(ctx, query, args).Scan(&state.Name, &timezone); errors.Is(err, pgx.ErrNoRows) {
+			return models.ErrRoomNotFound
+		} else if err != nil {
+			return err
+		}
+		if loc == nil {
+			office, err := time.LoadLocation(timezone)
+			if err != nil {
+				return err
+			}
+			loc = office
+		}
+		args["day_end"] = models.EndOfDay(at, loc)
+
+		locks := make([]models.Lock, 0, 1)
+		query = `select reason, locked_by, until from room_locks
+					where room_id = @room_id
+					  and unlocked_at is null
+					  and (until is null or until > @at)`
+		if err := pgxscan.Select(ctx, tx, &locks, query, args); err != nil {
+			return err
+		}
+		if len(locks) > 0 {
+			state.Lock = &locks[0]
+		}
+
+		query = `select b.id, b.host, b.start_at, b.end_at, b.agenda, b.attendees,
+						b.checked_in_at is not null as checked_in,
+						i.hash as agenda_image_hash
+					from bookings b
+					left join agenda_images i
+						on i.room_id = b.room_id
+					   and i.hash = encode(sha256(convert_to(b.agenda, 'UTF8')), 'hex')
+					   and i.status = 'ready'
+					where b.room_id = @room_id
+					  and b.cancelled_at is null
+					  and b.end_at > @at
+					  and b.start_at < @day_end
+					order by b.start_at`
+		if err := pgxscan.Select(ctx, tx, &state.Meetings, query, args); err != nil {
+			return err
+		}
+
+		query = `select min(start_at) from bookings
+					where room_id = @room_id and cancelled_at is null and start_at > @at`
+		return tx.QueryRow(ctx, query, args).Scan(&state.NextStart)
+	})
+	return state, err // wrap error
+}
+
+const foreignKeyViolation = "23503"
+
+const imageColumns = "room_id, hash, status, error, requested_at, generated_at"
+
+func (impl *impl) RequestImage(ctx context.Context, roomId *uuid.UUID, hash string, at time.Time, timeout time.Duration) (models.AgendaImage, bool, error) {
+	var image models.AgendaImage
+	query := `insert into agenda_images (room_id, hash, status, requested_at)
+				values (@room_id, @hash, @status, @at)
+				on conflict (room_id, hash) do update
+					set status = excluded.status, error = null, requested_at = excluded.requested_at
+					-- the generation of a stale pending image died with its process
+					where agenda_images.status = 'failed'
+					   or (agenda_images.status = 'pending' and agenda_images.requested_at < @stale_before)
+				returning ` + imageColumns
+	args := pgx.NamedArgs{
+		"room_id":      roomId,
+		"hash":         hash,
+		"status":       models.ImagePending,
+		"at":           at,
+		"stale_before": at.Add(-timeout),
+	}
+	err := pgxscan.Get(ctx, impl.dbpool, &image, query, args)
+	var pgErr *pgconn.PgError
+	switch {
+	case err == nil:
+		return image, true, nil
+	case errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation:
+		return image, false, models.ErrRoomNotFound
+	case !pgxscan.NotFound(err):
+		return image, false, err // wrap error
+	}
+
+	query = "select " + imageColumns + " from agenda_images where room_id = @room_id and hash = @hash"
+	err = pgxscan.Get(ctx, impl.dbpool, &image, query, args)
+	return image, false, err // wrap error
+}
+
+func (impl *impl) StoreImage(ctx context.Context, roomId *uuid.UUID, hash string, png []byte, at time.Time) error {
+	query := `update agenda_images
+				set status = @status, png = @png, error = null, generated_at = @at
+				where room_id = @room_id and hash = @hash`
+	args := pgx.NamedArgs{"room_id": roomId, "hash": hash, "status": models.ImageReady, "png": png, "at": at}
+	_, err := impl.dbpool.Exec(ctx, query, args)
+	return err // wrap error
+}
+
+func (impl *impl) FailImage(ctx context.Context, roomId *uuid.UUID, hash string, reason string, at time.Time) error {
+	query := `update agenda_images
+				set status = @status, error = @error, generated_at = @at
+				where room_id = @room_id and hash = @hash`
+	args := pgx.NamedArgs{"room_id": roomId, "hash": hash, "status": models.ImageFailed, "error": reason, "at": at}
+	_, err := impl.dbpool.Exec(ctx, query, args)
+	return err // wrap error
+}
+
+func (impl *impl) Image(ctx context.Context, roomId *uuid.UUID, hash string) (models.AgendaImage, error) {
+	var image models.AgendaImage
+	query := "select " + imageColumns + ", png from agenda_images where room_id = @room_id and hash = @hash"
+	err := pgxscan.Get(ctx, impl.dbpool, &image, query, pgx.NamedArgs{"room_id": roomId, "hash": hash})
+	if pgxscan.NotFound(err) {
+		return image, models.ErrImageNotFound
+	}
+	return image, err // wrap error
+}
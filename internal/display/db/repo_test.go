@@ -0,0 +1,161 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	testHelpers "github.com/optician/meeting-room-booking/internal/administration/db/testing"
+	"github.com/optician/meeting-room-booking/internal/dbPool"
+	"github.com/optician/meeting-room-booking/internal/display/models"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"go.uber.org/zap"
+)
+
+type DisplayRepositoryTestSuite struct {
+	suite.Suite
+	pgContainer *postgres.PostgresContainer
+	pool        *pgxpool.Pool
+	repository  *DB
+	ctx         context.Context
+	logger      *zap.SugaredLogger
+}
+
+func (suite *DisplayRepositoryTestSuite) SetupTest() {
+	ctx := context.Background()
+	logger := zap.NewExample().Sugar()
+	container, err := testHelpers.CreatePostgresContainer(ctx)
+	if err != nil {
+		logger.Fatalf("cannot setup postgres container in DisplayRepositoryTestSuite, %v", err)
+	}
+	migrationsPath := "../../../migrations/" // better to use env instead
+	if err := testHelpers.Migrate(ctx, container.ConnectionString, migrationsPath); err != nil {
+		logger.Fatalf("%v", err)
+	}
+	if err := testHelpers.CreateOffices(ctx, container.ConnectionString, "FoodCourt"); err != nil {
+		logger.Fatalf("%v", err)
+	}
+
+	config := dbPool.DBConfig{Url: container.ConnectionString}
+	dbPool, dbPoolErr := dbPool.NewDBPool(&config, logger)
+	if dbPoolErr != nil {
+		logger.Fatalf("application terminated: %v", dbPoolErr)
+	}
+
+	displaysDB := New(dbPool.GetPool(), logger)
+
+	suite.pgContainer = container.Container
+	suite.pool = dbPool.GetPool()
+	suite.repository = &displaysDB
+	suite.ctx = ctx
+	suite.logger = logger
+}
+
+func (suite *DisplayRepositoryTestSuite) TearDownTest() {
+	if err := suite.pgContainer.Terminate(suite.ctx); err != nil {
+		suite.logger.Fatalf("error terminating postgres container: %s", err)
+	}
+}
+
+func (suite *DisplayRepositoryTestSuite) TestRoomState() {
+	roomId := uuid.New()
+	_, err := suite.pool.Exec(suite.ctx, "insert into meeting_rooms (id, name, capacity, office, stage, labels) values ($1, 'Belyash', 6, 'FoodCourt', 1, '{}')", roomId)
+	require.Nil(suite.T(), err, "room creation error")
+
+	at := time.Date(2030, 1, 10, 14, 30, 0, 0, time.UTC)
+	insert := "insert into bookings (id, room_id, host, start_at, end_at, cancelled_at) values ($1, $2, 'ivan', $3, $4, $5)"
+	current, cancelled, tomorrow := uuid.New(), uuid.New(), uuid.New()
+	_, err = suite.pool.Exec(suite.ctx, insert, current, roomId, at.Add(-30*time.Minute), at.Add(30*time.Minute), nil)
+	require.Nil(suite.T(), err, "booking error")
+	_, err = suite.pool.Exec(suite.ctx, insert, cancelled, roomId, at.Add(time.Hour), at.Add(2*time.Hour), at)
+	require.Nil(suite.T(), err, "booking error")
+	_, err = suite.pool.Exec(suite.ctx, insert, tomorrow, roomId, at.Add(24*time.Hour), at.Add(25*time.Hour), nil)
+	require.Nil(suite.T(), err, "booking error")
+
+	state, err := (*suite.repository).RoomState(suite.ctx, &roomId, at, nil)
+	require.Nil(suite.T(), err, "RoomState error")
+	require.Equal(suite.T(), "Belyash", state.Name)
+	require.Nil(suite.T(), state.Lock)
+	require.Len(suite.T(), state.Meetings, 1)
+	require.Equal(suite.T(), current, state.Meetings[0].BookingId)
+	require.True(suite.T(), at.Add(24*time.Hour).Equal(*state.NextStart))
+
+	missing := uuid.New()
+	_, err = (*suite.repository).RoomState(suite.ctx, &missing, at, nil)
+	require.ErrorIs(suite.T(), err, models.ErrRoomNotFound)
+}
+
+func (suite *DisplayRepositoryTestSuite) TestRoomStateTodayByOfficeClock() {
+	roomId := uuid.New()
+	_, err := suite.pool.Exec(suite.ctx, "update offices set timezone = 'America/New_York' where name = 'FoodCourt'")
+	require.Nil(suite.T(), err, "office update error")
+	_, err = suite.pool.Exec(suite.ctx, "insert into meeting_rooms (id, name, capacity, office, stage, labels) values ($1, 'Belyash', 6, 'FoodCourt', 1, '{}')", roomId)
+	require.Nil(suite.T(), err, "room creation error")
+
+	// 19:30 in New York, past midnight in UTC
+	at := time.Date(2030, 1, 10, 14, 30, 0, 0, time.UTC)
+	evening := at.Add(10 * time.Hour)
+	insert := "insert into bookings (id, room_id, host, start_at, end_at) values ($1, $2, 'ivan', $3, $4)"
+	_, err = suite.pool.Exec(suite.ctx, insert, uuid.New(), roomId, evening, evening.Add(time.Hour))
+	require.Nil(suite.T(), err, "booking error")
+
+	state, err := (*suite.repository).RoomState(suite.ctx, &roomId, at, nil)
+	require.Nil(suite.T(), err, "RoomState error")
+	require.Len(suite.T(), state.Meetings, 1, "the evening is today by the office clock")
+	state, err = (*suite.repository).RoomState(suite.ctx, &roomId, at, time.UTC)
+	require.Nil(suite.T(), err, "RoomState error")
+	require.Empty(suite.T(), state.Meetings, "the evening is tomorrow in UTC")
+}
+
+func (suite *DisplayRepositoryTestSuite) TestAgendaImage() {
+	roomId := uuid.New()
+	_, err := suite.pool.Exec(suite.ctx, "insert into meeting_rooms (id, name, capacity, office, stage, labels) values ($1, 'Belyash', 6, 'FoodCourt', 1, '{}')", roomId)
+	require.Nil(suite.T(), err, "room creation error")
+	at := time.Date(2030, 1, 10, 14, 30, 0, 0, time.UTC)
+	booking := uuid.New()
+	insert := "insert into bookings (id, room_id, host, start_at, end_at, agenda) values ($1, $2, 'ivan', $3, $4, 'Планирование')"
+	_, err = suite.pool.Exec(suite.ctx, insert, booking, roomId, at, at.Add(time.Hour))
+	require.Nil(suite.T(), err, "booking error")
+	hash := models.AgendaHash("Планирование")
+
+	image, generate, err := (*suite.repository).RequestImage(suite.ctx, &roomId, hash, at, time.Minute)
+	require.Nil(suite.T(), err, "RequestImage error")
+	require.True(suite.T(), generate)
+	require.Equal(suite.T(), models.ImagePending, image.Status)
+	_, generate, err = (*suite.repository).RequestImage(suite.ctx, &roomId, hash, at, time.Minute)
+	require.Nil(suite.T(), err, "RequestImage error")
+	require.False(suite.T(), generate, "a pending image is generated once")
+	_, generate, err = (*suite.repository).RequestImage(suite.ctx, &roomId, hash, at.Add(2*time.Minute), time.Minute)
+	require.Nil(suite.T(), err, "RequestImage error")
+	require.True(suite.T(), generate, "a stale pending image is generated again")
+
+	require.Nil(suite.T(), (*suite.repository).FailImage(suite.ctx, &roomId, hash, "timeout", at))
+	image, generate, err = (*suite.repository).RequestImage(suite.ctx, &roomId, hash, at, time.Minute)
+	require.Nil(suite.T(), err, "RequestImage error")
+	require.True(suite.T(), generate, "a failed image is generated again")
+	require.Nil(suite.T(), image.Error)
+
+	require.Nil(suite.T(), (*suite.repository).StoreImage(suite.ctx, &roomId, hash, []byte("png"), at))
+	image, err = (*suite.repository).Image(suite.ctx, &roomId, hash)
+	require.Nil(suite.T(), err, "Image error")
+	require.Equal(suite.T(), models.ImageReady, image.Status)
+	require.Equal(suite.T(), []byte("png"), image.Png)
+
+	state, err := (*suite.repository).RoomState(suite.ctx, &roomId, at, nil)
+	require.Nil(suite.T(), err, "RoomState error")
+	require.Equal(suite.T(), hash, *state.Meetings[0].AgendaImageHash, "the display query hashes agendas the same way")
+
+	_, err = (*suite.repository).Image(suite.ctx, &roomId, models.AgendaHash("other"))
+	require.ErrorIs(suite.T(), err, models.ErrImageNotFound)
+	missing := uuid.New()
+	_, _, err = (*suite.repository).RequestImage(suite.ctx, &missing, hash, at, time.Minute)
+	require.ErrorIs(suite.T(), err, models.ErrRoomNotFound)
+}
+
+func TestDisplayRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(DisplayRepositoryTestSuite))
+}
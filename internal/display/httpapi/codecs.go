@@ -0,0 +1,26 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/optician/meeting-room-booking/internal/display/models"
+)
+
+func deserializeNewAgendaImage(stream io.Reader) (models.NewAgendaImage, error) {
+	image := &models.NewAgendaImage{}
+	if err := json.NewDecoder(stream).Decode(image); err != nil {
+		return *image, fmt.Errorf("can't deserialize NewAgendaImage: %w", err)
+	} else {
+		return *image, nil
+	}
+}
+
+func fromBytesNewAgendaImage(stream io.Reader) (models.NewAgendaImage, error) {
+	if image, err := deserializeNewAgendaImage(stream); err != nil {
+		return image, err
+	} else {
+		return models.ValidateNewAgendaImage(&image)
+	}
+}
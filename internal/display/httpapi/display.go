@@ -0,0 +1,224 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/optician/meeting-room-booking/internal/display/models"
+	"github.com/optician/meeting-room-booking/internal/display/service"
+	"go.uber.org/zap"
+)
+
+// keeps proxies from closing an idle stream
+const heartbeatInterval = 15 * time.Second
+
+type Controller struct {
+	logger *zap.SugaredLogger
+	logic  *service.Logic
+	hub    *service.Hub
+}
+
+// mutates router. Streams are long-lived, so the router mustn't have a request timeout.
+func Make(logic *service.Logic, hub *service.Hub, logger *zap.SugaredLogger) func(chi.Router) {
+	defer logger.Sync()
+
+	controller := Controller{
+		logger: logger,
+		logic:  logic,
+		hub:    hub,
+	}
+	return controller.routes
+}
+
+func (ctrl *Controller) routes(r chi.Router) {
+	r.Get("/rooms/{id}/display", ctrl.getDisplayController)
+	r.Get("/rooms/{id}/display/stream", ctrl.streamDisplayController)
+	r.Post("/rooms/{id}/agenda-image", ctrl.requestAgendaImageController)
+	r.Get("/rooms/{id}/agenda-image/{hash}", ctrl.getAgendaImageController)
+}
+
+// "today" is by the clock of the room office, ?tz= overrides it with an IANA timezone
+func (ctrl *Controller) getDisplayController(w http.ResponseWriter, r *http.Request) {
+	id, loc, ok := ctrl.displayParams(w, r)
+	if !ok {
+		return
+	}
+
+	if display, err := (*ctrl.logic).Display(r.Context(), &id, loc); err != nil {
+		ctrl.writeError(w, err, fmt.Sprintf("Display of %v room raised error", id))
+	} else {
+		ctrl.writeJSON(w, http.StatusOK, display)
+	}
+}
+
+// server-sent events: the display right away and a fresh one after every change of the room
+func (ctrl *Controller) streamDisplayController(w http.ResponseWriter, r *http.Request) {
+	id, loc, ok := ctrl.displayParams(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	// subscribed before the first display, so no change is lost in between
+	changes, unsubscribe := ctrl.hub.Subscribe(id)
+	defer unsubscribe()
+
+	display, err := (*ctrl.logic).Display(ctx, &id, loc)
+	if err != nil {
+		ctrl.writeError(w, err, fmt.Sprintf("Display of %v room raised error", id))
+		return
+	}
+	w.Header().Set("content-type", "text/event-stream")
+	w.Header().Set("cache-control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	controller := http.NewResponseController(w)
+	if err := ctrl.writeEvent(w, controller, "display", display); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			if err := controller.Flush(); err != nil {
+				return
+			}
+		case change := <-changes:
+			if display, err = (*ctrl.logic).Display(ctx, &id, loc); err != nil {
+				ctrl.logger.Errorf("Display of %v room raised error: %v", id, err)
+				return
+			}
+			if err := ctrl.writeEvent(w, controller, string(change), display); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// 202 while the image is generated, 200 if it's cached already
+func (ctrl *Controller) requestAgendaImageController(w http.ResponseWriter, r *http.Request) {
+	id, ok := ctrl.roomId(w, r)
+	if !ok {
+		return
+	}
+	request, err := fromBytesNewAgendaImage(r.Body)
+	if err != nil {
+		ctrl.logger.Errorf("Bad Request. Invalid NewAgendaImage: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if image, err := (*ctrl.logic).RequestAgendaImage(r.Context(), &id, &request); err != nil {
+		ctrl.writeError(w, err, fmt.Sprintf("Agenda image of %v room raised error", id))
+	} else {
+		ctrl.writeJSON(w, imageStatusCode(image.Status), image)
+	}
+}
+
+// the PNG if it's ready, otherwise the image status
+func (ctrl *Controller) getAgendaImageController(w http.ResponseWriter, r *http.Request) {
+	id, ok := ctrl.roomId(w, r)
+	if !ok {
+		return
+	}
+	hash := chi.URLParam(r, "hash")
+
+	image, err := (*ctrl.logic).AgendaImage(r.Context(), &id, hash)
+	switch {
+	case err != nil:
+		ctrl.writeError(w, err, fmt.Sprintf("Agenda image of %v room raised error", id))
+	case image.Status != models.ImageReady:
+		ctrl.writeJSON(w, imageStatusCode(image.Status), image)
+	default:
+		// an image is addressed by its agenda, so it never changes
+		w.Header().Set("content-type", "image/png")
+		w.Header().Set("cache-control", "public, max-age=86400")
+		w.Write(image.Png)
+	}
+}
+
+// a failed image is generated again by the next POST of its agenda
+func imageStatusCode(status models.ImageStatus) int {
+	switch status {
+	case models.ImageReady:
+		return http.StatusOK
+	case models.ImageFailed:
+		return http.StatusBadGateway
+	default:
+		return http.StatusAccepted
+	}
+}
+
+func (ctrl *Controller) writeEvent(w http.ResponseWriter, controller *http.ResponseController, event string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		ctrl.logger.Errorf("internal error: %v", err)
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %v\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	return controller.Flush()
+}
+
+func (ctrl *Controller) roomId(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	strId := chi.URLParam(r, "id")
+	id, err := uuid.Parse(strId)
+	if err != nil {
+		ctrl.logger.Errorf(`display of a room called with malformed id "%v"`, strId)
+		w.WriteHeader(http.StatusBadRequest)
+		return id, false
+	}
+	return id, true
+}
+
+func (ctrl *Controller) displayParams(w http.ResponseWriter, r *http.Request) (uuid.UUID, *time.Location, bool) {
+	id, ok := ctrl.roomId(w, r)
+	if !ok {
+		return id, nil, false
+	}
+	tz := r.URL.Query().Get("tz")
+	if tz == "" {
+		return id, nil, true
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		ctrl.logger.Errorf("Bad Request. Invalid timezone: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return id, nil, false
+	}
+	return id, loc, true
+}
+
+func (ctrl *Controller) writeError(w http.ResponseWriter, err error, what string) {
+	if errors.Is(err, models.ErrRoomNotFound) || errors.Is(err, models.ErrImageNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+	} else {
+		ctrl.logger.Errorf("%v: %v", what, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (ctrl *Controller) writeJSON(w http.ResponseWriter, status int, payload any) {
+	if json, err := json.Marshal(payload); err != nil {
+		ctrl.logger.Errorf("internal error: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	} else {
+		w.Header().Add("content-type", "application/json")
+		w.WriteHeader(status)
+		w.Write(json)
+	}
+}
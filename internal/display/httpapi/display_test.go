@@ -0,0 +1,191 @@
+package httpapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/optician/meeting-room-booking/internal/display/models"
+	"github.com/optician/meeting-room-booking/internal/display/service"
+	outbox "github.com/optician/meeting-room-booking/internal/outbox/models"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var logger = zap.NewExample().Sugar()
+
+var stubRoomId = uuid.New()
+
+func router(logic service.Logic, hub *service.Hub) *chi.Mux {
+	r := chi.NewRouter()
+	r.Group(Make(&logic, hub, logger))
+	return r
+}
+
+func TestGetDisplaySuccessfully(t *testing.T) {
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/rooms/%v/display?tz=Europe/Berlin", stubRoomId), nil)
+	rr := httptest.NewRecorder()
+	logic := &logicStub{}
+
+	router(logic, service.NewHub()).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), `"roomName":"Belyash"`)
+	require.Equal(t, "Europe/Berlin", logic.loc.String())
+}
+
+func TestGetDisplayOfMissingRoom(t *testing.T) {
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/rooms/%v/display", uuid.New()), nil)
+	rr := httptest.NewRecorder()
+
+	router(&logicStub{}, service.NewHub()).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestGetDisplayByOfficeClockByDefault(t *testing.T) {
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/rooms/%v/display", stubRoomId), nil)
+	rr := httptest.NewRecorder()
+	logic := &logicStub{}
+
+	router(logic, service.NewHub()).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Nil(t, logic.loc, "no tz means the office timezone")
+}
+
+func TestGetDisplayWithUnknownTimezone(t *testing.T) {
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/rooms/%v/display?tz=Mars/Olympus", stubRoomId), nil)
+	rr := httptest.NewRecorder()
+
+	router(&logicStub{}, service.NewHub()).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestStreamPushesChangesOfTheRoom(t *testing.T) {
+	hub := service.NewHub()
+	logic := &logicStub{}
+	server := httptest.NewServer(router(logic, hub))
+	defer server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%v/rooms/%v/display/stream", server.URL, stubRoomId), nil)
+	response, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	defer response.Body.Close()
+	require.Equal(t, "text/event-stream", response.Header.Get("content-type"))
+	reader := bufio.NewReader(response.Body)
+
+	require.Equal(t, "event: display", readEvent(t, reader)[0])
+
+	payload, _ := json.Marshal(map[string]string{"roomId": uuid.NewString()})
+	otherRoom := outbox.Event{Type: outbox.BookingCreated, Payload: payload}
+	payload, _ = json.Marshal(map[string]string{"roomId": stubRoomId.String()})
+	thisRoom := outbox.Event{Type: outbox.BookingCancelled, Payload: payload}
+	require.Nil(t, hub.Publish(ctx, []outbox.Event{otherRoom, thisRoom}))
+
+	event := readEvent(t, reader)
+	require.Equal(t, "event: booking_cancelled", event[0])
+	require.Contains(t, event[1], `"roomName":"Belyash"`)
+}
+
+func TestRequestAgendaImage(t *testing.T) {
+	body := strings.NewReader(`{"agenda":"Quarterly planning"}`)
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/rooms/%v/agenda-image", stubRoomId), body)
+	rr := httptest.NewRecorder()
+
+	router(&logicStub{}, service.NewHub()).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusAccepted, rr.Code)
+	require.Contains(t, rr.Body.String(), `"status":"pending"`)
+	require.Contains(t, rr.Body.String(), models.AgendaHash("Quarterly planning"))
+}
+
+func TestRequestAgendaImageWithEmptyAgenda(t *testing.T) {
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/rooms/%v/agenda-image", stubRoomId), strings.NewReader(`{"agenda":""}`))
+	rr := httptest.NewRecorder()
+
+	router(&logicStub{}, service.NewHub()).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestGetAgendaImage(t *testing.T) {
+	testCases := []struct {
+		hash        string
+		code        int
+		contentType string
+	}{
+		{hash: "ready", code: http.StatusOK, contentType: "image/png"},
+		{hash: "pending", code: http.StatusAccepted, contentType: "application/json"},
+		{hash: "failed", code: http.StatusBadGateway, contentType: "application/json"},
+		{hash: "unknown", code: http.StatusNotFound},
+	}
+	for _, testCase := range testCases {
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/rooms/%v/agenda-image/%v", stubRoomId, testCase.hash), nil)
+		rr := httptest.NewRecorder()
+
+		router(&logicStub{}, service.NewHub()).ServeHTTP(rr, req)
+
+		require.Equal(t, testCase.code, rr.Code, testCase.hash)
+		if testCase.contentType != "" {
+			require.Equal(t, testCase.contentType, rr.Header().Get("content-type"), testCase.hash)
+		}
+	}
+}
+
+// lines of the next event without the blank separator
+func readEvent(t *testing.T, reader *bufio.Reader) []string {
+	lines := make([]string, 0, 2)
+	for {
+		line, err := reader.ReadString('\n')
+		require.Nil(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+type logicStub struct {
+	// of the last display
+	loc *time.Location
+}
+
+func (stub *logicStub) Display(ctx context.Context, roomId *uuid.UUID, loc *time.Location) (models.Display, error) {
+	stub.loc = loc
+	if *roomId != stubRoomId {
+		return models.Display{}, models.ErrRoomNotFound
+	}
+	state := models.RoomState{Name: "Belyash", Meetings: []models.Meeting{}}
+	return models.NewDisplay(*roomId, time.Date(2030, 1, 10, 14, 30, 0, 0, time.UTC), &state), nil
+}
+
+func (*logicStub) RequestAgendaImage(ctx context.Context, roomId *uuid.UUID, request *models.NewAgendaImage) (models.AgendaImage, error) {
+	hash := models.AgendaHash(request.Agenda)
+	return models.AgendaImage{RoomId: *roomId, Hash: hash, Status: models.ImagePending, Path: models.AgendaImagePath(*roomId, hash)}, nil
+}
+
+// the hash is the status of the image
+func (*logicStub) AgendaImage(ctx context.Context, roomId *uuid.UUID, hash string) (models.AgendaImage, error) {
+	status := models.ImageStatus(hash)
+	switch status {
+	case models.ImageReady:
+		return models.AgendaImage{RoomId: *roomId, Hash: hash, Status: status, Png: []byte{0x89, 'P', 'N', 'G'}}, nil
+	case models.ImagePending, models.ImageFailed:
+		return models.AgendaImage{RoomId: *roomId, Hash: hash, Status: status}, nil
+	default:
+		return models.AgendaImage{}, models.ErrImageNotFound
+	}
+}
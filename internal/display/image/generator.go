@@ -0,0 +1,9 @@
+package image
+
+import "context"
+
+// ImageGenerator draws an image for a meeting agenda, e.g. with a text-to-image model
+type ImageGenerator interface {
+	// returns a PNG
+	Generate(ctx context.Context, agenda string) ([]byte, error)
+}
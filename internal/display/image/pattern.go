@@ -0,0 +1,84 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+)
+
+// Pattern draws a symmetric pattern derived from the agenda hash.
+// It works offline and the same agenda always gets the same image.
+type Pattern struct {
+	// side of the square image in pixels
+	Size int
+}
+
+const patternCells = 8
+
+func (pattern Pattern) Generate(ctx context.Context, agenda string) ([]byte, error) {
+	hash := sha256.Sum256([]byte(agenda))
+	foreground := hueColor(hash[0], 0.6, 0.45)
+	background := hueColor(hash[1], 0.3, 0.92)
+	accent := hueColor(hash[2], 0.7, 0.3)
+
+	cell := max(pattern.Size/patternCells, 1)
+	canvas := image.NewRGBA(image.Rect(0, 0, cell*patternCells, cell*patternCells))
+	for row := 0; row < patternCells; row++ {
+		// the left half is taken from the hash bits, the right half mirrors it
+		for column := 0; column < patternCells/2; column++ {
+			bit := row*patternCells/2 + column
+			fill := background
+			if hash[3+bit/8]&(1<<(bit%8)) != 0 {
+				fill = foreground
+				if hash[11+bit/8]&(1<<(bit%8)) != 0 {
+					fill = accent
+				}
+			}
+			fillCell(canvas, cell, row, column, fill)
+			fillCell(canvas, cell, row, patternCells-1-column, fill)
+		}
+	}
+
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, canvas); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func fillCell(canvas *image.RGBA, cell, row, column int, fill color.RGBA) {
+	for y := row * cell; y < (row+1)*cell; y++ {
+		for x := column * cell; x < (column+1)*cell; x++ {
+			canvas.SetRGBA(x, y, fill)
+		}
+	}
+}
+
+// hueColor converts HSL to RGB, the hue is picked by b
+func hueColor(b byte, saturation, lightness float64) color.RGBA {
+	hue := float64(b) / 256 * 360
+	chroma := (1 - math.Abs(2*lightness-1)) * saturation
+	x := chroma * (1 - math.Abs(math.Mod(hue/60, 2)-1))
+	var r, g, bl float64
+	switch {
+	case hue < 60:
+		r, g, bl = chroma, x, 0
+	case hue < 120:
+		r, g, bl = x, chroma, 0
+	case hue < 180:
+		r, g, bl = 0, chroma, x
+	case hue < 240:
+		r, g, bl = 0, x, chroma
+	case hue < 300:
+		r, g, bl = x, 0, chroma
+	default:
+		r, g, bl = chroma, 0, x
+	}
+	m := lightness - chroma/2
+	channel := func(c float64) uint8 { return uint8(math.Round((c + m) * 255)) }
+	return color.RGBA{R: channel(r), G: channel(g), B: channel(bl), A: 255}
+}
@@ -0,0 +1,28 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatternIsDeterministicPng(t *testing.T) {
+	generator := Pattern{Size: 64}
+
+	first, err := generator.Generate(context.Background(), "Quarterly planning")
+	require.Nil(t, err)
+	second, err := generator.Generate(context.Background(), "Quarterly planning")
+	require.Nil(t, err)
+	other, err := generator.Generate(context.Background(), "Retrospective")
+	require.Nil(t, err)
+
+	require.Equal(t, first, second)
+	require.NotEqual(t, first, other)
+	decoded, err := png.Decode(bytes.NewReader(first))
+	require.Nil(t, err)
+	require.Equal(t, 64, decoded.Bounds().Dx())
+	require.Equal(t, 64, decoded.Bounds().Dy())
+}
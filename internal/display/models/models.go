@@ -0,0 +1,142 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRoomNotFound  = errors.New("room not found")
+	ErrImageNotFound = errors.New("agenda image not found")
+)
+
+type Meeting struct {
+	BookingId uuid.UUID `json:"bookingId" db:"id"`
+	Host      string    `json:"host"`
+	Start     time.Time `json:"start" db:"start_at"`
+	End       time.Time `json:"end" db:"end_at"`
+	Agenda    string    `json:"agenda"`
+	Attendees []string  `json:"attendees"`
+	CheckedIn bool      `json:"checkedIn"`
+	// path of the agenda image if one is generated
+	AgendaImage     *string `json:"agendaImage,omitempty" db:"-"`
+	AgendaImageHash *string `json:"-"`
+}
+
+type Lock struct {
+	Reason   string     `json:"reason"`
+	LockedBy string     `json:"lockedBy"`
+	Until    *time.Time `json:"until,omitempty"`
+}
+
+// RoomState is what a display is made of
+type RoomState struct {
+	Name string
+	Lock *Lock
+	// active meetings which didn't end yet and start before the end of the day, ordered by start
+	Meetings []Meeting
+	// start of the first meeting after now, whatever the day
+	NextStart *time.Time
+}
+
+// Display is shown on a pad next to the room door
+type Display struct {
+	RoomId   uuid.UUID `json:"roomId"`
+	RoomName string    `json:"roomName"`
+	At       time.Time `json:"at"`
+	Locked   bool      `json:"locked"`
+	Lock     *Lock     `json:"lock,omitempty"`
+	Current  *Meeting  `json:"current"`
+	// the rest of meetings today
+	Next []Meeting `json:"next"`
+	Free bool      `json:"free"`
+	// set only if the room is free, null means nothing is booked ahead
+	FreeUntil *time.Time `json:"freeUntil"`
+}
+
+func NewDisplay(roomId uuid.UUID, at time.Time, state *RoomState) Display {
+	display := Display{
+		RoomId:   roomId,
+		RoomName: state.Name,
+		At:       at,
+		Locked:   state.Lock != nil,
+		Lock:     state.Lock,
+		Next:     make([]Meeting, 0, len(state.Meetings)),
+	}
+	for i := range state.Meetings {
+		meeting := state.Meetings[i]
+		if meeting.AgendaImageHash != nil {
+			path := AgendaImagePath(roomId, *meeting.AgendaImageHash)
+			meeting.AgendaImage = &path
+		}
+		if !meeting.Start.After(at) {
+			display.Current = &meeting
+		} else {
+			display.Next = append(display.Next, meeting)
+		}
+	}
+	if !display.Locked && display.Current == nil {
+		display.Free = true
+		display.FreeUntil = state.NextStart
+	}
+	return display
+}
+
+// EndOfDay is the next midnight in loc
+func EndOfDay(at time.Time, loc *time.Location) time.Time {
+	local := at.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, loc)
+}
+
+// the longest agenda an image can be generated for
+const MaxAgendaLength = 2000
+
+type NewAgendaImage struct {
+	Agenda string `json:"agenda"`
+}
+
+func ValidateNewAgendaImage(image *NewAgendaImage) (NewAgendaImage, error) {
+	if image.Agenda == "" {
+		return *image, errors.New("agenda can't be empty")
+	}
+	if len(image.Agenda) > MaxAgendaLength {
+		return *image, fmt.Errorf("agenda can't be longer than %d bytes", MaxAgendaLength)
+	}
+
+	return *image, nil
+}
+
+type ImageStatus string
+
+const (
+	ImagePending ImageStatus = "pending"
+	ImageReady   ImageStatus = "ready"
+	ImageFailed  ImageStatus = "failed"
+)
+
+// AgendaImage is cached per room and agenda
+type AgendaImage struct {
+	RoomId      uuid.UUID   `json:"roomId"`
+	Hash        string      `json:"hash"`
+	Status      ImageStatus `json:"status"`
+	Error       *string     `json:"error,omitempty"`
+	RequestedAt time.Time   `json:"requestedAt"`
+	GeneratedAt *time.Time  `json:"generatedAt,omitempty"`
+	Path        string      `json:"path" db:"-"`
+	Png         []byte      `json:"-"`
+}
+
+// AgendaHash identifies an agenda, the same hash is computed by the display query
+func AgendaHash(agenda string) string {
+	hash := sha256.Sum256([]byte(agenda))
+	return hex.EncodeToString(hash[:])
+}
+
+func AgendaImagePath(roomId uuid.UUID, hash string) string {
+	return fmt.Sprintf("/rooms/%v/agenda-image/%v", roomId, hash)
+}
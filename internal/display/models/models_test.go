@@ -0,0 +1,76 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+var at = time.Date(2030, 1, 10, 14, 30, 0, 0, time.UTC)
+
+func meeting(startHour, endHour int) Meeting {
+	return Meeting{
+		BookingId: uuid.New(),
+		Host:      "ivan",
+		Start:     time.Date(2030, 1, 10, startHour, 0, 0, 0, time.UTC),
+		End:       time.Date(2030, 1, 10, endHour, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestDisplayWithCurrentMeeting(t *testing.T) {
+	current, next := meeting(14, 15), meeting(16, 17)
+	state := RoomState{Name: "Belyash", Meetings: []Meeting{current, next}, NextStart: &next.Start}
+
+	display := NewDisplay(uuid.New(), at, &state)
+
+	require.Equal(t, &current, display.Current)
+	require.Equal(t, []Meeting{next}, display.Next)
+	require.False(t, display.Free)
+	require.Nil(t, display.FreeUntil)
+}
+
+func TestFreeDisplayUntilNextMeeting(t *testing.T) {
+	tomorrow := at.Add(24 * time.Hour)
+	state := RoomState{Name: "Belyash", Meetings: []Meeting{}, NextStart: &tomorrow}
+
+	display := NewDisplay(uuid.New(), at, &state)
+
+	require.Nil(t, display.Current)
+	require.Empty(t, display.Next)
+	require.True(t, display.Free)
+	require.Equal(t, &tomorrow, display.FreeUntil)
+}
+
+func TestLockedRoomIsNotFree(t *testing.T) {
+	state := RoomState{Name: "Belyash", Lock: &Lock{Reason: "flooded", LockedBy: "facility"}, Meetings: []Meeting{}}
+
+	display := NewDisplay(uuid.New(), at, &state)
+
+	require.True(t, display.Locked)
+	require.False(t, display.Free)
+}
+
+func TestDisplayLinksReadyAgendaImages(t *testing.T) {
+	at := time.Date(2030, 1, 10, 14, 30, 0, 0, time.UTC)
+	hash := AgendaHash("Planning")
+	roomId := uuid.New()
+	state := RoomState{Name: "Belyash", Meetings: []Meeting{
+		{Start: at.Add(-time.Hour), End: at.Add(time.Hour), Agenda: "Planning", AgendaImageHash: &hash},
+		{Start: at.Add(2 * time.Hour), End: at.Add(3 * time.Hour)},
+	}}
+
+	display := NewDisplay(roomId, at, &state)
+
+	require.Equal(t, "/rooms/"+roomId.String()+"/agenda-image/"+hash, *display.Current.AgendaImage)
+	require.Nil(t, display.Next[0].AgendaImage)
+}
+
+func TestEndOfDayInLocation(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	require.Nil(t, err)
+
+	// 14:30 UTC is 23:30 in Tokyo
+	require.Equal(t, time.Date(2030, 1, 10, 15, 0, 0, 0, time.UTC), EndOfDay(at, loc).UTC())
+}
@@ -0,0 +1,10 @@
+package service
+
+import "time"
+
+type Config struct {
+	// the most agenda images generated at once
+	ImageWorkers int `koanf:"image_workers"`
+	// a generation taking longer is failed
+	ImageTimeout time.Duration `koanf:"image_timeout"`
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/google/uuid"
+	outbox "github.com/optician/meeting-room-booking/internal/outbox/models"
+)
+
+// Hub is an outbox publisher which tells display streams that their room changed
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[chan outbox.EventType]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[uuid.UUID]map[chan outbox.EventType]struct{})}
+}
+
+// events which change what a pad shows
+var displayed = map[outbox.EventType]bool{
+	outbox.BookingCreated:     true,
+	outbox.BookingUpdated:     true,
+	outbox.BookingCancelled:   true,
+	outbox.BookingCheckedIn:   true,
+	outbox.BookingConfirmed:   true,
+	outbox.BookingHoldExpired: true,
+	outbox.RoomLocked:         true,
+	outbox.RoomUnlocked:       true,
+}
+
+// Subscribe returns changes of the room and a function to stop receiving them.
+// A slow subscriber misses changes, it's fine as every change means "reload the display".
+func (hub *Hub) Subscribe(roomId uuid.UUID) (<-chan outbox.EventType, func()) {
+	changes := make(chan outbox.EventType, 8)
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if hub.subscribers[roomId] == nil {
+		hub.subscribers[roomId] = make(map[chan outbox.EventType]struct{})
+	}
+	hub.subscribers[roomId][changes] = struct{}{}
+
+	return changes, func() {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		delete(hub.subscribers[roomId], changes)
+		if len(hub.subscribers[roomId]) == 0 {
+			delete(hub.subscribers, roomId)
+		}
+	}
+}
+
+func (hub *Hub) Publish(ctx context.Context, events []outbox.Event) error {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	for _, event := range events {
+		if !displayed[event.Type] {
+			continue
+		}
+		// every payload of displayed events has the room id, a booking moved to another room has its previous one
+		var payload struct {
+			RoomId uuid.UUID `json:"roomId"`
+			Before *struct {
+				RoomId uuid.UUID `json:"roomId"`
+			} `json:"before"`
+		}
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			continue
+		}
+		hub.notify(payload.RoomId, event.Type)
+		if payload.Before != nil && payload.Before.RoomId != payload.RoomId {
+			hub.notify(payload.Before.RoomId, event.Type)
+		}
+	}
+	return nil
+}
+
+// hub.mu must be held
+func (hub *Hub) notify(roomId uuid.UUID, eventType outbox.EventType) {
+	for changes := range hub.subscribers[roomId] {
+		select {
+		case changes <- eventType:
+		default:
+		}
+	}
+}
+
+func (hub *Hub) Close() error {
+	return nil
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	outbox "github.com/optician/meeting-room-booking/internal/outbox/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMovedBookingChangesBothRooms(t *testing.T) {
+	hub := NewHub()
+	from, to, other := uuid.New(), uuid.New(), uuid.New()
+	fromChanges, unsubscribeFrom := hub.Subscribe(from)
+	defer unsubscribeFrom()
+	toChanges, unsubscribeTo := hub.Subscribe(to)
+	defer unsubscribeTo()
+	otherChanges, unsubscribeOther := hub.Subscribe(other)
+	defer unsubscribeOther()
+
+	payload, _ := json.Marshal(map[string]any{"roomId": to, "before": map[string]any{"roomId": from}})
+	require.Nil(t, hub.Publish(context.Background(), []outbox.Event{{Type: outbox.BookingUpdated, Payload: payload}}))
+
+	require.Equal(t, outbox.BookingUpdated, <-fromChanges)
+	require.Equal(t, outbox.BookingUpdated, <-toChanges)
+	require.Empty(t, otherChanges)
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/optician/meeting-room-booking/internal/display/db"
+	"github.com/optician/meeting-room-booking/internal/display/image"
+	"github.com/optician/meeting-room-booking/internal/display/models"
+	"go.uber.org/zap"
+)
+
+type Logic interface {
+	// "today" ends at midnight in loc, by the clock of the room office if loc is nil
+	Display(ctx context.Context, roomId *uuid.UUID, loc *time.Location) (models.Display, error)
+	// returns at once, the image is generated in background unless it's cached
+	RequestAgendaImage(ctx context.Context, roomId *uuid.UUID, request *models.NewAgendaImage) (models.AgendaImage, error)
+	AgendaImage(ctx context.Context, roomId *uuid.UUID, hash string) (models.AgendaImage, error)
+}
+
+type impl struct {
+	logger    *zap.SugaredLogger
+	db        *db.DB
+	generator image.ImageGenerator
+	config    *Config
+	// limits concurrent generations
+	workers chan struct{}
+	now     func() time.Time
+}
+
+func Make(db *db.DB, generator image.ImageGenerator, config *Config, logger *zap.SugaredLogger) Logic {
+	defer logger.Sync()
+
+	return impl{
+		logger:    logger,
+		db:        db,
+		generator: generator,
+		config:    config,
+		workers:   make(chan struct{}, max(config.ImageWorkers, 1)),
+		now:       time.Now,
+	}
+}
+
+func (impl impl) Display(ctx context.Context, roomId *uuid.UUID, loc *time.Location) (models.Display, error) {
+	at := impl.now()
+	state, err := (*impl.db).RoomState(ctx, roomId, at, loc) // wrap error
+	if err != nil {
+		return models.Display{}, err
+	}
+	return models.NewDisplay(*roomId, at, &state), nil
+}
+
+func (impl impl) RequestAgendaImage(ctx context.Context, roomId *uuid.UUID, request *models.NewAgendaImage) (models.AgendaImage, error) {
+	hash := models.AgendaHash(request.Agenda)
+	image, generate, err := (*impl.db).RequestImage(ctx, roomId, hash, impl.now(), impl.config.ImageTimeout) // wrap error
+	if err != nil {
+		return image, err
+	}
+	if generate {
+		go impl.generate(*roomId, hash, request.Agenda)
+	}
+	image.Path = models.AgendaImagePath(*roomId, hash)
+	return image, nil
+}
+
+func (impl impl) AgendaImage(ctx context.Context, roomId *uuid.UUID, hash string) (models.AgendaImage, error) {
+	image, err := (*impl.db).Image(ctx, roomId, hash) // wrap error
+	image.Path = models.AgendaImagePath(*roomId, hash)
+	return image, err
+}
+
+// outlives the request, so it has its own context. The timeout includes waiting for a worker,
+// so an image isn't pending longer than it unless the process dies.
+func (impl impl) generate(roomId uuid.UUID, hash string, agenda string) {
+	ctx, cancel := context.WithTimeout(context.Background(), impl.config.ImageTimeout)
+	defer cancel()
+	png, err := impl.render(ctx, agenda)
+	if err != nil {
+		impl.logger.Warnf("agenda image %v of room %v is not generated: %v", hash, roomId, err)
+	} else if err = (*impl.db).StoreImage(context.Background(), &roomId, hash, png, impl.now()); err != nil {
+		impl.logger.Errorf("agenda image %v of room %v is not stored: %v", hash, roomId, err)
+	} else {
+		return
+	}
+	// a failed image is generated again on the next request
+	if err := (*impl.db).FailImage(context.Background(), &roomId, hash, err.Error(), impl.now()); err != nil {
+		impl.logger.Errorf("agenda image %v of room %v is not marked failed: %v", hash, roomId, err)
+	}
+}
+
+func (impl impl) render(ctx context.Context, agenda string) ([]byte, error) {
+	select {
+	case impl.workers <- struct{}{}:
+		defer func() { <-impl.workers }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return impl.generator.Generate(ctx, agenda)
+}
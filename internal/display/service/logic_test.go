@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/optician/meeting-room-booking/internal/display/db"
+	"github.com/optician/meeting-room-booking/internal/display/models"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// overrides only methods used by a test, others panic
+type dbStub struct {
+	db.DB
+	generate bool
+	storeErr error
+	stored   chan []byte
+	failed   chan string
+}
+
+func (stub *dbStub) RequestImage(ctx context.Context, roomId *uuid.UUID, hash string, at time.Time, timeout time.Duration) (models.AgendaImage, bool, error) {
+	return models.AgendaImage{RoomId: *roomId, Hash: hash, Status: models.ImagePending, RequestedAt: at}, stub.generate, nil
+}
+
+func (stub *dbStub) StoreImage(ctx context.Context, roomId *uuid.UUID, hash string, png []byte, at time.Time) error {
+	if stub.storeErr != nil {
+		return stub.storeErr
+	}
+	stub.stored <- png
+	return nil
+}
+
+func (stub *dbStub) FailImage(ctx context.Context, roomId *uuid.UUID, hash string, reason string, at time.Time) error {
+	stub.failed <- reason
+	return nil
+}
+
+type generatorStub struct {
+	err error
+}
+
+func (stub generatorStub) Generate(ctx context.Context, agenda string) ([]byte, error) {
+	return []byte(agenda), stub.err
+}
+
+func makeLogic(stub *dbStub, generator generatorStub) Logic {
+	var repository db.DB = stub
+	return Make(&repository, generator, &Config{ImageWorkers: 1, ImageTimeout: time.Second}, zap.NewExample().Sugar())
+}
+
+func TestRequestedImageIsGeneratedInBackground(t *testing.T) {
+	stub := &dbStub{generate: true, stored: make(chan []byte, 1)}
+	roomId := uuid.New()
+
+	image, err := makeLogic(stub, generatorStub{}).RequestAgendaImage(context.Background(), &roomId, &models.NewAgendaImage{Agenda: "Planning"})
+
+	require.Nil(t, err)
+	require.Equal(t, models.ImagePending, image.Status)
+	require.Equal(t, models.AgendaImagePath(roomId, models.AgendaHash("Planning")), image.Path)
+	select {
+	case png := <-stub.stored:
+		require.Equal(t, []byte("Planning"), png)
+	case <-time.After(time.Second):
+		require.Fail(t, "image is not stored")
+	}
+}
+
+func TestFailedGenerationIsStored(t *testing.T) {
+	stub := &dbStub{generate: true, failed: make(chan string, 1)}
+	roomId := uuid.New()
+
+	_, err := makeLogic(stub, generatorStub{err: errors.New("model is down")}).RequestAgendaImage(context.Background(), &roomId, &models.NewAgendaImage{Agenda: "Planning"})
+
+	require.Nil(t, err)
+	select {
+	case reason := <-stub.failed:
+		require.Equal(t, "model is down", reason)
+	case <-time.After(time.Second):
+		require.Fail(t, "failure is not stored")
+	}
+}
+
+func TestUnstoredImageIsFailed(t *testing.T) {
+	stub := &dbStub{generate: true, storeErr: errors.New("image is too large"), failed: make(chan string, 1)}
+	roomId := uuid.New()
+
+	_, err := makeLogic(stub, generatorStub{}).RequestAgendaImage(context.Background(), &roomId, &models.NewAgendaImage{Agenda: "Planning"})
+
+	require.Nil(t, err)
+	select {
+	case reason := <-stub.failed:
+		require.Equal(t, "image is too large", reason)
+	case <-time.After(time.Second):
+		require.Fail(t, "failure is not stored")
+	}
+}
@@ -0,0 +1,12 @@
+package channel
+
+import (
+	"context"
+
+	"github.com/optician/meeting-room-booking/internal/notification/models"
+)
+
+// Channel delivers a message to an address, the address format depends on the channel
+type Channel interface {
+	Send(ctx context.Context, address string, message models.Message) error
+}
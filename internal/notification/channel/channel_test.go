@@ -0,0 +1,162 @@
+package channel
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/optician/meeting-room-booking/internal/notification/models"
+	"github.com/stretchr/testify/require"
+)
+
+var message = models.Message{Subject: "Your booking was cancelled", Body: "The room is flooded"}
+
+func TestWebhookPostsMessage(t *testing.T) {
+	received := make(chan models.Message, 1)
+	sink := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var actual models.Message
+		json.NewDecoder(r.Body).Decode(&actual)
+		received <- actual
+	}))
+	defer sink.Close()
+
+	err := Webhook{client: sink.Client()}.Send(context.Background(), sink.URL, message)
+
+	require.Nil(t, err)
+	require.Equal(t, message, <-received)
+}
+
+func TestWebhookFailsOnErrorStatus(t *testing.T) {
+	sink := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer sink.Close()
+
+	err := Webhook{client: sink.Client()}.Send(context.Background(), sink.URL, message)
+
+	require.EqualError(t, err, "webhook responded with 503 Service Unavailable")
+}
+
+func TestWebhookDoesNotDialLoopback(t *testing.T) {
+	delivered := false
+	sink := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered = true
+	}))
+	defer sink.Close()
+
+	err := NewWebhook(time.Second, nil).Send(context.Background(), sink.URL, message)
+
+	require.ErrorContains(t, err, "is not public")
+	require.False(t, delivered)
+}
+
+func TestWebhookRefusesPlainHttp(t *testing.T) {
+	err := NewWebhook(time.Second, nil).Send(context.Background(), "http://hooks.example.com/hook", message)
+
+	require.EqualError(t, err, "webhook url must be an absolute https url without credentials")
+}
+
+func TestWebhookRefusesHostOutsideAllowed(t *testing.T) {
+	err := NewWebhook(time.Second, []string{"example.com"}).Send(context.Background(), "https://example.org/hook", message)
+
+	require.EqualError(t, err, "webhook host example.org is not allowed")
+}
+
+// fakeSmtp accepts a single mail and sends its recipient and data to the channel
+func fakeSmtp(t *testing.T) (string, chan []string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() { listener.Close() })
+	mails := make(chan []string, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+		reply("220 localhost fake smtp")
+		var rcpt string
+		var data strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "MAIL FROM"):
+				reply("250 OK")
+			case strings.HasPrefix(command, "RCPT TO"):
+				rcpt = strings.TrimSpace(line[len("RCPT TO:"):])
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 go ahead")
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				mails <- []string{rcpt, data.String()}
+				reply("250 OK")
+			case command == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+	return listener.Addr().String(), mails
+}
+
+func TestEmailSendsPlainText(t *testing.T) {
+	addr, mails := fakeSmtp(t)
+
+	err := NewEmail(addr, "booking@localhost", nil, time.Second).Send(context.Background(), "ivan@localhost", message)
+
+	require.Nil(t, err)
+	mail := <-mails
+	require.Equal(t, "<ivan@localhost>", mail[0])
+	require.Contains(t, mail[1], "To: ivan@localhost\r\n")
+	require.Contains(t, mail[1], "Subject: Your booking was cancelled\r\n")
+	require.Contains(t, mail[1], "\r\n\r\nThe room is flooded\r\n")
+}
+
+func TestEmailGivesUpOnHungRelay(t *testing.T) {
+	// the relay accepts connections and never greets
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	err = NewEmail(listener.Addr().String(), "booking@localhost", nil, time.Minute).Send(ctx, "ivan@localhost", message)
+
+	require.Error(t, err)
+	require.Less(t, time.Since(started), 5*time.Second)
+}
@@ -0,0 +1,102 @@
+package channel
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/optician/meeting-room-booking/internal/notification/models"
+)
+
+// Email sends plain text messages through an SMTP relay
+type Email struct {
+	addr    string
+	from    string
+	auth    smtp.Auth
+	timeout time.Duration
+}
+
+// auth may be nil for relays without authentication, e.g. a local one.
+// The timeout bounds a whole mail including the dial.
+func NewEmail(addr, from string, auth smtp.Auth, timeout time.Duration) Email {
+	return Email{addr: addr, from: from, auth: auth, timeout: timeout}
+}
+
+// the connection deadline is the earliest of the timeout and the ctx deadline, a cancelled ctx closes it
+func (channel Email) Send(ctx context.Context, address string, message models.Message) error {
+	if strings.ContainsAny(address, "\r\n") {
+		return fmt.Errorf("invalid email %q", address)
+	}
+	var mail strings.Builder
+	fmt.Fprintf(&mail, "From: %v\r\n", channel.from)
+	fmt.Fprintf(&mail, "To: %v\r\n", address)
+	fmt.Fprintf(&mail, "Subject: %v\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	mail.WriteString("MIME-Version: 1.0\r\n")
+	mail.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	mail.WriteString("\r\n")
+	mail.WriteString(message.Body)
+	mail.WriteString("\r\n")
+
+	ctx, cancel := context.WithTimeout(ctx, channel.timeout)
+	defer cancel()
+	dialer := net.Dialer{Timeout: channel.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", channel.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	host, _, _ := net.SplitHostPort(channel.addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return channel.deliver(client, host, address, mail.String())
+}
+
+// the same conversation as smtp.SendMail has
+func (channel Email) deliver(client *smtp.Client, host string, address string, mail string) error {
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if channel.auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp relay doesn't support AUTH")
+		}
+		if err := client.Auth(channel.auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(channel.from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(address); err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write([]byte(mail)); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,22 @@
+package channel
+
+import (
+	"context"
+
+	"github.com/optician/meeting-room-booking/internal/notification/models"
+	"go.uber.org/zap"
+)
+
+// Log only writes messages to the application log, it's the default channel
+type Log struct {
+	logger *zap.SugaredLogger
+}
+
+func NewLog(logger *zap.SugaredLogger) Log {
+	return Log{logger: logger}
+}
+
+func (channel Log) Send(ctx context.Context, address string, message models.Message) error {
+	channel.logger.Infow("notification", "subject", message.Subject, "body", message.Body)
+	return nil
+}
@@ -0,0 +1,89 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/optician/meeting-room-booking/internal/notification/models"
+)
+
+// Webhook posts a message as JSON to the address, any status but 2xx is a failure
+type Webhook struct {
+	client *http.Client
+	hosts  models.WebhookHosts
+}
+
+// NewWebhook connects only to public addresses of the hosts, redirects are checked the same way
+func NewWebhook(timeout time.Duration, hosts []string) Webhook {
+	dialer := &net.Dialer{Timeout: timeout, Control: dialPublic}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would be dialed instead of the host
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	webhook := Webhook{hosts: hosts}
+	webhook.client = &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return webhook.check(request.URL.String())
+		},
+	}
+	return webhook
+}
+
+// dialPublic runs after the name is resolved, so a host can't point a webhook inside
+func dialPublic(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !models.PublicIP(net.IP(addrPort.Addr().AsSlice())) {
+		return fmt.Errorf("webhook address %v is not public", addrPort.Addr())
+	}
+	return nil
+}
+
+func (channel Webhook) check(address string) error {
+	webhook, err := models.ParseWebhookUrl(address)
+	if err != nil {
+		return err
+	}
+	return channel.hosts.Check(webhook)
+}
+
+func (channel Webhook) Send(ctx context.Context, address string, message models.Message) error {
+	if err := channel.check(address); err != nil {
+		return err
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, address, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("content-type", "application/json")
+
+	response, err := channel.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %v", response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/optician/meeting-room-booking/internal/notification/models"
+	"go.uber.org/zap"
+)
+
+type DB interface {
+	SetPreference(context.Context, *models.Preference) error
+	// people without a preference are missing in the result
+	Preferences(ctx context.Context, persons []string) ([]models.Preference, error)
+	// a notification already enqueued for the same event, person and channel is skipped
+	Enqueue(ctx context.Context, notifications []models.NewNotification, at time.Time) (int, error)
+	// Dispatch sends up to limit due notifications and reschedules failed ones according to policy.
+	// Notifications claimed by another dispatcher are skipped, no transaction is open while they are sent.
+	Dispatch(ctx context.Context, limit int, at time.Time, policy *models.RetryPolicy, send func(context.Context, *models.Notification) error) (models.DispatchReport, error)
+	// the latest first
+	Log(ctx context.Context, person string) ([]models.Notification, error)
+}
+
+type impl struct {
+	logger *zap.SugaredLogger
+	dbpool *pgxpool.Pool
+}
+
+func New(dbPool *pgxpool.Pool, logger *zap.SugaredLogger) DB {
+	return &impl{
+		logger: logger,
+		dbpool: dbPool,
+	}
+}
+
+const notificationColumns = `id, event_id, person, channel, address, subject, body,
+	status, attempts, next_attempt_at, last_error, created_at, sent_at`
+
+func (impl *impl) SetPreference(ctx context.Context, preference *models.Preference) error {
+	query := `insert into notification_preferences (person, channels, email, webhook_url)
+				values (@person, @channels, @email, @webhook_url)
+				on conflict (person) do update
+				set channels = excluded.channels, email = excluded.email, webhook_url = excluded.webhook_url`
+	args := pgx.NamedArgs{
+		"person":      preference.Person,
+		"channels":    preference.Channels,
+		"email":       preference.Email,
+		"webhook_url": preference.WebhookUrl,
+	}
+	_, err := impl.dbpool.Exec(ctx, query, args)
+	return err // wrap error
+}
+
+// slice can't be nil if error is nil
+func (impl *impl) Preferences(ctx context.Context, persons []string) ([]models.Preference, error) {
+	list := make([]models.Preference, 0)
+	query := `select person, channels, email, webhook_url from notification_preferences
+				where person = any(@persons)`
+	err := pgxscan.Select(ctx, impl.dbpool, &list, query, pgx.NamedArgs{"persons": persons})
+	return list, err // wrap error
+}
+
+func (impl *impl) Enqueue(ctx context.Context, notifications []models.NewNotification, at time.Time) (int, error) {
+	enqueued := 0
+	err := pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		query := `insert into notifications
+					(event_id, person, channel, address, subject, body, next_attempt_at, created_at)
+					values (@event_id, @person, @channel, @address, @subject, @body, @at, @at)
+					on conflict on constraint notifications_once do nothing`
+		for _, notification := range notifications {
+			args := pgx.NamedArgs{
+				"event_id": notification.EventId,
+				"person":   notification.Person,
+				"channel":  notification.Channel,
+				"address":  notification.Address,
+				"subject":  notification.Subject,
+				"body":     notification.Body,
+				"at":       at,
+			}
+			tag, err := tx.Exec(ctx, query, args)
+			if err != nil {
+				return err
+			}
+			enqueued += int(tag.RowsAffected())
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err // wrap error
+	}
+	return enqueued, nil
+}
+
+// due notifications are claimed and sent outside of a transaction, so a slow channel holds no locks.
+// A claim pushes next_attempt_at by the lease, a notification claimed by a dispatcher which died is due again after it.
+func (impl *impl) Dispatch(ctx context.Context, limit int, at time.Time, policy *models.RetryPolicy, send func(context.Context, *models.Notification) error) (models.DispatchReport, error) {
+	var report models.DispatchReport
+	claimed, err := impl.claim(ctx, limit, at, at.Add(policy.Lease))
+	if err != nil || len(claimed) == 0 {
+		return report, err // wrap error
+	}
+
+	// a send which doesn't finish within the lease is a failed attempt, the claim would expire otherwise.
+	// Notifications left when the lease is over weren't tried, they are due again at once.
+	sendCtx, cancel := context.WithTimeout(ctx, policy.Lease)
+	defer cancel()
+	for i := range claimed {
+		notification := &claimed[i]
+		if sendCtx.Err() != nil {
+			notification.NextAttemptAt = at
+			report.Deferred++
+			continue
+		}
+		notification.Attempts++
+		if err := send(sendCtx, notification); err != nil {
+			message := err.Error()
+			notification.LastError = &message
+			if notification.Attempts >= policy.MaxAttempts {
+				notification.Status = models.StatusFailed
+				report.Failed++
+			} else {
+				notification.NextAttemptAt = at.Add(policy.Delay(notification.Attempts))
+				report.Retried++
+			}
+		} else {
+			notification.Status = models.StatusSent
+			notification.SentAt = &at
+			report.Sent++
+		}
+	}
+	return report, impl.record(ctx, claimed, at.Add(policy.Lease)) // wrap error
+}
+
+func (impl *impl) claim(ctx context.Context, limit int, at time.Time, until time.Time) ([]models.Notification, error) {
+	due := make([]models.Notification, 0)
+	err := pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		query := "select " + notificationColumns + ` from notifications
+					where status = @pending and next_attempt_at <= @at
+					order by next_attempt_at, id
+					limit @limit
+					for update skip locked`
+		args := pgx.NamedArgs{"pending": models.StatusPending, "at": at, "limit": limit}
+		if err := pgxscan.Select(ctx, tx, &due, query, args); err != nil {
+			return err
+		}
+		ids := make([]int64, len(due))
+		for i := range due {
+			ids[i] = due[i].Id
+		}
+		claim := "update notifications set next_attempt_at = @until where id = any(@ids)"
+		_, err := tx.Exec(ctx, claim, pgx.NamedArgs{"until": until, "ids": ids})
+		return err
+	})
+	return due, err
+}
+
+// a notification whose claim expired and was claimed again by another dispatcher is left to it
+func (impl *impl) record(ctx context.Context, sent []models.Notification, until time.Time) error {
+	return pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		update := `update notifications
+					set status = @status, attempts = @attempts, next_attempt_at = @next_attempt_at,
+						last_error = @last_error, sent_at = @sent_at
+					where id = @id and next_attempt_at = @until`
+		for _, notification := range sent {
+			args := pgx.NamedArgs{
+				"id":              notification.Id,
+				"until":           until,
+				"status":          notification.Status,
+				"attempts":        notification.Attempts,
+				"next_attempt_at": notification.NextAttemptAt,
+				"last_error":      notification.LastError,
+				"sent_at":         notification.SentAt,
+			}
+			if _, err := tx.Exec(ctx, update, args); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// slice can't be nil if error is nil
+func (impl *impl) Log(ctx context.Context, person string) ([]models.Notification, error) {
+	list := make([]models.Notification, 0)
+	query := "select " + notificationColumns + ` from notifications
+				where person = @person
+				order by created_at desc, id desc`
+	err := pgxscan.Select(ctx, impl.dbpool, &list, query, pgx.NamedArgs{"person": person})
+	return list, err // wrap error
+}
@@ -0,0 +1,186 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	testHelpers "github.com/optician/meeting-room-booking/internal/administration/db/testing"
+	"github.com/optician/meeting-room-booking/internal/dbPool"
+	"github.com/optician/meeting-room-booking/internal/notification/models"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"go.uber.org/zap"
+)
+
+type NotificationRepositoryTestSuite struct {
+	suite.Suite
+	pgContainer *postgres.PostgresContainer
+	pool        *pgxpool.Pool
+	repository  *DB
+	ctx         context.Context
+	logger      *zap.SugaredLogger
+}
+
+func (suite *NotificationRepositoryTestSuite) SetupTest() {
+	ctx := context.Background()
+	logger := zap.NewExample().Sugar()
+	container, err := testHelpers.CreatePostgresContainer(ctx)
+	if err != nil {
+		logger.Fatalf("cannot setup postgres container in NotificationRepositoryTestSuite, %v", err)
+	}
+	migrationsPath := "../../../migrations/" // better to use env instead
+	if err := testHelpers.Migrate(ctx, container.ConnectionString, migrationsPath); err != nil {
+		logger.Fatalf("%v", err)
+	}
+
+	config := dbPool.DBConfig{Url: container.ConnectionString}
+	dbPool, dbPoolErr := dbPool.NewDBPool(&config, logger)
+	if dbPoolErr != nil {
+		logger.Fatalf("application terminated: %v", dbPoolErr)
+	}
+
+	notificationsDB := New(dbPool.GetPool(), logger)
+
+	suite.pgContainer = container.Container
+	suite.pool = dbPool.GetPool()
+	suite.repository = &notificationsDB
+	suite.ctx = ctx
+	suite.logger = logger
+}
+
+func (suite *NotificationRepositoryTestSuite) TearDownTest() {
+	if err := suite.pgContainer.Terminate(suite.ctx); err != nil {
+		suite.logger.Fatalf("error terminating postgres container: %s", err)
+	}
+}
+
+func (suite *NotificationRepositoryTestSuite) TestEnqueueOnce() {
+	at := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	notification := models.NewNotification{
+		EventId: 1,
+		Person:  "ivan",
+		Channel: models.ChannelLog,
+		Message: models.Message{Subject: "Your booking was cancelled", Body: "flooded"},
+	}
+
+	enqueued, err := (*suite.repository).Enqueue(suite.ctx, []models.NewNotification{notification}, at)
+	require.Nil(suite.T(), err, "Enqueue error")
+	require.Equal(suite.T(), 1, enqueued)
+
+	// the relay published the same event again
+	enqueued, err = (*suite.repository).Enqueue(suite.ctx, []models.NewNotification{notification}, at)
+	require.Nil(suite.T(), err, "Enqueue error")
+	require.Equal(suite.T(), 0, enqueued)
+}
+
+func (suite *NotificationRepositoryTestSuite) TestDispatchRetriesThenFails() {
+	at := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	notification := models.NewNotification{EventId: 1, Person: "ivan", Channel: models.ChannelEmail, Address: "ivan@localhost"}
+	_, err := (*suite.repository).Enqueue(suite.ctx, []models.NewNotification{notification}, at)
+	require.Nil(suite.T(), err, "Enqueue error")
+
+	policy := models.RetryPolicy{MaxAttempts: 2, Base: time.Minute, Max: time.Hour, Lease: time.Minute}
+	failing := func(ctx context.Context, notification *models.Notification) error {
+		return errors.New("relay is down")
+	}
+
+	report, err := (*suite.repository).Dispatch(suite.ctx, 10, at, &policy, failing)
+	require.Nil(suite.T(), err, "Dispatch error")
+	require.Equal(suite.T(), models.DispatchReport{Retried: 1}, report)
+
+	// not due before the backoff passes
+	report, err = (*suite.repository).Dispatch(suite.ctx, 10, at.Add(30*time.Second), &policy, failing)
+	require.Nil(suite.T(), err, "Dispatch error")
+	require.Equal(suite.T(), models.DispatchReport{}, report)
+
+	report, err = (*suite.repository).Dispatch(suite.ctx, 10, at.Add(time.Minute), &policy, failing)
+	require.Nil(suite.T(), err, "Dispatch error")
+	require.Equal(suite.T(), models.DispatchReport{Failed: 1}, report)
+
+	log, err := (*suite.repository).Log(suite.ctx, "ivan")
+	require.Nil(suite.T(), err, "Log error")
+	require.Len(suite.T(), log, 1)
+	require.Equal(suite.T(), models.StatusFailed, log[0].Status)
+	require.Equal(suite.T(), 2, log[0].Attempts)
+	require.Equal(suite.T(), "relay is down", *log[0].LastError)
+}
+
+func (suite *NotificationRepositoryTestSuite) TestDispatchSendsWithoutLocks() {
+	at := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	notification := models.NewNotification{EventId: 1, Person: "ivan", Channel: models.ChannelEmail, Address: "ivan@localhost"}
+	_, err := (*suite.repository).Enqueue(suite.ctx, []models.NewNotification{notification}, at)
+	require.Nil(suite.T(), err, "Enqueue error")
+
+	policy := models.RetryPolicy{MaxAttempts: 2, Base: time.Minute, Max: time.Hour, Lease: time.Minute}
+	send := func(ctx context.Context, notification *models.Notification) error {
+		// a slow channel doesn't keep the row locked
+		lockCtx, cancel := context.WithTimeout(suite.ctx, time.Second)
+		defer cancel()
+		_, err := suite.pool.Exec(lockCtx, "update notifications set subject = subject where id = $1", notification.Id)
+		require.Nil(suite.T(), err, "row is locked while sending")
+
+		// and another dispatcher doesn't send it twice
+		report, err := (*suite.repository).Dispatch(suite.ctx, 10, at, &policy, func(context.Context, *models.Notification) error { return nil })
+		require.Nil(suite.T(), err, "concurrent Dispatch error")
+		require.Equal(suite.T(), models.DispatchReport{}, report)
+		return nil
+	}
+
+	report, err := (*suite.repository).Dispatch(suite.ctx, 10, at, &policy, send)
+	require.Nil(suite.T(), err, "Dispatch error")
+	require.Equal(suite.T(), models.DispatchReport{Sent: 1}, report)
+}
+
+func (suite *NotificationRepositoryTestSuite) TestDispatchDefersWhatTheLeaseDidNotCover() {
+	at := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	notifications := []models.NewNotification{
+		{EventId: 1, Person: "ivan", Channel: models.ChannelEmail, Address: "ivan@localhost"},
+		{EventId: 2, Person: "ivan", Channel: models.ChannelEmail, Address: "ivan@localhost"},
+		{EventId: 3, Person: "ivan", Channel: models.ChannelEmail, Address: "ivan@localhost"},
+	}
+	_, err := (*suite.repository).Enqueue(suite.ctx, notifications, at)
+	require.Nil(suite.T(), err, "Enqueue error")
+
+	policy := models.RetryPolicy{MaxAttempts: 1, Base: time.Minute, Max: time.Hour, Lease: 100 * time.Millisecond}
+	// the first send takes the whole lease
+	slow := func(ctx context.Context, notification *models.Notification) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	report, err := (*suite.repository).Dispatch(suite.ctx, 10, at, &policy, slow)
+	require.Nil(suite.T(), err, "Dispatch error")
+	require.Equal(suite.T(), models.DispatchReport{Failed: 1, Deferred: 2}, report)
+
+	report, err = (*suite.repository).Dispatch(suite.ctx, 10, at, &policy, func(context.Context, *models.Notification) error { return nil })
+	require.Nil(suite.T(), err, "Dispatch error")
+	require.Equal(suite.T(), models.DispatchReport{Sent: 2}, report)
+
+	log, err := (*suite.repository).Log(suite.ctx, "ivan")
+	require.Nil(suite.T(), err, "Log error")
+	for _, notification := range log {
+		require.Equal(suite.T(), 1, notification.Attempts)
+	}
+}
+
+func (suite *NotificationRepositoryTestSuite) TestPreferences() {
+	email := "ivan@localhost"
+	for _, person := range []string{"ivan", "petr"} {
+		preference := models.Preference{Person: person, Channels: []models.ChannelName{models.ChannelEmail}, Email: &email}
+		require.Nil(suite.T(), (*suite.repository).SetPreference(suite.ctx, &preference), fmt.Sprintf("SetPreference %v error", person))
+	}
+
+	preferences, err := (*suite.repository).Preferences(suite.ctx, []string{"ivan", "olga"})
+	require.Nil(suite.T(), err, "Preferences error")
+	require.Len(suite.T(), preferences, 1)
+	require.Equal(suite.T(), []models.ChannelName{models.ChannelEmail}, preferences[0].Channels)
+}
+
+func TestNotificationRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(NotificationRepositoryTestSuite))
+}
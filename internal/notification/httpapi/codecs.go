@@ -0,0 +1,26 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/optician/meeting-room-booking/internal/notification/models"
+)
+
+func deserializePreference(stream io.Reader) (models.Preference, error) {
+	preference := &models.Preference{}
+	if err := json.NewDecoder(stream).Decode(preference); err != nil {
+		return *preference, fmt.Errorf("can't deserialize Preference: %w", err)
+	} else {
+		return *preference, nil
+	}
+}
+
+func fromBytesPreference(stream io.Reader) (models.Preference, error) {
+	if preference, err := deserializePreference(stream); err != nil {
+		return preference, err
+	} else {
+		return models.ValidatePreference(&preference)
+	}
+}
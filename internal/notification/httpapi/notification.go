@@ -0,0 +1,99 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/optician/meeting-room-booking/internal/notification/models"
+	"github.com/optician/meeting-room-booking/internal/notification/service"
+	"go.uber.org/zap"
+)
+
+type Controller struct {
+	logger *zap.SugaredLogger
+	logic  *service.Logic
+}
+
+// mutates router
+func Make(logic *service.Logic, logger *zap.SugaredLogger) func(chi.Router) {
+	defer logger.Sync()
+
+	controller := Controller{
+		logger: logger,
+		logic:  logic,
+	}
+	return controller.routes
+}
+
+func (ctrl *Controller) routes(r chi.Router) {
+	r.Route("/notifications", func(r chi.Router) {
+		r.Get("/", ctrl.getLogController)
+		r.Post("/preferences/update", ctrl.setPreferenceController)
+		r.Get("/preferences/{person}", ctrl.getPreferenceController)
+	})
+}
+
+// ?person= is required
+func (ctrl *Controller) getLogController(w http.ResponseWriter, r *http.Request) {
+	person := r.URL.Query().Get("person")
+	if person == "" {
+		ctrl.badRequest(w, "Invalid delivery log query", errors.New("person is required"))
+		return
+	}
+
+	if list, err := (*ctrl.logic).Log(r.Context(), person); err != nil {
+		ctrl.internalError(w, "Reading of a delivery log raised error", err)
+	} else {
+		ctrl.writeJSON(w, list)
+	}
+}
+
+func (ctrl *Controller) setPreferenceController(w http.ResponseWriter, r *http.Request) {
+	preference, err := fromBytesPreference(r.Body)
+	if err != nil {
+		ctrl.badRequest(w, "Invalid Preference", err)
+		return
+	}
+
+	var hostErr *models.WebhookHostError
+	if err := (*ctrl.logic).SetPreference(r.Context(), &preference); errors.As(err, &hostErr) {
+		ctrl.badRequest(w, "Invalid Preference", err)
+	} else if err != nil {
+		ctrl.internalError(w, "Update of a notification preference raised error", err)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (ctrl *Controller) getPreferenceController(w http.ResponseWriter, r *http.Request) {
+	person := chi.URLParam(r, "person")
+
+	if preference, err := (*ctrl.logic).Preference(r.Context(), person); err != nil {
+		ctrl.internalError(w, "Reading of a notification preference raised error", err)
+	} else {
+		ctrl.writeJSON(w, preference)
+	}
+}
+
+func (ctrl *Controller) badRequest(w http.ResponseWriter, what string, err error) {
+	ctrl.logger.Errorf("Bad Request. %v: %v", what, err)
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write([]byte(err.Error()))
+}
+
+func (ctrl *Controller) internalError(w http.ResponseWriter, what string, err error) {
+	ctrl.logger.Errorf("%v: %v", what, err)
+	w.WriteHeader(http.StatusInternalServerError)
+}
+
+func (ctrl *Controller) writeJSON(w http.ResponseWriter, payload any) {
+	if json, err := json.Marshal(payload); err != nil {
+		ctrl.logger.Errorf("internal error: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	} else {
+		w.Header().Add("content-type", "application/json")
+		w.Write(json)
+	}
+}
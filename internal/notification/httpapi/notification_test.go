@@ -0,0 +1,75 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/optician/meeting-room-booking/internal/notification/models"
+	"github.com/optician/meeting-room-booking/internal/notification/service"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var logger = zap.NewExample().Sugar()
+
+func executeRequest(req *http.Request, logic service.Logic) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.Route("/", Make(&logic, logger))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestSetPreferenceSuccessfully(t *testing.T) {
+	json := `{"person":"ivan","channels":["webhook"],"webhookUrl":"https://hooks.example.com/hook"}`
+	req, _ := http.NewRequest("POST", "/notifications/preferences/update", strings.NewReader(json))
+
+	response := executeRequest(req, logicStub{})
+
+	require.Equal(t, http.StatusOK, response.Code)
+}
+
+func TestSetPreferenceWithoutWebhookUrl(t *testing.T) {
+	json := `{"person":"ivan","channels":["webhook"]}`
+	req, _ := http.NewRequest("POST", "/notifications/preferences/update", strings.NewReader(json))
+
+	response := executeRequest(req, logicStub{})
+
+	require.Equal(t, http.StatusBadRequest, response.Code)
+	require.Equal(t, "webhook channel requires a webhook url", response.Body.String())
+}
+
+func TestDeliveryLogWithoutPerson(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/notifications", nil)
+
+	response := executeRequest(req, logicStub{})
+
+	require.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestGetDefaultPreference(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/notifications/preferences/ivan", nil)
+
+	response := executeRequest(req, logicStub{})
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Equal(t, `{"person":"ivan","channels":["log"]}`, response.Body.String())
+}
+
+type logicStub struct{}
+
+func (logicStub) SetPreference(ctx context.Context, preference *models.Preference) error {
+	return nil
+}
+
+func (logicStub) Preference(ctx context.Context, person string) (models.Preference, error) {
+	return models.DefaultPreference(person), nil
+}
+
+func (logicStub) Log(ctx context.Context, person string) ([]models.Notification, error) {
+	return []models.Notification{}, nil
+}
@@ -0,0 +1,271 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	outbox "github.com/optician/meeting-room-booking/internal/outbox/models"
+)
+
+type ChannelName string
+
+const (
+	ChannelLog     ChannelName = "log"
+	ChannelEmail   ChannelName = "email"
+	ChannelWebhook ChannelName = "webhook"
+)
+
+type Preference struct {
+	Person     string        `json:"person"`
+	Channels   []ChannelName `json:"channels"`
+	Email      *string       `json:"email,omitempty"`
+	WebhookUrl *string       `json:"webhookUrl,omitempty"`
+}
+
+func ValidatePreference(preference *Preference) (Preference, error) {
+	if preference.Person == "" {
+		return *preference, errors.New("person can't be empty")
+	}
+	for _, channel := range preference.Channels {
+		switch channel {
+		case ChannelLog:
+		case ChannelEmail:
+			if preference.Email == nil || *preference.Email == "" {
+				return *preference, errors.New("email channel requires an email")
+			}
+		case ChannelWebhook:
+			if preference.WebhookUrl == nil || *preference.WebhookUrl == "" {
+				return *preference, errors.New("webhook channel requires a webhook url")
+			}
+		default:
+			return *preference, fmt.Errorf("unknown channel %q", channel)
+		}
+	}
+	if preference.WebhookUrl != nil && *preference.WebhookUrl != "" {
+		if _, err := ParseWebhookUrl(*preference.WebhookUrl); err != nil {
+			return *preference, err
+		}
+	}
+	if preference.Channels == nil {
+		preference.Channels = []ChannelName{}
+	}
+
+	return *preference, nil
+}
+
+var errWebhookUrl = errors.New("webhook url must be an absolute https url without credentials")
+
+// ParseWebhookUrl accepts only absolute https urls, hosts are checked against WebhookHosts and PublicIP
+func ParseWebhookUrl(address string) (*url.URL, error) {
+	parsed, err := url.Parse(address)
+	if err != nil || !parsed.IsAbs() || parsed.Scheme != "https" || parsed.Hostname() == "" || parsed.User != nil {
+		return nil, errWebhookUrl
+	}
+	return parsed, nil
+}
+
+// WebhookHostError is a webhook url on a host which isn't allowed
+type WebhookHostError struct {
+	Host string
+}
+
+func (e *WebhookHostError) Error() string {
+	return fmt.Sprintf("webhook host %v is not allowed", e.Host)
+}
+
+// WebhookHosts are the hosts webhooks may be sent to with their subdomains, any host if there are none
+type WebhookHosts []string
+
+func (hosts WebhookHosts) Check(webhook *url.URL) error {
+	if len(hosts) == 0 {
+		return nil
+	}
+	host := strings.ToLower(strings.TrimSuffix(webhook.Hostname(), "."))
+	for _, allowed := range hosts {
+		allowed = strings.ToLower(strings.TrimSuffix(allowed, "."))
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return nil
+		}
+	}
+	return &WebhookHostError{Host: webhook.Hostname()}
+}
+
+// PublicIP tells whether a webhook may connect to the address,
+// loopback, private, link-local and other local ones are reachable only from inside
+func PublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified())
+}
+
+// DefaultPreference is used for people who haven't set one
+func DefaultPreference(person string) Preference {
+	return Preference{Person: person, Channels: []ChannelName{ChannelLog}}
+}
+
+// Address is where the channel delivers to the person
+func (preference *Preference) Address(channel ChannelName) string {
+	switch {
+	case channel == ChannelEmail && preference.Email != nil:
+		return *preference.Email
+	case channel == ChannelWebhook && preference.WebhookUrl != nil:
+		return *preference.WebhookUrl
+	default:
+		return ""
+	}
+}
+
+type Message struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// CancellationMessage tells a host or an attendee which meeting is off and why
+func CancellationMessage(booking *outbox.CancelledBooking) Message {
+	return Message{
+		Subject: "Your booking was cancelled",
+		Body: fmt.Sprintf(
+			"The meeting hosted by %v from %v to %v in room %v was cancelled by %v: %v",
+			booking.Host,
+			booking.Start.UTC().Format(time.RFC3339),
+			booking.End.UTC().Format(time.RFC3339),
+			booking.RoomId,
+			booking.CancelledBy,
+			booking.Reason,
+		),
+	}
+}
+
+// OfferMessage tells a waitlisted person about a slot held or booked for them
+func OfferMessage(offer *outbox.WaitlistOffer) Message {
+	slot := fmt.Sprintf(
+		"room %v from %v to %v",
+		offer.RoomId,
+		offer.Start.UTC().Format(time.RFC3339),
+		offer.End.UTC().Format(time.RFC3339),
+	)
+	if offer.ExpiresAt == nil {
+		return Message{
+			Subject: "A slot you waited for is booked",
+			Body:    fmt.Sprintf("The %v is booked for you, booking %v", slot, offer.BookingId),
+		}
+	}
+	return Message{
+		Subject: "A slot you waited for is free",
+		Body: fmt.Sprintf(
+			"The %v is held for you until %v, confirm booking %v to keep it",
+			slot,
+			offer.ExpiresAt.UTC().Format(time.RFC3339),
+			offer.BookingId,
+		),
+	}
+}
+
+// Recipients are the host and attendees, except the person who cancelled the booking
+func Recipients(booking *outbox.CancelledBooking) []string {
+	recipients := make([]string, 0, len(booking.Attendees)+1)
+	for _, person := range append([]string{booking.Host}, booking.Attendees...) {
+		if person != booking.CancelledBy && !slices.Contains(recipients, person) {
+			recipients = append(recipients, person)
+		}
+	}
+	return recipients
+}
+
+// Notice is a message an event tells its recipients
+type Notice struct {
+	EventId    int64
+	Recipients []string
+	Message    Message
+}
+
+// NewNotice reads booking cancellations and waitlist offers, ok is false for other events
+func NewNotice(event *outbox.Event) (notice Notice, ok bool, err error) {
+	notice.EventId = event.Id
+	switch event.Type {
+	case outbox.BookingCancelled:
+		var booking outbox.CancelledBooking
+		if err := json.Unmarshal(event.Payload, &booking); err != nil {
+			return notice, false, fmt.Errorf("can't read %v event %v: %w", event.Type, event.Id, err)
+		}
+		notice.Recipients, notice.Message = Recipients(&booking), CancellationMessage(&booking)
+	case outbox.WaitlistOffered:
+		var offer outbox.WaitlistOffer
+		if err := json.Unmarshal(event.Payload, &offer); err != nil {
+			return notice, false, fmt.Errorf("can't read %v event %v: %w", event.Type, event.Id, err)
+		}
+		notice.Recipients, notice.Message = []string{offer.Person}, OfferMessage(&offer)
+	default:
+		return notice, false, nil
+	}
+	return notice, true, nil
+}
+
+type NewNotification struct {
+	EventId int64
+	Person  string
+	Channel ChannelName
+	Address string
+	Message
+}
+
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusSent    Status = "sent"
+	// delivery gave up after RetryPolicy.MaxAttempts
+	StatusFailed Status = "failed"
+)
+
+// Notification is an entry of the delivery log
+type Notification struct {
+	Id            int64       `json:"id"`
+	EventId       int64       `json:"eventId"`
+	Person        string      `json:"person"`
+	Channel       ChannelName `json:"channel"`
+	Address       string      `json:"address"`
+	Subject       string      `json:"subject"`
+	Body          string      `json:"body"`
+	Status        Status      `json:"status"`
+	Attempts      int         `json:"attempts"`
+	NextAttemptAt time.Time   `json:"nextAttemptAt"`
+	LastError     *string     `json:"lastError,omitempty"`
+	CreatedAt     time.Time   `json:"createdAt"`
+	SentAt        *time.Time  `json:"sentAt,omitempty"`
+}
+
+type RetryPolicy struct {
+	MaxAttempts int
+	Base        time.Duration
+	Max         time.Duration
+	// how long a dispatcher owns claimed notifications, they are sent within it
+	Lease time.Duration
+}
+
+// Delay is the exponential backoff before the next attempt, attempt counts from 1
+func (policy *RetryPolicy) Delay(attempt int) time.Duration {
+	delay := policy.Base
+	for i := 1; i < attempt && delay < policy.Max; i++ {
+		delay *= 2
+	}
+	return min(delay, policy.Max)
+}
+
+type DispatchReport struct {
+	Sent    int
+	Retried int
+	Failed  int
+	// not tried within the lease, they are due again without an attempt counted
+	Deferred int
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	outbox "github.com/optician/meeting-room-booking/internal/outbox/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryDelayDoublesUpToMax(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, Base: 30 * time.Second, Max: 5 * time.Minute}
+
+	require.Equal(t, 30*time.Second, policy.Delay(1))
+	require.Equal(t, time.Minute, policy.Delay(2))
+	require.Equal(t, 4*time.Minute, policy.Delay(4))
+	require.Equal(t, 5*time.Minute, policy.Delay(5))
+	require.Equal(t, 5*time.Minute, policy.Delay(60))
+}
+
+func TestRecipientsSkipWhoCancelled(t *testing.T) {
+	booking := outbox.CancelledBooking{Host: "ivan", Attendees: []string{"petr", "ivan", "olga"}, CancelledBy: "olga"}
+
+	require.Equal(t, []string{"ivan", "petr"}, Recipients(&booking))
+}
+
+func TestEmailPreferenceWithoutEmailFailed(t *testing.T) {
+	preference := Preference{Person: "ivan", Channels: []ChannelName{ChannelLog, ChannelEmail}}
+
+	_, err := ValidatePreference(&preference)
+
+	require.EqualError(t, err, "email channel requires an email")
+}
+
+func TestUnknownChannelFailed(t *testing.T) {
+	preference := Preference{Person: "ivan", Channels: []ChannelName{"pigeon"}}
+
+	_, err := ValidatePreference(&preference)
+
+	require.EqualError(t, err, `unknown channel "pigeon"`)
+}
+
+func TestRelativeWebhookUrlFailed(t *testing.T) {
+	webhook := "/hook"
+	preference := Preference{Person: "ivan", Channels: []ChannelName{ChannelWebhook}, WebhookUrl: &webhook}
+
+	_, err := ValidatePreference(&preference)
+
+	require.EqualError(t, err, "webhook url must be an absolute https url without credentials")
+}
+
+func TestWebhookHostsAllowSubdomains(t *testing.T) {
+	hosts := WebhookHosts{"example.com"}
+	check := func(address string) error {
+		webhook, _ := url.Parse(address)
+		return hosts.Check(webhook)
+	}
+
+	require.Nil(t, check("https://example.com/hook"))
+	require.Nil(t, check("https://hooks.Example.com/hook"))
+	require.EqualError(t, check("https://badexample.com/hook"), "webhook host badexample.com is not allowed")
+	require.Nil(t, WebhookHosts{}.Check(&url.URL{Host: "anything.org"}))
+}
+
+func TestLocalAddressesAreNotPublic(t *testing.T) {
+	for _, address := range []string{"127.0.0.1", "10.1.2.3", "192.168.0.1", "169.254.169.254", "0.0.0.0", "::1", "fe80::1", "fd00::1", "::ffff:127.0.0.1"} {
+		require.False(t, PublicIP(net.ParseIP(address)), address)
+	}
+	require.True(t, PublicIP(net.ParseIP("93.184.216.34")))
+}
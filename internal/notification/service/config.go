@@ -0,0 +1,28 @@
+package service
+
+import "time"
+
+type Config struct {
+	// how often the dispatcher looks for due notifications
+	DispatchInterval time.Duration `koanf:"dispatch_interval"`
+	// the most notifications sent at once
+	BatchSize int `koanf:"batch_size"`
+	// a notification is failed after this many attempts
+	MaxAttempts int `koanf:"max_attempts"`
+	// the delay after the first failed attempt, it doubles after every next one up to BackoffMax
+	BackoffBase time.Duration `koanf:"backoff_base"`
+	BackoffMax  time.Duration `koanf:"backoff_max"`
+	// a batch is sent within the lease, what isn't sent by then is retried
+	SendLease      time.Duration `koanf:"send_lease"`
+	WebhookTimeout time.Duration `koanf:"webhook_timeout"`
+	// webhooks go only to these hosts and their subdomains, to any public host if empty
+	WebhookHosts []string   `koanf:"webhook_hosts"`
+	Smtp         SmtpConfig `koanf:"smtp"`
+}
+
+type SmtpConfig struct {
+	Addr string `koanf:"addr"`
+	From string `koanf:"from"`
+	// of a single mail including the dial
+	Timeout time.Duration `koanf:"timeout"`
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/optician/meeting-room-booking/internal/notification/channel"
+	"github.com/optician/meeting-room-booking/internal/notification/db"
+	"github.com/optician/meeting-room-booking/internal/notification/models"
+	"go.uber.org/zap"
+)
+
+// Dispatcher periodically sends enqueued notifications and retries failed ones with a backoff
+type Dispatcher struct {
+	logger   *zap.SugaredLogger
+	db       *db.DB
+	channels map[models.ChannelName]channel.Channel
+	config   *Config
+	now      func() time.Time
+}
+
+func MakeDispatcher(db *db.DB, channels map[models.ChannelName]channel.Channel, config *Config, logger *zap.SugaredLogger) Dispatcher {
+	return Dispatcher{
+		logger:   logger,
+		db:       db,
+		channels: channels,
+		config:   config,
+		now:      time.Now,
+	}
+}
+
+// Run blocks until ctx is done
+func (dispatcher Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(dispatcher.config.DispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := dispatcher.DispatchOnce(ctx); err != nil {
+				dispatcher.logger.Errorf("notification dispatch failed: %v", err)
+			}
+		}
+	}
+}
+
+func (dispatcher Dispatcher) DispatchOnce(ctx context.Context) (models.DispatchReport, error) {
+	policy := models.RetryPolicy{
+		MaxAttempts: dispatcher.config.MaxAttempts,
+		Base:        dispatcher.config.BackoffBase,
+		Max:         dispatcher.config.BackoffMax,
+		Lease:       dispatcher.config.SendLease,
+	}
+	report, err := (*dispatcher.db).Dispatch(ctx, dispatcher.config.BatchSize, dispatcher.now(), &policy, dispatcher.send)
+	if err == nil && report != (models.DispatchReport{}) {
+		dispatcher.logger.Infof("notifications sent: %v, retried: %v, failed: %v, deferred: %v", report.Sent, report.Retried, report.Failed, report.Deferred)
+	}
+	return report, err
+}
+
+func (dispatcher Dispatcher) send(ctx context.Context, notification *models.Notification) error {
+	channel, ok := dispatcher.channels[notification.Channel]
+	if !ok {
+		return fmt.Errorf("channel %q isn't configured", notification.Channel)
+	}
+	message := models.Message{Subject: notification.Subject, Body: notification.Body}
+	return channel.Send(ctx, notification.Address, message)
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+
+	"github.com/optician/meeting-room-booking/internal/notification/db"
+	"github.com/optician/meeting-room-booking/internal/notification/models"
+	"go.uber.org/zap"
+)
+
+type Logic interface {
+	SetPreference(ctx context.Context, preference *models.Preference) error
+
+	// the default preference if the person hasn't set one
+	Preference(ctx context.Context, person string) (models.Preference, error)
+
+	// delivery log of notifications of the person
+	Log(ctx context.Context, person string) ([]models.Notification, error)
+}
+
+type impl struct {
+	logger *zap.SugaredLogger
+	db     *db.DB
+	hosts  models.WebhookHosts
+}
+
+func Make(db *db.DB, config *Config, logger *zap.SugaredLogger) Logic {
+	defer logger.Sync()
+
+	return impl{
+		logger: logger,
+		db:     db,
+		hosts:  config.WebhookHosts,
+	}
+}
+
+// the webhook url is expected to be validated by models.ValidatePreference
+func (impl impl) SetPreference(ctx context.Context, preference *models.Preference) error {
+	if preference.WebhookUrl != nil && *preference.WebhookUrl != "" {
+		webhook, err := models.ParseWebhookUrl(*preference.WebhookUrl)
+		if err != nil {
+			return err
+		}
+		if err := impl.hosts.Check(webhook); err != nil {
+			return err
+		}
+	}
+	impl.logger.Infof("set notification channels of %v to %v", preference.Person, preference.Channels)
+	return (*impl.db).SetPreference(ctx, preference) // wrap error
+}
+
+func (impl impl) Preference(ctx context.Context, person string) (models.Preference, error) {
+	preferences, err := (*impl.db).Preferences(ctx, []string{person}) // wrap error
+	if err != nil {
+		return models.Preference{}, err
+	}
+	if len(preferences) == 0 {
+		return models.DefaultPreference(person), nil
+	}
+	return preferences[0], nil
+}
+
+func (impl impl) Log(ctx context.Context, person string) ([]models.Notification, error) {
+	list, err := (*impl.db).Log(ctx, person) // wrap error
+	return list, err
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/optician/meeting-room-booking/internal/notification/db"
+	"github.com/optician/meeting-room-booking/internal/notification/models"
+	outbox "github.com/optician/meeting-room-booking/internal/outbox/models"
+	"github.com/optician/meeting-room-booking/internal/outbox/publisher"
+	"go.uber.org/zap"
+)
+
+// Notifier turns booking cancellations and waitlist offers into notifications, it's an outbox publisher.
+// Events published twice are enqueued once.
+type Notifier struct {
+	logger *zap.SugaredLogger
+	db     *db.DB
+	now    func() time.Time
+}
+
+func MakeNotifier(db *db.DB, logger *zap.SugaredLogger) Notifier {
+	return Notifier{
+		logger: logger,
+		db:     db,
+		now:    time.Now,
+	}
+}
+
+func (notifier Notifier) Publish(ctx context.Context, events []outbox.Event) error {
+	notifications := make([]models.NewNotification, 0)
+	for _, notice := range publisher.Decode(events, models.NewNotice, notifier.logger) {
+		prepared, err := notifier.prepare(ctx, notice.EventId, notice.Recipients, notice.Message)
+		if err != nil {
+			return err
+		}
+		notifications = append(notifications, prepared...)
+	}
+	if len(notifications) == 0 {
+		return nil
+	}
+	_, err := (*notifier.db).Enqueue(ctx, notifications, notifier.now())
+	return err
+}
+
+// a notification per recipient and channel of their preference
+func (notifier Notifier) prepare(ctx context.Context, eventId int64, recipients []string, message models.Message) ([]models.NewNotification, error) {
+	stored, err := (*notifier.db).Preferences(ctx, recipients)
+	if err != nil {
+		return nil, err
+	}
+	preferences := make(map[string]models.Preference, len(stored))
+	for _, preference := range stored {
+		preferences[preference.Person] = preference
+	}
+
+	notifications := make([]models.NewNotification, 0, len(recipients))
+	for _, person := range recipients {
+		preference, ok := preferences[person]
+		if !ok {
+			preference = models.DefaultPreference(person)
+		}
+		for _, channel := range preference.Channels {
+			notifications = append(notifications, models.NewNotification{
+				EventId: eventId,
+				Person:  person,
+				Channel: channel,
+				Address: preference.Address(channel),
+				Message: message,
+			})
+		}
+	}
+	return notifications, nil
+}
+
+func (notifier Notifier) Close() error {
+	return nil
+}
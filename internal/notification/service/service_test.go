@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/optician/meeting-room-booking/internal/notification/channel"
+	"github.com/optician/meeting-room-booking/internal/notification/db"
+	"github.com/optician/meeting-room-booking/internal/notification/models"
+	outbox "github.com/optician/meeting-room-booking/internal/outbox/models"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var logger = zap.NewExample().Sugar()
+
+// overrides only methods used by a test, others panic
+type dbStub struct {
+	db.DB
+	preferences []models.Preference
+	enqueued    []models.NewNotification
+	due         []models.Notification
+	errs        []error
+}
+
+func (stub *dbStub) Preferences(ctx context.Context, persons []string) ([]models.Preference, error) {
+	return stub.preferences, nil
+}
+
+func (stub *dbStub) Enqueue(ctx context.Context, notifications []models.NewNotification, at time.Time) (int, error) {
+	stub.enqueued = append(stub.enqueued, notifications...)
+	return len(notifications), nil
+}
+
+func (stub *dbStub) Dispatch(ctx context.Context, limit int, at time.Time, policy *models.RetryPolicy, send func(context.Context, *models.Notification) error) (models.DispatchReport, error) {
+	var report models.DispatchReport
+	for i := range stub.due {
+		err := send(ctx, &stub.due[i])
+		stub.errs = append(stub.errs, err)
+		if err != nil {
+			report.Retried++
+		} else {
+			report.Sent++
+		}
+	}
+	return report, nil
+}
+
+func cancelledEvent(t *testing.T) outbox.Event {
+	booking := outbox.CancelledBooking{
+		BookingId:   uuid.New(),
+		RoomId:      uuid.New(),
+		Host:        "ivan",
+		Attendees:   []string{"petr", "olga"},
+		Start:       time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC),
+		End:         time.Date(2030, 1, 10, 15, 0, 0, 0, time.UTC),
+		CancelledBy: "olga",
+		Reason:      "room locked: flooded",
+	}
+	payload, err := json.Marshal(booking)
+	require.Nil(t, err)
+	return outbox.Event{Id: 42, Type: outbox.BookingCancelled, AggregateId: booking.BookingId.String(), Payload: payload}
+}
+
+func TestNotifierEnqueuesByPreferences(t *testing.T) {
+	email := "ivan@localhost"
+	stub := &dbStub{preferences: []models.Preference{
+		{Person: "ivan", Channels: []models.ChannelName{models.ChannelEmail, models.ChannelLog}, Email: &email},
+	}}
+	var repository db.DB = stub
+	notifier := MakeNotifier(&repository, logger)
+	created := outbox.Event{Id: 41, Type: outbox.BookingCreated, Payload: []byte("{}")}
+
+	err := notifier.Publish(context.Background(), []outbox.Event{created, cancelledEvent(t)})
+
+	require.Nil(t, err)
+	require.Len(t, stub.enqueued, 3)
+	require.Equal(t, "ivan", stub.enqueued[0].Person)
+	require.Equal(t, models.ChannelEmail, stub.enqueued[0].Channel)
+	require.Equal(t, email, stub.enqueued[0].Address)
+	require.Equal(t, models.ChannelLog, stub.enqueued[1].Channel)
+	// petr hasn't set a preference, olga cancelled the booking herself
+	require.Equal(t, "petr", stub.enqueued[2].Person)
+	require.Equal(t, models.ChannelLog, stub.enqueued[2].Channel)
+	require.Equal(t, int64(42), stub.enqueued[2].EventId)
+	require.Contains(t, stub.enqueued[2].Body, "room locked: flooded")
+}
+
+func TestNotifierNotifiesWaitlistOffer(t *testing.T) {
+	stub := &dbStub{}
+	var repository db.DB = stub
+	notifier := MakeNotifier(&repository, logger)
+	expiresAt := time.Date(2030, 1, 10, 13, 30, 0, 0, time.UTC)
+	offer := outbox.WaitlistOffer{
+		EntryId:   uuid.New(),
+		Person:    "olga",
+		BookingId: uuid.New(),
+		RoomId:    uuid.New(),
+		Start:     time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC),
+		End:       time.Date(2030, 1, 10, 15, 0, 0, 0, time.UTC),
+		ExpiresAt: &expiresAt,
+	}
+	payload, err := json.Marshal(offer)
+	require.Nil(t, err)
+	event := outbox.Event{Id: 43, Type: outbox.WaitlistOffered, AggregateId: offer.EntryId.String(), Payload: payload}
+
+	err = notifier.Publish(context.Background(), []outbox.Event{event})
+
+	require.Nil(t, err)
+	require.Len(t, stub.enqueued, 1)
+	require.Equal(t, "olga", stub.enqueued[0].Person)
+	require.Equal(t, models.ChannelLog, stub.enqueued[0].Channel)
+	require.Contains(t, stub.enqueued[0].Body, "held for you until 2030-01-10T13:30:00Z")
+}
+
+type channelStub struct {
+	err  error
+	sent []string
+}
+
+func (stub *channelStub) Send(ctx context.Context, address string, message models.Message) error {
+	stub.sent = append(stub.sent, address)
+	return stub.err
+}
+
+func TestDispatcherRoutesToChannels(t *testing.T) {
+	stub := &dbStub{due: []models.Notification{
+		{Id: 1, Channel: models.ChannelWebhook, Address: "http://localhost/hook"},
+		{Id: 2, Channel: models.ChannelEmail, Address: "ivan@localhost"},
+		{Id: 3, Channel: models.ChannelLog},
+	}}
+	var repository db.DB = stub
+	webhook := &channelStub{}
+	email := &channelStub{err: errors.New("relay is down")}
+	channels := map[models.ChannelName]channel.Channel{
+		models.ChannelWebhook: webhook,
+		models.ChannelEmail:   email,
+	}
+	config := Config{BatchSize: 10, MaxAttempts: 3, BackoffBase: time.Second, BackoffMax: time.Minute}
+	dispatcher := MakeDispatcher(&repository, channels, &config, logger)
+
+	report, err := dispatcher.DispatchOnce(context.Background())
+
+	require.Nil(t, err)
+	require.Equal(t, models.DispatchReport{Sent: 1, Retried: 2}, report)
+	require.Equal(t, []string{"http://localhost/hook"}, webhook.sent)
+	require.Equal(t, []string{"ivan@localhost"}, email.sent)
+	require.EqualError(t, stub.errs[2], `channel "log" isn't configured`)
+}
@@ -0,0 +1,156 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/optician/meeting-room-booking/internal/outbox/models"
+	"go.uber.org/zap"
+)
+
+// both a pool and a transaction
+type Execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// Write puts an event into the outbox, q is the transaction of the change the event is about
+func Write(ctx context.Context, q Execer, eventType models.EventType, aggregateId string, at time.Time, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	query := `insert into outbox (type, aggregate_id, payload, occurred_at)
+				values (@type, @aggregate_id, @payload, @occurred_at)`
+	args := pgx.NamedArgs{
+		"type":         eventType,
+		"aggregate_id": aggregateId,
+		"payload":      string(body),
+		"occurred_at":  at,
+	}
+	_, err = q.Exec(ctx, query, args)
+	return err
+}
+
+type DB interface {
+	// Relay hands up to limit events which the consumer hasn't got yet to publish in order.
+	// Events are taken for the lease before publish and publish runs outside of any transaction,
+	// a failed publish gives them back. Events taken by another relay of the consumer are skipped.
+	Relay(ctx context.Context, delivery *models.Delivery, limit int, publish func(context.Context, []models.Event) error, at time.Time) (int, error)
+}
+
+type impl struct {
+	logger *zap.SugaredLogger
+	dbpool *pgxpool.Pool
+}
+
+func New(dbPool *pgxpool.Pool, logger *zap.SugaredLogger) DB {
+	return &impl{
+		logger: logger,
+		dbpool: dbPool,
+	}
+}
+
+func (impl *impl) Relay(ctx context.Context, delivery *models.Delivery, limit int, publish func(context.Context, []models.Event) error, at time.Time) (int, error) {
+	until := at.Add(delivery.Lease)
+	events, err := impl.take(ctx, delivery.Consumer, limit, at, until)
+	if err != nil {
+		return 0, err // wrap error
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+	ids := make([]int64, len(events))
+	for i, event := range events {
+		ids[i] = event.Id
+	}
+
+	publishCtx, cancel := context.WithTimeout(ctx, delivery.Lease)
+	defer cancel()
+	if err := publish(publishCtx, events); err != nil {
+		// the next attempt starts from the same events, so they are still in order
+		if releaseErr := impl.release(ctx, delivery.Consumer, ids, until); releaseErr != nil {
+			impl.logger.Errorf("cannot give back events of %v: %v", delivery.Consumer, releaseErr)
+		}
+		return 0, err
+	}
+
+	// a failure here publishes the events again after the lease, so they are published at least once
+	if err := impl.delivered(ctx, delivery, ids, at, until); err != nil {
+		return 0, err // wrap error
+	}
+	return len(events), nil
+}
+
+// take leases the earliest events the consumer hasn't got, an expired lease of a crashed relay is taken over
+func (impl *impl) take(ctx context.Context, consumer string, limit int, at time.Time, until time.Time) ([]models.Event, error) {
+	query := `with taken as (
+					insert into outbox_deliveries (consumer, event_id, leased_until)
+					select @consumer, o.id, @until from outbox o
+					where o.published_at is null
+						and not exists (select 1 from outbox_deliveries d
+							where d.consumer = @consumer and d.event_id = o.id
+								and (d.delivered_at is not null or d.leased_until > @at))
+					order by o.id
+					limit @limit
+					on conflict (consumer, event_id) do update set leased_until = excluded.leased_until
+						where outbox_deliveries.delivered_at is null and outbox_deliveries.leased_until <= @at
+					returning event_id
+				)
+				select o.id, o.type, o.aggregate_id, o.payload, o.occurred_at from outbox o
+				join taken on taken.event_id = o.id
+				order by o.id`
+	args := pgx.NamedArgs{
+		"consumer": consumer,
+		"limit":    limit,
+		"at":       at,
+		"until":    until,
+	}
+	events := make([]models.Event, 0)
+	if err := pgxscan.Select(ctx, impl.dbpool, &events, query, args); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (impl *impl) release(ctx context.Context, consumer string, ids []int64, until time.Time) error {
+	query := `delete from outbox_deliveries
+				where consumer = @consumer and event_id = any(@ids) and leased_until = @until and delivered_at is null`
+	_, err := impl.dbpool.Exec(ctx, query, pgx.NamedArgs{"consumer": consumer, "ids": ids, "until": until})
+	return err
+}
+
+// delivered records the delivery and publishes events every consumer has got
+func (impl *impl) delivered(ctx context.Context, delivery *models.Delivery, ids []int64, at time.Time, until time.Time) error {
+	return pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		// relays of other consumers wait here, so the last of them sees every delivery
+		query := "select id from outbox where id = any(@ids) order by id for update"
+		if _, err := tx.Exec(ctx, query, pgx.NamedArgs{"ids": ids}); err != nil {
+			return err
+		}
+
+		query = `update outbox_deliveries set delivered_at = @at
+					where consumer = @consumer and event_id = any(@ids) and leased_until = @until`
+		args := pgx.NamedArgs{
+			"consumer": delivery.Consumer,
+			"ids":      ids,
+			"at":       at,
+			"until":    until,
+		}
+		if _, err := tx.Exec(ctx, query, args); err != nil {
+			return err
+		}
+
+		query = `update outbox set published_at = @at
+					where id = any(@ids) and published_at is null
+						and (select count(*) from outbox_deliveries d
+							where d.event_id = outbox.id and d.delivered_at is not null and d.consumer = any(@consumers)
+						) = cardinality(@consumers::text[])`
+		_, err := tx.Exec(ctx, query, pgx.NamedArgs{"ids": ids, "at": at, "consumers": delivery.Consumers})
+		return err
+	})
+}
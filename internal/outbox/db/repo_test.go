@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	testHelpers "github.com/optician/meeting-room-booking/internal/administration/db/testing"
+	"github.com/optician/meeting-room-booking/internal/dbPool"
+	"github.com/optician/meeting-room-booking/internal/outbox/models"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"go.uber.org/zap"
+)
+
+type OutboxRepositoryTestSuite struct {
+	suite.Suite
+	pgContainer *postgres.PostgresContainer
+	pool        *pgxpool.Pool
+	repository  *DB
+	ctx         context.Context
+	logger      *zap.SugaredLogger
+}
+
+func (suite *OutboxRepositoryTestSuite) SetupTest() {
+	ctx := context.Background()
+	logger := zap.NewExample().Sugar()
+	container, err := testHelpers.CreatePostgresContainer(ctx)
+	if err != nil {
+		logger.Fatalf("cannot setup postgres container in OutboxRepositoryTestSuite, %v", err)
+	}
+	migrationsPath := "../../../migrations/" // better to use env instead
+	if err := testHelpers.Migrate(ctx, container.ConnectionString, migrationsPath); err != nil {
+		logger.Fatalf("%v", err)
+	}
+
+	config := dbPool.DBConfig{Url: container.ConnectionString}
+	dbPool, dbPoolErr := dbPool.NewDBPool(&config, logger)
+	if dbPoolErr != nil {
+		logger.Fatalf("application terminated: %v", dbPoolErr)
+	}
+
+	outboxDB := New(dbPool.GetPool(), logger)
+
+	suite.pgContainer = container.Container
+	suite.pool = dbPool.GetPool()
+	suite.repository = &outboxDB
+	suite.ctx = ctx
+	suite.logger = logger
+}
+
+func (suite *OutboxRepositoryTestSuite) TearDownTest() {
+	if err := suite.pgContainer.Terminate(suite.ctx); err != nil {
+		suite.logger.Fatalf("error terminating postgres container: %s", err)
+	}
+}
+
+var broker = models.Delivery{Consumer: "broker", Consumers: []string{"broker"}, Lease: time.Minute}
+
+func (suite *OutboxRepositoryTestSuite) TestRelayPublishesOnce() {
+	at := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	err := pgx.BeginFunc(suite.ctx, suite.pool, func(tx pgx.Tx) error {
+		if err := Write(suite.ctx, tx, models.RoomCreated, "a", at, map[string]string{"name": "Belyash"}); err != nil {
+			return err
+		}
+		return Write(suite.ctx, tx, models.RoomDeleted, "a", at, map[string]string{"id": "a"})
+	})
+	require.Nil(suite.T(), err, "Write error")
+
+	published := make([]models.Event, 0)
+	publish := func(ctx context.Context, events []models.Event) error {
+		published = append(published, events...)
+		return nil
+	}
+	count, err := (*suite.repository).Relay(suite.ctx, &broker, 10, publish, at)
+	require.Nil(suite.T(), err, "Relay error")
+	require.Equal(suite.T(), 2, count)
+	require.Equal(suite.T(), models.RoomCreated, published[0].Type)
+	require.JSONEq(suite.T(), `{"name":"Belyash"}`, string(published[0].Payload))
+	require.Equal(suite.T(), models.RoomDeleted, published[1].Type)
+
+	count, err = (*suite.repository).Relay(suite.ctx, &broker, 10, publish, at)
+	require.Nil(suite.T(), err, "Relay error")
+	require.Equal(suite.T(), 0, count)
+}
+
+func (suite *OutboxRepositoryTestSuite) TestFailedPublishKeepsEvents() {
+	at := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	err := Write(suite.ctx, suite.pool, models.RoomCreated, "a", at, map[string]string{"name": "Belyash"})
+	require.Nil(suite.T(), err, "Write error")
+
+	failing := func(ctx context.Context, events []models.Event) error { return errors.New("broker is down") }
+	_, err = (*suite.repository).Relay(suite.ctx, &broker, 10, failing, at)
+	require.EqualError(suite.T(), err, "broker is down")
+
+	succeeding := func(ctx context.Context, events []models.Event) error { return nil }
+	count, err := (*suite.repository).Relay(suite.ctx, &broker, 10, succeeding, at)
+	require.Nil(suite.T(), err, "Relay error")
+	require.Equal(suite.T(), 1, count)
+}
+
+func (suite *OutboxRepositoryTestSuite) TestConsumersAreRelayedApart() {
+	at := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	err := Write(suite.ctx, suite.pool, models.RoomCreated, "a", at, map[string]string{"name": "Belyash"})
+	require.Nil(suite.T(), err, "Write error")
+
+	consumers := []string{"broker", "notifier"}
+	broker := models.Delivery{Consumer: "broker", Consumers: consumers, Lease: time.Minute}
+	notifier := models.Delivery{Consumer: "notifier", Consumers: consumers, Lease: time.Minute}
+	failing := func(ctx context.Context, events []models.Event) error { return errors.New("broker is down") }
+	succeeding := func(ctx context.Context, events []models.Event) error { return nil }
+
+	_, err = (*suite.repository).Relay(suite.ctx, &broker, 10, failing, at)
+	require.EqualError(suite.T(), err, "broker is down")
+	count, err := (*suite.repository).Relay(suite.ctx, &notifier, 10, succeeding, at)
+	require.Nil(suite.T(), err, "Relay error")
+	require.Equal(suite.T(), 1, count)
+	count, err = (*suite.repository).Relay(suite.ctx, &notifier, 10, succeeding, at)
+	require.Nil(suite.T(), err, "Relay error")
+	require.Equal(suite.T(), 0, count)
+	suite.requirePublished(false)
+
+	count, err = (*suite.repository).Relay(suite.ctx, &broker, 10, succeeding, at)
+	require.Nil(suite.T(), err, "Relay error")
+	require.Equal(suite.T(), 1, count)
+	suite.requirePublished(true)
+}
+
+func (suite *OutboxRepositoryTestSuite) TestExpiredLeaseIsTakenOver() {
+	at := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	err := Write(suite.ctx, suite.pool, models.RoomCreated, "a", at, map[string]string{"name": "Belyash"})
+	require.Nil(suite.T(), err, "Write error")
+
+	// a relay crashed while publishing, it never gives the event back
+	query := "insert into outbox_deliveries (consumer, event_id, leased_until) select 'broker', id, $1 from outbox"
+	_, err = suite.pool.Exec(suite.ctx, query, at.Add(time.Minute))
+	require.Nil(suite.T(), err)
+
+	succeeding := func(ctx context.Context, events []models.Event) error { return nil }
+	count, err := (*suite.repository).Relay(suite.ctx, &broker, 10, succeeding, at)
+	require.Nil(suite.T(), err, "Relay error")
+	require.Equal(suite.T(), 0, count)
+
+	count, err = (*suite.repository).Relay(suite.ctx, &broker, 10, succeeding, at.Add(time.Minute))
+	require.Nil(suite.T(), err, "Relay error")
+	require.Equal(suite.T(), 1, count)
+	suite.requirePublished(true)
+}
+
+func (suite *OutboxRepositoryTestSuite) requirePublished(published bool) {
+	var publishedAt *time.Time
+	err := suite.pool.QueryRow(suite.ctx, "select published_at from outbox").Scan(&publishedAt)
+	require.Nil(suite.T(), err)
+	require.Equal(suite.T(), published, publishedAt != nil)
+}
+
+func TestOutboxRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(OutboxRepositoryTestSuite))
+}
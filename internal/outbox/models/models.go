@@ -0,0 +1,78 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EventType string
+
+// the payload of room events is the room, of lock events the lock and of booking events the booking,
+// except cancellations which carry CancelledBooking whatever the cause and deletions carrying DeletedRoom.
+// An updated booking has its state before the change in "before".
+const (
+	RoomCreated      EventType = "room_created"
+	RoomUpdated      EventType = "room_updated"
+	RoomDeleted      EventType = "room_deleted"
+	RoomLocked       EventType = "room_locked"
+	RoomUnlocked     EventType = "room_unlocked"
+	BookingCreated   EventType = "booking_created"
+	BookingUpdated   EventType = "booking_updated"
+	BookingCancelled EventType = "booking_cancelled"
+	BookingCheckedIn EventType = "booking_checked_in"
+	// a hold became a real booking
+	BookingConfirmed EventType = "booking_confirmed"
+	// carries CancelledBooking, the slot of the hold is free
+	BookingHoldExpired EventType = "booking_hold_expired"
+	// carries WaitlistOffer, a freed slot is held or booked for a waitlisted person
+	WaitlistOffered EventType = "waitlist_offered"
+)
+
+// Event is a domain event in the outbox, events of the same aggregate are published in order
+type Event struct {
+	Id          int64           `json:"id"`
+	Type        EventType       `json:"type"`
+	AggregateId string          `json:"aggregateId"`
+	OccurredAt  time.Time       `json:"occurredAt"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+// CancelledBooking is shared by the administration and the booking modules,
+// a room lock cancels bookings as well as hosts, preemption and the no-show releaser
+type CancelledBooking struct {
+	BookingId   uuid.UUID `json:"bookingId" db:"id"`
+	RoomId      uuid.UUID `json:"roomId"`
+	Host        string    `json:"host"`
+	Attendees   []string  `json:"attendees"`
+	Start       time.Time `json:"start" db:"start_at"`
+	End         time.Time `json:"end" db:"end_at"`
+	CancelledBy string    `json:"cancelledBy"`
+	Reason      string    `json:"reason" db:"cancel_reason"`
+}
+
+// WaitlistOffer tells a waitlisted person about a booking made for them.
+// ExpiresAt is set if the booking is a hold to confirm, it's empty if the slot is assigned.
+type WaitlistOffer struct {
+	EntryId   uuid.UUID  `json:"entryId"`
+	Person    string     `json:"person"`
+	BookingId uuid.UUID  `json:"bookingId"`
+	RoomId    uuid.UUID  `json:"roomId"`
+	Start     time.Time  `json:"start"`
+	End       time.Time  `json:"end"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+type DeletedRoom struct {
+	Id uuid.UUID `json:"id"`
+}
+
+// Delivery is how the relay of one consumer takes events
+type Delivery struct {
+	Consumer string
+	// all consumers, an event delivered to every one of them is published
+	Consumers []string
+	// how long a relay owns the events it took, a crashed relay leaves them to another one after that
+	Lease time.Duration
+}
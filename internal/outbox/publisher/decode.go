@@ -0,0 +1,23 @@
+package publisher
+
+import (
+	"github.com/optician/meeting-room-booking/internal/outbox/models"
+	"go.uber.org/zap"
+)
+
+// Decode reads the events a consumer is interested in, ok is false for the others.
+// A malformed event is logged and skipped, it would be relayed to the consumer again and again otherwise.
+func Decode[T any](events []models.Event, decode func(event *models.Event) (T, bool, error), logger *zap.SugaredLogger) []T {
+	decoded := make([]T, 0, len(events))
+	for i := range events {
+		value, ok, err := decode(&events[i])
+		if err != nil {
+			logger.Errorf("skipped malformed event: %v", err)
+			continue
+		}
+		if ok {
+			decoded = append(decoded, value)
+		}
+	}
+	return decoded
+}
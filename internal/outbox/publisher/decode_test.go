@@ -0,0 +1,33 @@
+package publisher
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/optician/meeting-room-booking/internal/outbox/models"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDecodeSkipsMalformedAndOtherEvents(t *testing.T) {
+	events := []models.Event{
+		{Id: 1, Type: models.RoomCreated, Payload: json.RawMessage(`{"name":"Belyash"}`)},
+		{Id: 2, Type: models.RoomDeleted, Payload: json.RawMessage(`{"name":`)},
+		{Id: 3, Type: models.RoomCreated, Payload: json.RawMessage(`{"name":"Chebupelya"}`)},
+		{Id: 4, Type: models.RoomLocked, Payload: json.RawMessage(`{}`)},
+	}
+	name := func(event *models.Event) (string, bool, error) {
+		if event.Type == models.RoomLocked {
+			return "", false, nil
+		}
+		var payload struct {
+			Name string `json:"name"`
+		}
+		err := json.Unmarshal(event.Payload, &payload)
+		return payload.Name, err == nil, err
+	}
+
+	actual := Decode(events, name, zap.NewExample().Sugar())
+
+	require.Equal(t, []string{"Belyash", "Chebupelya"}, actual)
+}
@@ -0,0 +1,46 @@
+package publisher
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/optician/meeting-room-booking/internal/outbox/models"
+)
+
+// File appends events to a JSON Lines file, one event per line
+type File struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFile(path string) (*File, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &File{file: file}, nil
+}
+
+func (publisher *File) Publish(ctx context.Context, events []models.Event) error {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	writer := bufio.NewWriter(publisher.file)
+	encoder := json.NewEncoder(writer)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return publisher.file.Sync()
+}
+
+func (publisher *File) Close() error {
+	return publisher.file.Close()
+}
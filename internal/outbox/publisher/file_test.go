@@ -0,0 +1,48 @@
+package publisher
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/optician/meeting-room-booking/internal/outbox/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileAppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	events := []models.Event{
+		{Id: 1, Type: models.RoomCreated, AggregateId: "a", OccurredAt: time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC), Payload: json.RawMessage(`{"name":"Belyash"}`)},
+		{Id: 2, Type: models.RoomDeleted, AggregateId: "a", OccurredAt: time.Date(2030, 1, 10, 15, 0, 0, 0, time.UTC), Payload: json.RawMessage(`{"id":"a"}`)},
+	}
+
+	// the second publisher appends to the file of the first one
+	for _, event := range events {
+		publisher, err := NewFile(path)
+		require.Nil(t, err)
+		require.Nil(t, publisher.Publish(context.Background(), []models.Event{event}))
+		require.Nil(t, publisher.Close())
+	}
+
+	file, err := os.Open(path)
+	require.Nil(t, err)
+	defer file.Close()
+	actual := make([]models.Event, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event models.Event
+		require.Nil(t, json.Unmarshal(scanner.Bytes(), &event))
+		actual = append(actual, event)
+	}
+	require.Equal(t, events, actual)
+}
+
+func TestUnknownPublisherKind(t *testing.T) {
+	_, err := New(&Config{Kind: "carrier-pigeon"}, nil)
+
+	require.EqualError(t, err, `unknown publisher kind "carrier-pigeon"`)
+}
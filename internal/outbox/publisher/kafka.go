@@ -0,0 +1,46 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/optician/meeting-room-booking/internal/outbox/models"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// Kafka keys messages by aggregate id, so events of a room or a booking stay in one partition and in order
+type Kafka struct {
+	writer *kafka.Writer
+}
+
+func NewKafka(brokers []string, topic string, logger *zap.SugaredLogger) *Kafka {
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		ErrorLogger:  kafka.LoggerFunc(logger.Errorf),
+	}
+	return &Kafka{writer: writer}
+}
+
+func (publisher *Kafka) Publish(ctx context.Context, events []models.Event) error {
+	messages := make([]kafka.Message, len(events))
+	for i, event := range events {
+		value, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		messages[i] = kafka.Message{
+			Key:     []byte(event.AggregateId),
+			Value:   value,
+			Headers: []kafka.Header{{Key: "type", Value: []byte(event.Type)}},
+		}
+	}
+	return publisher.writer.WriteMessages(ctx, messages...)
+}
+
+func (publisher *Kafka) Close() error {
+	return publisher.writer.Close()
+}
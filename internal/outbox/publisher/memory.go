@@ -0,0 +1,37 @@
+package publisher
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/optician/meeting-room-booking/internal/outbox/models"
+)
+
+// Memory keeps published events, it's meant for tests and local runs
+type Memory struct {
+	mu     sync.Mutex
+	events []models.Event
+}
+
+func NewMemory() *Memory {
+	return &Memory{events: make([]models.Event, 0)}
+}
+
+func (memory *Memory) Publish(ctx context.Context, events []models.Event) error {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+	memory.events = append(memory.events, events...)
+	return nil
+}
+
+// Events returns a copy of everything published so far
+func (memory *Memory) Events() []models.Event {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+	return slices.Clone(memory.events)
+}
+
+func (memory *Memory) Close() error {
+	return nil
+}
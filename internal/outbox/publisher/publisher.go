@@ -0,0 +1,45 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/optician/meeting-room-booking/internal/outbox/models"
+	"go.uber.org/zap"
+)
+
+// Publisher delivers events to consumers, e.g. a DWH
+type Publisher interface {
+	// either all events are delivered or an error is returned, a retry may deliver some of them twice
+	Publish(ctx context.Context, events []models.Event) error
+	Close() error
+}
+
+type Kind string
+
+const (
+	KindKafka  Kind = "kafka"
+	KindFile   Kind = "file"
+	KindMemory Kind = "memory"
+)
+
+type Config struct {
+	Kind Kind `koanf:"kind"`
+	// JSON Lines file for the file publisher
+	File    string   `koanf:"file"`
+	Brokers []string `koanf:"brokers"`
+	Topic   string   `koanf:"topic"`
+}
+
+func New(config *Config, logger *zap.SugaredLogger) (Publisher, error) {
+	switch config.Kind {
+	case KindKafka:
+		return NewKafka(config.Brokers, config.Topic, logger), nil
+	case KindFile:
+		return NewFile(config.File)
+	case KindMemory:
+		return NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unknown publisher kind %q", config.Kind)
+	}
+}
@@ -0,0 +1,17 @@
+package service
+
+import (
+	"time"
+
+	"github.com/optician/meeting-room-booking/internal/outbox/publisher"
+)
+
+type Config struct {
+	// how often the relay looks for unpublished events
+	RelayInterval time.Duration `koanf:"relay_interval"`
+	// the most events published at once
+	BatchSize int `koanf:"batch_size"`
+	// how long a relay may publish a batch before another one takes it over
+	Lease     time.Duration    `koanf:"lease"`
+	Publisher publisher.Config `koanf:"publisher"`
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/optician/meeting-room-booking/internal/outbox/db"
+	"github.com/optician/meeting-room-booking/internal/outbox/models"
+	"github.com/optician/meeting-room-booking/internal/outbox/publisher"
+	"go.uber.org/zap"
+)
+
+// Relay publishes events written to the outbox by repositories to one consumer
+type Relay struct {
+	logger    *zap.SugaredLogger
+	db        *db.DB
+	delivery  models.Delivery
+	publisher publisher.Publisher
+	config    *Config
+	now       func() time.Time
+}
+
+// MakeRelays makes a relay of every consumer, each of them retries only its own failures
+func MakeRelays(db *db.DB, consumers map[string]publisher.Publisher, config *Config, logger *zap.SugaredLogger) []Relay {
+	names := make([]string, 0, len(consumers))
+	for name := range consumers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	relays := make([]Relay, len(names))
+	for i, name := range names {
+		relays[i] = Relay{
+			logger:    logger,
+			db:        db,
+			delivery:  models.Delivery{Consumer: name, Consumers: names, Lease: config.Lease},
+			publisher: consumers[name],
+			config:    config,
+			now:       time.Now,
+		}
+	}
+	return relays
+}
+
+// Run blocks until ctx is done
+func (relay Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(relay.config.RelayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := relay.Drain(ctx); err != nil {
+				relay.logger.Errorf("outbox relay of %v failed: %v", relay.delivery.Consumer, err)
+			}
+		}
+	}
+}
+
+// RelayOnce publishes a single batch and returns its size
+func (relay Relay) RelayOnce(ctx context.Context) (int, error) {
+	return (*relay.db).Relay(ctx, &relay.delivery, relay.config.BatchSize, relay.publisher.Publish, relay.now())
+}
+
+// Drain publishes batches until the outbox is empty, returns the number of published events
+func (relay Relay) Drain(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		published, err := relay.RelayOnce(ctx)
+		total += published
+		if err != nil || published < relay.config.BatchSize {
+			return total, err
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/optician/meeting-room-booking/internal/outbox/db"
+	"github.com/optician/meeting-room-booking/internal/outbox/models"
+	"github.com/optician/meeting-room-booking/internal/outbox/publisher"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// keeps pending events of every consumer in memory, a failed publish leaves them pending
+type dbStub struct {
+	pending map[string][]models.Event
+}
+
+func newDBStub(events []models.Event, consumers ...string) *dbStub {
+	stub := &dbStub{pending: make(map[string][]models.Event)}
+	for _, consumer := range consumers {
+		stub.pending[consumer] = events
+	}
+	return stub
+}
+
+func (stub *dbStub) Relay(ctx context.Context, delivery *models.Delivery, limit int, publish func(context.Context, []models.Event) error, at time.Time) (int, error) {
+	pending := stub.pending[delivery.Consumer]
+	batch := pending[:min(limit, len(pending))]
+	if len(batch) == 0 {
+		return 0, nil
+	}
+	if err := publish(ctx, batch); err != nil {
+		return 0, err
+	}
+	stub.pending[delivery.Consumer] = pending[len(batch):]
+	return len(batch), nil
+}
+
+func pendingEvents(count int) []models.Event {
+	events := make([]models.Event, count)
+	for i := range events {
+		events[i] = models.Event{Id: int64(i + 1), Type: models.RoomCreated, AggregateId: "room", Payload: []byte("{}")}
+	}
+	return events
+}
+
+type failingPublisher struct{}
+
+func (failingPublisher) Publish(ctx context.Context, events []models.Event) error {
+	return errors.New("broker is down")
+}
+
+func (failingPublisher) Close() error {
+	return nil
+}
+
+func TestDrainPublishesEveryBatch(t *testing.T) {
+	var repository db.DB = newDBStub(pendingEvents(5), "memory")
+	memory := publisher.NewMemory()
+	config := Config{RelayInterval: time.Second, BatchSize: 2, Lease: time.Minute}
+	consumers := map[string]publisher.Publisher{"memory": memory}
+	relays := MakeRelays(&repository, consumers, &config, zap.NewExample().Sugar())
+
+	published, err := relays[0].Drain(context.Background())
+
+	require.Nil(t, err)
+	require.Equal(t, 5, published)
+	require.Equal(t, pendingEvents(5), memory.Events())
+}
+
+func TestFailedPublishKeepsEventsPending(t *testing.T) {
+	stub := newDBStub(pendingEvents(3), "broker")
+	var repository db.DB = stub
+	config := Config{RelayInterval: time.Second, BatchSize: 2, Lease: time.Minute}
+	consumers := map[string]publisher.Publisher{"broker": failingPublisher{}}
+	relays := MakeRelays(&repository, consumers, &config, zap.NewExample().Sugar())
+
+	published, err := relays[0].Drain(context.Background())
+
+	require.EqualError(t, err, "broker is down")
+	require.Equal(t, 0, published)
+	require.Len(t, stub.pending["broker"], 3)
+}
+
+func TestFailingConsumerDoesNotHoldUpOthers(t *testing.T) {
+	stub := newDBStub(pendingEvents(3), "broker", "memory")
+	var repository db.DB = stub
+	memory := publisher.NewMemory()
+	config := Config{RelayInterval: time.Second, BatchSize: 2, Lease: time.Minute}
+	consumers := map[string]publisher.Publisher{"broker": failingPublisher{}, "memory": memory}
+	relays := MakeRelays(&repository, consumers, &config, zap.NewExample().Sugar())
+
+	_, brokerErr := relays[0].Drain(context.Background())
+	published, memoryErr := relays[1].Drain(context.Background())
+
+	require.EqualError(t, brokerErr, "broker is down")
+	require.Nil(t, memoryErr)
+	require.Equal(t, 3, published)
+	require.Equal(t, pendingEvents(3), memory.Events())
+	require.Len(t, stub.pending["broker"], 3)
+}
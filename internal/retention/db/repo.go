@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/optician/meeting-room-booking/internal/retention/models"
+	"go.uber.org/zap"
+)
+
+type DB interface {
+	// Purge deletes up to limit rows older than cutoff in a transaction and returns their number.
+	// archive gets the deleted rows before the commit, the deletion is rolled back if it fails.
+	Purge(ctx context.Context, policy *models.Policy, cutoff time.Time, limit int, archive func([]json.RawMessage) error) (int, error)
+	// Log adds reservations to the reservation log, logged events are skipped
+	Log(ctx context.Context, reservations []models.Reservation) error
+}
+
+type impl struct {
+	logger *zap.SugaredLogger
+	dbpool *pgxpool.Pool
+}
+
+func New(dbPool *pgxpool.Pool, logger *zap.SugaredLogger) DB {
+	return &impl{
+		logger: logger,
+		dbpool: dbPool,
+	}
+}
+
+func (impl *impl) Purge(ctx context.Context, policy *models.Policy, cutoff time.Time, limit int, archive func([]json.RawMessage) error) (int, error) {
+	keep := "false"
+	if policy.Keep != "" {
+		keep = policy.Keep
+	}
+	// tables and columns come from models.Policies, not from users
+	query := fmt.Sprintf(`delete from %[1]v t
+				where t.ctid in (
+					select ctid from %[1]v
+					where %[2]v < @cutoff and not (%[3]v)
+					limit @limit
+					for update skip locked
+				)
+				returning to_jsonb(t)`, policy.Table, policy.AgeColumn, keep)
+
+	removed := 0
+	err := pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		rows, _ := tx.Query(ctx, query, pgx.NamedArgs{"cutoff": cutoff, "limit": limit})
+		deleted, err := pgx.CollectRows(rows, pgx.RowTo[json.RawMessage])
+		if err != nil || len(deleted) == 0 {
+			return err
+		}
+		removed = len(deleted)
+		if archive != nil {
+			return archive(deleted)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err // wrap error
+	}
+	return removed, nil
+}
+
+func (impl *impl) Log(ctx context.Context, reservations []models.Reservation) error {
+	return pgx.BeginFunc(ctx, impl.dbpool, func(tx pgx.Tx) error {
+		// the room snapshot is taken while the room exists
+		query := `insert into reservation_log
+						(event_id, type, booking_id, room_id, host, start_at, end_at, occurred_at,
+						 room_name, office, capacity, attendees, checked_in, cancelled_at, cancel_reason)
+					values (@event_id, @type::text, @booking_id, @room_id, @host, @start_at, @end_at, @occurred_at,
+						(select name from meeting_rooms where id = @room_id),
+						(select office from meeting_rooms where id = @room_id),
+						(select capacity from meeting_rooms where id = @room_id),
+						@attendees, @checked_in, @cancelled_at, @cancel_reason)
+					on conflict (event_id) do nothing`
+		for _, reservation := range reservations {
+			args := pgx.NamedArgs{
+				"event_id":      reservation.EventId,
+				"type":          reservation.Type,
+				"booking_id":    reservation.BookingId,
+				"room_id":       reservation.RoomId,
+				"host":          reservation.Host,
+				"start_at":      reservation.Start,
+				"end_at":        reservation.End,
+				"occurred_at":   reservation.OccurredAt,
+				"attendees":     reservation.Attendees,
+				"checked_in":    reservation.CheckedIn,
+				"cancelled_at":  reservation.CancelledAt,
+				"cancel_reason": reservation.CancelReason,
+			}
+			if _, err := tx.Exec(ctx, query, args); err != nil {
+				return err
+			}
+		}
+		return nil
+	}) // wrap error
+}
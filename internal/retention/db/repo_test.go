@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	testHelpers "github.com/optician/meeting-room-booking/internal/administration/db/testing"
+	"github.com/optician/meeting-room-booking/internal/dbPool"
+	outbox "github.com/optician/meeting-room-booking/internal/outbox/models"
+	"github.com/optician/meeting-room-booking/internal/retention/models"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"go.uber.org/zap"
+)
+
+type RetentionRepositoryTestSuite struct {
+	suite.Suite
+	pgContainer *postgres.PostgresContainer
+	pool        *pgxpool.Pool
+	repository  *DB
+	ctx         context.Context
+	logger      *zap.SugaredLogger
+}
+
+func (suite *RetentionRepositoryTestSuite) SetupTest() {
+	ctx := context.Background()
+	logger := zap.NewExample().Sugar()
+	container, err := testHelpers.CreatePostgresContainer(ctx)
+	if err != nil {
+		logger.Fatalf("cannot setup postgres container in RetentionRepositoryTestSuite, %v", err)
+	}
+	migrationsPath := "../../../migrations/" // better to use env instead
+	if err := testHelpers.Migrate(ctx, container.ConnectionString, migrationsPath); err != nil {
+		logger.Fatalf("%v", err)
+	}
+	if err := testHelpers.CreateOffices(ctx, container.ConnectionString, "FoodCourt"); err != nil {
+		logger.Fatalf("%v", err)
+	}
+
+	config := dbPool.DBConfig{Url: container.ConnectionString}
+	dbPool, dbPoolErr := dbPool.NewDBPool(&config, logger)
+	if dbPoolErr != nil {
+		logger.Fatalf("application terminated: %v", dbPoolErr)
+	}
+
+	retentionDB := New(dbPool.GetPool(), logger)
+
+	suite.pgContainer = container.Container
+	suite.pool = dbPool.GetPool()
+	suite.repository = &retentionDB
+	suite.ctx = ctx
+	suite.logger = logger
+}
+
+func (suite *RetentionRepositoryTestSuite) TearDownTest() {
+	if err := suite.pgContainer.Terminate(suite.ctx); err != nil {
+		suite.logger.Fatalf("error terminating postgres container: %s", err)
+	}
+}
+
+func (suite *RetentionRepositoryTestSuite) TestPurgeOldBookings() {
+	roomId := uuid.New()
+	_, err := suite.pool.Exec(suite.ctx, "insert into meeting_rooms (id, name, capacity, office, stage, labels) values ($1, 'Belyash', 6, 'FoodCourt', 1, '{}')", roomId)
+	require.Nil(suite.T(), err, "room creation error")
+	at := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	insert := "insert into bookings (id, room_id, host, start_at, end_at) values ($1, $2, 'ivan', $3, $4)"
+	for i := 1; i <= 3; i++ {
+		_, err = suite.pool.Exec(suite.ctx, insert, uuid.New(), roomId, at.Add(-time.Duration(i)*24*time.Hour), at.Add(-time.Duration(i)*24*time.Hour+time.Hour))
+		require.Nil(suite.T(), err, "booking error")
+	}
+	recent := uuid.New()
+	_, err = suite.pool.Exec(suite.ctx, insert, recent, roomId, at, at.Add(time.Hour))
+	require.Nil(suite.T(), err, "booking error")
+
+	policy := models.Policies["bookings"]
+	archived := make([]json.RawMessage, 0)
+	archive := func(rows []json.RawMessage) error {
+		archived = append(archived, rows...)
+		return nil
+	}
+	removed, err := (*suite.repository).Purge(suite.ctx, &policy, at.Add(-time.Hour), 2, archive)
+	require.Nil(suite.T(), err, "Purge error")
+	require.Equal(suite.T(), 2, removed, "a batch is bounded")
+	removed, err = (*suite.repository).Purge(suite.ctx, &policy, at.Add(-time.Hour), 2, archive)
+	require.Nil(suite.T(), err, "Purge error")
+	require.Equal(suite.T(), 1, removed)
+	require.Len(suite.T(), archived, 3)
+	require.Contains(suite.T(), string(archived[0]), `"host": "ivan"`)
+
+	rows, _ := suite.pool.Query(suite.ctx, "select id from bookings")
+	left, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	require.Nil(suite.T(), err, "bookings error")
+	require.Equal(suite.T(), []uuid.UUID{recent}, left)
+}
+
+func (suite *RetentionRepositoryTestSuite) TestFailedArchiveKeepsRows() {
+	at := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	_, err := suite.pool.Exec(suite.ctx, "insert into outbox (type, aggregate_id, payload, occurred_at, published_at) values ('room_created', 'room', '{}', $1, $1)", at.Add(-48*time.Hour))
+	require.Nil(suite.T(), err, "outbox error")
+	_, err = suite.pool.Exec(suite.ctx, "insert into outbox (type, aggregate_id, payload, occurred_at) values ('room_created', 'room', '{}', $1)", at.Add(-48*time.Hour))
+	require.Nil(suite.T(), err, "outbox error")
+
+	policy := models.Policies["outbox"]
+	_, err = (*suite.repository).Purge(suite.ctx, &policy, at, 10, func([]json.RawMessage) error { return errors.New("disk is full") })
+	require.NotNil(suite.T(), err)
+
+	removed, err := (*suite.repository).Purge(suite.ctx, &policy, at, 10, nil)
+	require.Nil(suite.T(), err, "Purge error")
+	require.Equal(suite.T(), 1, removed, "unpublished events stay")
+}
+
+func (suite *RetentionRepositoryTestSuite) TestLogReservationsOnce() {
+	reservation := models.Reservation{
+		EventId:    1,
+		Type:       outbox.BookingCreated,
+		BookingId:  uuid.New(),
+		RoomId:     uuid.New(),
+		Host:       "ivan",
+		Start:      time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC),
+		End:        time.Date(2030, 1, 10, 15, 0, 0, 0, time.UTC),
+		OccurredAt: time.Date(2030, 1, 9, 14, 0, 0, 0, time.UTC),
+	}
+	require.Nil(suite.T(), (*suite.repository).Log(suite.ctx, []models.Reservation{reservation}))
+	require.Nil(suite.T(), (*suite.repository).Log(suite.ctx, []models.Reservation{reservation}), "a redelivered event is skipped")
+
+	var logged int
+	err := suite.pool.QueryRow(suite.ctx, "select count(*) from reservation_log where booking_id = $1", reservation.BookingId).Scan(&logged)
+	require.Nil(suite.T(), err, "reservation log error")
+	require.Equal(suite.T(), 1, logged)
+}
+
+func TestRetentionRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(RetentionRepositoryTestSuite))
+}
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	outbox "github.com/optician/meeting-room-booking/internal/outbox/models"
+)
+
+// Policy tells which rows of a table are old, rows older than the cutoff are deleted
+type Policy struct {
+	Table string
+	// when a row became old, e.g. the end of a booking. A row with null is never old.
+	AgeColumn string
+	// rows which are never deleted whatever their age, e.g. undelivered notifications
+	Keep string
+}
+
+// Policies are the tables the retention worker can clean up
+var Policies = map[string]Policy{
+	"bookings":        {Table: "bookings", AgeColumn: "end_at"},
+	"booking_events":  {Table: "booking_events", AgeColumn: "occurred_at"},
+	"reservation_log": {Table: "reservation_log", AgeColumn: "occurred_at"},
+	"outbox":          {Table: "outbox", AgeColumn: "published_at"},
+	"notifications":   {Table: "notifications", AgeColumn: "created_at", Keep: "status = 'pending'"},
+	"room_locks":      {Table: "room_locks", AgeColumn: "unlocked_at"},
+	"agenda_images":   {Table: "agenda_images", AgeColumn: "requested_at"},
+	"waitlist":        {Table: "waitlist", AgeColumn: "end_at"},
+}
+
+// Retention is how long rows of a table are kept
+type Retention struct {
+	Policy Policy
+	Period time.Duration
+}
+
+func ValidateRetention(table string, period time.Duration) (Retention, error) {
+	policy, ok := Policies[table]
+	if !ok {
+		return Retention{}, fmt.Errorf("retention of unknown table %q", table)
+	}
+	if period <= 0 {
+		return Retention{}, fmt.Errorf("retention of %q must be positive", table)
+	}
+	return Retention{Policy: policy, Period: period}, nil
+}
+
+type TableReport struct {
+	Table   string `json:"table"`
+	Removed int    `json:"removed"`
+	// empty if rows are not archived or nothing is removed
+	Archive string `json:"archive,omitempty"`
+}
+
+type Report struct {
+	At     time.Time     `json:"at"`
+	Tables []TableReport `json:"tables"`
+}
+
+func (report *Report) Removed() int {
+	removed := 0
+	for _, table := range report.Tables {
+		removed += table.Removed
+	}
+	return removed
+}
+
+// Reservation is an entry of the reservation log, the state of the booking after the event.
+// Analytics reads the latest state of every booking from the log.
+type Reservation struct {
+	EventId      int64            `json:"eventId"`
+	Type         outbox.EventType `json:"type"`
+	BookingId    uuid.UUID        `json:"bookingId"`
+	RoomId       uuid.UUID        `json:"roomId"`
+	Host         string           `json:"host"`
+	Start        time.Time        `json:"start" db:"start_at"`
+	End          time.Time        `json:"end" db:"end_at"`
+	Attendees    int              `json:"attendees"`
+	CheckedIn    bool             `json:"checkedIn"`
+	CancelledAt  *time.Time       `json:"cancelledAt,omitempty"`
+	CancelReason *string          `json:"cancelReason,omitempty"`
+	OccurredAt   time.Time        `json:"occurredAt"`
+}
+
+var logged = map[outbox.EventType]bool{
+	outbox.BookingCreated:     true,
+	outbox.BookingUpdated:     true,
+	outbox.BookingCancelled:   true,
+	outbox.BookingCheckedIn:   true,
+	outbox.BookingConfirmed:   true,
+	outbox.BookingHoldExpired: true,
+}
+
+// NewReservation reads booking events, ok is false for other events
+func NewReservation(event *outbox.Event) (reservation Reservation, ok bool, err error) {
+	if !logged[event.Type] {
+		return reservation, false, nil
+	}
+	// a booking has id, a cancelled booking or an expired hold has bookingId and reason
+	var payload struct {
+		Id           uuid.UUID  `json:"id"`
+		BookingId    uuid.UUID  `json:"bookingId"`
+		RoomId       uuid.UUID  `json:"roomId"`
+		Host         string     `json:"host"`
+		Start        time.Time  `json:"start"`
+		End          time.Time  `json:"end"`
+		Attendees    []string   `json:"attendees"`
+		CheckedInAt  *time.Time `json:"checkedInAt"`
+		CancelledAt  *time.Time `json:"cancelledAt"`
+		CancelReason *string    `json:"cancelReason"`
+		Reason       *string    `json:"reason"`
+	}
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return reservation, false, fmt.Errorf("can't read %v event %v: %w", event.Type, event.Id, err)
+	}
+	reservation = Reservation{
+		EventId:      event.Id,
+		Type:         event.Type,
+		BookingId:    payload.Id,
+		RoomId:       payload.RoomId,
+		Host:         payload.Host,
+		Start:        payload.Start,
+		End:          payload.End,
+		Attendees:    len(payload.Attendees),
+		CheckedIn:    payload.CheckedInAt != nil,
+		CancelledAt:  payload.CancelledAt,
+		CancelReason: payload.CancelReason,
+		OccurredAt:   event.OccurredAt,
+	}
+	if event.Type == outbox.BookingCancelled || event.Type == outbox.BookingHoldExpired {
+		occurredAt := event.OccurredAt
+		reservation.BookingId = payload.BookingId
+		reservation.CancelledAt = &occurredAt
+		reservation.CancelReason = payload.Reason
+	}
+	return reservation, true, nil
+}
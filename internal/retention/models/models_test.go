@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	outbox "github.com/optician/meeting-room-booking/internal/outbox/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReservationOfCancelledBooking(t *testing.T) {
+	booking := outbox.CancelledBooking{
+		BookingId: uuid.New(),
+		RoomId:    uuid.New(),
+		Host:      "ivan",
+		Attendees: []string{"olga"},
+		Start:     time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC),
+		End:       time.Date(2030, 1, 10, 15, 0, 0, 0, time.UTC),
+		Reason:    "no-show",
+	}
+	payload, _ := json.Marshal(booking)
+	at := time.Date(2030, 1, 10, 14, 10, 0, 0, time.UTC)
+	event := outbox.Event{Id: 7, Type: outbox.BookingCancelled, OccurredAt: at, Payload: payload}
+
+	reservation, ok, err := NewReservation(&event)
+
+	require.Nil(t, err)
+	require.True(t, ok)
+	require.Equal(t, booking.BookingId, reservation.BookingId)
+	require.Equal(t, booking.RoomId, reservation.RoomId)
+	require.True(t, booking.Start.Equal(reservation.Start))
+	require.Equal(t, 1, reservation.Attendees)
+	require.Equal(t, at, *reservation.CancelledAt)
+	require.Equal(t, "no-show", *reservation.CancelReason)
+}
+
+func TestRoomEventsAreNotReservations(t *testing.T) {
+	event := outbox.Event{Id: 7, Type: outbox.RoomCreated, Payload: []byte(`{}`)}
+
+	_, ok, err := NewReservation(&event)
+
+	require.Nil(t, err)
+	require.False(t, ok)
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Archive appends rows to a gzipped JSON Lines file, one row per line
+type Archive struct {
+	Path   string
+	file   *os.File
+	writer *gzip.Writer
+}
+
+// a file per table and run, e.g. bookings-20300110T140000Z.jsonl.gz
+func CreateArchive(dir string, table string, at time.Time) (*Archive, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%v-%v.jsonl.gz", table, at.UTC().Format("20060102T150405Z")))
+	// appending to an existing archive is fine, concatenated gzip streams are a valid gzip file
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &Archive{Path: path, file: file, writer: gzip.NewWriter(file)}, nil
+}
+
+// Write returns when rows are on disk, so they can be deleted from the DB
+func (archive *Archive) Write(rows []json.RawMessage) error {
+	for _, row := range rows {
+		if _, err := archive.writer.Write(row); err != nil {
+			return err
+		}
+		if _, err := archive.writer.Write([]byte("\n")); err != nil {
+			return err
+		}
+	}
+	if err := archive.writer.Flush(); err != nil {
+		return err
+	}
+	return archive.file.Sync()
+}
+
+func (archive *Archive) Close() error {
+	if err := archive.writer.Close(); err != nil {
+		archive.file.Close()
+		return err
+	}
+	return archive.file.Close()
+}
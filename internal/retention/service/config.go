@@ -0,0 +1,14 @@
+package service
+
+import "time"
+
+type Config struct {
+	// how often the worker looks for old rows
+	Interval time.Duration `koanf:"interval"`
+	// the most rows deleted in a transaction
+	BatchSize int `koanf:"batch_size"`
+	// deleted rows are archived to gzipped JSON Lines files in this directory, nothing is archived if it's empty
+	ArchiveDir string `koanf:"archive_dir"`
+	// how long rows of a table are kept, tables missing here are kept forever
+	Keep map[string]time.Duration `koanf:"keep"`
+}
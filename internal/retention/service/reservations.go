@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+
+	outbox "github.com/optician/meeting-room-booking/internal/outbox/models"
+	"github.com/optician/meeting-room-booking/internal/outbox/publisher"
+	"github.com/optician/meeting-room-booking/internal/retention/db"
+	"github.com/optician/meeting-room-booking/internal/retention/models"
+	"go.uber.org/zap"
+)
+
+// ReservationLog is an outbox publisher which keeps booking events in the reservation log
+type ReservationLog struct {
+	logger *zap.SugaredLogger
+	db     *db.DB
+}
+
+func MakeReservationLog(db *db.DB, logger *zap.SugaredLogger) ReservationLog {
+	return ReservationLog{
+		logger: logger,
+		db:     db,
+	}
+}
+
+func (log ReservationLog) Publish(ctx context.Context, events []outbox.Event) error {
+	reservations := publisher.Decode(events, models.NewReservation, log.logger)
+	if len(reservations) == 0 {
+		return nil
+	}
+	return (*log.db).Log(ctx, reservations)
+}
+
+func (log ReservationLog) Close() error {
+	return nil
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/optician/meeting-room-booking/internal/retention/db"
+	"github.com/optician/meeting-room-booking/internal/retention/models"
+	"go.uber.org/zap"
+)
+
+// Worker deletes rows older than their retention period
+type Worker struct {
+	logger     *zap.SugaredLogger
+	db         *db.DB
+	config     *Config
+	retentions []models.Retention
+	now        func() time.Time
+}
+
+// fails on unknown tables in the config
+func MakeWorker(db *db.DB, config *Config, logger *zap.SugaredLogger) (Worker, error) {
+	retentions := make([]models.Retention, 0, len(config.Keep))
+	for table, period := range config.Keep {
+		retention, err := models.ValidateRetention(table, period)
+		if err != nil {
+			return Worker{}, err
+		}
+		retentions = append(retentions, retention)
+	}
+	// stable order of tables in reports
+	sort.Slice(retentions, func(i, j int) bool { return retentions[i].Policy.Table < retentions[j].Policy.Table })
+
+	return Worker{
+		logger:     logger,
+		db:         db,
+		config:     config,
+		retentions: retentions,
+		now:        time.Now,
+	}, nil
+}
+
+// Run blocks until ctx is done
+func (worker Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(worker.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := worker.RunOnce(ctx)
+			for _, table := range report.Tables {
+				if table.Removed > 0 {
+					worker.logger.Infof("retention removed %d rows from %v, archive: %q", table.Removed, table.Table, table.Archive)
+				}
+			}
+			if err != nil {
+				worker.logger.Errorf("retention failed: %v", err)
+			}
+		}
+	}
+}
+
+// RunOnce cleans up every table, the report has tables cleaned up before a failure
+func (worker Worker) RunOnce(ctx context.Context) (models.Report, error) {
+	at := worker.now()
+	report := models.Report{At: at, Tables: make([]models.TableReport, 0, len(worker.retentions))}
+	for _, retention := range worker.retentions {
+		table, err := worker.purge(ctx, &retention.Policy, at.Add(-retention.Period), at)
+		report.Tables = append(report.Tables, table)
+		if err != nil {
+			return report, fmt.Errorf("retention of %v: %w", retention.Policy.Table, err)
+		}
+	}
+	return report, nil
+}
+
+// deletes batches until a short one
+func (worker Worker) purge(ctx context.Context, policy *models.Policy, cutoff time.Time, at time.Time) (report models.TableReport, err error) {
+	report.Table = policy.Table
+	var archive *Archive
+	defer func() {
+		if archive == nil {
+			return
+		}
+		if closeErr := archive.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	var write func([]json.RawMessage) error
+	if worker.config.ArchiveDir != "" {
+		// the file is created with the first deleted rows
+		write = func(rows []json.RawMessage) error {
+			if archive == nil {
+				created, err := CreateArchive(worker.config.ArchiveDir, policy.Table, at)
+				if err != nil {
+					return err
+				}
+				archive = created
+				report.Archive = created.Path
+			}
+			return archive.Write(rows)
+		}
+	}
+
+	for {
+		removed, err := (*worker.db).Purge(ctx, policy, cutoff, worker.config.BatchSize, write)
+		report.Removed += removed
+		if err != nil || removed < worker.config.BatchSize {
+			return report, err
+		}
+	}
+}
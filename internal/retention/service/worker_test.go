@@ -0,0 +1,106 @@
+package service
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/optician/meeting-room-booking/internal/retention/db"
+	"github.com/optician/meeting-room-booking/internal/retention/models"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// overrides only methods used by a test, others panic
+type dbStub struct {
+	db.DB
+	// rows left per table, every row is older than any cutoff
+	rows    map[string]int
+	cutoffs map[string]time.Time
+	err     error
+}
+
+func (stub *dbStub) Purge(ctx context.Context, policy *models.Policy, cutoff time.Time, limit int, archive func([]json.RawMessage) error) (int, error) {
+	if stub.err != nil {
+		return 0, stub.err
+	}
+	stub.cutoffs[policy.Table] = cutoff
+	batch := make([]json.RawMessage, min(limit, stub.rows[policy.Table]))
+	for i := range batch {
+		batch[i] = json.RawMessage(`{"table":"` + policy.Table + `"}`)
+	}
+	if archive != nil && len(batch) > 0 {
+		if err := archive(batch); err != nil {
+			return 0, err
+		}
+	}
+	stub.rows[policy.Table] -= len(batch)
+	return len(batch), nil
+}
+
+func makeWorker(t *testing.T, stub *dbStub, config *Config) Worker {
+	var repository db.DB = stub
+	worker, err := MakeWorker(&repository, config, zap.NewExample().Sugar())
+	require.Nil(t, err)
+	return worker
+}
+
+func TestRunOnceDeletesInBatches(t *testing.T) {
+	stub := &dbStub{rows: map[string]int{"bookings": 5, "outbox": 0, "notifications": 7}, cutoffs: map[string]time.Time{}}
+	config := Config{BatchSize: 2, Keep: map[string]time.Duration{"outbox": time.Hour, "bookings": 24 * time.Hour}}
+	worker := makeWorker(t, stub, &config)
+	now := time.Date(2030, 1, 10, 14, 0, 0, 0, time.UTC)
+	worker.now = func() time.Time { return now }
+
+	report, err := worker.RunOnce(context.Background())
+
+	require.Nil(t, err)
+	require.Equal(t, []models.TableReport{{Table: "bookings", Removed: 5}, {Table: "outbox", Removed: 0}}, report.Tables)
+	require.Equal(t, 5, report.Removed())
+	require.Equal(t, now.Add(-24*time.Hour), stub.cutoffs["bookings"])
+	require.Equal(t, 7, stub.rows["notifications"], "tables without retention are kept")
+}
+
+func TestRunOnceArchivesDeletedRows(t *testing.T) {
+	stub := &dbStub{rows: map[string]int{"bookings": 3, "outbox": 0}, cutoffs: map[string]time.Time{}}
+	config := Config{BatchSize: 2, ArchiveDir: t.TempDir(), Keep: map[string]time.Duration{"bookings": time.Hour, "outbox": time.Hour}}
+	worker := makeWorker(t, stub, &config)
+
+	report, err := worker.RunOnce(context.Background())
+
+	require.Nil(t, err)
+	require.Empty(t, report.Tables[1].Archive, "nothing is archived if nothing is removed")
+	file, err := os.Open(report.Tables[0].Archive)
+	require.Nil(t, err)
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	require.Nil(t, err)
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	require.Nil(t, scanner.Err())
+	require.Equal(t, []string{`{"table":"bookings"}`, `{"table":"bookings"}`, `{"table":"bookings"}`}, lines)
+}
+
+func TestRunOnceReportsFailedTable(t *testing.T) {
+	stub := &dbStub{err: errors.New("connection refused")}
+	worker := makeWorker(t, stub, &Config{BatchSize: 2, Keep: map[string]time.Duration{"bookings": time.Hour}})
+
+	_, err := worker.RunOnce(context.Background())
+
+	require.ErrorContains(t, err, "bookings")
+}
+
+func TestUnknownTableIsRejected(t *testing.T) {
+	var repository db.DB = &dbStub{}
+	_, err := MakeWorker(&repository, &Config{Keep: map[string]time.Duration{"meeting_rooms": time.Hour}}, zap.NewExample().Sugar())
+
+	require.NotNil(t, err)
+}
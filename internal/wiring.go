@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"os"
 	"time"
 
@@ -11,7 +12,27 @@ import (
 	"github.com/optician/meeting-room-booking/internal/administration/db"
 	"github.com/optician/meeting-room-booking/internal/administration/httpapi"
 	"github.com/optician/meeting-room-booking/internal/administration/service"
+	analyticsDB "github.com/optician/meeting-room-booking/internal/analytics/db"
+	analyticsHttpApi "github.com/optician/meeting-room-booking/internal/analytics/httpapi"
+	analyticsService "github.com/optician/meeting-room-booking/internal/analytics/service"
+	bookingDB "github.com/optician/meeting-room-booking/internal/booking/db"
+	bookingHttpApi "github.com/optician/meeting-room-booking/internal/booking/httpapi"
+	bookingService "github.com/optician/meeting-room-booking/internal/booking/service"
 	"github.com/optician/meeting-room-booking/internal/dbPool"
+	displayDB "github.com/optician/meeting-room-booking/internal/display/db"
+	displayHttpApi "github.com/optician/meeting-room-booking/internal/display/httpapi"
+	displayImage "github.com/optician/meeting-room-booking/internal/display/image"
+	displayService "github.com/optician/meeting-room-booking/internal/display/service"
+	notificationChannel "github.com/optician/meeting-room-booking/internal/notification/channel"
+	notificationDB "github.com/optician/meeting-room-booking/internal/notification/db"
+	notificationHttpApi "github.com/optician/meeting-room-booking/internal/notification/httpapi"
+	notificationModels "github.com/optician/meeting-room-booking/internal/notification/models"
+	notificationService "github.com/optician/meeting-room-booking/internal/notification/service"
+	outboxDB "github.com/optician/meeting-room-booking/internal/outbox/db"
+	outboxPublisher "github.com/optician/meeting-room-booking/internal/outbox/publisher"
+	outboxService "github.com/optician/meeting-room-booking/internal/outbox/service"
+	retentionDB "github.com/optician/meeting-room-booking/internal/retention/db"
+	retentionService "github.com/optician/meeting-room-booking/internal/retention/service"
 	"go.uber.org/zap"
 )
 
@@ -26,6 +47,60 @@ func Make(httpLogger *httplog.Logger, logger *zap.SugaredLogger, config *Config)
 	roomsDB := db.New(dbPool.GetPool(), logger)
 	adminLogic := service.Make(&roomsDB, logger)
 
+	bookingsDB := bookingDB.New(dbPool.GetPool(), logger)
+	bookingLogic := bookingService.Make(&bookingsDB, logger)
+	noShowReleaser := bookingService.MakeNoShowReleaser(&bookingsDB, &config.Booking, logger)
+	go noShowReleaser.Run(context.Background()) // lives as long as the application
+	holdSweeper := bookingService.MakeHoldSweeper(&bookingsDB, &config.Booking, logger)
+	go holdSweeper.Run(context.Background()) // lives as long as the application
+
+	publisher, publisherErr := outboxPublisher.New(&config.Outbox.Publisher, logger)
+	if publisherErr != nil {
+		logger.Fatalf("application terminated: %v", publisherErr)
+		os.Exit(-1)
+	}
+	notificationsDB := notificationDB.New(dbPool.GetPool(), logger)
+	notificationLogic := notificationService.Make(&notificationsDB, &config.Notification, logger)
+	notifier := notificationService.MakeNotifier(&notificationsDB, logger)
+	channels := map[notificationModels.ChannelName]notificationChannel.Channel{
+		notificationModels.ChannelLog:     notificationChannel.NewLog(logger),
+		notificationModels.ChannelWebhook: notificationChannel.NewWebhook(config.Notification.WebhookTimeout, config.Notification.WebhookHosts),
+		notificationModels.ChannelEmail:   notificationChannel.NewEmail(config.Notification.Smtp.Addr, config.Notification.Smtp.From, nil, config.Notification.Smtp.Timeout),
+	}
+	dispatcher := notificationService.MakeDispatcher(&notificationsDB, channels, &config.Notification, logger)
+	go dispatcher.Run(context.Background()) // lives as long as the application
+
+	displaysDB := displayDB.New(dbPool.GetPool(), logger)
+	// an offline generator, an AI one only has to implement image.ImageGenerator
+	displayLogic := displayService.Make(&displaysDB, displayImage.Pattern{Size: 256}, &config.Display, logger)
+	hub := displayService.NewHub()
+
+	reportsDB := analyticsDB.New(dbPool.GetPool(), logger)
+	analyticsLogic := analyticsService.Make(&reportsDB, &config.Analytics, logger)
+
+	retentionsDB := retentionDB.New(dbPool.GetPool(), logger)
+	reservationLog := retentionService.MakeReservationLog(&retentionsDB, logger)
+	retentionWorker, retentionErr := retentionService.MakeWorker(&retentionsDB, &config.Retention, logger)
+	if retentionErr != nil {
+		logger.Fatalf("application terminated: %v", retentionErr)
+		os.Exit(-1)
+	}
+	go retentionWorker.Run(context.Background()) // lives as long as the application
+
+	offerer := bookingService.MakeWaitlistOfferer(&bookingsDB, &config.Booking, logger)
+
+	outboxDB := outboxDB.New(dbPool.GetPool(), logger)
+	consumers := map[string]outboxPublisher.Publisher{
+		"publisher":       publisher,
+		"notifier":        notifier,
+		"displays":        hub,
+		"reservation_log": reservationLog,
+		"waitlist":        offerer,
+	}
+	for _, relay := range outboxService.MakeRelays(&outboxDB, consumers, &config.Outbox, logger) {
+		go relay.Run(context.Background()) // lives as long as the application
+	}
+
 	r := chi.NewRouter()
 
 	corsOptions := cors.Options{
@@ -44,11 +119,21 @@ func Make(httpLogger *httplog.Logger, logger *zap.SugaredLogger, config *Config)
 		middleware.CleanPath,
 		middleware.ContentCharset(allowedCharsets...),
 		cors.Handler(corsOptions),
-		middleware.Timeout(20*time.Second),
 		middleware.Recoverer,
 	)
+	// unknown routes and methods are answered with problem details like errors of the api
+	r.NotFound(httpapi.NotFound)
+	r.MethodNotAllowed(httpapi.MethodNotAllowed)
 
-	r.Group(httpapi.Make(&adminLogic, logger))
+	// display streams live longer than any request timeout
+	r.Group(displayHttpApi.Make(&displayLogic, hub, logger))
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(20 * time.Second))
+		r.Group(httpapi.Make(&adminLogic, logger))
+		r.Group(bookingHttpApi.Make(&bookingLogic, logger))
+		r.Group(notificationHttpApi.Make(&notificationLogic, logger))
+		r.Group(analyticsHttpApi.Make(&analyticsLogic, logger))
+	})
 
 	return r
 }
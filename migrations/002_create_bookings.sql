@@ -0,0 +1,19 @@
+-- btree_gist is required to mix uuid equality and range overlap in one exclusion constraint
+create extension if not exists btree_gist;
+
+create table bookings
+(
+	id uuid primary key,
+	room_id uuid not null references meeting_rooms (id) on delete cascade,
+	host text not null,
+	start_at timestamptz not null,
+	end_at timestamptz not null,
+	attendees text[] not null default '{}',
+	agenda text not null default '',
+	booked_at timestamptz not null default now(),
+	constraint bookings_interval_check check (start_at < end_at),
+	constraint bookings_no_overlap exclude using gist (
+		room_id with =,
+		tstzrange(start_at, end_at) with &&
+	)
+);
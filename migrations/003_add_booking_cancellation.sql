@@ -0,0 +1,11 @@
+alter table bookings
+	add column cancelled_at timestamptz,
+	add column cancelled_by text,
+	add column cancel_reason text;
+
+-- cancelled bookings stay for history but must not block their slot
+alter table bookings drop constraint bookings_no_overlap;
+alter table bookings add constraint bookings_no_overlap exclude using gist (
+	room_id with =,
+	tstzrange(start_at, end_at) with &&
+) where (cancelled_at is null);
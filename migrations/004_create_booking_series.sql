@@ -0,0 +1,17 @@
+-- a series is a recurrence rule, its occurrences are materialized into bookings
+create table booking_series
+(
+	id uuid primary key,
+	room_id uuid not null references meeting_rooms (id) on delete cascade,
+	host text not null,
+	rrule text not null,
+	timezone text not null,
+	start_at timestamptz not null,
+	end_at timestamptz not null,
+	exdates timestamptz[] not null default '{}',
+	created_at timestamptz not null default now()
+);
+
+alter table bookings add column series_id uuid references booking_series (id) on delete cascade;
+
+create index bookings_series_idx on bookings (series_id, start_at);
@@ -0,0 +1,18 @@
+alter table bookings
+	add column checked_in_at timestamptz,
+	add column checked_in_by text;
+
+-- history of booking lifecycle events
+create table booking_events
+(
+	id bigserial primary key,
+	booking_id uuid not null references bookings (id) on delete cascade,
+	type text not null,
+	actor text not null,
+	occurred_at timestamptz not null
+);
+
+create index booking_events_booking_idx on booking_events (booking_id, occurred_at);
+
+-- the no-show releaser looks for started bookings nobody checked in
+create index bookings_unconfirmed_idx on bookings (start_at) where cancelled_at is null and checked_in_at is null;
@@ -0,0 +1,12 @@
+-- hosts without a row have priority 0
+create table host_priorities
+(
+	host text primary key,
+	priority int not null check (priority >= 0)
+);
+
+-- priority of the host at the moment of booking
+alter table bookings add column host_priority int not null default 0;
+
+-- e.g. the booking which preempted this one
+alter table booking_events add column related_booking_id uuid;
@@ -0,0 +1,16 @@
+-- every lock is kept as a history entry, an open lock has no unlocked_at
+create table room_locks
+(
+	id uuid primary key,
+	room_id uuid not null references meeting_rooms (id) on delete cascade,
+	reason text not null,
+	locked_by text not null,
+	locked_at timestamptz not null,
+	until timestamptz,
+	unlocked_at timestamptz,
+	unlocked_by text,
+	constraint room_locks_interval_check check (until is null or locked_at < until)
+);
+
+create unique index room_locks_open_idx on room_locks (room_id) where unlocked_at is null;
+create index room_locks_room_idx on room_locks (room_id, locked_at);
@@ -0,0 +1,12 @@
+-- domain events are written in the same transaction as the change and published by the relay
+create table outbox
+(
+	id bigserial primary key,
+	type text not null,
+	aggregate_id text not null,
+	payload jsonb not null,
+	occurred_at timestamptz not null,
+	published_at timestamptz
+);
+
+create index outbox_unpublished_idx on outbox (id) where published_at is null;
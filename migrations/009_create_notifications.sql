@@ -0,0 +1,31 @@
+-- people without preferences are notified through the log channel
+create table notification_preferences
+(
+	person text primary key,
+	channels text[] not null,
+	email text,
+	webhook_url text
+);
+
+-- the delivery log, a row notifies a person through a channel about an outbox event.
+-- There is no foreign key to the outbox, published events can be cleaned up.
+create table notifications
+(
+	id bigserial primary key,
+	event_id bigint not null,
+	person text not null,
+	channel text not null,
+	address text not null default '',
+	subject text not null,
+	body text not null,
+	status text not null default 'pending',
+	attempts int not null default 0,
+	next_attempt_at timestamptz not null,
+	last_error text,
+	created_at timestamptz not null,
+	sent_at timestamptz,
+	constraint notifications_once unique (event_id, person, channel)
+);
+
+create index notifications_due_idx on notifications (next_attempt_at) where status = 'pending';
+create index notifications_person_idx on notifications (person, created_at);
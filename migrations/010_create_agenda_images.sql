@@ -0,0 +1,12 @@
+-- generated images of meeting agendas, hash is sha256 of the agenda in hex
+create table agenda_images
+(
+	room_id uuid not null references meeting_rooms (id) on delete cascade,
+	hash text not null,
+	status text not null,
+	png bytea,
+	error text,
+	requested_at timestamptz not null,
+	generated_at timestamptz,
+	primary key (room_id, hash)
+);
@@ -0,0 +1,23 @@
+-- reservation history outliving bookings, which are deleted by the retention worker.
+-- no foreign keys: rows stay after their booking or room is gone
+create table reservation_log
+(
+	id bigserial primary key,
+	event_id bigint not null unique,
+	type text not null,
+	booking_id uuid not null,
+	room_id uuid not null,
+	host text not null,
+	start_at timestamptz not null,
+	end_at timestamptz not null,
+	occurred_at timestamptz not null
+);
+
+create index reservation_log_room_idx on reservation_log (room_id, start_at);
+create index reservation_log_occurred_idx on reservation_log (occurred_at);
+
+-- the retention worker looks for old rows by these columns
+create index bookings_end_idx on bookings (end_at);
+create index booking_events_occurred_idx on booking_events (occurred_at);
+create index outbox_published_idx on outbox (published_at) where published_at is not null;
+create index notifications_created_idx on notifications (created_at);
@@ -0,0 +1,30 @@
+-- the latest state of every booking with a snapshot of its room for analytics.
+-- no foreign keys: the history outlives bookings and rooms
+create table reservation_history
+(
+	booking_id uuid primary key,
+	room_id uuid not null,
+	room_name text not null,
+	office text not null,
+	capacity int not null,
+	host text not null,
+	start_at timestamptz not null,
+	end_at timestamptz not null,
+	attendees int not null,
+	checked_in boolean not null,
+	cancelled_at timestamptz,
+	cancel_reason text,
+	-- the outbox event of the state, older events are ignored
+	last_event_id bigint not null
+);
+
+create index reservation_history_start_idx on reservation_history (start_at);
+create index reservation_history_office_idx on reservation_history (office, start_at);
+create index reservation_history_end_idx on reservation_history (end_at);
+
+insert into reservation_history
+	(booking_id, room_id, room_name, office, capacity, host, start_at, end_at, attendees, checked_in, cancelled_at, cancel_reason, last_event_id)
+select b.id, b.room_id, coalesce(r.name, ''), coalesce(r.office, ''), coalesce(r.capacity, 0), b.host, b.start_at, b.end_at,
+	cardinality(b.attendees), b.checked_in_at is not null, b.cancelled_at, b.cancel_reason, 0
+from bookings b
+join meeting_rooms r on r.id = b.room_id;
@@ -0,0 +1,4 @@
+-- a modification event keeps the booking before and after the change
+alter table booking_events
+	add column before jsonb,
+	add column after jsonb;
@@ -0,0 +1,4 @@
+-- a tentative booking blocks its slot until it's confirmed or expires
+alter table bookings add column hold_expires_at timestamptz;
+
+create index bookings_hold_idx on bookings (hold_expires_at) where hold_expires_at is not null and cancelled_at is null;
@@ -0,0 +1,24 @@
+-- people queued for a slot of a room, or of any room matching criteria.
+-- A freed slot goes to the first matching entry as a hold or a booking.
+create table waitlist
+(
+	id uuid primary key,
+	person text not null,
+	room_id uuid references meeting_rooms (id) on delete cascade,
+	criteria jsonb,
+	start_at timestamptz not null,
+	end_at timestamptz not null,
+	attendees text[] not null default '{}',
+	agenda text not null default '',
+	auto_assign boolean not null default false,
+	status text not null default 'waiting',
+	created_at timestamptz not null,
+	booking_id uuid references bookings (id) on delete set null,
+	offer_expires_at timestamptz,
+	constraint waitlist_interval_check check (start_at < end_at),
+	constraint waitlist_target_check check ((room_id is null) <> (criteria is null))
+);
+
+create index waitlist_waiting_idx on waitlist (created_at) where status = 'waiting';
+create index waitlist_person_idx on waitlist (person, created_at);
+create index waitlist_booking_idx on waitlist (booking_id);
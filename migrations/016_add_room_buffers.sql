@@ -0,0 +1,5 @@
+-- minutes a room needs before a meeting for setup and after it for cleaning and AV reset
+alter table meeting_rooms
+	add column buffer_before int not null default 0,
+	add column buffer_after int not null default 0,
+	add constraint meeting_rooms_buffers_check check (buffer_before >= 0 and buffer_after >= 0);
@@ -0,0 +1,20 @@
+-- rooms refer to offices by name, so the office of a room stays a readable string in every module
+create table offices
+(
+	id uuid primary key,
+	name text not null unique,
+	address text not null default '',
+	timezone text not null,
+	opening_hours jsonb not null default '[]',
+	floors int[] not null
+);
+
+-- offices of existing rooms are created in UTC with floors of their rooms, opening hours are left to admins
+insert into offices (id, name, timezone, floors)
+select gen_random_uuid(), office, 'UTC', array_agg(distinct stage)
+from meeting_rooms
+where office is not null
+group by office;
+
+alter table meeting_rooms
+	add constraint meeting_rooms_office_fkey foreign key (office) references offices (name) on update cascade;
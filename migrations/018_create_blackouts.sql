@@ -0,0 +1,16 @@
+-- a blackout closes either a whole office or a single room, a yearly one repeats on the same dates
+create table blackouts
+(
+	id uuid primary key,
+	office_id uuid references offices (id) on delete cascade,
+	room_id uuid references meeting_rooms (id) on delete cascade,
+	reason text not null default '',
+	start_at timestamptz not null,
+	end_at timestamptz not null,
+	yearly boolean not null default false,
+	constraint blackouts_target_check check ((office_id is null) <> (room_id is null)),
+	constraint blackouts_interval_check check (start_at < end_at)
+);
+
+create index blackouts_office_id_idx on blackouts (office_id);
+create index blackouts_room_id_idx on blackouts (room_id);
@@ -0,0 +1,2 @@
+-- rooms are filtered by labels with @> (all of) and && (any of)
+create index meeting_rooms_labels_idx on meeting_rooms using gin (labels);
@@ -0,0 +1,3 @@
+-- optimistic concurrency, an update or a deletion of a room must name the version it was made against
+alter table meeting_rooms
+	add column version bigint not null default 1;
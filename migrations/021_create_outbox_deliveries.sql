@@ -0,0 +1,12 @@
+-- every consumer of the outbox is relayed apart, so a failing consumer doesn't hold up the others.
+-- An event delivered to every consumer is marked published in the outbox.
+create table outbox_deliveries
+(
+	consumer text not null,
+	event_id bigint not null references outbox (id) on delete cascade,
+	-- the relay which took the event owns it until then
+	leased_until timestamptz not null,
+	delivered_at timestamptz,
+	primary key (consumer, event_id)
+);
+
@@ -0,0 +1,7 @@
+-- a series moved by its interval or more overlaps its own occurrences until all of them are moved,
+-- such an update defers the check to its end
+alter table bookings drop constraint bookings_no_overlap;
+alter table bookings add constraint bookings_no_overlap exclude using gist (
+	room_id with =,
+	tstzrange(start_at, end_at) with &&
+) where (cancelled_at is null) deferrable initially immediate;
@@ -0,0 +1,51 @@
+-- reservation history for analytics is read from the reservation log instead of a table of its own,
+-- so the log keeps what analytics needs of every event. The room snapshot is taken while the room exists.
+alter table reservation_log
+	add column room_name text,
+	add column office text,
+	add column capacity int,
+	add column attendees int not null default 0,
+	add column checked_in boolean not null default false,
+	add column cancelled_at timestamptz,
+	add column cancel_reason text;
+
+update reservation_log l
+set room_name = h.room_name,
+	office = h.office,
+	capacity = h.capacity,
+	attendees = h.attendees,
+	checked_in = h.checked_in,
+	cancelled_at = case when l.type in ('booking_cancelled', 'booking_hold_expired') then h.cancelled_at end,
+	cancel_reason = case when l.type in ('booking_cancelled', 'booking_hold_expired') then h.cancel_reason end
+from reservation_history h
+where h.booking_id = l.booking_id;
+
+-- bookings older than the log are only in the history, they are logged under negative event ids
+-- which outbox events never have
+insert into reservation_log
+	(event_id, type, booking_id, room_id, host, start_at, end_at, occurred_at,
+	 room_name, office, capacity, attendees, checked_in, cancelled_at, cancel_reason)
+select -row_number() over (order by h.start_at, h.booking_id),
+	case when h.cancelled_at is null then 'booking_created' else 'booking_cancelled' end,
+	h.booking_id, h.room_id, h.host, h.start_at, h.end_at, coalesce(h.cancelled_at, h.start_at),
+	h.room_name, h.office, h.capacity, h.attendees, h.checked_in, h.cancelled_at, h.cancel_reason
+from reservation_history h
+where not exists (select from reservation_log l where l.booking_id = h.booking_id);
+
+drop table reservation_history;
+
+create index reservation_log_booking_idx on reservation_log (booking_id, event_id);
+create index reservation_log_start_idx on reservation_log (start_at);
+
+-- analytics isn't an outbox consumer anymore
+delete from outbox_deliveries where consumer = 'analytics';
+
+-- events which only analytics was missing are delivered to every remaining consumer,
+-- the relay won't take them again to mark them published
+update outbox o
+set published_at = now()
+where o.published_at is null
+  and (select count(*) from outbox_deliveries d
+		where d.event_id = o.id and d.delivered_at is not null
+		  and d.consumer = any(array['publisher', 'notifier', 'displays', 'reservation_log', 'waitlist'])
+	) = 5;